@@ -2,27 +2,35 @@ package multichain_sync
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 	"time"
 
 	multichain_transaction_syncs "github.com/CavnHan/multichain-sync-account"
 
 	"github.com/urfave/cli/v2"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"gopkg.in/yaml.v3"
 
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 
+	"github.com/CavnHan/multichain-sync-account/coldbundle"
 	"github.com/CavnHan/multichain-sync-account/common/cliapp"
+	"github.com/CavnHan/multichain-sync-account/common/clock"
 	"github.com/CavnHan/multichain-sync-account/common/opio"
 	"github.com/CavnHan/multichain-sync-account/config"
 	"github.com/CavnHan/multichain-sync-account/database"
 	flags2 "github.com/CavnHan/multichain-sync-account/flags"
+	"github.com/CavnHan/multichain-sync-account/leader"
 	"github.com/CavnHan/multichain-sync-account/notifier"
-	"github.com/CavnHan/multichain-sync-account/rpcclient"
-	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+	dal_wallet_go "github.com/CavnHan/multichain-sync-account/protobuf/dal-wallet-go"
 	"github.com/CavnHan/multichain-sync-account/services"
+	"github.com/CavnHan/multichain-sync-account/signer"
+	"github.com/CavnHan/multichain-sync-account/worker"
 )
 
 const (
@@ -49,66 +57,274 @@ func runRpc(ctx *cli.Context, shutdown context.CancelCauseFunc) (cliapp.Lifecycl
 		return nil, err
 	}
 	grpcServerCfg := &services.BusinessMiddleConfig{
-		GrpcHostname: cfg.RpcServer.Host,
-		GrpcPort:     cfg.RpcServer.Port,
+		GrpcHostname:           cfg.RpcServer.Host,
+		GrpcPort:               cfg.RpcServer.Port,
+		HttpHostname:           cfg.HttpServer.Host,
+		HttpPort:               cfg.HttpServer.Port,
+		ConsumerTokens:         cfg.ConsumerTokens,
+		SignerTokens:           cfg.SignerTokens,
+		SigningJobTimeout:      cfg.SigningJobTimeout,
+		Confirmations:          cfg.ChainNode.Confirmations,
+		ChainName:              cfg.ChainNode.ChainName,
+		ChainId:                cfg.ChainNode.ChainId,
+		RpcUrl:                 cfg.ChainNode.RpcUrl,
+		PoolSize:               cfg.AddressPoolSize,
+		MultisendContract:      cfg.MultisendContract,
+		EventVisibilityTimeout: cfg.EventVisibilityTimeout,
+		FeePolicy:              cfg.ChainNode.FeePolicy,
+		Tokens:                 cfg.ChainNode.Tokens,
 	}
-	db, err := database.NewDB(ctx.Context, cfg.MasterDB)
+	if cfg.LocalSignerKeystore != "" {
+		grpcServerCfg.LocalSigner, err = signer.NewKeystoreSigner(cfg.LocalSignerKeystore, cfg.LocalSignerPasswordFile, cfg.ChainNode.ChainId)
+		if err != nil {
+			log.Error("failed to load local signer keystore", "err", err)
+			return nil, err
+		}
+	}
+	db, err := database.NewDB(ctx.Context, &cfg)
 	if err != nil {
 		log.Error("failed to connect to database", "err", err)
 		return nil, err
 	}
 
 	log.Info("Chain account rpc", "rpc uri", cfg.ChainAccountRpc)
-	// conn, err := grpc.NewClient(cfg.ChainAccountRpc, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	conn, err := grpc.Dial(cfg.ChainAccountRpc, grpc.WithTransportCredentials(insecure.NewCredentials()))
-
+	// 与 sync 同时录制时写入单独的文件，避免两个进程写同一个 gzip 文件
+	accountCfg := cfg
+	accountCfg.ChainAccountRecord = cfg.ChainAccountRecordFile("rpc")
+	accountClient, closeAccount, err := worker.NewAccountClient(&accountCfg)
 	if err != nil {
-		log.Error("Connect to da retriever fail", "err", err)
+		log.Error("new wallet account client fail", "err", err)
 		return nil, err
 	}
-	//defer func(conn *grpc.ClientConn) {
-	//	err := conn.Close()
-	//	if err != nil {
-	//		return
-	//	}
-	//}(conn)
-	// 	client := account.NewWalletAccountServiceClient(conn)
-	// 	accountClient, err := rpcclient.NewWalletChainAccountClient(context.Background(), client, "Ethereum")
-	// 	if err != nil {
-	// 		log.Error("new wallet account client fail", "err", err)
-	// 		return nil, err
-	// 	}
-	// 	return services.NewBusinessMiddleWireServices(db, grpcServerCfg, accountClient)
-	// }
-	client := account.NewWalletAccountServiceClient(conn)
-	accountClient, err := rpcclient.NewWalletChainAccountClient(context.Background(), client, "Ethereum")
+	bws, err := services.NewBusinessMiddleWireServices(db, grpcServerCfg, accountClient)
 	if err != nil {
-		log.Error("new wallet account client fail", "err", err)
+		closeAccount()
 		return nil, err
 	}
-	return services.NewBusinessMiddleWireServices(db, grpcServerCfg, accountClient)
+	return &rpcService{BusinessMiddleWireServices: bws, closeAccount: closeAccount}, nil
 }
 
-func runMigrations(ctx *cli.Context) error {
+// rpcService 服务停止后关闭 chain-account 连接，同时停止节点池的链头比对协程，录制文件在关闭时才写完整
+type rpcService struct {
+	*services.BusinessMiddleWireServices
+	closeAccount func() error
+}
+
+// Start 启动失败时不会再调用 Stop，在这里释放节点池
+func (s *rpcService) Start(ctx context.Context) error {
+	if err := s.BusinessMiddleWireServices.Start(ctx); err != nil {
+		if closeErr := s.closeAccount(); closeErr != nil {
+			log.Error("close chain account client fail", "err", closeErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *rpcService) Stop(ctx context.Context) error {
+	err := s.BusinessMiddleWireServices.Stop(ctx)
+	if closeErr := s.closeAccount(); closeErr != nil {
+		err = errors.Join(err, fmt.Errorf("failed to close chain account client: %w", closeErr))
+	}
+	return err
+}
+
+func runRescan(ctx *cli.Context) error {
 	ctx.Context = opio.CancelOnInterrupt(ctx.Context)
-	log.Info("running migrations...")
 	cfg, err := config.LoadConfig(ctx)
 	if err != nil {
 		log.Error("failed to load config", "err", err)
 		return err
 	}
-	db, err := database.NewDB(ctx.Context, cfg.MasterDB)
+	db, err := database.NewDB(ctx.Context, &cfg)
 	if err != nil {
 		log.Error("failed to connect to database", "err", err)
 		return err
 	}
-	defer func(db *database.DB) {
-		err := db.Close()
+	defer closeDB(db)
+
+	accountClient, closeAccount, err := worker.NewAccountClient(&cfg)
+	if err != nil {
+		log.Error("new wallet account client fail", "err", err)
+		return err
+	}
+	defer closeAccount()
+
+	rescanner := worker.NewRescanner(accountClient, cfg.ChainNode.RpcUrl, db, uint8(cfg.ChainNode.Confirmations))
+	report, rescanErr := rescanner.Rescan(ctx.Context, ctx.String(flags2.RescanBusinessFlag.Name), ctx.Uint64(flags2.RescanFromFlag.Name), ctx.Uint64(flags2.RescanToFlag.Name))
+	if report != nil {
+		out, err := json.MarshalIndent(report, "", "  ")
 		if err != nil {
-			log.Error("fail to close database", "err", err)
+			return err
 		}
-	}(db)
-	return db.ExecuteSQLMigration(cfg.Migrations)
+		fmt.Println(string(out))
+	}
+	return rescanErr
+}
+
+// newColdBundleServices 离线签名命令直接调用服务的导出和导入逻辑，不启动 rpc 服务
+func newColdBundleServices(ctx *cli.Context) (*services.BusinessMiddleWireServices, func(), error) {
+	cfg, err := config.LoadConfig(ctx)
+	if err != nil {
+		log.Error("failed to load config", "err", err)
+		return nil, nil, err
+	}
+	db, err := database.NewDB(ctx.Context, &cfg)
+	if err != nil {
+		log.Error("failed to connect to database", "err", err)
+		return nil, nil, err
+	}
+	accountClient, closeAccount, err := worker.NewAccountClient(&cfg)
+	if err != nil {
+		log.Error("new wallet account client fail", "err", err)
+		closeDB(db)
+		return nil, nil, err
+	}
+	bws, err := services.NewBusinessMiddleWireServices(db, &services.BusinessMiddleConfig{
+		ChainName: cfg.ChainNode.ChainName,
+		ChainId:   cfg.ChainNode.ChainId,
+		FeePolicy: cfg.ChainNode.FeePolicy,
+	}, accountClient)
+	if err != nil {
+		closeAccount()
+		closeDB(db)
+		return nil, nil, err
+	}
+	return bws, func() {
+		closeAccount()
+		closeDB(db)
+	}, nil
+}
+
+func runColdBundleExport(ctx *cli.Context) error {
+	ctx.Context = opio.CancelOnInterrupt(ctx.Context)
+	bws, closeAll, err := newColdBundleServices(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+	resp, err := bws.ExportColdBundle(ctx.Context, &dal_wallet_go.ExportColdBundleRequest{
+		RequestId: ctx.String(flags2.ColdBundleBusinessFlag.Name),
+		TxType:    ctx.String(flags2.ColdBundleTxTypeFlag.Name),
+		Limit:     uint32(ctx.Uint(flags2.ColdBundleLimitFlag.Name)),
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Code != dal_wallet_go.ReturnCode_SUCCESS {
+		return errors.New(resp.Msg)
+	}
+	out := resp.Bundle + "\n"
+	if size := ctx.Int(flags2.ColdBundleChunkSizeFlag.Name); size > 0 {
+		out = strings.Join(coldbundle.Split([]byte(resp.Bundle), size), "\n") + "\n"
+	}
+	log.Info("exported cold bundle", "count", resp.Count)
+	if path := ctx.String(flags2.ColdBundleFileFlag.Name); path != "-" {
+		return os.WriteFile(path, []byte(out), 0o600)
+	}
+	_, err = fmt.Print(out)
+	return err
+}
+
+func runColdBundleImport(ctx *cli.Context) error {
+	ctx.Context = opio.CancelOnInterrupt(ctx.Context)
+	var data []byte
+	var err error
+	if path := ctx.String(flags2.ColdBundleFileFlag.Name); path != "-" {
+		data, err = os.ReadFile(path)
+	} else {
+		data, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return err
+	}
+	bws, closeAll, err := newColdBundleServices(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeAll()
+	resp, err := bws.ImportColdBundle(ctx.Context, &dal_wallet_go.ImportColdBundleRequest{
+		RequestId: ctx.String(flags2.ColdBundleBusinessFlag.Name),
+		Bundle:    strings.TrimSpace(string(data)),
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Code != dal_wallet_go.ReturnCode_SUCCESS {
+		return errors.New(resp.Msg)
+	}
+	for _, result := range resp.Results {
+		fmt.Printf("%s  accepted=%t  %s\n", result.TransactionId, result.Accepted, result.Msg)
+	}
+	fmt.Println(resp.Msg)
+	return nil
+}
+
+func newMigrator(ctx *cli.Context) (*database.DB, *database.Migrator, error) {
+	cfg, err := config.LoadConfig(ctx)
+	if err != nil {
+		log.Error("failed to load config", "err", err)
+		return nil, nil, err
+	}
+	db, err := database.NewDB(ctx.Context, &cfg)
+	if err != nil {
+		log.Error("failed to connect to database", "err", err)
+		return nil, nil, err
+	}
+	migrator := db.NewMigrator(cfg.Migrations)
+	migrator.DryRun = ctx.Bool(flags2.MigrateDryRunFlag.Name)
+	return db, migrator, nil
+}
+
+func closeDB(db *database.DB) {
+	if err := db.Close(); err != nil {
+		log.Error("fail to close database", "err", err)
+	}
+}
+
+func runMigrations(ctx *cli.Context) error {
+	ctx.Context = opio.CancelOnInterrupt(ctx.Context)
+	log.Info("running migrations...")
+	db, migrator, err := newMigrator(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeDB(db)
+	return migrator.Up(ctx.Uint64(flags2.MigrateTargetFlag.Name))
+}
+
+func runMigrationsDown(ctx *cli.Context) error {
+	ctx.Context = opio.CancelOnInterrupt(ctx.Context)
+	log.Info("rolling back migrations...")
+	db, migrator, err := newMigrator(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeDB(db)
+	return migrator.Down(ctx.Int(flags2.MigrateStepsFlag.Name))
+}
+
+func runMigrationsStatus(ctx *cli.Context) error {
+	db, migrator, err := newMigrator(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeDB(db)
+	statusList, err := migrator.Status()
+	if err != nil {
+		return err
+	}
+	for _, status := range statusList {
+		state := "pending"
+		if status.Applied {
+			state = "applied " + time.Unix(int64(status.AppliedAt), 0).UTC().Format(time.RFC3339)
+		}
+		if status.Modified {
+			state += " (modified after apply)"
+		}
+		fmt.Printf("%05d  %-40s  %s\n", status.Version, status.Name, state)
+	}
+	return nil
 }
 
 func runNotify(ctx *cli.Context, shutdown context.CancelCauseFunc) (cliapp.Lifecycle, error) {
@@ -118,16 +334,55 @@ func runNotify(ctx *cli.Context, shutdown context.CancelCauseFunc) (cliapp.Lifec
 		log.Error("failed to load config", "err", err)
 		return nil, err
 	}
-	db, err := database.NewDB(ctx.Context, cfg.MasterDB)
+	db, err := database.NewDB(ctx.Context, &cfg)
 	if err != nil {
 		log.Error("failed to connect to database", "err", err)
 		return nil, err
 	}
-	return notifier.NewNotifier(db, shutdown)
+	return notifier.NewNotifier(db, notifier.Config{
+		Sinks:           notifier.SinkConfig{FileDir: cfg.EventFileDir, BrokerUrl: cfg.EventBrokerUrl},
+		RefreshInterval: cfg.BusinessRefreshInterval,
+		LeaseName:       leader.LeaseName(leader.RoleNotify, cfg.ChainNode.ChainName),
+		LeaseTTL:        cfg.LeaderLeaseTTL,
+		Supervisor:      worker.NewSupervisorConfig(&cfg, clock.SystemClock),
+		StatusAddr:      fmt.Sprintf("%s:%d", cfg.MetricsServer.Host, cfg.MetricsServer.Port),
+		Clock:           clock.SystemClock,
+	}, shutdown)
+}
+
+// runConfigCheck 校验合并后的配置，并打印隐藏了密钥的生效配置
+func runConfigCheck(ctx *cli.Context) error {
+	cfg, err := config.LoadConfig(ctx)
+	if err != nil {
+		log.Error("failed to load config", "err", err)
+		return err
+	}
+	out, err := yaml.Marshal(cfg.Redacted())
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(out))
+	return nil
+}
+
+func runOpenAPI(ctx *cli.Context) error {
+	bws, err := services.NewBusinessMiddleWireServices(nil, &services.BusinessMiddleConfig{}, nil)
+	if err != nil {
+		return err
+	}
+	spec, err := json.MarshalIndent(bws.OpenAPISpec(), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(spec))
+	return nil
 }
 
 func NewCli(GitCommit string, GitData string) *cli.App {
 	flags := flags2.Flags
+	migrateFlags := append(flags2.MigrateFlags, flags...)
+	rescanFlags := append(flags2.RescanFlags, flags...)
+	coldBundleFlags := append(flags2.ColdBundleFlags, flags...)
 	return &cli.App{
 		Version:              params.VersionWithCommit(GitCommit, GitData),
 		Description:          "An exchange wallet scanner services with rpc and rest api server",
@@ -153,9 +408,70 @@ func NewCli(GitCommit string, GitData string) *cli.App {
 			},
 			{
 				Name:        "migrate",
-				Flags:       flags,
+				Flags:       migrateFlags,
 				Description: "Run database migrations",
 				Action:      runMigrations,
+				Subcommands: []*cli.Command{
+					{
+						Name:        "status",
+						Flags:       migrateFlags,
+						Description: "Show applied and pending migrations",
+						Action:      runMigrationsStatus,
+					},
+					{
+						Name:        "up",
+						Flags:       migrateFlags,
+						Description: "Apply pending migrations",
+						Action:      runMigrations,
+					},
+					{
+						Name:        "down",
+						Flags:       migrateFlags,
+						Description: "Roll back applied migrations",
+						Action:      runMigrationsDown,
+					},
+				},
+			},
+			{
+				Name:        "rescan",
+				Flags:       rescanFlags,
+				Description: "Rescan a block range and backfill missed transactions without moving the sync cursor",
+				Action:      runRescan,
+			},
+			{
+				Name:        "cold-bundle",
+				Description: "Offline signing of cold wallet transfers",
+				Subcommands: []*cli.Command{
+					{
+						Name:        "export",
+						Flags:       coldBundleFlags,
+						Description: "Export pending internal transactions as a checksummed bundle for an air-gapped signer",
+						Action:      runColdBundleExport,
+					},
+					{
+						Name:        "import",
+						Flags:       coldBundleFlags,
+						Description: "Import a signed bundle, verify every signature against its record and queue it for broadcast",
+						Action:      runColdBundleImport,
+					},
+				},
+			},
+			{
+				Name:        "config",
+				Description: "Inspect the merged configuration",
+				Subcommands: []*cli.Command{
+					{
+						Name:        "check",
+						Flags:       flags,
+						Description: "Validate the configuration file, env vars and flags, then print the effective configuration with secrets redacted",
+						Action:      runConfigCheck,
+					},
+				},
+			},
+			{
+				Name:        "openapi",
+				Description: "Print the OpenAPI spec of the http json gateway",
+				Action:      runOpenAPI,
 			},
 			{
 				Name:        "version",
@@ -0,0 +1,97 @@
+// Package coldbundle 冷钱包离线签名的交易包格式。服务导出未签名交易包，离线签名机签名后填入 signature 并改为 signed，
+// 重新计算校验和后导入。交易包是自描述的 JSON，可以按二维码容量拆分成多段传输
+package coldbundle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	Format  = "multichain-sync-account/cold-bundle"
+	Version = 1
+
+	KindUnsigned = "unsigned"
+	KindSigned   = "signed"
+)
+
+// ErrChecksum 交易包内容与校验和不一致，传输中损坏或被修改
+var ErrChecksum = errors.New("cold bundle checksum mismatch")
+
+type Bundle struct {
+	Format    string `json:"format"`
+	Version   int    `json:"version"`
+	Kind      string `json:"kind"`
+	Chain     string `json:"chain"`
+	ChainId   string `json:"chain_id"`
+	RequestId string `json:"request_id"`
+	CreatedAt uint64 `json:"created_at"`
+	Items     []Item `json:"items"`
+	// Checksum checksum 置空后紧凑 JSON 的 sha256
+	Checksum string `json:"checksum"`
+}
+
+// Item 离线签名需要的全部交易要素，payload 是 chain-account 的交易结构，导入时按它组装签名交易
+type Item struct {
+	TransactionId   string `json:"transaction_id"`
+	TxType          string `json:"tx_type"`
+	From            string `json:"from"`
+	To              string `json:"to"`
+	Value           string `json:"value"`
+	ContractAddress string `json:"contract_address"`
+	TokenId         string `json:"token_id,omitempty"`
+	Nonce           uint64 `json:"nonce"`
+	Payload         string `json:"payload"`
+	UnSignTx        string `json:"un_sign_tx"`
+	Signature       string `json:"signature,omitempty"`
+}
+
+func (b *Bundle) checksum() (string, error) {
+	c := *b
+	c.Checksum = ""
+	data, err := json.Marshal(&c)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Marshal 计算校验和后编码
+func Marshal(b *Bundle) ([]byte, error) {
+	b.Format, b.Version = Format, Version
+	checksum, err := b.checksum()
+	if err != nil {
+		return nil, err
+	}
+	b.Checksum = checksum
+	return json.MarshalIndent(b, "", "  ")
+}
+
+// Unmarshal 解码并校验格式、版本和校验和
+func Unmarshal(data []byte) (*Bundle, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var b Bundle
+	if err := decoder.Decode(&b); err != nil {
+		return nil, fmt.Errorf("decode cold bundle: %w", err)
+	}
+	if b.Format != Format || b.Version != Version {
+		return nil, fmt.Errorf("unsupported cold bundle %s version %d", b.Format, b.Version)
+	}
+	if b.Kind != KindUnsigned && b.Kind != KindSigned {
+		return nil, fmt.Errorf("unsupported cold bundle kind %q", b.Kind)
+	}
+	checksum, err := b.checksum()
+	if err != nil {
+		return nil, err
+	}
+	if checksum != b.Checksum {
+		return nil, ErrChecksum
+	}
+	return &b, nil
+}
@@ -0,0 +1,80 @@
+package coldbundle
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testBundle() *Bundle {
+	return &Bundle{
+		Kind:      KindUnsigned,
+		Chain:     "Ethereum",
+		ChainId:   "11155111",
+		RequestId: "biz",
+		CreatedAt: 1_700_000_000,
+		Items: []Item{{
+			TransactionId: "tx-1",
+			TxType:        "cold2hot",
+			From:          "0x1111111111111111111111111111111111111111",
+			To:            "0x2222222222222222222222222222222222222222",
+			Value:         "1000",
+			Nonce:         7,
+			Payload:       "e30=",
+			UnSignTx:      "0xabcd",
+		}},
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	data, err := Marshal(testBundle())
+	require.NoError(t, err)
+	b, err := Unmarshal(data)
+	require.NoError(t, err)
+	require.Equal(t, Format, b.Format)
+	require.Equal(t, "tx-1", b.Items[0].TransactionId)
+
+	// 离线签名机填入签名后重新计算校验和
+	b.Kind, b.Items[0].Signature = KindSigned, "0x01"
+	signed, err := Marshal(b)
+	require.NoError(t, err)
+	b, err = Unmarshal(signed)
+	require.NoError(t, err)
+	require.Equal(t, "0x01", b.Items[0].Signature)
+
+	// 修改内容而不更新校验和
+	tampered := strings.Replace(string(signed), `"value": "1000"`, `"value": "9000"`, 1)
+	_, err = Unmarshal([]byte(tampered))
+	require.ErrorIs(t, err, ErrChecksum)
+
+	_, err = Unmarshal([]byte(strings.Replace(string(data), `"kind"`, `"extra": 1, "kind"`, 1)))
+	require.ErrorContains(t, err, "unknown field")
+}
+
+func TestSplitJoin(t *testing.T) {
+	data, err := Marshal(testBundle())
+	require.NoError(t, err)
+	chunks := Split(data, 100)
+	require.Greater(t, len(chunks), 2)
+	require.True(t, IsChunk(chunks[0]))
+
+	// 分段顺序任意
+	reversed := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		reversed[len(chunks)-1-i] = chunk
+	}
+	joined, err := Join(reversed)
+	require.NoError(t, err)
+	require.Equal(t, data, joined)
+
+	_, err = Join(chunks[1:])
+	require.ErrorContains(t, err, "missing cold bundle chunk 1/")
+	_, err = Join(append(chunks, chunks[0]))
+	require.ErrorContains(t, err, "duplicate")
+
+	other, err := Marshal(&Bundle{Kind: KindSigned})
+	require.NoError(t, err)
+	_, err = Join(append(chunks[:1:1], Split(other, 100)[1]))
+	require.ErrorContains(t, err, "another bundle")
+}
@@ -0,0 +1,100 @@
+package coldbundle
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// chunkPrefix 每段的格式为 mcsb:<序号>/<总段数>:<整包 sha256 前 8 位>:<base64url 数据>
+const chunkPrefix = "mcsb:"
+
+const maxChunks = 4096
+
+// IsChunk 判断输入是拆分后的分段还是完整 JSON
+func IsChunk(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), chunkPrefix)
+}
+
+// Split 把编码后的交易包按 size 字节拆成适合二维码的分段，size 为 base64 前的数据长度
+func Split(data []byte, size int) []string {
+	if size <= 0 {
+		size = len(data)
+	}
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:4])
+	total := (len(data) + size - 1) / size
+	chunks := make([]string, 0, total)
+	for i := 0; i < total; i++ {
+		part := data[i*size : min((i+1)*size, len(data))]
+		chunks = append(chunks, fmt.Sprintf("%s%d/%d:%s:%s", chunkPrefix, i+1, total, digest, base64.RawURLEncoding.EncodeToString(part)))
+	}
+	return chunks
+}
+
+// Join 按序号拼接分段，分段顺序任意，缺段、重复或来自不同交易包时报错
+func Join(chunks []string) ([]byte, error) {
+	var digest string
+	var parts [][]byte
+	for _, chunk := range chunks {
+		chunk = strings.TrimSpace(chunk)
+		if chunk == "" {
+			continue
+		}
+		fields := strings.SplitN(strings.TrimPrefix(chunk, chunkPrefix), ":", 3)
+		if !strings.HasPrefix(chunk, chunkPrefix) || len(fields) != 3 {
+			return nil, fmt.Errorf("invalid cold bundle chunk %.20q", chunk)
+		}
+		index, total, ok := parseIndex(fields[0])
+		if !ok {
+			return nil, fmt.Errorf("invalid cold bundle chunk index %q", fields[0])
+		}
+		if parts == nil {
+			digest, parts = fields[1], make([][]byte, total)
+		}
+		if fields[1] != digest || total != len(parts) {
+			return nil, fmt.Errorf("cold bundle chunk %d/%d belongs to another bundle", index, total)
+		}
+		if parts[index-1] != nil {
+			return nil, fmt.Errorf("duplicate cold bundle chunk %d/%d", index, total)
+		}
+		part, err := base64.RawURLEncoding.DecodeString(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("cold bundle chunk %d/%d: %w", index, total, err)
+		}
+		parts[index-1] = part
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("no cold bundle chunks")
+	}
+	var data []byte
+	for i, part := range parts {
+		if part == nil {
+			return nil, fmt.Errorf("missing cold bundle chunk %d/%d", i+1, len(parts))
+		}
+		data = append(data, part...)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:4]) != digest {
+		return nil, ErrChecksum
+	}
+	return data, nil
+}
+
+func parseIndex(s string) (int, int, bool) {
+	index, total, ok := strings.Cut(s, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	i, err := strconv.Atoi(index)
+	if err != nil {
+		return 0, 0, false
+	}
+	n, err := strconv.Atoi(total)
+	if err != nil || n <= 0 || n > maxChunks || i < 1 || i > n {
+		return 0, 0, false
+	}
+	return i, n, true
+}
@@ -41,3 +41,22 @@ func StringToBigInt(input string) (num *big.Int) {
 	}
 	return
 }
+
+// Split 把 total 平均分成 n 份，除不尽的余数从第一份开始逐份加 1，各份之和等于 total
+func Split(total *big.Int, n int) []*big.Int {
+	if n <= 0 {
+		return nil
+	}
+	if total == nil {
+		total = Zero
+	}
+	share, remainder := new(big.Int).QuoRem(total, big.NewInt(int64(n)), new(big.Int))
+	parts := make([]*big.Int, n)
+	for i := range parts {
+		parts[i] = new(big.Int).Set(share)
+		if big.NewInt(int64(i)).Cmp(remainder) < 0 {
+			parts[i].Add(parts[i], One)
+		}
+	}
+	return parts
+}
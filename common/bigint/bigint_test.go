@@ -21,3 +21,13 @@ func TestClamp(t *testing.T) {
 	require.False(t, end == result)
 	require.Equal(t, uint64(5), result.Uint64())
 }
+
+func TestSplit(t *testing.T) {
+	parts := Split(big.NewInt(10), 3)
+	require.Equal(t, []*big.Int{big.NewInt(4), big.NewInt(3), big.NewInt(3)}, parts)
+
+	parts = Split(big.NewInt(2), 4)
+	require.Equal(t, []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(0), big.NewInt(0)}, parts)
+
+	require.Nil(t, Split(big.NewInt(1), 0))
+}
@@ -0,0 +1,138 @@
+package chainaddr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrInvalidAddress   = errors.New("invalid address")
+	ErrInvalidHash      = errors.New("invalid hash")
+	ErrUnsupportedChain = errors.New("unsupported chain")
+)
+
+// Address 规范化后的链上地址。不同链的地址格式不同(hex、base58、bech32)，统一以字符串保存，
+// 只能通过 Codec 解析得到，保证同一地址在数据库中只有一种写法
+type Address string
+
+// Hash 规范化后的交易或区块哈希，空字符串表示尚未上链
+type Hash string
+
+func (a Address) String() string {
+	return string(a)
+}
+
+func (a Address) IsZero() bool {
+	return a == ""
+}
+
+func (h Hash) String() string {
+	return string(h)
+}
+
+func (h Hash) IsZero() bool {
+	return h == ""
+}
+
+type Family string
+
+const (
+	FamilyEVM     Family = "evm"
+	FamilyBitcoin Family = "bitcoin"
+	FamilyTron    Family = "tron"
+	FamilySolana  Family = "solana"
+	FamilyCosmos  Family = "cosmos"
+)
+
+// Codec 负责某一类链的地址和哈希的校验与规范化
+type Codec interface {
+	Family() Family
+	ParseAddress(s string) (Address, error)
+	ParseHash(s string) (Hash, error)
+	// NativeToken 原生币在 token_address 列中的表示
+	NativeToken() Address
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{}
+
+	defaultMu    sync.RWMutex
+	defaultCodec Codec = EVM
+)
+
+func init() {
+	Register(EVM, "ethereum", "eth", "bsc", "binance", "polygon", "arbitrum", "optimism", "base", "avalanche", "linea", "scroll", "zksync", "mantle")
+	Register(Bitcoin, "bitcoin", "btc")
+	Register(BitcoinTestnet, "bitcoin-testnet", "btc-testnet")
+	Register(Litecoin, "litecoin", "ltc")
+	Register(Dogecoin, "dogecoin", "doge")
+	Register(Tron, "tron", "trx")
+	Register(Solana, "solana", "sol")
+	Register(Cosmos, "cosmos", "atom")
+}
+
+// Register 为链名注册 Codec，链名不区分大小写
+func Register(codec Codec, chainNames ...string) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	for _, name := range chainNames {
+		codecs[strings.ToLower(name)] = codec
+	}
+}
+
+func Lookup(chainName string) (Codec, error) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	codec, ok := codecs[strings.ToLower(strings.TrimSpace(chainName))]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedChain, chainName)
+	}
+	return codec, nil
+}
+
+// SetDefaultChain 设置进程内默认使用的链，数据库序列化和链上数据解析都按该链规范化。
+// 一个进程只同步一条链，启动时根据 chain-name 配置调用一次
+func SetDefaultChain(chainName string) error {
+	codec, err := Lookup(chainName)
+	if err != nil {
+		return err
+	}
+	defaultMu.Lock()
+	defaultCodec = codec
+	defaultMu.Unlock()
+	return nil
+}
+
+func Default() Codec {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultCodec
+}
+
+// ParseAddress 按默认链规范化地址，空字符串返回空地址
+func ParseAddress(s string) (Address, error) {
+	if strings.TrimSpace(s) == "" {
+		return "", nil
+	}
+	return Default().ParseAddress(s)
+}
+
+// ParseHash 按默认链规范化哈希，空字符串返回空哈希
+func ParseHash(s string) (Hash, error) {
+	if strings.TrimSpace(s) == "" {
+		return "", nil
+	}
+	return Default().ParseHash(s)
+}
+
+func NativeToken() Address {
+	return Default().NativeToken()
+}
+
+// IsUTXO 默认链是否为 UTXO 模型，比特币系的链(BTC/LTC/DOGE)按输出记账，没有账户 nonce
+func IsUTXO() bool {
+	return Default().Family() == FamilyBitcoin
+}
@@ -0,0 +1,159 @@
+package chainaddr
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// bech32Encode 仅用于构造测试地址
+func bech32Encode(hrp string, data []byte) string {
+	values := append(bech32HrpExpand(hrp), data...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ bech32Const
+	var sb strings.Builder
+	sb.WriteString(hrp + "1")
+	for _, d := range data {
+		sb.WriteByte(bech32Charset[d])
+	}
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(bech32Charset[(polymod>>uint(5*(5-i)))&31])
+	}
+	return sb.String()
+}
+
+func TestEVM(t *testing.T) {
+	address, err := EVM.ParseAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")
+	require.NoError(t, err)
+	require.Equal(t, Address("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"), address)
+
+	_, err = EVM.ParseAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeA")
+	require.ErrorIs(t, err, ErrInvalidAddress)
+
+	hash, err := EVM.ParseHash(strings.Repeat("AB", 32))
+	require.NoError(t, err)
+	require.Equal(t, Hash("0x"+strings.Repeat("ab", 32)), hash)
+	require.Equal(t, Address("0x"+strings.Repeat("0", 40)), EVM.NativeToken())
+}
+
+func TestBitcoin(t *testing.T) {
+	pubKeyHash, err := hex.DecodeString("0062e907b15cbf27d5425399ebf6f0fb50ebb88f18")
+	require.NoError(t, err)
+	p2pkh := Base58CheckEncode(pubKeyHash)
+	address, err := Bitcoin.ParseAddress(p2pkh)
+	require.NoError(t, err)
+	require.Equal(t, Address(p2pkh), address)
+
+	address, err = Bitcoin.ParseAddress("BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4")
+	require.NoError(t, err)
+	require.Equal(t, Address("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"), address)
+
+	_, err = Bitcoin.ParseAddress("bc1p5d7rjq7g6rdk2yhzks9smlaqtedr4dekq08ge8ztwac72sfr9rusxg3297")
+	require.NoError(t, err)
+
+	_, err = Bitcoin.ParseAddress("bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5")
+	require.ErrorIs(t, err, ErrInvalidAddress)
+	_, err = Bitcoin.ParseAddress(p2pkh[:len(p2pkh)-1] + "1")
+	require.ErrorIs(t, err, ErrInvalidAddress)
+	_, err = BitcoinTestnet.ParseAddress(p2pkh)
+	require.ErrorIs(t, err, ErrInvalidAddress)
+
+	hash, err := Bitcoin.ParseHash("0x" + strings.Repeat("CD", 32))
+	require.NoError(t, err)
+	require.Equal(t, Hash(strings.Repeat("cd", 32)), hash)
+}
+
+func TestLitecoinAndDogecoin(t *testing.T) {
+	pubKeyHash, err := hex.DecodeString("62e907b15cbf27d5425399ebf6f0fb50ebb88f18")
+	require.NoError(t, err)
+
+	ltc := Base58CheckEncode(append([]byte{0x30}, pubKeyHash...))
+	_, err = Litecoin.ParseAddress(ltc)
+	require.NoError(t, err)
+	_, err = Bitcoin.ParseAddress(ltc)
+	require.ErrorIs(t, err, ErrInvalidAddress)
+
+	doge := Base58CheckEncode(append([]byte{0x1e}, pubKeyHash...))
+	require.True(t, strings.HasPrefix(doge, "D"))
+	_, err = Dogecoin.ParseAddress(doge)
+	require.NoError(t, err)
+	_, err = Dogecoin.ParseAddress(ltc)
+	require.ErrorIs(t, err, ErrInvalidAddress)
+}
+
+func TestTron(t *testing.T) {
+	raw, err := hex.DecodeString("41a614f803b6fd780986a42c78ec9c7f77e6ded13c")
+	require.NoError(t, err)
+	encoded := Base58CheckEncode(raw)
+	require.True(t, strings.HasPrefix(encoded, "T"))
+
+	address, err := Tron.ParseAddress(encoded)
+	require.NoError(t, err)
+	require.Equal(t, Address(encoded), address)
+
+	fromHex, err := Tron.ParseAddress("41a614f803b6fd780986a42c78ec9c7f77e6ded13c")
+	require.NoError(t, err)
+	require.Equal(t, address, fromHex)
+
+	bitcoinRaw := append([]byte{0x00}, raw[1:]...)
+	_, err = Tron.ParseAddress(Base58CheckEncode(bitcoinRaw))
+	require.ErrorIs(t, err, ErrInvalidAddress)
+}
+
+func TestSolana(t *testing.T) {
+	address, err := Solana.ParseAddress("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
+	require.NoError(t, err)
+	require.Equal(t, Address("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"), address)
+
+	address, err = Solana.ParseAddress("11111111111111111111111111111111")
+	require.NoError(t, err)
+	require.Equal(t, Address("11111111111111111111111111111111"), address)
+
+	_, err = Solana.ParseAddress("0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed")
+	require.ErrorIs(t, err, ErrInvalidAddress)
+
+	signature := Base58Encode(append([]byte{1}, make([]byte, 63)...))
+	hash, err := Solana.ParseHash(signature)
+	require.NoError(t, err)
+	require.Equal(t, Hash(signature), hash)
+}
+
+func TestCosmos(t *testing.T) {
+	data, err := ConvertBits(make([]byte, 20), 8, 5, true)
+	require.NoError(t, err)
+	valid := bech32Encode("cosmos", data)
+
+	address, err := Cosmos.ParseAddress(strings.ToUpper(valid))
+	require.NoError(t, err)
+	require.Equal(t, Address(valid), address)
+
+	_, err = Cosmos.ParseAddress(bech32Encode("osmo", data))
+	require.ErrorIs(t, err, ErrInvalidAddress)
+
+	hash, err := Cosmos.ParseHash(strings.Repeat("ef", 32))
+	require.NoError(t, err)
+	require.Equal(t, Hash(strings.Repeat("EF", 32)), hash)
+}
+
+func TestDefaultChain(t *testing.T) {
+	defer func() { require.NoError(t, SetDefaultChain("Ethereum")) }()
+
+	require.ErrorIs(t, SetDefaultChain("unknown"), ErrUnsupportedChain)
+	require.NoError(t, SetDefaultChain("Solana"))
+	require.Equal(t, FamilySolana, Default().Family())
+	require.False(t, IsUTXO())
+	require.NoError(t, SetDefaultChain("dogecoin"))
+	require.True(t, IsUTXO())
+
+	address, err := ParseAddress("")
+	require.NoError(t, err)
+	require.True(t, address.IsZero())
+}
+
+func TestBase58RoundTrip(t *testing.T) {
+	payload := []byte{0, 0, 1, 2, 3, 255}
+	decoded, err := Base58CheckDecode(Base58CheckEncode(payload))
+	require.NoError(t, err)
+	require.Equal(t, payload, decoded)
+}
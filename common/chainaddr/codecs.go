@@ -0,0 +1,205 @@
+package chainaddr
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+var (
+	EVM            Codec = evmCodec{}
+	Bitcoin        Codec = bitcoinCodec{hrp: "bc", versions: []byte{0x00, 0x05}}
+	BitcoinTestnet Codec = bitcoinCodec{hrp: "tb", versions: []byte{0x6f, 0xc4}}
+	Litecoin       Codec = bitcoinCodec{hrp: "ltc", versions: []byte{0x30, 0x32, 0x05}}
+	Dogecoin       Codec = bitcoinCodec{versions: []byte{0x1e, 0x16}}
+	Tron           Codec = tronCodec{}
+	Solana         Codec = solanaCodec{}
+	Cosmos         Codec = cosmosCodec{hrp: "cosmos"}
+)
+
+// evmCodec 0x 开头的 20 字节地址和 32 字节哈希，统一小写，与旧版 bytes 序列化结果一致
+type evmCodec struct{}
+
+func (evmCodec) Family() Family {
+	return FamilyEVM
+}
+
+func (evmCodec) ParseAddress(s string) (Address, error) {
+	hexStr, ok := fixedHex(s, 20)
+	if !ok {
+		return "", fmt.Errorf("%w: %q is not an evm address", ErrInvalidAddress, s)
+	}
+	return Address("0x" + hexStr), nil
+}
+
+func (evmCodec) ParseHash(s string) (Hash, error) {
+	hexStr, ok := fixedHex(s, 32)
+	if !ok {
+		return "", fmt.Errorf("%w: %q is not an evm hash", ErrInvalidHash, s)
+	}
+	return Hash("0x" + hexStr), nil
+}
+
+func (evmCodec) NativeToken() Address {
+	return Address("0x" + strings.Repeat("0", 40))
+}
+
+// bitcoinCodec 支持 base58 的 P2PKH/P2SH 地址和 bech32/bech32m 的隔离见证地址，哈希为不带 0x 的小写 hex。
+// hrp 为空的链(DOGE)不支持隔离见证地址
+type bitcoinCodec struct {
+	hrp      string
+	versions []byte
+}
+
+func (bitcoinCodec) Family() Family {
+	return FamilyBitcoin
+}
+
+func (c bitcoinCodec) ParseAddress(s string) (Address, error) {
+	s = strings.TrimSpace(s)
+	if c.hrp != "" && strings.HasPrefix(strings.ToLower(s), c.hrp+"1") {
+		hrp, data, err := Bech32Decode(s)
+		if err != nil || hrp != c.hrp || len(data) == 0 || data[0] > 16 {
+			return "", fmt.Errorf("%w: %q is not a segwit address", ErrInvalidAddress, s)
+		}
+		program, err := ConvertBits(data[1:], 5, 8, false)
+		if err != nil || len(program) < 2 || len(program) > 40 || (data[0] == 0 && len(program) != 20 && len(program) != 32) {
+			return "", fmt.Errorf("%w: %q has an invalid witness program", ErrInvalidAddress, s)
+		}
+		return Address(strings.ToLower(s)), nil
+	}
+	payload, err := Base58CheckDecode(s)
+	if err != nil || len(payload) != 21 || !containsByte(c.versions, payload[0]) {
+		return "", fmt.Errorf("%w: %q is not a bitcoin address", ErrInvalidAddress, s)
+	}
+	return Address(Base58CheckEncode(payload)), nil
+}
+
+func (bitcoinCodec) ParseHash(s string) (Hash, error) {
+	hexStr, ok := fixedHex(s, 32)
+	if !ok {
+		return "", fmt.Errorf("%w: %q is not a bitcoin hash", ErrInvalidHash, s)
+	}
+	return Hash(hexStr), nil
+}
+
+func (bitcoinCodec) NativeToken() Address {
+	return ""
+}
+
+// tronCodec base58check 地址(T 开头)，同时接受 41 开头的 hex 地址并转换为 base58
+type tronCodec struct{}
+
+const tronAddressPrefix = 0x41
+
+func (tronCodec) Family() Family {
+	return FamilyTron
+}
+
+func (tronCodec) ParseAddress(s string) (Address, error) {
+	s = strings.TrimSpace(s)
+	if raw, err := hex.DecodeString(strings.TrimPrefix(s, "0x")); err == nil && len(raw) == 21 && raw[0] == tronAddressPrefix {
+		return Address(Base58CheckEncode(raw)), nil
+	}
+	payload, err := Base58CheckDecode(s)
+	if err != nil || len(payload) != 21 || payload[0] != tronAddressPrefix {
+		return "", fmt.Errorf("%w: %q is not a tron address", ErrInvalidAddress, s)
+	}
+	return Address(Base58CheckEncode(payload)), nil
+}
+
+func (tronCodec) ParseHash(s string) (Hash, error) {
+	hexStr, ok := fixedHex(s, 32)
+	if !ok {
+		return "", fmt.Errorf("%w: %q is not a tron hash", ErrInvalidHash, s)
+	}
+	return Hash(hexStr), nil
+}
+
+func (tronCodec) NativeToken() Address {
+	return ""
+}
+
+// solanaCodec 32 字节公钥和 64 字节签名的 base58 编码，大小写敏感
+type solanaCodec struct{}
+
+func (solanaCodec) Family() Family {
+	return FamilySolana
+}
+
+func (solanaCodec) ParseAddress(s string) (Address, error) {
+	decoded, err := Base58Decode(strings.TrimSpace(s))
+	if err != nil || len(decoded) != 32 {
+		return "", fmt.Errorf("%w: %q is not a solana address", ErrInvalidAddress, s)
+	}
+	return Address(Base58Encode(decoded)), nil
+}
+
+func (solanaCodec) ParseHash(s string) (Hash, error) {
+	decoded, err := Base58Decode(strings.TrimSpace(s))
+	if err != nil || len(decoded) != 64 {
+		return "", fmt.Errorf("%w: %q is not a solana signature", ErrInvalidHash, s)
+	}
+	return Hash(Base58Encode(decoded)), nil
+}
+
+func (solanaCodec) NativeToken() Address {
+	return ""
+}
+
+// cosmosCodec bech32 地址统一小写，交易哈希按 cosmos 惯例使用大写 hex
+type cosmosCodec struct {
+	hrp string
+}
+
+func (cosmosCodec) Family() Family {
+	return FamilyCosmos
+}
+
+func (c cosmosCodec) ParseAddress(s string) (Address, error) {
+	hrp, data, err := Bech32Decode(strings.TrimSpace(s))
+	if err != nil || hrp != c.hrp {
+		return "", fmt.Errorf("%w: %q is not a %s address", ErrInvalidAddress, s, c.hrp)
+	}
+	program, err := ConvertBits(data, 5, 8, false)
+	if err != nil || (len(program) != 20 && len(program) != 32) {
+		return "", fmt.Errorf("%w: %q has an invalid length", ErrInvalidAddress, s)
+	}
+	return Address(strings.ToLower(strings.TrimSpace(s))), nil
+}
+
+func (cosmosCodec) ParseHash(s string) (Hash, error) {
+	hexStr, ok := fixedHex(s, 32)
+	if !ok {
+		return "", fmt.Errorf("%w: %q is not a cosmos hash", ErrInvalidHash, s)
+	}
+	return Hash(strings.ToUpper(hexStr)), nil
+}
+
+func (cosmosCodec) NativeToken() Address {
+	return ""
+}
+
+// fixedHex 校验可选 0x 前缀的定长 hex，返回不带前缀的小写形式
+func fixedHex(s string, size int) (string, bool) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	if len(s) != size*2 {
+		return "", false
+	}
+	if _, err := hex.DecodeString(s); err != nil {
+		return "", false
+	}
+	return strings.ToLower(s), true
+}
+
+func containsByte(set []byte, b byte) bool {
+	for _, v := range set {
+		if v == b {
+			return true
+		}
+	}
+	return false
+}
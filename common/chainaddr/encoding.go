@@ -0,0 +1,175 @@
+package chainaddr
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var (
+	errInvalidBase58   = errors.New("invalid base58 string")
+	errInvalidChecksum = errors.New("invalid checksum")
+	errInvalidBech32   = errors.New("invalid bech32 string")
+)
+
+func Base58Decode(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errInvalidBase58
+	}
+	result := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range s {
+		digit := strings.IndexRune(base58Alphabet, c)
+		if digit < 0 {
+			return nil, errInvalidBase58
+		}
+		result.Mul(result, radix)
+		result.Add(result, big.NewInt(int64(digit)))
+	}
+	leadingZeros := 0
+	for leadingZeros < len(s) && s[leadingZeros] == base58Alphabet[0] {
+		leadingZeros++
+	}
+	return append(make([]byte, leadingZeros), result.Bytes()...), nil
+}
+
+func Base58Encode(b []byte) string {
+	value := new(big.Int).SetBytes(b)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var out []byte
+	for value.Sign() > 0 {
+		value.DivMod(value, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// Base58CheckDecode 解码带 4 字节双 sha256 校验和的 base58 字符串，返回去掉校验和的内容
+func Base58CheckDecode(s string) ([]byte, error) {
+	decoded, err := Base58Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) < 5 {
+		return nil, errInvalidChecksum
+	}
+	payload, checksum := decoded[:len(decoded)-4], decoded[len(decoded)-4:]
+	if !bytes.Equal(doubleSha256(payload)[:4], checksum) {
+		return nil, errInvalidChecksum
+	}
+	return payload, nil
+}
+
+func Base58CheckEncode(payload []byte) string {
+	return Base58Encode(append(append([]byte(nil), payload...), doubleSha256(payload)[:4]...))
+}
+
+func doubleSha256(data []byte) []byte {
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	return second[:]
+}
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+// Bech32Decode 校验 bech32/bech32m 字符串，返回小写的 hrp 和 5 bit 分组的数据部分(不含校验和)
+func Bech32Decode(s string) (string, []byte, error) {
+	if len(s) < 8 || len(s) > 90 {
+		return "", nil, errInvalidBech32
+	}
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, errInvalidBech32
+	}
+	s = strings.ToLower(s)
+	sep := strings.LastIndexByte(s, '1')
+	if sep < 1 || sep+7 > len(s) {
+		return "", nil, errInvalidBech32
+	}
+	hrp := s[:sep]
+	data := make([]byte, 0, len(s)-sep-1)
+	for _, c := range s[sep+1:] {
+		value := strings.IndexRune(bech32Charset, c)
+		if value < 0 {
+			return "", nil, errInvalidBech32
+		}
+		data = append(data, byte(value))
+	}
+	checksum := bech32Polymod(append(bech32HrpExpand(hrp), data...))
+	if checksum != bech32Const && checksum != bech32mConst {
+		return "", nil, errInvalidChecksum
+	}
+	return hrp, data[:len(data)-6], nil
+}
+
+// ConvertBits 在不同位宽的分组之间转换，bech32 数据部分为 5 bit 分组
+func ConvertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	var acc uint
+	var bits uint
+	var out []byte
+	maxValue := uint(1)<<toBits - 1
+	for _, value := range data {
+		if uint(value)>>fromBits != 0 {
+			return nil, errInvalidBech32
+		}
+		acc = acc<<fromBits | uint(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte(acc>>bits&maxValue))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(toBits-bits)&maxValue))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxValue != 0 {
+		return nil, errInvalidBech32
+	}
+	return out, nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, value := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(value)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32HrpExpand(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
@@ -0,0 +1,50 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// StatusServer 以 JSON 暴露 worker 状态，GET /workers
+type StatusServer struct {
+	server *http.Server
+}
+
+func NewStatusServer(addr string, status func() []WorkerStatus) *StatusServer {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/workers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(status()); err != nil {
+			log.Error("write worker status fail", "err", err)
+		}
+	})
+	return &StatusServer{server: &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}}
+}
+
+func (s *StatusServer) Start() error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	log.Info("start worker status server", "addr", listener.Addr())
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("worker status server stopped", "err", err)
+		}
+	}()
+	return nil
+}
+
+func (s *StatusServer) Close(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
@@ -0,0 +1,208 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/common/clock"
+	"github.com/CavnHan/multichain-sync-account/common/retry"
+)
+
+const (
+	StateRunning = "running"
+	StateBackoff = "backoff"
+	StateStopped = "stopped"
+	StateFailed  = "failed"
+)
+
+const defaultFailureWindow = 10 * time.Minute
+
+type fatalError struct {
+	err error
+}
+
+func (e fatalError) Error() string {
+	return e.err.Error()
+}
+
+func (e fatalError) Unwrap() error {
+	return e.err
+}
+
+// Fatal 标记无法通过重启恢复的错误，supervisor 收到后直接上报，不再重启
+func Fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fatalError{err: err}
+}
+
+func IsFatal(err error) bool {
+	var fatal fatalError
+	return errors.As(err, &fatal)
+}
+
+type SupervisorConfig struct {
+	// Backoff 按窗口内的失败次数计算重启前的等待时间
+	Backoff retry.Strategy
+	// FailureBudget 窗口内允许的失败次数，超过后调用 handleCrit
+	FailureBudget int
+	FailureWindow time.Duration
+	Clock         clock.Clock
+}
+
+// WorkerStatus worker 的运行状态，Failures 只统计窗口内的失败
+type WorkerStatus struct {
+	Name          string `json:"name"`
+	State         string `json:"state"`
+	Restarts      int    `json:"restarts"`
+	Failures      int    `json:"failures"`
+	LastError     string `json:"last_error,omitempty"`
+	LastFailureAt uint64 `json:"last_failure_at,omitempty"`
+}
+
+type workerState struct {
+	status   WorkerStatus
+	failures []time.Time
+}
+
+// Supervisor 在 Group 之上运行可重启的 worker：worker 返回错误或 panic 后按退避时间重启，
+// 致命错误或窗口内失败次数超过预算时才调用 handleCrit
+type Supervisor struct {
+	cfg        SupervisorConfig
+	handleCrit func(err error)
+	ctx        context.Context
+	cancel     context.CancelFunc
+	group      Group
+
+	mu      sync.Mutex
+	workers []*workerState
+}
+
+func NewSupervisor(cfg SupervisorConfig, handleCrit func(err error)) *Supervisor {
+	if cfg.Backoff == nil {
+		cfg.Backoff = &retry.ExponentialStrategy{Min: time.Second, Max: time.Minute, MaxJitter: 250 * time.Millisecond}
+	}
+	if cfg.FailureWindow == 0 {
+		cfg.FailureWindow = defaultFailureWindow
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.SystemClock
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Supervisor{
+		cfg:        cfg,
+		handleCrit: handleCrit,
+		ctx:        ctx,
+		cancel:     cancel,
+		group:      Group{HandleCrit: handleCrit},
+	}
+}
+
+// Go 启动 worker，fn 在 ctx 取消时应返回 nil
+func (s *Supervisor) Go(name string, fn func(ctx context.Context) error) {
+	state := &workerState{status: WorkerStatus{Name: name, State: StateRunning}}
+	s.mu.Lock()
+	s.workers = append(s.workers, state)
+	s.mu.Unlock()
+	s.group.Go(func() error {
+		return s.supervise(state, fn)
+	})
+}
+
+// Close 停止所有 worker 并等待退出，返回已上报的 worker 错误
+func (s *Supervisor) Close() error {
+	s.cancel()
+	return s.group.Wait()
+}
+
+func (s *Supervisor) Status() []WorkerStatus {
+	now := s.cfg.Clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]WorkerStatus, 0, len(s.workers))
+	for _, state := range s.workers {
+		status := state.status
+		status.Failures = 0
+		for _, at := range state.failures {
+			if now.Sub(at) < s.cfg.FailureWindow {
+				status.Failures++
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func (s *Supervisor) supervise(state *workerState, fn func(ctx context.Context) error) error {
+	name := state.status.Name
+	for {
+		s.setState(state, StateRunning)
+		err := s.runOnce(fn)
+		if s.ctx.Err() != nil || err == nil {
+			s.setState(state, StateStopped)
+			return nil
+		}
+
+		failures := s.recordFailure(state, err)
+		if IsFatal(err) || failures > s.cfg.FailureBudget {
+			s.setState(state, StateFailed)
+			log.Error("worker failed, escalate", "worker", name, "failures", failures, "fatal", IsFatal(err), "err", err)
+			err = fmt.Errorf("worker %s failed: %w", name, err)
+			s.handleCrit(err)
+			return err
+		}
+
+		delay := s.cfg.Backoff.Duration(failures - 1)
+		s.setState(state, StateBackoff)
+		log.Warn("worker failed, restart after backoff", "worker", name, "failures", failures, "budget", s.cfg.FailureBudget, "backoff", delay, "err", err)
+		if s.cfg.Clock.SleepCtx(s.ctx, delay) != nil {
+			s.setState(state, StateStopped)
+			return nil
+		}
+		s.mu.Lock()
+		state.status.Restarts++
+		s.mu.Unlock()
+	}
+}
+
+// runOnce panic 与返回错误一样按失败处理
+func (s *Supervisor) runOnce(fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			debug.PrintStack()
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(s.ctx)
+}
+
+func (s *Supervisor) setState(state *workerState, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state.status.State = value
+}
+
+// recordFailure 记录失败并返回窗口内的失败次数
+func (s *Supervisor) recordFailure(state *workerState, err error) int {
+	now := s.cfg.Clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recent := state.failures[:0]
+	for _, at := range state.failures {
+		if now.Sub(at) < s.cfg.FailureWindow {
+			recent = append(recent, at)
+		}
+	}
+	state.failures = append(recent, now)
+	state.status.Failures = len(state.failures)
+	state.status.LastError = err.Error()
+	state.status.LastFailureAt = uint64(now.Unix())
+	return len(state.failures)
+}
@@ -0,0 +1,98 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/common/clock"
+	"github.com/CavnHan/multichain-sync-account/common/retry"
+)
+
+func newTestSupervisor(clk *clock.DeterministicClock, budget int) (*Supervisor, chan error) {
+	crits := make(chan error, 1)
+	sv := NewSupervisor(SupervisorConfig{
+		Backoff:       retry.Fixed(time.Second),
+		FailureBudget: budget,
+		FailureWindow: time.Minute,
+		Clock:         clk,
+	}, func(err error) {
+		crits <- err
+	})
+	return sv, crits
+}
+
+// advanceBackoff 等待 worker 进入退避后推进时间触发重启
+func advanceBackoff(t *testing.T, clk *clock.DeterministicClock) {
+	require.True(t, clk.WaitForNewPendingTaskWithTimeout(5*time.Second))
+	clk.AdvanceTime(time.Second)
+}
+
+func TestSupervisorRestartsTransientFailures(t *testing.T) {
+	clk := clock.NewDeterministicClock(time.Unix(1_700_000_000, 0))
+	sv, crits := newTestSupervisor(clk, 3)
+
+	var attempts atomic.Int32
+	running := make(chan struct{})
+	sv.Go("withdraw", func(ctx context.Context) error {
+		switch attempts.Add(1) {
+		case 1:
+			return errors.New("connection refused")
+		case 2:
+			panic("nil pointer")
+		}
+		close(running)
+		<-ctx.Done()
+		return nil
+	})
+	advanceBackoff(t, clk)
+	advanceBackoff(t, clk)
+	<-running
+
+	status := sv.Status()
+	require.Len(t, status, 1)
+	require.Equal(t, "withdraw", status[0].Name)
+	require.Equal(t, StateRunning, status[0].State)
+	require.Equal(t, 2, status[0].Restarts)
+	require.Equal(t, 2, status[0].Failures)
+	require.Contains(t, status[0].LastError, "panic: nil pointer")
+
+	// 窗口之外的失败不再计入
+	clk.AdvanceTime(2 * time.Minute)
+	require.Equal(t, 0, sv.Status()[0].Failures)
+
+	require.NoError(t, sv.Close())
+	require.Equal(t, StateStopped, sv.Status()[0].State)
+	require.Empty(t, crits)
+}
+
+func TestSupervisorEscalates(t *testing.T) {
+	clk := clock.NewDeterministicClock(time.Unix(1_700_000_000, 0))
+	sv, crits := newTestSupervisor(clk, 1)
+	sv.Go("internal", func(ctx context.Context) error {
+		return errors.New("database is down")
+	})
+	// 预算内重启一次，第二次失败超过预算
+	advanceBackoff(t, clk)
+	require.ErrorContains(t, <-crits, "worker internal failed: database is down")
+	require.Error(t, sv.Close())
+	status := sv.Status()[0]
+	require.Equal(t, StateFailed, status.State)
+	require.Equal(t, 1, status.Restarts)
+
+	fatalErr := errors.New("lease lost")
+	sv, crits = newTestSupervisor(clk, 5)
+	sv.Go("notifier", func(ctx context.Context) error {
+		return Fatal(fatalErr)
+	})
+	require.ErrorIs(t, <-crits, fatalErr)
+	err := sv.Close()
+	require.ErrorIs(t, err, fatalErr)
+	require.True(t, IsFatal(err))
+	require.Equal(t, StateFailed, sv.Status()[0].State)
+	require.Equal(t, 0, sv.Status()[0].Restarts)
+}
@@ -1,94 +1,211 @@
 package config
 
 import (
+	"errors"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/urfave/cli/v2"
 
 	"github.com/ethereum/go-ethereum/log"
 
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
 	"github.com/CavnHan/multichain-sync-account/flags"
 )
 
-const (
-	defaulConfirmations         = 64
-	defaultSynchronizerInterval = 5000
-	defaultWorkerInterval       = 500
-	defaultBlocksStep           = 500
-)
-
 type Config struct {
-	Migrations     string
-	ChainNode      ChainNodeConfig
-	MasterDB       DBConfig
-	SlaveDB        DBConfig
-	SlaveDbEnable  bool
-	ApiCacheEnable bool
-	CacheConfig    CacheConfig
-	RpcServer      ServerConfig
-	MetricsServer  ServerConfig
-	ChainAccountRpc string
+	Migrations               string             `yaml:"migrations_dir"`
+	ChainNode                ChainNodeConfig    `yaml:"chain"`
+	MasterDB                 DBConfig           `yaml:"master_db"`
+	SlaveDB                  DBConfig           `yaml:"slave_db"`
+	SlaveDbEnable            bool               `yaml:"slave_db_enable"`
+	SlaveDbMaxLag            time.Duration      `yaml:"slave_db_max_lag"`
+	ApiCacheEnable           bool               `yaml:"api_cache_enable"`
+	CacheConfig              CacheConfig        `yaml:"api_cache"`
+	RpcServer                ServerConfig       `yaml:"rpc_server"`
+	HttpServer               ServerConfig       `yaml:"http_server"`
+	ConsumerTokens           []string           `yaml:"consumer_tokens"`
+	SignerTokens             []string           `yaml:"signer_tokens"`
+	SigningJobTimeout        time.Duration      `yaml:"signing_job_timeout"`
+	LocalSignerKeystore      string             `yaml:"local_signer_keystore"`
+	LocalSignerPasswordFile  string             `yaml:"local_signer_password_file"`
+	MetricsServer            ServerConfig       `yaml:"metrics_server"`
+	ChainAccountRpc          string             `yaml:"chain_account_rpc"`
+	ChainAccount             ChainAccountConfig `yaml:"chain_account"`
+	ChainAccountRecord       string             `yaml:"chain_account_record"`
+	ChainAccountRecordWindow time.Duration      `yaml:"chain_account_record_window"`
+	ChainAccountReplay       string             `yaml:"chain_account_replay"`
+	BusinessRefreshInterval  time.Duration      `yaml:"business_refresh_interval"`
+	AddressPoolSize          int                `yaml:"address_pool_size"`
+	MultisendContract        string             `yaml:"multisend_contract"`
+	EventFileDir             string             `yaml:"event_file_dir"`
+	EventBrokerUrl           string             `yaml:"event_broker_url"`
+	EventVisibilityTimeout   time.Duration      `yaml:"event_visibility_timeout"`
+	LeaderLeaseTTL           time.Duration      `yaml:"leader_lease_ttl"`
+	WorkerFailureBudget      int                `yaml:"worker_failure_budget"`
+	WorkerFailureWindow      time.Duration      `yaml:"worker_failure_window"`
 }
 
 type ChainNodeConfig struct {
-	ChainId              uint64
-	ChainName            string
-	RpcUrl               string
-	StartingHeight       uint
-	Confirmations        uint
-	SynchronizerInterval time.Duration
-	WorkerInterval       time.Duration
-	BlocksStep           uint64
+	ChainId              uint64        `yaml:"chain_id"`
+	ChainName            string        `yaml:"chain_name"`
+	RpcUrl               string        `yaml:"rpc_url"`
+	StartingHeight       uint          `yaml:"starting_height"`
+	Confirmations        uint          `yaml:"confirmations"`
+	SynchronizerInterval time.Duration `yaml:"sync_interval"`
+	WorkerInterval       time.Duration `yaml:"worker_interval"`
+	BlocksStep           uint64        `yaml:"blocks_step"`
+	FeePolicy            FeePolicy     `yaml:"fee_policy"`
+	Tokens               []TokenConfig `yaml:"tokens"`
+}
+
+// FeePolicy 覆盖构建交易时的默认手续费参数，为空时使用内置默认值
+type FeePolicy struct {
+	MaxFeePerGas         string `yaml:"max_fee_per_gas,omitempty"`
+	MaxPriorityFeePerGas string `yaml:"max_priority_fee_per_gas,omitempty"`
+	UtxoFeeRate          int64  `yaml:"utxo_fee_rate,omitempty"` // sat/vB
+}
+
+// TokenConfig 新注册业务方默认导入的代币
+type TokenConfig struct {
+	Symbol        string `yaml:"symbol"`
+	Address       string `yaml:"address"`
+	Decimals      uint8  `yaml:"decimals"`
+	CollectAmount string `yaml:"collect_amount,omitempty"`
+	ColdAmount    string `yaml:"cold_amount,omitempty"`
 }
 
 type DBConfig struct {
-	Host     string
-	Port     int
-	Name     string
-	User     string
-	Password string
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Name     string `yaml:"name"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password"`
 }
 
 type CacheConfig struct {
-	ListSize         int
-	DetailSize       int
-	ListExpireTime   time.Duration
-	DetailExpireTime time.Duration
+	ListSize         int           `yaml:"list_size"`
+	DetailSize       int           `yaml:"detail_size"`
+	ListExpireTime   time.Duration `yaml:"list_expire_time"`
+	DetailExpireTime time.Duration `yaml:"detail_expire_time"`
 }
 
-type ServerConfig struct {
-	Host string
-	Port int
+// ChainAccountConfig chain-account 调用的超时、重试和多节点健康检查
+type ChainAccountConfig struct {
+	Timeout       time.Duration `yaml:"timeout"`
+	Retries       int           `yaml:"retries"`
+	MaxLag        uint64        `yaml:"max_lag"`
+	CheckInterval time.Duration `yaml:"check_interval"`
 }
 
-func LoadConfig(cliCtx *cli.Context) (Config, error) {
-	var cfg Config
-	cfg = NewConfig(cliCtx)
+// ChainAccountEndpoints chain-account-rpc 按逗号拆分的节点列表，第一个为首选节点
+func (c *Config) ChainAccountEndpoints() []string {
+	var endpoints []string
+	for _, endpoint := range strings.Split(c.ChainAccountRpc, ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// AllBusinesses 可访问全部业务方的 consumer token 作用域
+const AllBusinesses = "*"
+
+// ConsumerToken 业务方 token 及其可访问的业务方，配置格式为 token:biz1|biz2，token:* 可访问全部业务方
+type ConsumerToken struct {
+	Token       string
+	BusinessIds []string
+}
 
-	if cfg.ChainNode.Confirmations == 0 {
-		cfg.ChainNode.Confirmations = defaulConfirmations
+func ParseConsumerToken(entry string) (ConsumerToken, error) {
+	idx := strings.LastIndex(entry, ":")
+	if idx <= 0 {
+		return ConsumerToken{}, errors.New("consumer token must be scoped as token:business_id|... or token:*")
 	}
+	var businessIds []string
+	for _, businessId := range strings.Split(entry[idx+1:], "|") {
+		if businessId = strings.TrimSpace(businessId); businessId != "" {
+			businessIds = append(businessIds, businessId)
+		}
+	}
+	if len(businessIds) == 0 {
+		return ConsumerToken{}, errors.New("consumer token must list at least one business id or *")
+	}
+	return ConsumerToken{Token: entry[:idx], BusinessIds: businessIds}, nil
+}
 
-	if cfg.ChainNode.SynchronizerInterval == 0 {
-		cfg.ChainNode.SynchronizerInterval = defaultSynchronizerInterval
+// ChainAccountRecordFile rpc 等进程与 sync 使用同一份配置时各自录制，role 插在文件名的第一个扩展名之前，
+// 例如 calls.jsonl.gz 变为 calls.rpc.jsonl.gz
+func (c *Config) ChainAccountRecordFile(role string) string {
+	if c.ChainAccountRecord == "" || role == "" {
+		return c.ChainAccountRecord
+	}
+	dir, base := filepath.Split(c.ChainAccountRecord)
+	if i := strings.Index(base, "."); i > 0 {
+		return dir + base[:i] + "." + role + base[i:]
 	}
+	return c.ChainAccountRecord + "." + role
+}
 
-	if cfg.ChainNode.WorkerInterval == 0 {
-		cfg.ChainNode.WorkerInterval = defaultWorkerInterval
+type ServerConfig struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+}
+
+// LoadConfig 合并配置文件、环境变量和命令行参数，优先级从低到高，合并后做完整校验
+func LoadConfig(cliCtx *cli.Context) (Config, error) {
+	var chain *ChainFile
+	if path := cliCtx.String(flags.ConfigFileFlag.Name); path != "" {
+		var err error
+		chain, err = applyFile(cliCtx, path)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+	cfg := NewConfig(cliCtx)
+	if chain != nil {
+		cfg.ChainNode.FeePolicy = chain.FeePolicy
+		cfg.ChainNode.Tokens = chain.Tokens
 	}
 
-	if cfg.ChainNode.BlocksStep == 0 {
-		cfg.ChainNode.BlocksStep = defaultBlocksStep
+	// 地址和哈希按配置的链规范化后落库
+	if cfg.ChainNode.ChainName != "" {
+		if err := chainaddr.SetDefaultChain(cfg.ChainNode.ChainName); err != nil {
+			return cfg, err
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
 	}
 
-	log.Info("loaded chain config", "config", cfg.ChainNode)
+	log.Info("loaded chain config", "config", cfg.Redacted().ChainNode)
 	return cfg, nil
 }
 
 func NewConfig(ctx *cli.Context) Config {
 	return Config{
-		Migrations: ctx.String(flags.MigrationsFlag.Name),
+		Migrations:      ctx.String(flags.MigrationsFlag.Name),
 		ChainAccountRpc: ctx.String(flags.ChainAccountRpcFlag.Name),
+		ChainAccount: ChainAccountConfig{
+			Timeout:       ctx.Duration(flags.ChainAccountTimeoutFlag.Name),
+			Retries:       ctx.Int(flags.ChainAccountRetriesFlag.Name),
+			MaxLag:        ctx.Uint64(flags.ChainAccountMaxLagFlag.Name),
+			CheckInterval: ctx.Duration(flags.ChainAccountCheckIntervalFlag.Name),
+		},
+		ChainAccountRecord:       ctx.String(flags.ChainAccountRecordFlag.Name),
+		ChainAccountRecordWindow: ctx.Duration(flags.ChainAccountRecordWindowFlag.Name),
+		ChainAccountReplay:       ctx.String(flags.ChainAccountReplayFlag.Name),
+		BusinessRefreshInterval:  ctx.Duration(flags.BusinessRefreshIntervalFlag.Name),
+		AddressPoolSize:          ctx.Int(flags.AddressPoolSizeFlag.Name),
+		MultisendContract:        ctx.String(flags.MultisendContractFlag.Name),
+		EventFileDir:             ctx.String(flags.EventFileDirFlag.Name),
+		EventBrokerUrl:           ctx.String(flags.EventBrokerUrlFlag.Name),
+		EventVisibilityTimeout:   ctx.Duration(flags.EventVisibilityTimeoutFlag.Name),
+		LeaderLeaseTTL:           ctx.Duration(flags.LeaderLeaseTtlFlag.Name),
+		WorkerFailureBudget:      ctx.Int(flags.WorkerFailureBudgetFlag.Name),
+		WorkerFailureWindow:      ctx.Duration(flags.WorkerFailureWindowFlag.Name),
 		ChainNode: ChainNodeConfig{
 			ChainId:              ctx.Uint64(flags.ChainIdFlag.Name),
 			ChainName:            ctx.String(flags.ChainNameFlag.Name),
@@ -114,6 +231,7 @@ func NewConfig(ctx *cli.Context) Config {
 			Password: ctx.String(flags.SlaveDbPasswordFlag.Name),
 		},
 		SlaveDbEnable:  ctx.Bool(flags.SlaveDbEnableFlag.Name),
+		SlaveDbMaxLag:  ctx.Duration(flags.SlaveDbMaxLagFlag.Name),
 		ApiCacheEnable: ctx.Bool(flags.ApiCacheEnableFlag.Name),
 		CacheConfig: CacheConfig{
 			ListSize:         ctx.Int(flags.ApiCacheListSizeFlag.Name),
@@ -125,6 +243,15 @@ func NewConfig(ctx *cli.Context) Config {
 			Host: ctx.String(flags.RpcHostFlag.Name),
 			Port: ctx.Int(flags.RpcPortFlag.Name),
 		},
+		HttpServer: ServerConfig{
+			Host: ctx.String(flags.HttpHostFlag.Name),
+			Port: ctx.Int(flags.HttpPortFlag.Name),
+		},
+		ConsumerTokens:          ctx.StringSlice(flags.ConsumerTokensFlag.Name),
+		SignerTokens:            ctx.StringSlice(flags.SignerTokensFlag.Name),
+		SigningJobTimeout:       ctx.Duration(flags.SigningJobTimeoutFlag.Name),
+		LocalSignerKeystore:     ctx.String(flags.LocalSignerKeystoreFlag.Name),
+		LocalSignerPasswordFile: ctx.String(flags.LocalSignerPasswordFileFlag.Name),
 		MetricsServer: ServerConfig{
 			Host: ctx.String(flags.MetricsHostFlag.Name),
 			Port: ctx.Int(flags.MetricsPortFlag.Name),
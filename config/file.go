@@ -0,0 +1,250 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/CavnHan/multichain-sync-account/flags"
+)
+
+// File 配置文件结构，YAML 和 TOML 使用相同的键名，未知键报错。
+// 命令行参数和环境变量优先于配置文件
+type File struct {
+	MigrationsDir           string               `yaml:"migrations_dir"`
+	ChainAccountRpc         string               `yaml:"chain_account_rpc"` // 多个节点用逗号分隔
+	ChainAccount            ChainAccountConfig   `yaml:"chain_account"`
+	Chain                   string               `yaml:"chain"` // 当前进程同步的链，只有一条链时可省略
+	Chains                  map[string]ChainFile `yaml:"chains"`
+	BusinessRefreshInterval time.Duration        `yaml:"business_refresh_interval"`
+	AddressPoolSize         int                  `yaml:"address_pool_size"`
+	ConsumerTokens          []string             `yaml:"consumer_tokens"`
+	SignerTokens            []string             `yaml:"signer_tokens"`
+	SigningJobTimeout       time.Duration        `yaml:"signing_job_timeout"`
+	LocalSigner             LocalSignerFile      `yaml:"local_signer"`
+	RpcServer               ServerConfig         `yaml:"rpc_server"`
+	HttpServer              ServerConfig         `yaml:"http_server"`
+	MetricsServer           ServerConfig         `yaml:"metrics_server"`
+	MasterDB                DBConfig             `yaml:"master_db"`
+	SlaveDB                 SlaveDBFile          `yaml:"slave_db"`
+	ApiCache                ApiCacheFile         `yaml:"api_cache"`
+	Events                  EventsFile           `yaml:"events"`
+	LeaderLeaseTTL          time.Duration        `yaml:"leader_lease_ttl"`
+	WorkerFailureBudget     int                  `yaml:"worker_failure_budget"`
+	WorkerFailureWindow     time.Duration        `yaml:"worker_failure_window"`
+}
+
+// ChainFile 每条链一段配置，键为链名
+type ChainFile struct {
+	ChainId              uint64        `yaml:"chain_id"`
+	RpcUrl               string        `yaml:"rpc_url"`
+	StartingHeight       uint          `yaml:"starting_height"`
+	Confirmations        uint          `yaml:"confirmations"`
+	SynchronizerInterval time.Duration `yaml:"sync_interval"`
+	WorkerInterval       time.Duration `yaml:"worker_interval"`
+	BlocksStep           uint64        `yaml:"blocks_step"`
+	MultisendContract    string        `yaml:"multisend_contract"`
+	FeePolicy            FeePolicy     `yaml:"fee_policy"`
+	Tokens               []TokenConfig `yaml:"tokens"`
+}
+
+type SlaveDBFile struct {
+	DBConfig `yaml:",inline"`
+	Enable   bool          `yaml:"enable"`
+	MaxLag   time.Duration `yaml:"max_lag"`
+}
+
+type ApiCacheFile struct {
+	CacheConfig `yaml:",inline"`
+	Enable      bool `yaml:"enable"`
+}
+
+type EventsFile struct {
+	FileDir           string        `yaml:"file_dir"`
+	BrokerUrl         string        `yaml:"broker_url"`
+	VisibilityTimeout time.Duration `yaml:"visibility_timeout"`
+}
+
+// LocalSignerFile 开发网和测试用的本地签名 keystore
+type LocalSignerFile struct {
+	Keystore     string `yaml:"keystore"`
+	PasswordFile string `yaml:"password_file"`
+}
+
+// LoadFile 按扩展名解析 .yaml/.yml/.toml 配置文件
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+	case ".toml":
+		tree, err := parseToml(data)
+		if err != nil {
+			return nil, err
+		}
+		// TOML 先转成 YAML，两种格式共用同一套严格解码
+		if data, err = yaml.Marshal(tree); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file %s, expect .yaml, .yml or .toml", path)
+	}
+	var file File
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// SelectChain 命令行指定的链优先，其次是文件中的 chain，只配置了一条链时直接使用
+func (f *File) SelectChain(chainName string) (string, *ChainFile, error) {
+	if chainName == "" {
+		chainName = f.Chain
+	}
+	if chainName == "" && len(f.Chains) == 1 {
+		for name := range f.Chains {
+			chainName = name
+		}
+	}
+	if len(f.Chains) == 0 {
+		return chainName, nil, nil
+	}
+	if chainName == "" {
+		names := make([]string, 0, len(f.Chains))
+		for name := range f.Chains {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", nil, fmt.Errorf("multiple chains configured (%s), select one with chain or --%s", strings.Join(names, ", "), flags.ChainNameFlag.Name)
+	}
+	for name, chain := range f.Chains {
+		if strings.EqualFold(name, chainName) {
+			return chainName, &chain, nil
+		}
+	}
+	return "", nil, fmt.Errorf("chain %s is not configured in chains", chainName)
+}
+
+// flagValues 把配置文件展开为命令行参数值，零值视为未配置
+func (f *File) flagValues(chainName string, chain *ChainFile) map[string][]string {
+	values := make(map[string][]string)
+	str := func(flag cli.Flag, value string) {
+		if value != "" {
+			values[flag.Names()[0]] = []string{value}
+		}
+	}
+	num := func(flag cli.Flag, value uint64) {
+		if value != 0 {
+			str(flag, strconv.FormatUint(value, 10))
+		}
+	}
+	dur := func(flag cli.Flag, value time.Duration) {
+		if value != 0 {
+			str(flag, value.String())
+		}
+	}
+	boolean := func(flag cli.Flag, value bool) {
+		if value {
+			str(flag, "true")
+		}
+	}
+
+	str(flags.MigrationsFlag, f.MigrationsDir)
+	str(flags.ChainAccountRpcFlag, f.ChainAccountRpc)
+	dur(flags.ChainAccountTimeoutFlag, f.ChainAccount.Timeout)
+	num(flags.ChainAccountRetriesFlag, uint64(f.ChainAccount.Retries))
+	num(flags.ChainAccountMaxLagFlag, f.ChainAccount.MaxLag)
+	dur(flags.ChainAccountCheckIntervalFlag, f.ChainAccount.CheckInterval)
+	str(flags.ChainNameFlag, chainName)
+	dur(flags.BusinessRefreshIntervalFlag, f.BusinessRefreshInterval)
+	num(flags.AddressPoolSizeFlag, uint64(f.AddressPoolSize))
+	if len(f.ConsumerTokens) > 0 {
+		values[flags.ConsumerTokensFlag.Name] = f.ConsumerTokens
+	}
+	if len(f.SignerTokens) > 0 {
+		values[flags.SignerTokensFlag.Name] = f.SignerTokens
+	}
+	dur(flags.SigningJobTimeoutFlag, f.SigningJobTimeout)
+	str(flags.LocalSignerKeystoreFlag, f.LocalSigner.Keystore)
+	str(flags.LocalSignerPasswordFileFlag, f.LocalSigner.PasswordFile)
+	str(flags.RpcHostFlag, f.RpcServer.Host)
+	num(flags.RpcPortFlag, uint64(f.RpcServer.Port))
+	str(flags.HttpHostFlag, f.HttpServer.Host)
+	num(flags.HttpPortFlag, uint64(f.HttpServer.Port))
+	str(flags.MetricsHostFlag, f.MetricsServer.Host)
+	num(flags.MetricsPortFlag, uint64(f.MetricsServer.Port))
+	str(flags.MasterDbHostFlag, f.MasterDB.Host)
+	num(flags.MasterDbPortFlag, uint64(f.MasterDB.Port))
+	str(flags.MasterDbNameFlag, f.MasterDB.Name)
+	str(flags.MasterDbUserFlag, f.MasterDB.User)
+	str(flags.MasterDbPasswordFlag, f.MasterDB.Password)
+	boolean(flags.SlaveDbEnableFlag, f.SlaveDB.Enable)
+	str(flags.SlaveDbHostFlag, f.SlaveDB.Host)
+	num(flags.SlaveDbPortFlag, uint64(f.SlaveDB.Port))
+	str(flags.SlaveDbNameFlag, f.SlaveDB.Name)
+	str(flags.SlaveDbUserFlag, f.SlaveDB.User)
+	str(flags.SlaveDbPasswordFlag, f.SlaveDB.Password)
+	dur(flags.SlaveDbMaxLagFlag, f.SlaveDB.MaxLag)
+	boolean(flags.ApiCacheEnableFlag, f.ApiCache.Enable)
+	num(flags.ApiCacheListSizeFlag, uint64(f.ApiCache.ListSize))
+	num(flags.ApiCacheDetailSizeFlag, uint64(f.ApiCache.DetailSize))
+	dur(flags.ApiCacheListExpireTimeFlag, f.ApiCache.ListExpireTime)
+	dur(flags.ApiCacheDetailExpireTimeFlag, f.ApiCache.DetailExpireTime)
+	str(flags.EventFileDirFlag, f.Events.FileDir)
+	str(flags.EventBrokerUrlFlag, f.Events.BrokerUrl)
+	dur(flags.EventVisibilityTimeoutFlag, f.Events.VisibilityTimeout)
+	dur(flags.LeaderLeaseTtlFlag, f.LeaderLeaseTTL)
+	num(flags.WorkerFailureBudgetFlag, uint64(f.WorkerFailureBudget))
+	dur(flags.WorkerFailureWindowFlag, f.WorkerFailureWindow)
+
+	if chain != nil {
+		num(flags.ChainIdFlag, chain.ChainId)
+		str(flags.RpcUrlFlag, chain.RpcUrl)
+		num(flags.StartingHeightFlag, uint64(chain.StartingHeight))
+		num(flags.ConfirmationsFlag, uint64(chain.Confirmations))
+		dur(flags.SynchronizerIntervalFlag, chain.SynchronizerInterval)
+		dur(flags.WorkerIntervalFlag, chain.WorkerInterval)
+		num(flags.BlocksStepFlag, chain.BlocksStep)
+		str(flags.MultisendContractFlag, chain.MultisendContract)
+	}
+	return values
+}
+
+// applyFile 把配置文件中的值写入没有通过命令行或环境变量设置的参数，返回选中的链配置
+func applyFile(cliCtx *cli.Context, path string) (*ChainFile, error) {
+	file, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	chainName := ""
+	if cliCtx.IsSet(flags.ChainNameFlag.Name) {
+		chainName = cliCtx.String(flags.ChainNameFlag.Name)
+	}
+	chainName, chain, err := file.SelectChain(chainName)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range file.flagValues(chainName, chain) {
+		if cliCtx.IsSet(name) {
+			continue
+		}
+		for _, value := range values {
+			if err := cliCtx.Set(name, value); err != nil {
+				return nil, fmt.Errorf("apply config %s=%s: %w", name, value, err)
+			}
+		}
+	}
+	return chain, nil
+}
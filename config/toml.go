@@ -0,0 +1,375 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseToml 解析配置文件用到的 TOML 子集：表、表数组、点分键、字符串、整数、浮点、布尔、数组和内联表，
+// 不支持多行字符串和日期时间
+func parseToml(data []byte) (map[string]any, error) {
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("toml: invalid utf-8")
+	}
+	p := &tomlParser{src: []rune(string(data)), line: 1, root: make(map[string]any)}
+	p.current = p.root
+	if err := p.parse(); err != nil {
+		return nil, fmt.Errorf("toml: line %d: %w", p.line, err)
+	}
+	return p.root, nil
+}
+
+type tomlParser struct {
+	src     []rune
+	pos     int
+	line    int
+	root    map[string]any
+	current map[string]any
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *tomlParser) next() rune {
+	r := p.src[p.pos]
+	p.pos++
+	if r == '\n' {
+		p.line++
+	}
+	return r
+}
+
+// skip 跳过空白和注释，newlines 为 true 时同时跳过换行
+func (p *tomlParser) skip(newlines bool) {
+	for !p.eof() {
+		switch r := p.peek(); {
+		case r == ' ' || r == '\t' || r == '\r':
+			p.next()
+		case r == '\n' && newlines:
+			p.next()
+		case r == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.next()
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) parse() error {
+	for {
+		p.skip(true)
+		if p.eof() {
+			return nil
+		}
+		var err error
+		if p.peek() == '[' {
+			err = p.parseHeader()
+		} else {
+			err = p.parseKeyValue(p.current)
+		}
+		if err != nil {
+			return err
+		}
+		p.skip(false)
+		if !p.eof() && p.peek() != '\n' {
+			return fmt.Errorf("unexpected %q after statement", p.peek())
+		}
+	}
+}
+
+func (p *tomlParser) parseHeader() error {
+	p.next()
+	array := false
+	if p.peek() == '[' {
+		p.next()
+		array = true
+	}
+	p.skip(false)
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skip(false)
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	for _, r := range closing {
+		if p.eof() || p.next() != r {
+			return fmt.Errorf("unterminated table header")
+		}
+	}
+
+	table := p.root
+	for i, key := range keys {
+		last := i == len(keys)-1
+		existing, ok := table[key]
+		switch {
+		case !ok && last && array:
+			item := make(map[string]any)
+			table[key] = []any{item}
+			table = item
+		case !ok:
+			item := make(map[string]any)
+			table[key] = item
+			table = item
+		default:
+			switch value := existing.(type) {
+			case map[string]any:
+				if last && array {
+					return fmt.Errorf("key %q is already a table", key)
+				}
+				table = value
+			case []any:
+				tables, ok := value[len(value)-1].(map[string]any)
+				if !ok {
+					return fmt.Errorf("key %q is not an array of tables", key)
+				}
+				if last && array {
+					item := make(map[string]any)
+					table[key] = append(value, item)
+					tables = item
+				}
+				table = tables
+			default:
+				return fmt.Errorf("key %q is already defined", key)
+			}
+		}
+	}
+	p.current = table
+	return nil
+}
+
+func (p *tomlParser) parseKeyValue(table map[string]any) error {
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skip(false)
+	if p.eof() || p.next() != '=' {
+		return fmt.Errorf("expected '=' after key %q", strings.Join(keys, "."))
+	}
+	p.skip(false)
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys[:len(keys)-1] {
+		existing, ok := table[key]
+		if !ok {
+			item := make(map[string]any)
+			table[key] = item
+			table = item
+			continue
+		}
+		item, ok := existing.(map[string]any)
+		if !ok {
+			return fmt.Errorf("key %q is already defined", key)
+		}
+		table = item
+	}
+	key := keys[len(keys)-1]
+	if _, ok := table[key]; ok {
+		return fmt.Errorf("duplicate key %q", key)
+	}
+	table[key] = value
+	return nil
+}
+
+func (p *tomlParser) parseKey() ([]string, error) {
+	var keys []string
+	for {
+		p.skip(false)
+		var key string
+		switch r := p.peek(); {
+		case r == '"':
+			value, err := p.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			key = value
+		case r == '\'':
+			value, err := p.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			key = value
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyRune(p.peek()) {
+				p.next()
+			}
+			if start == p.pos {
+				return nil, fmt.Errorf("invalid key")
+			}
+			key = string(p.src[start:p.pos])
+		}
+		keys = append(keys, key)
+		p.skip(false)
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.next()
+	}
+}
+
+func isBareKeyRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-'
+}
+
+func (p *tomlParser) parseValue() (any, error) {
+	switch r := p.peek(); {
+	case r == '"':
+		return p.parseBasicString()
+	case r == '\'':
+		return p.parseLiteralString()
+	case r == '[':
+		return p.parseArray()
+	case r == '{':
+		return p.parseInlineTable()
+	case p.eof() || r == '\n':
+		return nil, fmt.Errorf("missing value")
+	}
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", p.peek()) {
+		p.next()
+	}
+	token := string(p.src[start:p.pos])
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	number := strings.ReplaceAll(token, "_", "")
+	if value, err := strconv.ParseInt(number, 0, 64); err == nil {
+		return value, nil
+	}
+	if value, err := strconv.ParseFloat(number, 64); err == nil {
+		return value, nil
+	}
+	return nil, fmt.Errorf("unsupported value %q", token)
+}
+
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.next()
+	var sb strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", fmt.Errorf("unterminated string")
+		}
+		r := p.next()
+		switch r {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+			if p.eof() {
+				return "", fmt.Errorf("unterminated string")
+			}
+			switch escaped := p.next(); escaped {
+			case 'n':
+				sb.WriteRune('\n')
+			case 't':
+				sb.WriteRune('\t')
+			case 'r':
+				sb.WriteRune('\r')
+			case '"', '\\':
+				sb.WriteRune(escaped)
+			case 'u', 'U':
+				size := 4
+				if escaped == 'U' {
+					size = 8
+				}
+				if p.pos+size > len(p.src) {
+					return "", fmt.Errorf("invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(string(p.src[p.pos:p.pos+size]), 16, 32)
+				if err != nil {
+					return "", fmt.Errorf("invalid unicode escape")
+				}
+				p.pos += size
+				sb.WriteRune(rune(code))
+			default:
+				return "", fmt.Errorf("invalid escape \\%c", escaped)
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+}
+
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.next()
+	start := p.pos
+	for !p.eof() && p.peek() != '\'' && p.peek() != '\n' {
+		p.next()
+	}
+	if p.eof() || p.peek() != '\'' {
+		return "", fmt.Errorf("unterminated string")
+	}
+	value := string(p.src[start:p.pos])
+	p.next()
+	return value, nil
+}
+
+func (p *tomlParser) parseArray() ([]any, error) {
+	p.next()
+	values := []any{}
+	for {
+		p.skip(true)
+		if p.peek() == ']' {
+			p.next()
+			return values, nil
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		p.skip(true)
+		switch p.peek() {
+		case ',':
+			p.next()
+		case ']':
+		default:
+			return nil, fmt.Errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (map[string]any, error) {
+	p.next()
+	table := make(map[string]any)
+	p.skip(false)
+	if p.peek() == '}' {
+		p.next()
+		return table, nil
+	}
+	for {
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skip(false)
+		switch p.peek() {
+		case ',':
+			p.next()
+		case '}':
+			p.next()
+			return table, nil
+		default:
+			return nil, fmt.Errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/flags"
+	"github.com/CavnHan/multichain-sync-account/signer"
+)
+
+const redacted = "******"
+
+// Validate 检查必填项和取值范围，一次返回全部问题
+func (c *Config) Validate() error {
+	var errs []error
+	required := func(value string, name string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", name))
+		}
+	}
+	between := func(value, min, max int64, name string) {
+		if value < min || value > max {
+			errs = append(errs, fmt.Errorf("%s must be between %d and %d, got %d", name, min, max, value))
+		}
+	}
+	atLeast := func(value, min time.Duration, name string) {
+		if value < min {
+			errs = append(errs, fmt.Errorf("%s must be at least %s, got %s", name, min, value))
+		}
+	}
+
+	required(c.ChainNode.ChainName, flags.ChainNameFlag.Name)
+	if c.ChainNode.ChainId == 0 {
+		errs = append(errs, fmt.Errorf("%s is required", flags.ChainIdFlag.Name))
+	}
+	required(c.ChainNode.RpcUrl, flags.RpcUrlFlag.Name)
+	// 回放录制文件时不访问 chain-account 服务
+	if c.ChainAccountReplay == "" {
+		required(c.ChainAccountRpc, flags.ChainAccountRpcFlag.Name)
+	} else if c.ChainAccountRecord != "" {
+		errs = append(errs, fmt.Errorf("%s and %s cannot be used together", flags.ChainAccountRecordFlag.Name, flags.ChainAccountReplayFlag.Name))
+	}
+	atLeast(c.ChainAccount.Timeout, 100*time.Millisecond, flags.ChainAccountTimeoutFlag.Name)
+	between(int64(c.ChainAccount.Retries), 1, 10, flags.ChainAccountRetriesFlag.Name)
+	if c.ChainAccount.CheckInterval != 0 {
+		atLeast(c.ChainAccount.CheckInterval, time.Second, flags.ChainAccountCheckIntervalFlag.Name)
+	}
+	if c.ChainAccountRecordWindow < 0 {
+		errs = append(errs, fmt.Errorf("%s must not be negative, got %s", flags.ChainAccountRecordWindowFlag.Name, c.ChainAccountRecordWindow))
+	}
+	between(int64(c.ChainNode.Confirmations), 1, 10_000, flags.ConfirmationsFlag.Name)
+	between(int64(c.ChainNode.BlocksStep), 1, 10_000, flags.BlocksStepFlag.Name)
+	atLeast(c.ChainNode.SynchronizerInterval, 100*time.Millisecond, flags.SynchronizerIntervalFlag.Name)
+	atLeast(c.ChainNode.WorkerInterval, 100*time.Millisecond, flags.WorkerIntervalFlag.Name)
+	atLeast(c.BusinessRefreshInterval, time.Second, flags.BusinessRefreshIntervalFlag.Name)
+	atLeast(c.EventVisibilityTimeout, time.Second, flags.EventVisibilityTimeoutFlag.Name)
+	// 每 ttl/3 续约一次
+	atLeast(c.LeaderLeaseTTL, 3*time.Second, flags.LeaderLeaseTtlFlag.Name)
+	between(int64(c.WorkerFailureBudget), 0, 1000, flags.WorkerFailureBudgetFlag.Name)
+	atLeast(c.WorkerFailureWindow, time.Second, flags.WorkerFailureWindowFlag.Name)
+	between(int64(c.AddressPoolSize), 0, 100_000, flags.AddressPoolSizeFlag.Name)
+	atLeast(c.SigningJobTimeout, 10*time.Second, flags.SigningJobTimeoutFlag.Name)
+	// 业务方 token 必须限定可访问的业务方
+	var consumerTokens []string
+	for i, entry := range c.ConsumerTokens {
+		token, err := ParseConsumerToken(entry)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s[%d]: %w", flags.ConsumerTokensFlag.Name, i, err))
+			continue
+		}
+		consumerTokens = append(consumerTokens, token.Token)
+	}
+	// 签名机单独鉴权，业务方 token 不能调用签名队列
+	for _, token := range c.SignerTokens {
+		if token != "" && slices.Contains(consumerTokens, token) {
+			errs = append(errs, fmt.Errorf("%s must not reuse %s", flags.SignerTokensFlag.Name, flags.ConsumerTokensFlag.Name))
+			break
+		}
+	}
+	// 本地签名只用于开发网和测试，不在开发网和测试网名单中的链 id 直接拒绝启动
+	if c.LocalSignerKeystore != "" {
+		required(c.LocalSignerPasswordFile, flags.LocalSignerPasswordFileFlag.Name)
+		if !signer.IsDevChain(c.ChainNode.ChainId) {
+			errs = append(errs, fmt.Errorf("%s is only allowed on dev and test chain ids, got %d", flags.LocalSignerKeystoreFlag.Name, c.ChainNode.ChainId))
+		}
+	}
+
+	required(c.RpcServer.Host, flags.RpcHostFlag.Name)
+	between(int64(c.RpcServer.Port), 1, 65535, flags.RpcPortFlag.Name)
+	between(int64(c.HttpServer.Port), 0, 65535, flags.HttpPortFlag.Name)
+	required(c.MetricsServer.Host, flags.MetricsHostFlag.Name)
+	between(int64(c.MetricsServer.Port), 1, 65535, flags.MetricsPortFlag.Name)
+
+	required(c.MasterDB.Host, flags.MasterDbHostFlag.Name)
+	between(int64(c.MasterDB.Port), 1, 65535, flags.MasterDbPortFlag.Name)
+	required(c.MasterDB.Name, flags.MasterDbNameFlag.Name)
+	required(c.MasterDB.User, flags.MasterDbUserFlag.Name)
+	if c.SlaveDbEnable {
+		required(c.SlaveDB.Host, flags.SlaveDbHostFlag.Name)
+		between(int64(c.SlaveDB.Port), 1, 65535, flags.SlaveDbPortFlag.Name)
+		required(c.SlaveDB.Name, flags.SlaveDbNameFlag.Name)
+	}
+	if c.ApiCacheEnable {
+		between(int64(c.CacheConfig.ListSize), 1, 1_000_000, flags.ApiCacheListSizeFlag.Name)
+		between(int64(c.CacheConfig.DetailSize), 1, 1_000_000, flags.ApiCacheDetailSizeFlag.Name)
+	}
+	if c.EventBrokerUrl != "" {
+		if _, err := url.Parse(c.EventBrokerUrl); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", flags.EventBrokerUrlFlag.Name, err))
+		}
+	}
+
+	policy := c.ChainNode.FeePolicy
+	for name, value := range map[string]string{"fee_policy.max_fee_per_gas": policy.MaxFeePerGas, "fee_policy.max_priority_fee_per_gas": policy.MaxPriorityFeePerGas} {
+		if value == "" {
+			continue
+		}
+		if amount, ok := new(big.Int).SetString(value, 10); !ok || amount.Sign() <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be a positive integer, got %q", name, value))
+		}
+	}
+	if policy.UtxoFeeRate < 0 {
+		errs = append(errs, fmt.Errorf("fee_policy.utxo_fee_rate must not be negative"))
+	}
+	for i, token := range c.ChainNode.Tokens {
+		required(token.Symbol, fmt.Sprintf("tokens[%d].symbol", i))
+		// 链名无效时已在上面报错，此时按默认链解析地址没有意义
+		if c.ChainNode.ChainName != "" {
+			if address, err := chainaddr.ParseAddress(token.Address); err != nil || address.IsZero() {
+				errs = append(errs, fmt.Errorf("tokens[%d].address %q is invalid for chain %s", i, token.Address, c.ChainNode.ChainName))
+			}
+		}
+		for name, value := range map[string]string{"collect_amount": token.CollectAmount, "cold_amount": token.ColdAmount} {
+			if _, ok := new(big.Int).SetString(value, 10); value != "" && !ok {
+				errs = append(errs, fmt.Errorf("tokens[%d].%s must be an integer, got %q", i, name, value))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Redacted 返回隐藏了密码、consumer/signer token 和 URL 凭据的副本，用于打印配置
+func (c Config) Redacted() Config {
+	if c.MasterDB.Password != "" {
+		c.MasterDB.Password = redacted
+	}
+	if c.SlaveDB.Password != "" {
+		c.SlaveDB.Password = redacted
+	}
+	c.ConsumerTokens = redactAll(c.ConsumerTokens)
+	c.SignerTokens = redactAll(c.SignerTokens)
+	c.ChainNode.RpcUrl = redactUrl(c.ChainNode.RpcUrl)
+	c.EventBrokerUrl = redactUrl(c.EventBrokerUrl)
+	return c
+}
+
+func redactAll(values []string) []string {
+	redactedValues := make([]string, len(values))
+	for i := range redactedValues {
+		redactedValues[i] = redacted
+	}
+	return redactedValues
+}
+
+// redactUrl 隐藏 URL 中的用户信息和查询参数，节点服务商的 API key 通常放在这两处
+func redactUrl(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return raw
+	}
+	if parsed.User != nil {
+		parsed.User = url.UserPassword(parsed.User.Username(), redacted)
+	}
+	if parsed.RawQuery != "" {
+		keys := make([]string, 0)
+		for key := range parsed.Query() {
+			keys = append(keys, key+"="+redacted)
+		}
+		sort.Strings(keys)
+		parsed.RawQuery = strings.Join(keys, "&")
+	}
+	return parsed.String()
+}
@@ -2,43 +2,59 @@ package database
 
 import (
 	"errors"
+
 	"gorm.io/gorm"
-	"strings"
+	"gorm.io/gorm/clause"
 
 	"github.com/google/uuid"
 
-	"github.com/ethereum/go-ethereum/common"
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+)
+
+const (
+	BackfillStatusNone    uint8 = 0
+	BackfillStatusPending uint8 = 1
+	BackfillStatusDone    uint8 = 2
 )
 
 type Addresses struct {
-	GUID        uuid.UUID      `gorm:"primaryKey" json:"guid"`
-	Address     common.Address `json:"address" gorm:"serializer:bytes"`
-	AddressType uint8          `json:"address_type"` //0:用户地址；1:热钱包地址(归集地址)；2:冷钱包地址
-	PublicKey   string         `json:"public_key"`
-	Timestamp   uint64
+	GUID            uuid.UUID         `gorm:"primaryKey" json:"guid"`
+	Address         chainaddr.Address `json:"address" gorm:"serializer:chainaddr"`
+	AddressType     uint8             `json:"address_type"` //0:用户地址；1:热钱包地址(归集地址)；2:冷钱包地址
+	PublicKey       string            `json:"public_key"`
+	BackfillStatus  uint8             `json:"backfill_status"`  // 0:不回溯；1:待回溯历史充值；2:回溯完成
+	DerivationIndex *uint32           `json:"derivation_index"` // 由扩展公钥派生的地址索引，为空表示外部导入
+	UserUid         string            `json:"user_uid"`         // 分配给的业务方用户，为空表示仍在地址池中
+	Timestamp       uint64
 }
 
 type AddressesView interface {
-	QueryAddressesByToAddress(string, *common.Address) (*Addresses, error)
+	QueryAddressesByToAddress(string, chainaddr.Address) (*Addresses, error)
 	QueryHotWalletInfo(string) (*Addresses, error)
 	QueryColdWalletInfo(string) (*Addresses, error)
 	GetAllAddresses(string) ([]*Addresses, error)
-	AddressExist(requestId string, address *common.Address) (bool, uint8)
+	AddressExist(requestId string, address chainaddr.Address) (bool, uint8)
 }
 
 type AddressesDB interface {
 	AddressesView
 
 	StoreAddresses(string, []Addresses) error
+	QueryBackfillAddresses(requestId string, limit int) ([]*Addresses, error)
+	UpdateBackfillStatus(requestId string, address chainaddr.Address, status uint8) error
+	CountPoolAddresses(requestId string) (int64, error)
+	// AllocateAddress 从地址池取出一个地址分配给用户，用户已有地址时直接返回，地址池为空时返回 nil
+	AllocateAddress(requestId string, userUid string) (*Addresses, error)
 }
 
 type addressesDB struct {
-	gorm *gorm.DB
+	gorm   *gorm.DB
+	router *ReplicaRouter
 }
 
-func (db *addressesDB) AddressExist(requestId string, address *common.Address) (bool, uint8) {
+func (db *addressesDB) AddressExist(requestId string, address chainaddr.Address) (bool, uint8) {
 	var addressEntry Addresses
-	err := db.gorm.Table("addresses_"+requestId).Where("address", strings.ToLower(address.String())).First(&addressEntry).Error
+	err := db.gorm.Table("addresses_"+requestId).Where("address", address.String()).First(&addressEntry).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return false, 0
@@ -48,9 +64,9 @@ func (db *addressesDB) AddressExist(requestId string, address *common.Address) (
 	return true, addressEntry.AddressType
 }
 
-func (db *addressesDB) QueryAddressesByToAddress(requestId string, address *common.Address) (*Addresses, error) {
+func (db *addressesDB) QueryAddressesByToAddress(requestId string, address chainaddr.Address) (*Addresses, error) {
 	var addressEntry Addresses
-	err := db.gorm.Table("addresses_"+requestId).Where("address", strings.ToLower(address.String())).Take(&addressEntry).Error
+	err := db.gorm.Table("addresses_"+requestId).Where("address", address.String()).Take(&addressEntry).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, gorm.ErrRecordNotFound
@@ -60,8 +76,8 @@ func (db *addressesDB) QueryAddressesByToAddress(requestId string, address *comm
 	return &addressEntry, nil
 }
 
-func NewAddressesDB(db *gorm.DB) AddressesDB {
-	return &addressesDB{gorm: db}
+func NewAddressesDB(db *gorm.DB, router *ReplicaRouter) AddressesDB {
+	return &addressesDB{gorm: db, router: router}
 }
 
 // StoreAddresses store address
@@ -96,7 +112,7 @@ func (db *addressesDB) QueryColdWalletInfo(requestId string) (*Addresses, error)
 
 func (db *addressesDB) GetAllAddresses(requestId string) ([]*Addresses, error) {
 	var addresses []*Addresses
-	err := db.gorm.Table("addresses_" + requestId).Find(&addresses).Error
+	err := db.router.Reader(db.gorm).Table("addresses_" + requestId).Find(&addresses).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -105,3 +121,51 @@ func (db *addressesDB) GetAllAddresses(requestId string) ([]*Addresses, error) {
 	}
 	return addresses, nil
 }
+
+// QueryBackfillAddresses 查询待回溯历史充值的地址，按创建时间先后处理
+func (db *addressesDB) QueryBackfillAddresses(requestId string, limit int) ([]*Addresses, error) {
+	var addresses []*Addresses
+	err := db.gorm.Table("addresses_"+requestId).Where("backfill_status", BackfillStatusPending).Order("timestamp asc").Limit(limit).Find(&addresses).Error
+	if err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+func (db *addressesDB) UpdateBackfillStatus(requestId string, address chainaddr.Address, status uint8) error {
+	return db.gorm.Table("addresses_"+requestId).Where("address", address.String()).Update("backfill_status", status).Error
+}
+
+func (db *addressesDB) CountPoolAddresses(requestId string) (int64, error) {
+	var count int64
+	err := db.gorm.Table("addresses_" + requestId).Where("user_uid = '' and derivation_index is not null").Count(&count).Error
+	return count, err
+}
+
+func (db *addressesDB) AllocateAddress(requestId string, userUid string) (*Addresses, error) {
+	var addressEntry Addresses
+	err := db.gorm.Table("addresses_"+requestId).Where("user_uid", userUid).Take(&addressEntry).Error
+	if err == nil {
+		return &addressEntry, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	err = db.gorm.Table("addresses_" + requestId).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("user_uid = '' and derivation_index is not null").
+		Order("derivation_index asc").
+		Take(&addressEntry).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	err = db.gorm.Table("addresses_"+requestId).Where("guid", addressEntry.GUID).Update("user_uid", userUid).Error
+	if err != nil {
+		return nil, err
+	}
+	addressEntry.UserUid = userUid
+	return &addressEntry, nil
+}
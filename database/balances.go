@@ -2,31 +2,32 @@ package database
 
 import (
 	"errors"
-	"github.com/google/uuid"
-	"gorm.io/gorm"
 	"math/big"
-	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
 	"github.com/ethereum/go-ethereum/log"
 
-	"github.com/ethereum/go-ethereum/common"
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
 )
 
 type Balances struct {
-	GUID         uuid.UUID      `gorm:"primaryKey" json:"guid"`
-	Address      common.Address `json:"address" gorm:"serializer:bytes"`
-	TokenAddress common.Address `json:"token_address" gorm:"serializer:bytes"`
-	Balance      *big.Int       `gorm:"serializer:u256;column:balance" db:"balance" json:"Balance" form:"balance"`
-	LockBalance  *big.Int       `gorm:"serializer:u256;column:lock_balance" db:"lock_balance" json:"LockBalance" form:"lock_balance"`
-	Timestamp    uint64
+	GUID              uuid.UUID         `gorm:"primaryKey" json:"guid"`
+	Address           chainaddr.Address `json:"address" gorm:"serializer:chainaddr"`
+	TokenAddress      chainaddr.Address `json:"token_address" gorm:"serializer:chainaddr"`
+	Balance           *big.Int          `gorm:"serializer:u256;column:balance" db:"balance" json:"Balance" form:"balance"`
+	LockBalance       *big.Int          `gorm:"serializer:u256;column:lock_balance" db:"lock_balance" json:"LockBalance" form:"lock_balance"`
+	HistoricalBalance *big.Int          `gorm:"serializer:u256;column:historical_balance" db:"historical_balance" json:"HistoricalBalance" form:"historical_balance"` // 历史充值金额，不计入 Balance，NULL 视为 0
+	Timestamp         uint64
 }
 
 type BalancesView interface {
-	QueryWalletBalanceByTokenAndAddress(requestId string, address, tokenAddress common.Address) (*Balances, error)
+	QueryWalletBalanceByTokenAndAddress(requestId string, address, tokenAddress chainaddr.Address) (*Balances, error)
 	UnCollectionList(requestId string, amount *big.Int) ([]Balances, error)
 	QueryHotWalletBalances(requestId string, amount *big.Int) ([]Balances, error)
-	QueryBalancesByToAddress(requestId string, address *common.Address) (*Balances, error)
+	QueryBalancesByToAddress(requestId string, address chainaddr.Address) (*Balances, error)
 }
 
 type BalancesDB interface {
@@ -35,14 +36,16 @@ type BalancesDB interface {
 	UpdateOrCreate(string, []TokenBalance) error
 	StoreBalances(string, []Balances) error
 	UpdateBalances(string, []Balances, bool) error
+	AddHistoricalBalances(string, []TokenBalance) error
 }
 
 type balancesDB struct {
-	gorm *gorm.DB
+	gorm   *gorm.DB
+	router *ReplicaRouter
 }
 
-func NewBalancesDB(db *gorm.DB) BalancesDB {
-	return &balancesDB{gorm: db}
+func NewBalancesDB(db *gorm.DB, router *ReplicaRouter) BalancesDB {
+	return &balancesDB{gorm: db, router: router}
 }
 
 func (db *balancesDB) StoreBalances(requestId string, balanceList []Balances) error {
@@ -75,9 +78,9 @@ func (db *balancesDB) UpdateBalances(requestId string, balanceList []Balances, i
 	return nil
 }
 
-func (db *balancesDB) QueryBalancesByToAddress(requestId string, address *common.Address) (*Balances, error) {
+func (db *balancesDB) QueryBalancesByToAddress(requestId string, address chainaddr.Address) (*Balances, error) {
 	var balanceEntry Balances
-	err := db.gorm.Table("balances_"+requestId).Where("address", strings.ToLower(address.String())).Take(&balanceEntry).Error
+	err := db.router.Reader(db.gorm).Table("balances_"+requestId).Where("address", address.String()).Take(&balanceEntry).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -89,7 +92,7 @@ func (db *balancesDB) QueryBalancesByToAddress(requestId string, address *common
 
 func (db *balancesDB) QueryHotWalletBalances(requestId string, amount *big.Int) ([]Balances, error) {
 	var balanceList []Balances
-	err := db.gorm.Table("balances_"+requestId).Where("address_type = ? and balance >=?", 1, amount.Uint64()).Find(&balanceList).Error
+	err := db.router.Reader(db.gorm).Table("balances_"+requestId).Where("address_type = ? and balance >=?", 1, amount.Uint64()).Find(&balanceList).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -101,7 +104,7 @@ func (db *balancesDB) QueryHotWalletBalances(requestId string, amount *big.Int)
 
 func (db *balancesDB) UnCollectionList(requestId string, amount *big.Int) ([]Balances, error) {
 	var balanceList []Balances
-	err := db.gorm.Table("balances_"+requestId).Where("balance >=?", amount.Uint64()).Find(&balanceList).Error
+	err := db.router.Reader(db.gorm).Table("balances_"+requestId).Where("balance >=?", amount.Uint64()).Find(&balanceList).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -111,9 +114,9 @@ func (db *balancesDB) UnCollectionList(requestId string, amount *big.Int) ([]Bal
 	return balanceList, nil
 }
 
-func (db *balancesDB) QueryWalletBalanceByTokenAndAddress(requestId string, address, tokenAddress common.Address) (*Balances, error) {
+func (db *balancesDB) QueryWalletBalanceByTokenAndAddress(requestId string, address, tokenAddress chainaddr.Address) (*Balances, error) {
 	var balanceEntry Balances
-	err := db.gorm.Table("balances_"+requestId).Where("address = ? and token_address = ?", strings.ToLower(address.String()), strings.ToLower(tokenAddress.String())).Take(&balanceEntry).Error
+	err := db.router.Reader(db.gorm).Table("balances_"+requestId).Where("address = ? and token_address = ?", address.String(), tokenAddress.String()).Take(&balanceEntry).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -131,7 +134,7 @@ func (db *balancesDB) UpdateOrCreate(requestId string, balanceList []TokenBalanc
 	}
 	for _, value := range balanceList {
 		var userBalanceEntry Balances
-		err := db.gorm.Table("balances"+requestId).Where("address = ? and token_address = ? and address_type = ?", strings.ToLower(value.Address.String()), strings.ToLower(value.TokenAddress.String()), 0).Take(&userBalanceEntry).Error
+		err := db.gorm.Table("balances"+requestId).Where("address = ? and token_address = ? and address_type = ?", value.Address.String(), value.TokenAddress.String(), 0).Take(&userBalanceEntry).Error
 		if err != nil && errors.Is(err, gorm.ErrRecordNotFound) {
 			balanceValue := &Balances{
 				GUID:         uuid.New(),
@@ -160,7 +163,7 @@ func (db *balancesDB) UpdateOrCreate(requestId string, balanceList []TokenBalanc
 			} else if value.TxType == 1 { // 提现
 				for _, hotWallet := range hotWalletBalances {
 					if hotWallet.Address == value.Address && hotWallet.TokenAddress == value.TokenAddress {
-						hotWallet.LockBalance = new(big.Int).Sub(hotWallet.LockBalance,value.LockBalance)
+						hotWallet.LockBalance = new(big.Int).Sub(hotWallet.LockBalance, value.LockBalance)
 						errU := db.gorm.Table("balances" + requestId).Save(&hotWallet).Error
 						if errU != nil {
 							return errU
@@ -184,7 +187,7 @@ func (db *balancesDB) UpdateOrCreate(requestId string, balanceList []TokenBalanc
 						}
 					}
 				}
-			} else if value.TxType == 3 {//转冷
+			} else if value.TxType == 3 { //转冷
 				if len(hotWalletBalances) > 0 {
 					for _, hotWallet := range hotWalletBalances {
 						hotWallet.LockBalance = big.NewInt(0)
@@ -194,7 +197,7 @@ func (db *balancesDB) UpdateOrCreate(requestId string, balanceList []TokenBalanc
 						}
 					}
 				}
-			} else if value.TxType == 4 {//转热
+			} else if value.TxType == 4 { //转热
 				if len(hotWalletBalances) > 0 {
 					for _, hotWallet := range hotWalletBalances {
 						hotWallet.Balance = new(big.Int).Add(hotWallet.Balance, value.Balance)
@@ -212,3 +215,37 @@ func (db *balancesDB) UpdateOrCreate(requestId string, balanceList []TokenBalanc
 	}
 	return nil
 }
+
+// AddHistoricalBalances 累加历史充值金额，地址没有余额记录时新建一条 Balance 为 0 的记录
+func (db *balancesDB) AddHistoricalBalances(requestId string, balanceList []TokenBalance) error {
+	for _, value := range balanceList {
+		var balanceEntry Balances
+		err := db.gorm.Table("balances_"+requestId).Where("address = ? and token_address = ?", value.Address.String(), value.TokenAddress.String()).Take(&balanceEntry).Error
+		if err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			balanceEntry = Balances{
+				GUID:              uuid.New(),
+				Address:           value.Address,
+				TokenAddress:      value.TokenAddress,
+				Balance:           big.NewInt(0),
+				LockBalance:       big.NewInt(0),
+				HistoricalBalance: value.Balance,
+				Timestamp:         uint64(time.Now().Unix()),
+			}
+			if err := db.gorm.Table("balances_" + requestId).Create(&balanceEntry).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if balanceEntry.HistoricalBalance == nil {
+			balanceEntry.HistoricalBalance = big.NewInt(0)
+		}
+		balanceEntry.HistoricalBalance = new(big.Int).Add(balanceEntry.HistoricalBalance, value.Balance)
+		if err := db.gorm.Table("balances_" + requestId).Save(&balanceEntry).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
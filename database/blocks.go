@@ -6,22 +6,23 @@ import (
 
 	"gorm.io/gorm"
 
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
 	"github.com/CavnHan/multichain-sync-account/rpcclient"
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
 type Blocks struct {
-	Hash       common.Hash `gorm:"primaryKey;serializer:bytes"`
-	ParentHash common.Hash `gorm:"serializer:bytes"`
-	Number     *big.Int    `gorm:"serializer:u256"`
+	Hash       chainaddr.Hash `gorm:"primaryKey;serializer:chainaddr"`
+	ParentHash chainaddr.Hash `gorm:"serializer:chainaddr"`
+	Number     *big.Int       `gorm:"serializer:u256"`
 	Timestamp  uint64
 }
 
+// BlockHeaderFromHeader 只用于 EVM 链，common.Hash.Hex() 即为 EVM 的规范化小写形式
 func BlockHeaderFromHeader(header *types.Header) rpcclient.BlockHeader {
 	return rpcclient.BlockHeader{
-		Hash:       header.Hash(),
-		ParentHash: header.ParentHash,
+		Hash:       chainaddr.Hash(header.Hash().Hex()),
+		ParentHash: chainaddr.Hash(header.ParentHash.Hex()),
 		Number:     header.Number,
 		Timestamp:  header.Time,
 	}
@@ -10,35 +10,45 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 )
 
+const (
+	BusinessStatusActive    uint8 = 0
+	BusinessStatusSuspended uint8 = 1
+)
+
 type Business struct {
 	GUID        uuid.UUID `gorm:"primaryKey" json:"guid"`
 	BusinessUid string    `json:"business_uid"`
 	NotifyUrl   string    `json:"notify_url"`
+	Sinks       string    `json:"sinks"`  // 逗号分隔的事件投递方式 webhook/file/broker
+	Status      uint8     `json:"status"` // 0:正常；1:已暂停
 	Timestamp   uint64
 }
 
 type BusinessView interface {
 	QueryBusinessByUuid(string) (*Business, error)
-	QueryBusinessList() ([]Business,error)
+	QueryBusinessList() ([]Business, error)
 }
 
 type BusinessDB interface {
 	BusinessView
 
 	StoreBusiness(*Business) error
+	UpdateBusinessStatus(businessUid string, status uint8) error
+	RemoveBusiness(businessUid string) error
 }
 
 type businessDB struct {
-	gorm *gorm.DB
+	gorm   *gorm.DB
+	router *ReplicaRouter
 }
 
-func NewBusinessDB(db *gorm.DB) BusinessDB {
-	return &businessDB{gorm: db}
+func NewBusinessDB(db *gorm.DB, router *ReplicaRouter) BusinessDB {
+	return &businessDB{gorm: db, router: router}
 }
 
 func (db *businessDB) QueryBusinessList() ([]Business, error) {
 	var business []Business
-	err := db.gorm.Table("business").Find(&business).Error
+	err := db.router.Reader(db.gorm).Table("business").Find(&business).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, gorm.ErrRecordNotFound
@@ -55,10 +65,35 @@ func (db *businessDB) StoreBusiness(business *Business) error {
 
 func (db *businessDB) QueryBusinessByUuid(businessUid string) (*Business, error) {
 	var business *Business
-	result := db.gorm.Table("business").Where("business_uid", businessUid).First(&business)
+	result := db.router.Reader(db.gorm).Table("business").Where("business_uid", businessUid).First(&business)
 	if result.Error != nil {
 		log.Error("query business all fail", "Err", result.Error)
 		return nil, result.Error
 	}
 	return business, nil
 }
+
+func (db *businessDB) UpdateBusinessStatus(businessUid string, status uint8) error {
+	result := db.gorm.Table("business").Where("business_uid", businessUid).Update("status", status)
+	if result.Error != nil {
+		log.Error("update business status fail", "businessUid", businessUid, "Err", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// RemoveBusiness 只删除 business 记录，业务方的动态表保留以便审计
+func (db *businessDB) RemoveBusiness(businessUid string) error {
+	result := db.gorm.Table("business").Where("business_uid", businessUid).Delete(&Business{})
+	if result.Error != nil {
+		log.Error("remove business fail", "businessUid", businessUid, "Err", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -3,34 +3,96 @@ package database
 import (
 	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 
-	"github.com/pkg/errors"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"github.com/ethereum/go-ethereum/log"
+
 	retry2 "github.com/CavnHan/multichain-sync-account/common/retry"
 	"github.com/CavnHan/multichain-sync-account/config"
 	_ "github.com/CavnHan/multichain-sync-account/database/utils/serializers"
 )
 
+// Transactor 开启事务并在事务内加业务方锁，默认由 gorm 实现，模拟测试注入内存实现
+type Transactor interface {
+	// Transaction 在事务内执行 fn，fn 收到的 DB 读写都在该事务内，执行前确认 db 的租约
+	Transaction(db *DB, fn func(tx *DB) error) error
+	LockBusiness(businessId string) error
+}
+
 type DB struct {
-	gorm *gorm.DB
+	gorm    *gorm.DB
+	replica *ReplicaRouter
+	fence   *Fence
+
+	Transactor Transactor
+
+	CreateTable     CreateTableDB
+	Blocks          BlocksDB
+	Addresses       AddressesDB
+	Balances        BalancesDB
+	Deposits        DepositsDB
+	Withdraws       WithdrawsDB
+	Transactions    TransactionsDB
+	Tokens          TokensDB
+	Business        BusinessDB
+	Internals       InternalsDB
+	HDWallets       HDWalletsDB
+	Utxos           UtxosDB
+	WithdrawBatches WithdrawBatchesDB
+	NftHoldings     NftHoldingsDB
+	Memos           MemosDB
+	Events          EventsDB
+	Leases          LeasesDB
+	SigningJobs     SigningJobsDB
+}
+
+// NewDB 连接主库，开启 SlaveDbEnable 时同时连接从库，*View 查询在从库健康时走从库
+func NewDB(ctx context.Context, cfg *config.Config) (*DB, error) {
+	gorm, err := openGorm(cfg.MasterDB, 10)
+	if err != nil {
+		return nil, err
+	}
+
+	var router *ReplicaRouter
+	if cfg.SlaveDbEnable {
+		replica, err := openGorm(cfg.SlaveDB, 3)
+		if err != nil {
+			// 从库不可用时不影响启动，所有查询走主库
+			log.Error("connect to slave database fail, route all queries to master", "err", err)
+		} else {
+			router = NewReplicaRouter(replica, cfg.SlaveDbMaxLag)
+		}
+	}
 
-	CreateTable  CreateTableDB
-	Blocks       BlocksDB
-	Addresses    AddressesDB
-	Balances     BalancesDB
-	Deposits     DepositsDB
-	Withdraws    WithdrawsDB
-	Transactions TransactionsDB
-	Tokens       TokensDB
-	Business     BusinessDB
-	Internals    InternalsDB
+	db := &DB{
+		gorm:            gorm,
+		replica:         router,
+		Transactor:      gormTransactor{gorm: gorm},
+		CreateTable:     NewCreateTableDB(gorm),
+		Blocks:          NewBlocksDB(gorm), // 同步游标必须读主库，否则从库延迟会导致区块重复处理
+		Addresses:       NewAddressesDB(gorm, router),
+		Balances:        NewBalancesDB(gorm, router),
+		Deposits:        NewDepositsDB(gorm, router),
+		Withdraws:       NewWithdrawsDB(gorm, router),
+		Transactions:    NewTransactionsDB(gorm, router),
+		Tokens:          NewTokensDB(gorm, router),
+		Business:        NewBusinessDB(gorm, router),
+		Internals:       NewInternalsDB(gorm, router),
+		HDWallets:       NewHDWalletsDB(gorm, router),
+		Utxos:           NewUtxosDB(gorm, router),
+		WithdrawBatches: NewWithdrawBatchesDB(gorm, router),
+		NftHoldings:     NewNftHoldingsDB(gorm, router),
+		Memos:           NewMemosDB(gorm, router),
+		Events:          NewEventsDB(gorm), // 投递游标必须读主库，否则从库延迟会导致游标回退
+		Leases:          NewLeasesDB(gorm),
+		SigningJobs:     NewSigningJobsDB(gorm), // 租约状态必须读主库
+	}
+	return db, nil
 }
 
-func NewDB(ctx context.Context, dbConfig config.DBConfig) (*DB, error) {
+func openGorm(dbConfig config.DBConfig, maxAttempts int) (*gorm.DB, error) {
 	dsn := fmt.Sprintf("host=%s dbname=%s sslmode=disable", dbConfig.Host, dbConfig.Name)
 	if dbConfig.Port != 0 {
 		dsn += fmt.Sprintf(" port=%d", dbConfig.Port)
@@ -48,54 +110,89 @@ func NewDB(ctx context.Context, dbConfig config.DBConfig) (*DB, error) {
 	}
 
 	retryStrategy := &retry2.ExponentialStrategy{Min: 1000, Max: 20_000, MaxJitter: 250}
-	gorm, err := retry2.Do[*gorm.DB](context.Background(), 10, retryStrategy, func() (*gorm.DB, error) {
+	return retry2.Do[*gorm.DB](context.Background(), maxAttempts, retryStrategy, func() (*gorm.DB, error) {
 		gorm, err := gorm.Open(postgres.Open(dsn), &gormConfig)
 		if err != nil {
 			return nil, fmt.Errorf("failed to connect to database: %w", err)
 		}
 		return gorm, nil
 	})
+}
 
-	if err != nil {
-		return nil, err
-	}
+// WithFence 返回带 fencing 校验的副本，通过它开启的事务在执行前确认租约仍由自己持有
+func (db *DB) WithFence(fence Fence) *DB {
+	fenced := *db
+	fenced.fence = &fence
+	return &fenced
+}
 
-	db := &DB{
-		gorm:         gorm,
-		CreateTable:  NewCreateTableDB(gorm),
-		Blocks:       NewBlocksDB(gorm),
-		Addresses:    NewAddressesDB(gorm),
-		Balances:     NewBalancesDB(gorm),
-		Deposits:     NewDepositsDB(gorm),
-		Withdraws:    NewWithdrawsDB(gorm),
-		Transactions: NewTransactionsDB(gorm),
-		Tokens:       NewTokensDB(gorm),
-		Business:     NewBusinessDB(gorm),
-		Internals:    NewInternalsDB(gorm),
+// CheckFence 在发送交易等无法回滚的操作前确认租约，未设置 fence 时直接通过
+func (db *DB) CheckFence() error {
+	if db.fence == nil {
+		return nil
 	}
-	return db, nil
+	return db.Leases.CheckLease(*db.fence)
 }
 
-//事务处理
+// 事务处理，事务内的读写全部走主库
 func (db *DB) Transaction(fn func(db *DB) error) error {
-	return db.gorm.Transaction(func(tx *gorm.DB) error {
+	return db.Transactor.Transaction(db, fn)
+}
+
+// LockBusiness 获取业务方级别的事务 advisory lock，只能在 Transaction 内调用，事务结束自动释放
+func (db *DB) LockBusiness(businessId string) error {
+	return db.Transactor.LockBusiness(businessId)
+}
+
+type gormTransactor struct {
+	gorm *gorm.DB
+}
+
+func (t gormTransactor) Transaction(db *DB, fn func(db *DB) error) error {
+	return t.gorm.Transaction(func(tx *gorm.DB) error {
 		txDB := &DB{
-			gorm:         tx,
-			Blocks:       NewBlocksDB(tx),
-			Addresses:    NewAddressesDB(tx),
-			Balances:     NewBalancesDB(tx),
-			Deposits:     NewDepositsDB(tx),
-			Withdraws:    NewWithdrawsDB(tx),
-			Transactions: NewTransactionsDB(tx),
-			Tokens:       NewTokensDB(tx),
-			Business:     NewBusinessDB(tx),
-			Internals:    NewInternalsDB(tx),
+			gorm:            tx,
+			fence:           db.fence,
+			Transactor:      gormTransactor{gorm: tx},
+			Blocks:          NewBlocksDB(tx),
+			Addresses:       NewAddressesDB(tx, nil),
+			Balances:        NewBalancesDB(tx, nil),
+			Deposits:        NewDepositsDB(tx, nil),
+			Withdraws:       NewWithdrawsDB(tx, nil),
+			Transactions:    NewTransactionsDB(tx, nil),
+			Tokens:          NewTokensDB(tx, nil),
+			Business:        NewBusinessDB(tx, nil),
+			Internals:       NewInternalsDB(tx, nil),
+			HDWallets:       NewHDWalletsDB(tx, nil),
+			Utxos:           NewUtxosDB(tx, nil),
+			WithdrawBatches: NewWithdrawBatchesDB(tx, nil),
+			NftHoldings:     NewNftHoldingsDB(tx, nil),
+			Memos:           NewMemosDB(tx, nil),
+			Events:          NewEventsDB(tx),
+			Leases:          NewLeasesDB(tx),
+			SigningJobs:     NewSigningJobsDB(tx),
+		}
+		// 租约行在事务结束前被共享锁定，其他实例无法在提交前接管
+		if err := txDB.CheckFence(); err != nil {
+			return err
 		}
 		return fn(txDB)
 	})
 }
 
+func (t gormTransactor) LockBusiness(businessId string) error {
+	return t.gorm.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "business:"+businessId).Error
+}
+
+// ReplicaHealthy 表示从库当前是否在承接只读查询
+func (db *DB) ReplicaHealthy() bool {
+	return db.replica.Healthy()
+}
+
 func (db *DB) Close() error {
+	if err := db.replica.Close(); err != nil {
+		log.Error("close slave database fail", "err", err)
+	}
 	sql, err := db.gorm.DB()
 	if err != nil {
 		return err
@@ -103,24 +200,7 @@ func (db *DB) Close() error {
 	return sql.Close()
 }
 
+// ExecuteSQLMigration 应用目录下所有尚未执行的迁移
 func (db *DB) ExecuteSQLMigration(migrationsFolder string) error {
-	err := filepath.Walk(migrationsFolder, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return errors.Wrap(err, fmt.Sprintf("Failed to process migration file: %s", path))
-		}
-		if info.IsDir() {
-			return nil
-		}
-		fileContent, readErr := os.ReadFile(path)
-		if readErr != nil {
-			return errors.Wrap(readErr, fmt.Sprintf("Error reading SQL file: %s", path))
-		}
-
-		execErr := db.gorm.Exec(string(fileContent)).Error
-		if execErr != nil {
-			return errors.Wrap(execErr, fmt.Sprintf("Error executing SQL script: %s", path))
-		}
-		return nil
-	})
-	return err
+	return db.NewMigrator(migrationsFolder).Up(0)
 }
@@ -2,33 +2,42 @@ package database
 
 import (
 	"errors"
+	"math/big"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
-	"math/big"
 
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
 )
 
 type Deposits struct {
-	GUID         uuid.UUID      `gorm:"primaryKey" json:"guid"`
-	BlockHash    common.Hash    `gorm:"column:block_hash;serializer:bytes"  db:"block_hash" json:"block_hash"`
-	BlockNumber  *big.Int       `gorm:"serializer:u256;column:block_number" db:"block_number" json:"BlockNumber" form:"block_number"`
-	Hash         common.Hash    `gorm:"column:hash;serializer:bytes"  db:"hash" json:"hash"`
-	FromAddress  common.Address `json:"from_address" gorm:"serializer:bytes;column:from_address"`
-	ToAddress    common.Address `json:"to_address" gorm:"serializer:bytes;column:to_address"`
-	TokenAddress common.Address `json:"token_address" gorm:"serializer:bytes;column:token_address"`
-	TokenId      string         `json:"token_id" gorm:"column:token_id"`
-	TokenMeta    string         `json:"token_meta" gorm:"column:token_meta"`
-	Fee          *big.Int       `gorm:"serializer:u256;column:fee" db:"fee" json:"Fee" form:"fee"`
-	Amount       *big.Int       `gorm:"serializer:u256;column:amount" db:"amount" json:"Amount" form:"amount"`
-	Confirms     uint8          `json:"confirms"` // 交易确认位
-	Status       uint8          `json:"status"`   // 0:充值确认中,1:充值钱包层已到账；2:充值已通知业务层；3:充值完成;
-	Timestamp    uint64
+	GUID         uuid.UUID         `gorm:"primaryKey" json:"guid"`
+	BlockHash    chainaddr.Hash    `gorm:"column:block_hash;serializer:chainaddr"  db:"block_hash" json:"block_hash"`
+	BlockNumber  *big.Int          `gorm:"serializer:u256;column:block_number" db:"block_number" json:"BlockNumber" form:"block_number"`
+	Hash         chainaddr.Hash    `gorm:"column:hash;serializer:chainaddr"  db:"hash" json:"hash"`
+	FromAddress  chainaddr.Address `json:"from_address" gorm:"serializer:chainaddr;column:from_address"`
+	ToAddress    chainaddr.Address `json:"to_address" gorm:"serializer:chainaddr;column:to_address"`
+	TokenAddress chainaddr.Address `json:"token_address" gorm:"serializer:chainaddr;column:token_address"`
+	TokenId      string            `json:"token_id" gorm:"column:token_id"`
+	TokenMeta    string            `json:"token_meta" gorm:"column:token_meta"`
+	Fee          *big.Int          `gorm:"serializer:u256;column:fee" db:"fee" json:"Fee" form:"fee"`
+	Amount       *big.Int          `gorm:"serializer:u256;column:amount" db:"amount" json:"Amount" form:"amount"`
+	Confirms     uint8             `json:"confirms"`   // 交易确认位
+	Status       uint8             `json:"status"`     // 0:充值确认中,1:充值钱包层已到账；2:充值已通知业务层；3:充值完成;
+	Historical   bool              `json:"historical"` // 地址注册前的历史充值，由业务方决定是否入账
+	Memo         string            `json:"memo" gorm:"column:memo"`
+	Quarantined  bool              `json:"quarantined"` // 共享地址上缺少 memo 或 memo 未分配的充值，人工确认 memo 前不通知业务方
+	// 确认中的充值上次通知时的确认数，确认数变化后才再次通知
+	Notified         bool  `json:"notified" gorm:"column:notified"`
+	NotifiedConfirms uint8 `json:"notified_confirms" gorm:"column:notified_confirms"`
+	Timestamp        uint64
 }
 
 type DepositsView interface {
 	QueryNotifyDeposits(string) ([]Deposits, error)
+	QueryQuarantinedDeposits(requestId string) ([]Deposits, error)
 }
 
 type DepositsDB interface {
@@ -36,16 +45,24 @@ type DepositsDB interface {
 
 	StoreDeposits(string, []Deposits, uint64) error
 	UpdateDepositsNotifyStatus(requestId string, status uint8, depositList []Deposits) error
+	// UpdateDepositsNotifiedConfirms 记录确认中的充值已按当前确认数通知
+	UpdateDepositsNotifiedConfirms(requestId string, depositList []Deposits) error
 	UpdateDepositsComfirms(requestId string, blockNumber uint64, confirms uint64) error
+	// ResolveQuarantinedDeposit 为隔离的充值补充 memo 并解除隔离，之后按正常充值通知业务方
+	ResolveQuarantinedDeposit(requestId string, guid string, memo string) error
 }
 
 type depositsDB struct {
-	gorm *gorm.DB
+	gorm   *gorm.DB
+	router *ReplicaRouter
 }
 
 func (db *depositsDB) QueryNotifyDeposits(requestId string) ([]Deposits, error) {
 	var notifyDeposits []Deposits
-	result := db.gorm.Table("deposits_"+requestId).Where("status = ? or status = ?", 0, 1).Find(notifyDeposits)
+	// 确认中的充值只在首次和确认数变化时通知
+	result := db.gorm.Table("deposits_"+requestId).
+		Where("(status = ? or (status = ? and (not notified or confirms <> notified_confirms))) and quarantined = ?", 1, 0, false).
+		Find(&notifyDeposits)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, gorm.ErrRecordNotFound
@@ -55,10 +72,31 @@ func (db *depositsDB) QueryNotifyDeposits(requestId string) ([]Deposits, error)
 	return notifyDeposits, nil
 }
 
+func (db *depositsDB) QueryQuarantinedDeposits(requestId string) ([]Deposits, error) {
+	var deposits []Deposits
+	err := db.router.Reader(db.gorm).Table("deposits_"+requestId).Where("quarantined = ?", true).Order("timestamp asc").Find(&deposits).Error
+	if err != nil {
+		return nil, err
+	}
+	return deposits, nil
+}
+
+func (db *depositsDB) ResolveQuarantinedDeposit(requestId string, guid string, memo string) error {
+	result := db.gorm.Table("deposits_"+requestId).Where("guid = ? and quarantined = ?", guid, true).
+		Updates(map[string]interface{}{"memo": memo, "quarantined": false})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 // UpdateDepositsComfirms 查询所有还没有过确认位交易，用最新区块减去对应区块更新确认，如果这个大于我们预设的确认位，那么这笔交易可以认为已经入账
 func (db *depositsDB) UpdateDepositsComfirms(requestId string, blockNumber uint64, confirms uint64) error {
 	var unConfirmDeposits []Deposits
-	result := db.gorm.Table("deposits_"+requestId).Where("block_number <= ? AND status = ?", blockNumber, 0).Find(unConfirmDeposits)
+	result := db.gorm.Table("deposits_"+requestId).Where("block_number <= ? AND status = ?", blockNumber, 0).Find(&unConfirmDeposits)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil
@@ -93,15 +131,26 @@ func (db *depositsDB) UpdateDepositsNotifyStatus(requestId string, status uint8,
 			return result.Error
 		}
 		depositSingle.Status = status
-		err := db.gorm.Table("transactions_" + requestId).Save(&depositSingle).Error
+		err := db.gorm.Table("deposits_" + requestId).Save(&depositSingle).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+func (db *depositsDB) UpdateDepositsNotifiedConfirms(requestId string, depositList []Deposits) error {
+	for _, deposit := range depositList {
+		err := db.gorm.Table("deposits_"+requestId).Where("guid = ?", deposit.GUID).
+			Updates(map[string]interface{}{"notified": true, "notified_confirms": deposit.Confirms}).Error
 		if err != nil {
 			return err
 		}
 	}
 	return nil
 }
-func NewDepositsDB(db *gorm.DB) DepositsDB {
-	return &depositsDB{gorm: db}
+
+func NewDepositsDB(db *gorm.DB, router *ReplicaRouter) DepositsDB {
+	return &depositsDB{gorm: db, router: router}
 }
 
 func (db *depositsDB) StoreDeposits(requestId string, depositList []Deposits, depositLength uint64) error {
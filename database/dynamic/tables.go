@@ -14,6 +14,13 @@ func CreateTableFromTemplate(requestId string, db *database.DB) {
 	createTransactions(requestId, db)
 	createWithdraws(requestId, db)
 	createInternals(requestId, db)
+	createUtxos(requestId, db)
+	createWithdrawBatches(requestId, db)
+	createNftHoldings(requestId, db)
+	createMemos(requestId, db)
+	createEvents(requestId, db)
+	createEventCursors(requestId, db)
+	createSigningJobs(requestId, db)
 
 }
 
@@ -57,3 +64,45 @@ func createInternals(requestId string, db *database.DB) {
 	tableNameByChainId := fmt.Sprintf("internals_%s", requestId)
 	db.CreateTable.CreateTable(tableNameByChainId, tableName)
 }
+
+func createUtxos(requestId string, db *database.DB) {
+	tableName := "utxos"
+	tableNameByChainId := fmt.Sprintf("utxos_%s", requestId)
+	db.CreateTable.CreateTable(tableNameByChainId, tableName)
+}
+
+func createWithdrawBatches(requestId string, db *database.DB) {
+	tableName := "withdraw_batches"
+	tableNameByChainId := fmt.Sprintf("withdraw_batches_%s", requestId)
+	db.CreateTable.CreateTable(tableNameByChainId, tableName)
+}
+
+func createNftHoldings(requestId string, db *database.DB) {
+	tableName := "nft_holdings"
+	tableNameByChainId := fmt.Sprintf("nft_holdings_%s", requestId)
+	db.CreateTable.CreateTable(tableNameByChainId, tableName)
+}
+
+func createMemos(requestId string, db *database.DB) {
+	tableName := "memos"
+	tableNameByChainId := fmt.Sprintf("memos_%s", requestId)
+	db.CreateTable.CreateTable(tableNameByChainId, tableName)
+}
+
+func createEvents(requestId string, db *database.DB) {
+	tableName := "events"
+	tableNameByChainId := fmt.Sprintf("events_%s", requestId)
+	db.CreateTable.CreateTable(tableNameByChainId, tableName)
+}
+
+func createEventCursors(requestId string, db *database.DB) {
+	tableName := "event_cursors"
+	tableNameByChainId := fmt.Sprintf("event_cursors_%s", requestId)
+	db.CreateTable.CreateTable(tableNameByChainId, tableName)
+}
+
+func createSigningJobs(requestId string, db *database.DB) {
+	tableName := "signing_jobs"
+	tableNameByChainId := fmt.Sprintf("signing_jobs_%s", requestId)
+	db.CreateTable.CreateTable(tableNameByChainId, tableName)
+}
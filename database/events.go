@@ -0,0 +1,154 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	EventKindDeposit    = "deposit"
+	EventKindWithdraw   = "withdraw"
+	EventKindCollection = "collection"
+	EventKindHot2Cold   = "hot2cold"
+	EventKindCold2Hot   = "cold2hot"
+	EventKindReorg      = "reorg"
+)
+
+// Events 业务方事件发件箱，sequence 在业务方内单调递增，各投递方式按游标独立消费；
+// 交易写入发件箱时即更新为已通知，送达情况只记录在游标和逐条确认状态中。
+// Acked/VisibleAt/Deliveries 为拉取收件箱的逐条确认状态
+type Events struct {
+	GUID       uuid.UUID `gorm:"primaryKey" json:"guid"`
+	Sequence   uint64    `json:"sequence"`
+	Kind       string    `json:"kind"`
+	Payload    string    `json:"payload"`
+	Acked      bool      `json:"acked"`
+	VisibleAt  uint64    `json:"visible_at"` // 拉取后在该时间之前不再返回，未确认则重新可见
+	Deliveries uint32    `json:"deliveries"`
+	Timestamp  uint64
+}
+
+type EventCursors struct {
+	Sink      string `gorm:"primaryKey" json:"sink"`
+	Sequence  uint64 `json:"sequence"`
+	Timestamp uint64
+}
+
+type EventsView interface {
+	QueryEventsAfter(requestId string, sequence uint64, limit int) ([]Events, error)
+	QueryEventCursor(requestId string, sink string) (uint64, error)
+}
+
+type EventsDB interface {
+	EventsView
+
+	// StoreEvents 按顺序为事件分配序号后写入，序号写回 events
+	StoreEvents(requestId string, events []Events) error
+	UpdateEventCursor(requestId string, sink string, sequence uint64) error
+	// LeaseEvents 取出 cursor 之后未确认且已可见的事件，并在 visibility 时间内对其他拉取隐藏
+	LeaseEvents(requestId string, cursor uint64, limit int, visibility time.Duration) ([]Events, error)
+	AckEvents(requestId string, guids []string) (int64, error)
+}
+
+type eventsDB struct {
+	gorm *gorm.DB
+}
+
+func NewEventsDB(db *gorm.DB) EventsDB {
+	return &eventsDB{gorm: db}
+}
+
+func (db *eventsDB) QueryEventsAfter(requestId string, sequence uint64, limit int) ([]Events, error) {
+	var events []Events
+	err := db.gorm.Table("events_"+requestId).Where("sequence > ?", sequence).Order("sequence asc").Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (db *eventsDB) QueryEventCursor(requestId string, sink string) (uint64, error) {
+	var cursor EventCursors
+	err := db.gorm.Table("event_cursors_"+requestId).Where("sink = ?", sink).Take(&cursor).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return cursor.Sequence, nil
+}
+
+// StoreEvents 在 business 行上递增序号，行锁保证并发写入时序号连续且不重复
+func (db *eventsDB) StoreEvents(requestId string, events []Events) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return db.gorm.Transaction(func(tx *gorm.DB) error {
+		var last uint64
+		err := tx.Raw("UPDATE business SET event_sequence = event_sequence + ? WHERE business_uid = ? RETURNING event_sequence", len(events), requestId).Scan(&last).Error
+		if err != nil {
+			return err
+		}
+		if last < uint64(len(events)) {
+			return gorm.ErrRecordNotFound
+		}
+		first := last - uint64(len(events)) + 1
+		for i := range events {
+			events[i].Sequence = first + uint64(i)
+		}
+		return tx.Table("events_"+requestId).CreateInBatches(&events, len(events)).Error
+	})
+}
+
+func (db *eventsDB) UpdateEventCursor(requestId string, sink string, sequence uint64) error {
+	cursor := EventCursors{Sink: sink, Sequence: sequence, Timestamp: uint64(time.Now().Unix())}
+	return db.gorm.Table("event_cursors_" + requestId).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "sink"}},
+			DoUpdates: clause.AssignmentColumns([]string{"sequence", "timestamp"}),
+		}).
+		Create(&cursor).Error
+}
+
+func (db *eventsDB) LeaseEvents(requestId string, cursor uint64, limit int, visibility time.Duration) ([]Events, error) {
+	var events []Events
+	err := db.gorm.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Table("events_"+requestId).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("sequence > ? and acked = ? and visible_at <= ?", cursor, false, now.Unix()).
+			Order("sequence asc").Limit(limit).Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+		guids := make([]string, 0, len(events))
+		visibleAt := uint64(now.Add(visibility).Unix())
+		for i := range events {
+			guids = append(guids, events[i].GUID.String())
+			events[i].VisibleAt = visibleAt
+			events[i].Deliveries++
+		}
+		return tx.Table("events_"+requestId).Where("guid in ?", guids).
+			Updates(map[string]interface{}{"visible_at": visibleAt, "deliveries": gorm.Expr("deliveries + 1")}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (db *eventsDB) AckEvents(requestId string, guids []string) (int64, error) {
+	if len(guids) == 0 {
+		return 0, nil
+	}
+	result := db.gorm.Table("events_"+requestId).Where("guid in ? and acked = ?", guids, false).Update("acked", true)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HDWallets 业务方在某条链上注册的扩展公钥，地址按 change/index 路径在本地派生
+type HDWallets struct {
+	GUID        uuid.UUID `gorm:"primaryKey" json:"guid"`
+	BusinessUid string    `json:"business_uid"`
+	Chain       string    `json:"chain"`
+	Xpub        string    `json:"xpub"`
+	Change      uint32    `json:"change"`     // 派生路径中的 change 层，充值地址默认为 0
+	NextIndex   uint32    `json:"next_index"` // 下一个待派生的地址索引
+	Timestamp   uint64
+}
+
+func (HDWallets) TableName() string {
+	return "hd_wallets"
+}
+
+type HDWalletsView interface {
+	QueryHDWallet(businessUid, chain string) (*HDWallets, error)
+}
+
+type HDWalletsDB interface {
+	HDWalletsView
+
+	StoreHDWallet(*HDWallets) error
+	// LockHDWallet 在事务内锁定扩展公钥记录，派生地址和推进索引需要串行
+	LockHDWallet(businessUid, chain string) (*HDWallets, error)
+	UpdateNextIndex(businessUid, chain string, nextIndex uint32) error
+}
+
+type hdWalletsDB struct {
+	gorm   *gorm.DB
+	router *ReplicaRouter
+}
+
+func NewHDWalletsDB(db *gorm.DB, router *ReplicaRouter) HDWalletsDB {
+	return &hdWalletsDB{gorm: db, router: router}
+}
+
+func (db *hdWalletsDB) QueryHDWallet(businessUid, chain string) (*HDWallets, error) {
+	var wallet HDWallets
+	err := db.router.Reader(db.gorm).Where("business_uid = ? and chain = ?", businessUid, chain).Take(&wallet).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+// StoreHDWallet 同一业务方同一条链只能注册一次，重复注册相同 xpub 视为成功
+func (db *hdWalletsDB) StoreHDWallet(wallet *HDWallets) error {
+	return db.gorm.Clauses(clause.OnConflict{DoNothing: true}).Create(wallet).Error
+}
+
+func (db *hdWalletsDB) LockHDWallet(businessUid, chain string) (*HDWallets, error) {
+	var wallet HDWallets
+	err := db.gorm.Clauses(clause.Locking{Strength: "UPDATE"}).Where("business_uid = ? and chain = ?", businessUid, chain).Take(&wallet).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &wallet, nil
+}
+
+func (db *hdWalletsDB) UpdateNextIndex(businessUid, chain string, nextIndex uint32) error {
+	return db.gorm.Model(&HDWallets{}).Where("business_uid = ? and chain = ?", businessUid, chain).Update("next_index", nextIndex).Error
+}
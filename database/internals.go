@@ -2,28 +2,31 @@ package database
 
 import (
 	"errors"
+	"math/big"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
-	"math/big"
 
-	"github.com/ethereum/go-ethereum/common"
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
 )
 
 type Internals struct {
-	GUID         uuid.UUID      `gorm:"primaryKey" json:"guid"`
-	BlockHash    common.Hash    `gorm:"column:block_hash;serializer:bytes"  db:"block_hash" json:"block_hash"`
-	BlockNumber  *big.Int       `gorm:"serializer:u256;column:block_number" db:"block_number" json:"BlockNumber" form:"block_number"`
-	Hash         common.Hash    `gorm:"column:hash;serializer:bytes"  db:"hash" json:"hash"`
-	FromAddress  common.Address `json:"from_address" gorm:"serializer:bytes;column:from_address"`
-	ToAddress    common.Address `json:"to_address" gorm:"serializer:bytes;column:to_address"`
-	TokenAddress common.Address `json:"token_address" gorm:"serializer:bytes;column:token_address"`
-	TokenId      string         `json:"token_id" gorm:"column:token_id"`
-	TokenMeta    string         `json:"token_meta" gorm:"column:token_meta"`
-	Fee          *big.Int       `gorm:"serializer:u256;column:fee" db:"fee" json:"Fee" form:"fee"`
-	Amount       *big.Int       `gorm:"serializer:u256;column:amount" db:"amount" json:"Amount" form:"amount"`
-	Status       uint8          `json:"status"` // 0:交易未签名, 1:交易已签名, 2:交易已经发送到区块链网络；3:交易在钱包层已完成；4:已通知业务；5:成功
-	TxType       string         `json:"tx_type"`
-	TxSignHex    string         `json:"tx_sign_hex" gorm:"column:tx_sign_hex"`
+	GUID         uuid.UUID         `gorm:"primaryKey" json:"guid"`
+	BlockHash    chainaddr.Hash    `gorm:"column:block_hash;serializer:chainaddr"  db:"block_hash" json:"block_hash"`
+	BlockNumber  *big.Int          `gorm:"serializer:u256;column:block_number" db:"block_number" json:"BlockNumber" form:"block_number"`
+	Hash         chainaddr.Hash    `gorm:"column:hash;serializer:chainaddr"  db:"hash" json:"hash"`
+	FromAddress  chainaddr.Address `json:"from_address" gorm:"serializer:chainaddr;column:from_address"`
+	ToAddress    chainaddr.Address `json:"to_address" gorm:"serializer:chainaddr;column:to_address"`
+	TokenAddress chainaddr.Address `json:"token_address" gorm:"serializer:chainaddr;column:token_address"`
+	TokenId      string            `json:"token_id" gorm:"column:token_id"`
+	TokenMeta    string            `json:"token_meta" gorm:"column:token_meta"`
+	Fee          *big.Int          `gorm:"serializer:u256;column:fee" db:"fee" json:"Fee" form:"fee"`
+	Amount       *big.Int          `gorm:"serializer:u256;column:amount" db:"amount" json:"Amount" form:"amount"`
+	Status       uint8             `json:"status"` // 0:交易未签名, 1:交易已签名, 2:交易已经发送到区块链网络；3:交易在钱包层已完成；4:已通知业务；5:成功；6:签名交易校验不通过已拦截
+	TxType       string            `json:"tx_type"`
+	TxSignHex    string            `json:"tx_sign_hex" gorm:"column:tx_sign_hex"`
+	Nonce        uint64            `json:"nonce" gorm:"column:nonce"`       // 签名交易使用的账户 nonce，发送前校验
+	ChainId      string            `json:"chain_id" gorm:"column:chain_id"` // 签名交易的链 id，发送前校验
 	Timestamp    uint64
 }
 
@@ -32,6 +35,8 @@ type InternalsView interface {
 
 	QueryInternalsByHash(requestId string, txId string) (*Internals, error)
 	UnSendInternalsList(requestId string) ([]Internals, error)
+	// QueryUnsignedInternals 按创建时间返回指定类型的未签名内部交易
+	QueryUnsignedInternals(requestId string, txType string, limit int) ([]Internals, error)
 }
 
 type InternalsDB interface {
@@ -39,15 +44,20 @@ type InternalsDB interface {
 
 	StoreInternal(string, *Internals) error
 	UpdateInternalTx(requestId string, transactionId string, signedTx string, fee *big.Int, status uint8) error
+	// UpdateInternalSigned 写入校验通过的签名交易及其 nonce 和链 id，状态改为已签名
+	UpdateInternalSigned(requestId string, transactionId string, signedTx string, nonce uint64, chainId string) error
 	UpdateInternalstatus(requestId string, status uint8, InternalsList []Internals) error
+	// UpdateInternalsSent 按 guid 写入广播后的交易哈希和状态
+	UpdateInternalsSent(requestId string, internalsList []Internals) error
 }
 
 type internalsDB struct {
-	gorm *gorm.DB
+	gorm   *gorm.DB
+	router *ReplicaRouter
 }
 
-func NewInternalsDB(db *gorm.DB) InternalsDB {
-	return &internalsDB{gorm: db}
+func NewInternalsDB(db *gorm.DB, router *ReplicaRouter) InternalsDB {
+	return &internalsDB{gorm: db, router: router}
 }
 
 func (db *internalsDB) StoreInternal(requestId string, internals *Internals) error {
@@ -57,7 +67,7 @@ func (db *internalsDB) StoreInternal(requestId string, internals *Internals) err
 
 func (db *internalsDB) QueryInternalsByHash(requestId string, txId string) (*Internals, error) {
 	var internalsEntity Internals
-	result := db.gorm.Table("withdraws_"+requestId).Where("guid", txId).Take(&internalsEntity)
+	result := db.gorm.Table("internals_"+requestId).Where("guid", txId).Take(&internalsEntity)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -69,7 +79,7 @@ func (db *internalsDB) QueryInternalsByHash(requestId string, txId string) (*Int
 
 func (db *internalsDB) UnSendInternalsList(requestId string) ([]Internals, error) {
 	var InternalsList []Internals
-	err := db.gorm.Table("internals_"+requestId).Table("internals").Where("status = ?", 1).Find(&InternalsList).Error
+	err := db.gorm.Table("internals_"+requestId).Where("status = ?", 1).Find(&InternalsList).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -79,6 +89,18 @@ func (db *internalsDB) UnSendInternalsList(requestId string) ([]Internals, error
 	return InternalsList, nil
 }
 
+func (db *internalsDB) QueryUnsignedInternals(requestId string, txType string, limit int) ([]Internals, error) {
+	var internalsList []Internals
+	err := db.gorm.Table("internals_"+requestId).
+		Where("status = ? AND tx_type = ?", 0, txType).
+		Order("timestamp").Limit(limit).
+		Find(&internalsList).Error
+	if err != nil {
+		return nil, err
+	}
+	return internalsList, nil
+}
+
 func (db *internalsDB) UpdateInternalTx(requestId string, transactionId string, signedTx string, fee *big.Int, status uint8) error {
 	var InternalsSingle = Internals{}
 
@@ -103,9 +125,14 @@ func (db *internalsDB) UpdateInternalTx(requestId string, transactionId string,
 	return nil
 }
 
+func (db *internalsDB) UpdateInternalSigned(requestId string, transactionId string, signedTx string, nonce uint64, chainId string) error {
+	return db.gorm.Table("internals_"+requestId).Where("guid", transactionId).
+		Updates(map[string]interface{}{"tx_sign_hex": signedTx, "nonce": nonce, "chain_id": chainId, "status": 1}).Error
+}
+
 func (db *internalsDB) QueryNotifyInternal(requestId string) ([]Internals, error) {
 	var notifyInternals []Internals
-	result := db.gorm.Table("internals_"+requestId).Where("status = ?", 3).Find(notifyInternals)
+	result := db.gorm.Table("internals_"+requestId).Where("status = ?", 3).Find(&notifyInternals)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, gorm.ErrRecordNotFound
@@ -133,3 +160,14 @@ func (db *internalsDB) UpdateInternalstatus(requestId string, status uint8, Inte
 	}
 	return nil
 }
+
+func (db *internalsDB) UpdateInternalsSent(requestId string, internalsList []Internals) error {
+	for _, internal := range internalsList {
+		err := db.gorm.Table("internals_"+requestId).Where("guid", internal.GUID).
+			Updates(map[string]interface{}{"hash": internal.Hash.String(), "status": internal.Status}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrLeaseLost = errors.New("leader lease lost")
+
+// LeaderLeases 主备选举租约，过期时间使用数据库时钟，避免各实例时钟偏差。
+// token 每次换主时递增，作为 fencing token 校验写入方
+type LeaderLeases struct {
+	Name      string `gorm:"primaryKey"`
+	Holder    string
+	Token     uint64
+	ExpiresAt time.Time
+}
+
+// Fence 当选后持有的租约，写事务开始时校验租约仍属于自己
+type Fence struct {
+	Name   string
+	Holder string
+	Token  uint64
+}
+
+type LeasesView interface {
+	QueryLease(name string) (*LeaderLeases, error)
+}
+
+type LeasesDB interface {
+	LeasesView
+
+	// AcquireLease 租约空闲、已过期或属于 holder 时获取或续约，返回 token，被其他实例持有时返回 0
+	AcquireLease(name string, holder string, ttl time.Duration) (uint64, error)
+	ReleaseLease(fence Fence) error
+	// CheckLease 租约仍由 fence 持有时返回 nil，在事务内调用会锁住租约行直到事务结束，期间无法换主
+	CheckLease(fence Fence) error
+}
+
+type leasesDB struct {
+	gorm *gorm.DB
+}
+
+func NewLeasesDB(db *gorm.DB) LeasesDB {
+	return &leasesDB{gorm: db}
+}
+
+func (db *leasesDB) QueryLease(name string) (*LeaderLeases, error) {
+	var lease LeaderLeases
+	err := db.gorm.Table("leader_leases").Where("name = ?", name).Take(&lease).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &lease, nil
+}
+
+func (db *leasesDB) AcquireLease(name string, holder string, ttl time.Duration) (uint64, error) {
+	var tokens []uint64
+	// 同一持有者在有效期内续约不换 token，过期后重新获取视为新任期
+	err := db.gorm.Raw(`INSERT INTO leader_leases (name, holder, token, expires_at)
+VALUES (?, ?, 1, now() + ? * interval '1 millisecond')
+ON CONFLICT (name) DO UPDATE SET
+    token = CASE WHEN leader_leases.holder = EXCLUDED.holder AND leader_leases.expires_at > now()
+        THEN leader_leases.token ELSE leader_leases.token + 1 END,
+    holder = EXCLUDED.holder,
+    expires_at = EXCLUDED.expires_at
+WHERE leader_leases.holder = EXCLUDED.holder OR leader_leases.expires_at <= now()
+RETURNING token`, name, holder, ttl.Milliseconds()).Scan(&tokens).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(tokens) == 0 {
+		return 0, nil
+	}
+	return tokens[0], nil
+}
+
+func (db *leasesDB) ReleaseLease(fence Fence) error {
+	// 置为过期而不是删除，保留 token 保证下一任期继续递增
+	return db.gorm.Exec("UPDATE leader_leases SET expires_at = now() WHERE name = ? AND holder = ? AND token = ?",
+		fence.Name, fence.Holder, fence.Token).Error
+}
+
+func (db *leasesDB) CheckLease(fence Fence) error {
+	var count int64
+	err := db.gorm.Raw(`SELECT count(*) FROM (SELECT 1 FROM leader_leases
+WHERE name = ? AND holder = ? AND token = ? AND expires_at > now() FOR SHARE) held`,
+		fence.Name, fence.Holder, fence.Token).Scan(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("%w: %s token %d", ErrLeaseLost, fence.Name, fence.Token)
+	}
+	return nil
+}
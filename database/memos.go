@@ -0,0 +1,101 @@
+package database
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+)
+
+// Memos 共享充值地址上分配给用户的 memo(XRP 的 destination tag、EOS/Cosmos 的 memo 等)，
+// 地址一旦分配过 memo 即视为共享地址，充值按 (地址, memo) 匹配用户
+type Memos struct {
+	GUID      uuid.UUID         `gorm:"primaryKey" json:"guid"`
+	Address   chainaddr.Address `gorm:"column:address;serializer:chainaddr" json:"address"`
+	Memo      string            `gorm:"column:memo" json:"memo"`
+	UserUid   string            `gorm:"column:user_uid" json:"user_uid"`
+	Timestamp uint64
+}
+
+type MemosView interface {
+	QueryMemo(requestId string, address chainaddr.Address, memo string) (*Memos, error)
+	IsSharedAddress(requestId string, address chainaddr.Address) (bool, error)
+}
+
+type MemosDB interface {
+	MemosView
+
+	// AllocateMemo 为用户分配地址上的下一个数字 memo，用户已有 memo 时直接返回
+	AllocateMemo(requestId string, address chainaddr.Address, userUid string) (*Memos, error)
+}
+
+type memosDB struct {
+	gorm   *gorm.DB
+	router *ReplicaRouter
+}
+
+func NewMemosDB(db *gorm.DB, router *ReplicaRouter) MemosDB {
+	return &memosDB{gorm: db, router: router}
+}
+
+func (db *memosDB) QueryMemo(requestId string, address chainaddr.Address, memo string) (*Memos, error) {
+	var entry Memos
+	err := db.gorm.Table("memos_"+requestId).Where("address = ? and memo = ?", address.String(), memo).Take(&entry).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (db *memosDB) IsSharedAddress(requestId string, address chainaddr.Address) (bool, error) {
+	var count int64
+	err := db.gorm.Table("memos_"+requestId).Where("address = ?", address.String()).Limit(1).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (db *memosDB) AllocateMemo(requestId string, address chainaddr.Address, userUid string) (*Memos, error) {
+	var allocated *Memos
+	err := db.gorm.Transaction(func(tx *gorm.DB) error {
+		var existing Memos
+		err := tx.Table("memos_"+requestId).Where("address = ? and user_uid = ?", address.String(), userUid).Take(&existing).Error
+		if err == nil {
+			allocated = &existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		// 按地址加事务级锁，同一地址并发分配时 memo 不会重复
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "memos_"+requestId+":"+address.String()).Error; err != nil {
+			return err
+		}
+		var next int64
+		err = tx.Table("memos_"+requestId).Where("address = ?", address.String()).
+			Select("COALESCE(MAX(CAST(memo AS BIGINT)), 0) + 1").Scan(&next).Error
+		if err != nil {
+			return err
+		}
+		allocated = &Memos{
+			GUID:      uuid.New(),
+			Address:   address,
+			Memo:      strconv.FormatInt(next, 10),
+			UserUid:   userUid,
+			Timestamp: uint64(time.Now().Unix()),
+		}
+		return tx.Table("memos_" + requestId).Create(allocated).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allocated, nil
+}
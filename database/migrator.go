@@ -0,0 +1,327 @@
+package database
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	schemaMigrationsTable = "schema_migrations"
+
+	// 迁移文件内的分段标记，没有任何标记的文件整体视为 Up
+	directiveUp           = "-- +migrate Up"
+	directiveDown         = "-- +migrate Down"
+	directiveBusinessUp   = "-- +migrate BusinessUp"
+	directiveBusinessDown = "-- +migrate BusinessDown"
+
+	// BusinessTableSuffix 在 Business 分段中会被替换为空串(模板表)以及每个业务方的 "_<business_uid>"
+	BusinessTableSuffix = "${suffix}"
+)
+
+type Migration struct {
+	Version      uint64
+	Name         string
+	Path         string
+	Checksum     string
+	Up           string
+	Down         string
+	BusinessUp   string
+	BusinessDown string
+}
+
+type SchemaMigration struct {
+	Version   uint64 `gorm:"primaryKey"`
+	Name      string
+	Checksum  string
+	AppliedAt uint64
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt uint64
+	Modified  bool // 文件在应用之后被修改过
+}
+
+type Migrator struct {
+	gorm   *gorm.DB
+	dir    string
+	DryRun bool
+	// Output 在 DryRun 模式下接收将要执行的 SQL，默认写入 stdout
+	Output func(version uint64, target string, sql string)
+}
+
+func (db *DB) NewMigrator(migrationsFolder string) *Migrator {
+	return &Migrator{
+		gorm: db.gorm,
+		dir:  migrationsFolder,
+		Output: func(version uint64, target string, sql string) {
+			fmt.Printf("-- version %d (%s)\n%s\n", version, target, sql)
+		},
+	}
+}
+
+func (m *Migrator) ensureVersionTable() error {
+	return m.gorm.Exec(`CREATE TABLE IF NOT EXISTS ` + schemaMigrationsTable + ` (
+    version    BIGINT PRIMARY KEY,
+    name       VARCHAR NOT NULL,
+    checksum   VARCHAR NOT NULL,
+    applied_at INTEGER NOT NULL
+)`).Error
+}
+
+func (m *Migrator) appliedVersions() (map[uint64]SchemaMigration, error) {
+	var applied []SchemaMigration
+	if err := m.gorm.Table(schemaMigrationsTable).Find(&applied).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[uint64]SchemaMigration, len(applied))
+	for _, item := range applied {
+		out[item.Version] = item
+	}
+	return out, nil
+}
+
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations(m.dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.ensureVersionTable(); err != nil {
+		return nil, err
+	}
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+	statusList := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Migration: migration}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = record.AppliedAt
+			status.Modified = record.Checksum != migration.Checksum
+		}
+		statusList = append(statusList, status)
+	}
+	return statusList, nil
+}
+
+// Up 按版本顺序应用未执行的迁移，target 为 0 时应用全部
+func (m *Migrator) Up(target uint64) error {
+	statusList, err := m.Status()
+	if err != nil {
+		return err
+	}
+	for _, status := range statusList {
+		if status.Applied {
+			if status.Modified {
+				log.Warn("applied migration has been modified", "version", status.Version, "name", status.Name)
+			}
+			continue
+		}
+		if target != 0 && status.Version > target {
+			break
+		}
+		if err := m.apply(status.Migration, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Down 按版本倒序回滚最近 steps 个已应用的迁移
+func (m *Migrator) Down(steps int) error {
+	statusList, err := m.Status()
+	if err != nil {
+		return err
+	}
+	for i := len(statusList) - 1; i >= 0 && steps > 0; i-- {
+		if !statusList[i].Applied {
+			continue
+		}
+		if err := m.apply(statusList[i].Migration, false); err != nil {
+			return err
+		}
+		steps--
+	}
+	return nil
+}
+
+func (m *Migrator) apply(migration Migration, up bool) error {
+	sql, businessSql := migration.Up, migration.BusinessUp
+	direction := "up"
+	if !up {
+		sql, businessSql = migration.Down, migration.BusinessDown
+		direction = "down"
+		if strings.TrimSpace(sql) == "" && strings.TrimSpace(businessSql) == "" {
+			return fmt.Errorf("migration %d %s has no down section", migration.Version, migration.Name)
+		}
+	}
+
+	var businessIds []string
+	if strings.TrimSpace(businessSql) != "" {
+		if err := m.gorm.Raw("SELECT business_uid FROM business").Scan(&businessIds).Error; err != nil {
+			return errors.Wrap(err, "query business list for template migration")
+		}
+	}
+
+	if m.DryRun {
+		if strings.TrimSpace(sql) != "" {
+			m.Output(migration.Version, direction, sql)
+		}
+		if strings.TrimSpace(businessSql) != "" {
+			m.Output(migration.Version, direction+" template", expandBusinessSql(businessSql, ""))
+			for _, businessId := range businessIds {
+				m.Output(migration.Version, direction+" business "+businessId, expandBusinessSql(businessSql, businessId))
+			}
+		}
+		return nil
+	}
+
+	log.Info("apply migration", "version", migration.Version, "name", migration.Name, "direction", direction, "businesses", len(businessIds))
+	return m.gorm.Transaction(func(tx *gorm.DB) error {
+		// 回滚时先处理业务表，再处理全局表
+		if !up {
+			if err := execBusinessSql(tx, businessSql, businessIds); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("Error executing migration %d business down", migration.Version))
+			}
+		}
+		if strings.TrimSpace(sql) != "" {
+			if err := tx.Exec(sql).Error; err != nil {
+				return errors.Wrap(err, fmt.Sprintf("Error executing migration %d %s", migration.Version, direction))
+			}
+		}
+		if up {
+			if err := execBusinessSql(tx, businessSql, businessIds); err != nil {
+				return errors.Wrap(err, fmt.Sprintf("Error executing migration %d business up", migration.Version))
+			}
+			record := SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Checksum:  migration.Checksum,
+				AppliedAt: uint64(time.Now().Unix()),
+			}
+			return tx.Table(schemaMigrationsTable).Create(&record).Error
+		}
+		return tx.Table(schemaMigrationsTable).Where("version = ?", migration.Version).Delete(&SchemaMigration{}).Error
+	})
+}
+
+func execBusinessSql(tx *gorm.DB, businessSql string, businessIds []string) error {
+	if strings.TrimSpace(businessSql) == "" {
+		return nil
+	}
+	if err := tx.Exec(expandBusinessSql(businessSql, "")).Error; err != nil {
+		return err
+	}
+	for _, businessId := range businessIds {
+		if err := tx.Exec(expandBusinessSql(businessSql, businessId)).Error; err != nil {
+			return errors.Wrap(err, fmt.Sprintf("business %s", businessId))
+		}
+	}
+	return nil
+}
+
+func expandBusinessSql(businessSql string, businessId string) string {
+	suffix := ""
+	if businessId != "" {
+		suffix = "_" + businessId
+	}
+	return strings.ReplaceAll(businessSql, BusinessTableSuffix, suffix)
+}
+
+// LoadMigrations 读取目录下所有 "<version>_<name>.sql" 文件并按版本排序
+func LoadMigrations(migrationsFolder string) ([]Migration, error) {
+	var migrations []Migration
+	seen := make(map[uint64]string)
+	err := filepath.Walk(migrationsFolder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Failed to process migration file: %s", path))
+		}
+		if info.IsDir() || filepath.Ext(path) != ".sql" {
+			return nil
+		}
+		fileContent, readErr := os.ReadFile(path)
+		if readErr != nil {
+			return errors.Wrap(readErr, fmt.Sprintf("Error reading SQL file: %s", path))
+		}
+		migration, parseErr := parseMigration(filepath.Base(path), string(fileContent))
+		if parseErr != nil {
+			return errors.Wrap(parseErr, fmt.Sprintf("Error parsing SQL file: %s", path))
+		}
+		if other, ok := seen[migration.Version]; ok {
+			return fmt.Errorf("duplicate migration version %d: %s and %s", migration.Version, other, path)
+		}
+		seen[migration.Version] = path
+		migration.Path = path
+		migrations = append(migrations, migration)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func parseMigration(fileName string, content string) (Migration, error) {
+	base := strings.TrimSuffix(fileName, ".sql")
+	versionStr, name, found := strings.Cut(base, "_")
+	if !found {
+		return Migration{}, fmt.Errorf("migration file name must look like <version>_<name>.sql, got %s", fileName)
+	}
+	version, err := strconv.ParseUint(versionStr, 10, 64)
+	if err != nil {
+		return Migration{}, fmt.Errorf("invalid migration version %q: %w", versionStr, err)
+	}
+
+	checksum := sha256.Sum256([]byte(content))
+	migration := Migration{
+		Version:  version,
+		Name:     name,
+		Checksum: hex.EncodeToString(checksum[:]),
+	}
+
+	sections := map[string]*strings.Builder{
+		directiveUp:           {},
+		directiveDown:         {},
+		directiveBusinessUp:   {},
+		directiveBusinessDown: {},
+	}
+	current := sections[directiveUp]
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if section, ok := sections[strings.TrimSpace(line)]; ok {
+			current = section
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return Migration{}, err
+	}
+	migration.Up = sections[directiveUp].String()
+	migration.Down = sections[directiveDown].String()
+	migration.BusinessUp = sections[directiveBusinessUp].String()
+	migration.BusinessDown = sections[directiveBusinessDown].String()
+	return migration, nil
+}
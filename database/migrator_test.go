@@ -0,0 +1,39 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMigration(t *testing.T) {
+	content := `-- +migrate Up
+ALTER TABLE business ADD COLUMN IF NOT EXISTS status SMALLINT;
+
+-- +migrate Down
+ALTER TABLE business DROP COLUMN IF EXISTS status;
+
+-- +migrate BusinessUp
+ALTER TABLE deposits${suffix} ADD COLUMN IF NOT EXISTS memo VARCHAR;
+
+-- +migrate BusinessDown
+ALTER TABLE deposits${suffix} DROP COLUMN IF EXISTS memo;
+`
+	migration, err := parseMigration("00003_deposit_memo.sql", content)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), migration.Version)
+	require.Equal(t, "deposit_memo", migration.Name)
+	require.Contains(t, migration.Up, "ADD COLUMN IF NOT EXISTS status")
+	require.Contains(t, migration.Down, "DROP COLUMN IF EXISTS status")
+	require.NotContains(t, migration.Up, "deposits")
+	require.Equal(t, "ALTER TABLE deposits_b1 ADD COLUMN IF NOT EXISTS memo VARCHAR;\n\n", expandBusinessSql(migration.BusinessUp, "b1"))
+	require.Contains(t, expandBusinessSql(migration.BusinessDown, ""), "ALTER TABLE deposits DROP")
+
+	legacy, err := parseMigration("00001_create_schema.sql", "CREATE TABLE a(id INT);\n")
+	require.NoError(t, err)
+	require.Equal(t, "CREATE TABLE a(id INT);\n", legacy.Up)
+	require.Empty(t, legacy.Down)
+
+	_, err = parseMigration("create_schema.sql", "")
+	require.Error(t, err)
+}
@@ -0,0 +1,112 @@
+package database
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+)
+
+const (
+	NftStandardERC721  = "erc721"
+	NftStandardERC1155 = "erc1155"
+)
+
+// NftHoldings 业务方地址持有的 NFT，ERC-721 数量恒为 1，转出后数量为 0 的记录保留
+type NftHoldings struct {
+	GUID         uuid.UUID         `gorm:"primaryKey" json:"guid"`
+	Address      chainaddr.Address `gorm:"column:address;serializer:chainaddr" json:"address"`
+	TokenAddress chainaddr.Address `gorm:"column:token_address;serializer:chainaddr" json:"token_address"`
+	TokenId      string            `gorm:"column:token_id" json:"token_id"`
+	Standard     string            `gorm:"column:standard" json:"standard"`
+	Amount       *big.Int          `gorm:"serializer:u256;column:amount" json:"amount"`
+	Timestamp    uint64
+}
+
+type NftHoldingsView interface {
+	QueryNftHoldings(requestId string, address chainaddr.Address) ([]NftHoldings, error)
+	QueryNftHolding(requestId string, address, tokenAddress chainaddr.Address, tokenId string) (*NftHoldings, error)
+}
+
+type NftHoldingsDB interface {
+	NftHoldingsView
+
+	// AddNftHoldings 转入时累加持有数量，不存在则新建
+	AddNftHoldings(requestId string, holdings []NftHoldings) error
+	// SubNftHoldings 转出时扣减持有数量，没有持仓记录(如地址注册前转入)的转出忽略
+	SubNftHoldings(requestId string, holdings []NftHoldings) error
+}
+
+type nftHoldingsDB struct {
+	gorm   *gorm.DB
+	router *ReplicaRouter
+}
+
+func NewNftHoldingsDB(db *gorm.DB, router *ReplicaRouter) NftHoldingsDB {
+	return &nftHoldingsDB{gorm: db, router: router}
+}
+
+func (db *nftHoldingsDB) QueryNftHoldings(requestId string, address chainaddr.Address) ([]NftHoldings, error) {
+	var holdings []NftHoldings
+	err := db.router.Reader(db.gorm).Table("nft_holdings_"+requestId).
+		Where("address = ? and amount > 0", address.String()).
+		Order("token_address asc, token_id asc").
+		Find(&holdings).Error
+	if err != nil {
+		return nil, err
+	}
+	return holdings, nil
+}
+
+func (db *nftHoldingsDB) QueryNftHolding(requestId string, address, tokenAddress chainaddr.Address, tokenId string) (*NftHoldings, error) {
+	var holding NftHoldings
+	err := db.gorm.Table("nft_holdings_"+requestId).
+		Where("address = ? and token_address = ? and token_id = ?", address.String(), tokenAddress.String(), tokenId).
+		Take(&holding).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &holding, nil
+}
+
+func (db *nftHoldingsDB) AddNftHoldings(requestId string, holdings []NftHoldings) error {
+	tableName := "nft_holdings_" + requestId
+	for i := range holdings {
+		err := db.gorm.Table(tableName).
+			Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "address"}, {Name: "token_address"}, {Name: "token_id"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"amount":    gorm.Expr(tableName + ".amount + excluded.amount"),
+					"timestamp": gorm.Expr("excluded.timestamp"),
+				}),
+			}).
+			Create(&holdings[i]).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *nftHoldingsDB) SubNftHoldings(requestId string, holdings []NftHoldings) error {
+	for _, holding := range holdings {
+		result := db.gorm.Table("nft_holdings_"+requestId).
+			Where("address = ? and token_address = ? and token_id = ? and amount >= ?", holding.Address.String(), holding.TokenAddress.String(), holding.TokenId, holding.Amount.String()).
+			Updates(map[string]interface{}{"amount": gorm.Expr("amount - ?", holding.Amount.String()), "timestamp": holding.Timestamp})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			log.Warn("nft holding not found, skip debit", "address", holding.Address, "token", holding.TokenAddress, "tokenId", holding.TokenId)
+		}
+	}
+	return nil
+}
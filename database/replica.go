@@ -0,0 +1,106 @@
+package database
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/common/clock"
+)
+
+const (
+	defaultReplicaMaxLag    = 5 * time.Second
+	replicaLagCheckInterval = 5 * time.Second
+
+	// 主从 WAL 位置一致时认为没有延迟，否则取最后一次回放事务距今的时间
+	replicaLagQuery = `SELECT CASE
+    WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+    ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END`
+)
+
+// ReplicaRouter 决定只读 API 查询走从库还是主库。worker、通知和状态变更前的读取始终走主库，
+// 避免从库延迟导致重复广播、重复通知或漏掉新地址的充值。
+// 从库延迟超过 maxLag 或检测失败时回退到主库，直到下一次检测恢复。
+type ReplicaRouter struct {
+	replica *gorm.DB
+	maxLag  time.Duration
+
+	healthy atomic.Bool
+	lag     atomic.Int64
+	worker  *clock.LoopFn
+}
+
+func NewReplicaRouter(replica *gorm.DB, maxLag time.Duration) *ReplicaRouter {
+	if maxLag == 0 {
+		maxLag = defaultReplicaMaxLag
+	}
+	router := &ReplicaRouter{
+		replica: replica,
+		maxLag:  maxLag,
+	}
+	router.checkLag(context.Background())
+	router.worker = clock.NewLoopFn(clock.SystemClock, router.checkLag, nil, replicaLagCheckInterval)
+	return router
+}
+
+// Reader 返回只读查询应使用的连接，router 为 nil 时(例如事务内)始终使用主库
+func (r *ReplicaRouter) Reader(master *gorm.DB) *gorm.DB {
+	if r == nil || !r.healthy.Load() {
+		return master
+	}
+	return r.replica
+}
+
+func (r *ReplicaRouter) Healthy() bool {
+	return r != nil && r.healthy.Load()
+}
+
+func (r *ReplicaRouter) Lag() time.Duration {
+	if r == nil {
+		return 0
+	}
+	return time.Duration(r.lag.Load())
+}
+
+func (r *ReplicaRouter) Close() error {
+	if r == nil {
+		return nil
+	}
+	if r.worker != nil {
+		if err := r.worker.Close(); err != nil {
+			return err
+		}
+	}
+	sql, err := r.replica.DB()
+	if err != nil {
+		return err
+	}
+	return sql.Close()
+}
+
+func (r *ReplicaRouter) checkLag(ctx context.Context) {
+	var lagSeconds float64
+	err := r.replica.WithContext(ctx).Raw(replicaLagQuery).Scan(&lagSeconds).Error
+	if err != nil {
+		if r.healthy.Swap(false) {
+			log.Warn("replica lag check fail, route queries to master", "err", err)
+		}
+		return
+	}
+	lag := time.Duration(lagSeconds * float64(time.Second))
+	r.lag.Store(int64(lag))
+	if lag > r.maxLag {
+		if r.healthy.Swap(false) {
+			log.Warn("replica is lagging, route queries to master", "lag", lag, "maxLag", r.maxLag)
+		}
+		return
+	}
+	if !r.healthy.Swap(true) {
+		log.Info("replica is healthy, route queries to replica", "lag", lag)
+	}
+}
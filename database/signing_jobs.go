@@ -0,0 +1,124 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	SigningJobStatusPending   uint8 = 0
+	SigningJobStatusSigned    uint8 = 1
+	SigningJobStatusCancelled uint8 = 2 // 交易已通过其他方式签名或被拦截
+)
+
+// SigningJobs 签名队列，每笔提现/内部交易一行。签名机拉取时分配新的 job_id 并在 expires_at 前独占，
+// 过期未提交的任务重新可拉取，旧 job_id 失效
+type SigningJobs struct {
+	TransactionId string `gorm:"primaryKey;column:transaction_id" json:"transaction_id"`
+	TxType        string `gorm:"column:tx_type" json:"tx_type"`
+	ChainId       string `gorm:"column:chain_id" json:"chain_id"`
+	JobId         string `gorm:"column:job_id" json:"job_id"`
+	Signer        string `json:"signer"`
+	Status        uint8  `json:"status"`
+	Nonce         uint64 `json:"nonce"`
+	Payload       string `json:"payload"` // 拉取时构建的 base64 交易结构，提交签名时原样用于组装签名交易
+	ExpiresAt     uint64 `gorm:"column:expires_at" json:"expires_at"`
+	Attempts      uint32 `json:"attempts"`
+	Timestamp     uint64
+}
+
+type SigningJobsView interface {
+	QuerySigningJob(requestId string, jobId string) (*SigningJobs, error)
+}
+
+type SigningJobsDB interface {
+	SigningJobsView
+
+	StoreSigningJob(requestId string, job *SigningJobs) error
+	// LeaseSigningJobs 取出待签名且未被租用的任务，为其分配新的 job_id，在 timeout 内对其他签名机隐藏
+	LeaseSigningJobs(requestId string, signer string, limit int, timeout time.Duration) ([]SigningJobs, error)
+	UpdateSigningJobPayload(requestId string, jobId string, nonce uint64, payload string) error
+	// ReleaseSigningJob 构建待签名交易失败时释放租约，下次拉取重新构建
+	ReleaseSigningJob(requestId string, jobId string) error
+	// CompleteSigningJob 租约有效时结束任务，返回 false 表示任务已结束或租约已过期
+	CompleteSigningJob(requestId string, jobId string, status uint8) (bool, error)
+}
+
+type signingJobsDB struct {
+	gorm *gorm.DB
+}
+
+func NewSigningJobsDB(db *gorm.DB) SigningJobsDB {
+	return &signingJobsDB{gorm: db}
+}
+
+func (db *signingJobsDB) QuerySigningJob(requestId string, jobId string) (*SigningJobs, error) {
+	var job SigningJobs
+	err := db.gorm.Table("signing_jobs_"+requestId).Where("job_id = ?", jobId).Take(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (db *signingJobsDB) StoreSigningJob(requestId string, job *SigningJobs) error {
+	return db.gorm.Table("signing_jobs_" + requestId).Create(job).Error
+}
+
+func (db *signingJobsDB) LeaseSigningJobs(requestId string, signer string, limit int, timeout time.Duration) ([]SigningJobs, error) {
+	var jobs []SigningJobs
+	err := db.gorm.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Table("signing_jobs_"+requestId).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? and expires_at <= ?", SigningJobStatusPending, now.Unix()).
+			Order("timestamp asc").Limit(limit).Find(&jobs).Error
+		if err != nil || len(jobs) == 0 {
+			return err
+		}
+		expiresAt := uint64(now.Add(timeout).Unix())
+		for i := range jobs {
+			jobs[i].JobId = uuid.New().String()
+			jobs[i].Signer = signer
+			jobs[i].ExpiresAt = expiresAt
+			jobs[i].Attempts++
+			err := tx.Table("signing_jobs_"+requestId).Where("transaction_id = ?", jobs[i].TransactionId).
+				Updates(map[string]interface{}{"job_id": jobs[i].JobId, "signer": signer, "expires_at": expiresAt, "attempts": jobs[i].Attempts}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (db *signingJobsDB) UpdateSigningJobPayload(requestId string, jobId string, nonce uint64, payload string) error {
+	return db.gorm.Table("signing_jobs_"+requestId).Where("job_id = ?", jobId).
+		Updates(map[string]interface{}{"nonce": nonce, "payload": payload}).Error
+}
+
+func (db *signingJobsDB) ReleaseSigningJob(requestId string, jobId string) error {
+	return db.gorm.Table("signing_jobs_"+requestId).Where("job_id = ? and status = ?", jobId, SigningJobStatusPending).
+		Updates(map[string]interface{}{"job_id": "", "expires_at": 0}).Error
+}
+
+func (db *signingJobsDB) CompleteSigningJob(requestId string, jobId string, status uint8) (bool, error) {
+	result := db.gorm.Table("signing_jobs_"+requestId).
+		Where("job_id = ? and status = ? and expires_at > ?", jobId, SigningJobStatusPending, time.Now().Unix()).
+		Update("status", status)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
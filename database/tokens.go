@@ -2,21 +2,22 @@ package database
 
 import (
 	"errors"
+	"math/big"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
-	"math/big"
 
-	"github.com/ethereum/go-ethereum/common"
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
 )
 
 type Tokens struct {
-	GUID          uuid.UUID      `gorm:"primaryKey" json:"guid"`
-	TokenAddress  common.Address `gorm:"serializer:bytes" json:"token_address"`
-	Decimals      uint8          `json:"uint"`
-	TokenName     string         `json:"tokens_name"`
-	CollectAmount *big.Int       `gorm:"serializer:u256" json:"collect_amount"`
-	ColdAmount    *big.Int       `gorm:"serializer:u256" json:"cold_amount"`
-	Timestamp     uint64         `json:"timestamp"`
+	GUID          uuid.UUID         `gorm:"primaryKey" json:"guid"`
+	TokenAddress  chainaddr.Address `gorm:"serializer:chainaddr" json:"token_address"`
+	Decimals      uint8             `json:"uint"`
+	TokenName     string            `json:"tokens_name"`
+	CollectAmount *big.Int          `gorm:"serializer:u256" json:"collect_amount"`
+	ColdAmount    *big.Int          `gorm:"serializer:u256" json:"cold_amount"`
+	Timestamp     uint64            `json:"timestamp"`
 }
 
 type TokensView interface {
@@ -30,11 +31,12 @@ type TokensDB interface {
 }
 
 type tokensDB struct {
-	gorm *gorm.DB
+	gorm   *gorm.DB
+	router *ReplicaRouter
 }
 
-func NewTokensDB(db *gorm.DB) TokensDB {
-	return &tokensDB{gorm: db}
+func NewTokensDB(db *gorm.DB, router *ReplicaRouter) TokensDB {
+	return &tokensDB{gorm: db, router: router}
 }
 
 func (db *tokensDB) StoreTokens(requestId string, tokenList []Tokens) error {
@@ -44,7 +46,7 @@ func (db *tokensDB) StoreTokens(requestId string, tokenList []Tokens) error {
 
 func (db *tokensDB) TokensInfoByAddress(requestId string, address string) (*Tokens, error) {
 	var tokensEntry Tokens
-	err := db.gorm.Table("tokens_"+requestId).Where("token_address", address).Take(&tokensEntry).Error
+	err := db.router.Reader(db.gorm).Table("tokens_"+requestId).Where("token_address", address).Take(&tokensEntry).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
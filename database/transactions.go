@@ -2,34 +2,35 @@ package database
 
 import (
 	"errors"
-	"gorm.io/gorm"
 	"math/big"
 
+	"gorm.io/gorm"
+
 	"github.com/google/uuid"
 
-	"github.com/ethereum/go-ethereum/common"
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
 )
 
 type Transactions struct {
-	GUID             uuid.UUID      `gorm:"primaryKey" json:"guid"`
-	BlockHash        common.Hash    `gorm:"column:block_hash;serializer:bytes"  db:"block_hash" json:"block_hash"`
-	BlockNumber      *big.Int       `gorm:"serializer:u256;column:block_number" db:"block_number" json:"BlockNumber" form:"block_number"`
-	Hash             common.Hash    `gorm:"column:hash;serializer:bytes"  db:"hash" json:"hash"`
-	FromAddress      common.Address `json:"from_address" gorm:"serializer:bytes"`
-	ToAddress        common.Address `json:"to_address" gorm:"serializer:bytes"`
-	TokenAddress     common.Address `json:"token_address" gorm:"serializer:bytes"`
-	TokenId          string         `json:"token_id" gorm:"column:token_id"`
-	TokenMeta        string         `json:"token_meta" gorm:"column:token_meta"`
-	Fee              *big.Int       `gorm:"serializer:u256;column:fee" db:"fee" json:"Fee" form:"fee"`
-	Amount           *big.Int       `gorm:"serializer:u256;column:amount" db:"amount" json:"Amount" form:"amount"`
-	Status           uint8          `json:"status"`  // 0:交易确认中,1:钱包交易已到账；2:交易已通知业务层；3:交易完成
-	TxType           uint8          `json:"tx_type"` // 0:充值；1:提现；2:归集；3:热转冷；4:冷转热
-	TransactionIndex *big.Int       `gorm:"serializer:u256;column:transaction_index" db:"transaction_index" json:"TransactionIndex" form:"transaction_index"`
+	GUID             uuid.UUID         `gorm:"primaryKey" json:"guid"`
+	BlockHash        chainaddr.Hash    `gorm:"column:block_hash;serializer:chainaddr"  db:"block_hash" json:"block_hash"`
+	BlockNumber      *big.Int          `gorm:"serializer:u256;column:block_number" db:"block_number" json:"BlockNumber" form:"block_number"`
+	Hash             chainaddr.Hash    `gorm:"column:hash;serializer:chainaddr"  db:"hash" json:"hash"`
+	FromAddress      chainaddr.Address `json:"from_address" gorm:"serializer:chainaddr"`
+	ToAddress        chainaddr.Address `json:"to_address" gorm:"serializer:chainaddr"`
+	TokenAddress     chainaddr.Address `json:"token_address" gorm:"serializer:chainaddr"`
+	TokenId          string            `json:"token_id" gorm:"column:token_id"`
+	TokenMeta        string            `json:"token_meta" gorm:"column:token_meta"`
+	Fee              *big.Int          `gorm:"serializer:u256;column:fee" db:"fee" json:"Fee" form:"fee"`
+	Amount           *big.Int          `gorm:"serializer:u256;column:amount" db:"amount" json:"Amount" form:"amount"`
+	Status           uint8             `json:"status"`  // 0:交易确认中,1:钱包交易已到账；2:交易已通知业务层；3:交易完成
+	TxType           uint8             `json:"tx_type"` // 0:充值；1:提现；2:归集；3:热转冷；4:冷转热
+	TransactionIndex *big.Int          `gorm:"serializer:u256;column:transaction_index" db:"transaction_index" json:"TransactionIndex" form:"transaction_index"`
 	Timestamp        uint64
 }
 
 type TransactionsView interface {
-	QueryTransactionByHash(requestId string, hash common.Hash) (*Transactions, error)
+	QueryTransactionByHash(requestId string, hash chainaddr.Hash) (*Transactions, error)
 }
 
 type TransactionsDB interface {
@@ -41,10 +42,11 @@ type TransactionsDB interface {
 }
 
 type transactionsDB struct {
-	gorm *gorm.DB
+	gorm   *gorm.DB
+	router *ReplicaRouter
 }
 
-func (db *transactionsDB) QueryTransactionByHash(requestId string, hash common.Hash) (*Transactions, error) {
+func (db *transactionsDB) QueryTransactionByHash(requestId string, hash chainaddr.Hash) (*Transactions, error) {
 	var transactionEntry Transactions
 	result := db.gorm.Table("transactions_"+requestId).Where("hash", hash.String()).Take(&transactionEntry)
 	if result.Error != nil {
@@ -67,8 +69,8 @@ func (db *transactionsDB) UpdateTransactionsStatus(requestId string, blockNumber
 	return nil
 }
 
-func NewTransactionsDB(db *gorm.DB) TransactionsDB {
-	return &transactionsDB{gorm: db}
+func NewTransactionsDB(db *gorm.DB, router *ReplicaRouter) TransactionsDB {
+	return &transactionsDB{gorm: db, router: router}
 }
 
 func (db *transactionsDB) StoreTransactions(requestId string, transactionsList []Transactions, transactionsLength uint64) error {
@@ -0,0 +1,64 @@
+package serializers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+)
+
+// ChainAddrSerializer 读写 chainaddr.Address / chainaddr.Hash，写入前按当前链重新规范化，
+// 非法的地址或哈希直接报错，不会像 common.HexToAddress 那样静默截断
+type ChainAddrSerializer struct{}
+
+func init() {
+	schema.RegisterSerializer("chainaddr", ChainAddrSerializer{})
+}
+
+func (ChainAddrSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	if dbValue == nil {
+		return nil
+	}
+	var str string
+	switch v := dbValue.(type) {
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	default:
+		return fmt.Errorf("expected string as the database value: %T", dbValue)
+	}
+	if field.FieldType.Kind() != reflect.String {
+		return fmt.Errorf("can only deserialize into a string kind field: %s", field.FieldType)
+	}
+	field.ReflectValueOf(ctx, dst).Set(reflect.ValueOf(str).Convert(field.FieldType))
+	return nil
+}
+
+func (ChainAddrSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	switch v := fieldValue.(type) {
+	case chainaddr.Address:
+		if v.IsZero() {
+			return "", nil
+		}
+		address, err := chainaddr.Default().ParseAddress(v.String())
+		if err != nil {
+			return nil, err
+		}
+		return address.String(), nil
+	case chainaddr.Hash:
+		if v.IsZero() {
+			return "", nil
+		}
+		hash, err := chainaddr.Default().ParseHash(v.String())
+		if err != nil {
+			return nil, err
+		}
+		return hash.String(), nil
+	default:
+		return nil, fmt.Errorf("can only serialize chainaddr.Address or chainaddr.Hash: %T", fieldValue)
+	}
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"math/big"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+)
+
+const (
+	UtxoStatusUnspent uint8 = 0 // 可用
+	UtxoStatusLocked  uint8 = 1 // 已被未上链的提现或内部交易选中
+	UtxoStatusSpent   uint8 = 2 // 花费交易已上链
+)
+
+// Utxos UTXO 模型链上属于业务方地址的未花费输出，充值、找零和归集的输出都会记录
+type Utxos struct {
+	GUID          uuid.UUID         `gorm:"primaryKey" json:"guid"`
+	TxHash        chainaddr.Hash    `gorm:"column:tx_hash;serializer:chainaddr" json:"tx_hash"`
+	Vout          uint32            `gorm:"column:vout" json:"vout"`
+	Address       chainaddr.Address `gorm:"column:address;serializer:chainaddr" json:"address"`
+	Amount        *big.Int          `gorm:"serializer:u256;column:amount" json:"amount"`
+	BlockNumber   *big.Int          `gorm:"serializer:u256;column:block_number" json:"block_number"`
+	Status        uint8             `json:"status"`
+	TransactionId string            `gorm:"column:transaction_id" json:"transaction_id"` // 锁定该输出的提现或内部交易 guid
+	SpentHash     chainaddr.Hash    `gorm:"column:spent_hash;serializer:chainaddr" json:"spent_hash"`
+	Timestamp     uint64
+}
+
+type UtxosView interface {
+	QueryUnspentUtxos(requestId string, address chainaddr.Address) ([]Utxos, error)
+	QueryUtxosByTransactionId(requestId string, transactionId string) ([]Utxos, error)
+}
+
+type UtxosDB interface {
+	UtxosView
+
+	// StoreUtxos 同一输出重复写入时忽略，补扫和实时同步可能处理同一笔交易
+	StoreUtxos(requestId string, utxos []Utxos) error
+	// LockUnspentUtxos 在事务内锁定地址的可用输出供选币使用，并发构建交易时跳过已被锁定的行
+	LockUnspentUtxos(requestId string, address chainaddr.Address) ([]Utxos, error)
+	MarkUtxosLocked(requestId string, transactionId string, guids []uuid.UUID) error
+	UpdateUtxosSpentHash(requestId string, transactionId string, spentHash chainaddr.Hash) error
+	MarkUtxosSpent(requestId string, spentHashes []chainaddr.Hash) error
+	// ReleaseUtxos 交易作废时解锁其选中的输出
+	ReleaseUtxos(requestId string, transactionId string) error
+}
+
+type utxosDB struct {
+	gorm   *gorm.DB
+	router *ReplicaRouter
+}
+
+func NewUtxosDB(db *gorm.DB, router *ReplicaRouter) UtxosDB {
+	return &utxosDB{gorm: db, router: router}
+}
+
+func (db *utxosDB) QueryUnspentUtxos(requestId string, address chainaddr.Address) ([]Utxos, error) {
+	var utxos []Utxos
+	err := db.router.Reader(db.gorm).Table("utxos_"+requestId).
+		Where("address = ? and status = ?", address.String(), UtxoStatusUnspent).
+		Order("amount desc").
+		Find(&utxos).Error
+	if err != nil {
+		return nil, err
+	}
+	return utxos, nil
+}
+
+func (db *utxosDB) QueryUtxosByTransactionId(requestId string, transactionId string) ([]Utxos, error) {
+	var utxos []Utxos
+	err := db.gorm.Table("utxos_"+requestId).Where("transaction_id = ?", transactionId).Order("amount desc").Find(&utxos).Error
+	if err != nil {
+		return nil, err
+	}
+	return utxos, nil
+}
+
+func (db *utxosDB) StoreUtxos(requestId string, utxos []Utxos) error {
+	if len(utxos) == 0 {
+		return nil
+	}
+	return db.gorm.Table("utxos_"+requestId).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "tx_hash"}, {Name: "vout"}}, DoNothing: true}).
+		CreateInBatches(&utxos, len(utxos)).Error
+}
+
+func (db *utxosDB) LockUnspentUtxos(requestId string, address chainaddr.Address) ([]Utxos, error) {
+	var utxos []Utxos
+	err := db.gorm.Table("utxos_"+requestId).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("address = ? and status = ?", address.String(), UtxoStatusUnspent).
+		Order("amount desc").
+		Find(&utxos).Error
+	if err != nil {
+		return nil, err
+	}
+	return utxos, nil
+}
+
+func (db *utxosDB) MarkUtxosLocked(requestId string, transactionId string, guids []uuid.UUID) error {
+	if len(guids) == 0 {
+		return nil
+	}
+	return db.gorm.Table("utxos_"+requestId).
+		Where("guid in ? and status = ?", guids, UtxoStatusUnspent).
+		Updates(map[string]interface{}{"status": UtxoStatusLocked, "transaction_id": transactionId}).Error
+}
+
+// UpdateUtxosSpentHash 交易广播后记录花费交易哈希，等同步到该交易时再标记为已花费
+func (db *utxosDB) UpdateUtxosSpentHash(requestId string, transactionId string, spentHash chainaddr.Hash) error {
+	return db.gorm.Table("utxos_"+requestId).
+		Where("transaction_id = ? and status = ?", transactionId, UtxoStatusLocked).
+		Update("spent_hash", spentHash.String()).Error
+}
+
+func (db *utxosDB) MarkUtxosSpent(requestId string, spentHashes []chainaddr.Hash) error {
+	if len(spentHashes) == 0 {
+		return nil
+	}
+	hashes := make([]string, 0, len(spentHashes))
+	for _, hash := range spentHashes {
+		hashes = append(hashes, hash.String())
+	}
+	return db.gorm.Table("utxos_"+requestId).
+		Where("spent_hash in ? and status = ?", hashes, UtxoStatusLocked).
+		Update("status", UtxoStatusSpent).Error
+}
+
+func (db *utxosDB) ReleaseUtxos(requestId string, transactionId string) error {
+	return db.gorm.Table("utxos_"+requestId).
+		Where("transaction_id = ? and status = ?", transactionId, UtxoStatusLocked).
+		Updates(map[string]interface{}{"status": UtxoStatusUnspent, "transaction_id": "", "spent_hash": ""}).Error
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+)
+
+const (
+	WithdrawBatchStatusUnSigned  uint8 = 0
+	WithdrawBatchStatusSigned    uint8 = 1
+	WithdrawBatchStatusSent      uint8 = 2
+	WithdrawBatchStatusConfirmed uint8 = 3
+	WithdrawBatchStatusFailed    uint8 = 4 // 发送失败或链上回滚，成员提现已退回可重试状态
+)
+
+// WithdrawBatches 批量提现交易，一笔链上交易对应 withdraws 表中 batch_id 相同的多条提现
+type WithdrawBatches struct {
+	GUID         uuid.UUID         `gorm:"primaryKey" json:"guid"`
+	Hash         chainaddr.Hash    `gorm:"column:hash;serializer:chainaddr" json:"hash"`
+	FromAddress  chainaddr.Address `gorm:"column:from_address;serializer:chainaddr" json:"from_address"`
+	TokenAddress chainaddr.Address `gorm:"column:token_address;serializer:chainaddr" json:"token_address"`
+	Amount       *big.Int          `gorm:"serializer:u256;column:amount" json:"amount"`
+	Fee          *big.Int          `gorm:"serializer:u256;column:fee" json:"fee"`
+	Status       uint8             `json:"status"`
+	TxSignHex    string            `gorm:"column:tx_sign_hex" json:"tx_sign_hex"`
+	Nonce        uint64            `json:"nonce" gorm:"column:nonce"`       // 签名交易使用的账户 nonce，发送前校验
+	ChainId      string            `json:"chain_id" gorm:"column:chain_id"` // 签名交易的链 id，发送前校验
+	Timestamp    uint64
+}
+
+type WithdrawBatchesView interface {
+	QueryWithdrawBatch(requestId string, batchId string) (*WithdrawBatches, error)
+	QueryWithdrawBatchByHash(requestId string, hash chainaddr.Hash) (*WithdrawBatches, error)
+	UnSendWithdrawBatches(requestId string) ([]WithdrawBatches, error)
+}
+
+type WithdrawBatchesDB interface {
+	WithdrawBatchesView
+
+	StoreWithdrawBatch(requestId string, batch *WithdrawBatches) error
+	UpdateWithdrawBatchSigned(requestId string, batchId string, signedTx string, nonce uint64, chainId string) error
+	UpdateWithdrawBatchSent(requestId string, batchId string, hash chainaddr.Hash) error
+	UpdateWithdrawBatchStatus(requestId string, batchId string, status uint8, fee *big.Int) error
+}
+
+type withdrawBatchesDB struct {
+	gorm   *gorm.DB
+	router *ReplicaRouter
+}
+
+func NewWithdrawBatchesDB(db *gorm.DB, router *ReplicaRouter) WithdrawBatchesDB {
+	return &withdrawBatchesDB{gorm: db, router: router}
+}
+
+func (db *withdrawBatchesDB) QueryWithdrawBatch(requestId string, batchId string) (*WithdrawBatches, error) {
+	var batch WithdrawBatches
+	err := db.router.Reader(db.gorm).Table("withdraw_batches_"+requestId).Where("guid", batchId).Take(&batch).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// QueryWithdrawBatchByHash 同步流程用于确认批量交易，必须读主库
+func (db *withdrawBatchesDB) QueryWithdrawBatchByHash(requestId string, hash chainaddr.Hash) (*WithdrawBatches, error) {
+	var batch WithdrawBatches
+	err := db.gorm.Table("withdraw_batches_"+requestId).Where("hash = ? and status = ?", hash.String(), WithdrawBatchStatusSent).Take(&batch).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (db *withdrawBatchesDB) UnSendWithdrawBatches(requestId string) ([]WithdrawBatches, error) {
+	var batches []WithdrawBatches
+	err := db.gorm.Table("withdraw_batches_"+requestId).Where("status = ?", WithdrawBatchStatusSigned).Find(&batches).Error
+	if err != nil {
+		return nil, err
+	}
+	return batches, nil
+}
+
+func (db *withdrawBatchesDB) StoreWithdrawBatch(requestId string, batch *WithdrawBatches) error {
+	return db.gorm.Table("withdraw_batches_" + requestId).Create(batch).Error
+}
+
+func (db *withdrawBatchesDB) UpdateWithdrawBatchSigned(requestId string, batchId string, signedTx string, nonce uint64, chainId string) error {
+	return db.gorm.Table("withdraw_batches_"+requestId).Where("guid", batchId).
+		Updates(map[string]interface{}{"tx_sign_hex": signedTx, "nonce": nonce, "chain_id": chainId, "status": WithdrawBatchStatusSigned}).Error
+}
+
+func (db *withdrawBatchesDB) UpdateWithdrawBatchSent(requestId string, batchId string, hash chainaddr.Hash) error {
+	return db.gorm.Table("withdraw_batches_"+requestId).Where("guid", batchId).
+		Updates(map[string]interface{}{"hash": hash.String(), "status": WithdrawBatchStatusSent}).Error
+}
+
+func (db *withdrawBatchesDB) UpdateWithdrawBatchStatus(requestId string, batchId string, status uint8, fee *big.Int) error {
+	updates := map[string]interface{}{"status": status}
+	if fee != nil {
+		updates["fee"] = fee.String()
+	}
+	return db.gorm.Table("withdraw_batches_"+requestId).Where("guid", batchId).Updates(updates).Error
+}
@@ -2,30 +2,37 @@ package database
 
 import (
 	"errors"
-	"gorm.io/gorm"
 	"math/big"
 	"time"
 
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
 	"github.com/google/uuid"
 
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
 )
 
 type Withdraws struct {
-	GUID         uuid.UUID      `gorm:"primaryKey" json:"guid"`
-	BlockHash    common.Hash    `gorm:"column:block_hash;serializer:bytes"  db:"block_hash" json:"block_hash"`
-	BlockNumber  *big.Int       `gorm:"serializer:u256;column:block_number" db:"block_number" json:"BlockNumber" form:"block_number"`
-	Hash         common.Hash    `gorm:"column:hash;serializer:bytes"  db:"hash" json:"hash"`
-	FromAddress  common.Address `json:"from_address" gorm:"serializer:bytes;column:from_address"`
-	ToAddress    common.Address `json:"to_address" gorm:"serializer:bytes;column:to_address"`
-	TokenAddress common.Address `json:"token_address" gorm:"serializer:bytes;column:token_address"`
-	TokenId      string         `json:"token_id" gorm:"column:token_id"`
-	TokenMeta    string         `json:"token_meta" gorm:"column:token_meta"`
-	Fee          *big.Int       `gorm:"serializer:u256;column:fee" db:"fee" json:"Fee" form:"fee"`
-	Amount       *big.Int       `gorm:"serializer:u256;column:amount" db:"amount" json:"Amount" form:"amount"`
-	Status       uint8          `json:"status"` // 0:提现未签名, 1:提现交易已签名, 2:提现已经发送到区块链网络；3:提现在钱包层已完成；4:提现已通知业务；5:提现成功
-	TxSignHex    string         `json:"tx_sign_hex" gorm:"column:tx_sign_hex"`
+	GUID         uuid.UUID         `gorm:"primaryKey" json:"guid"`
+	BlockHash    chainaddr.Hash    `gorm:"column:block_hash;serializer:chainaddr"  db:"block_hash" json:"block_hash"`
+	BlockNumber  *big.Int          `gorm:"serializer:u256;column:block_number" db:"block_number" json:"BlockNumber" form:"block_number"`
+	Hash         chainaddr.Hash    `gorm:"column:hash;serializer:chainaddr"  db:"hash" json:"hash"`
+	FromAddress  chainaddr.Address `json:"from_address" gorm:"serializer:chainaddr;column:from_address"`
+	ToAddress    chainaddr.Address `json:"to_address" gorm:"serializer:chainaddr;column:to_address"`
+	TokenAddress chainaddr.Address `json:"token_address" gorm:"serializer:chainaddr;column:token_address"`
+	TokenId      string            `json:"token_id" gorm:"column:token_id"`
+	TokenMeta    string            `json:"token_meta" gorm:"column:token_meta"`
+	Fee          *big.Int          `gorm:"serializer:u256;column:fee" db:"fee" json:"Fee" form:"fee"`
+	Amount       *big.Int          `gorm:"serializer:u256;column:amount" db:"amount" json:"Amount" form:"amount"`
+	Status       uint8             `json:"status"` // 0:提现未签名, 1:提现交易已签名, 2:提现已经发送到区块链网络；3:提现在钱包层已完成；4:提现已通知业务；5:提现成功；6:签名交易校验不通过已拦截
+	TxSignHex    string            `json:"tx_sign_hex" gorm:"column:tx_sign_hex"`
+	BatchId      string            `json:"batch_id" gorm:"column:batch_id"` // 所属批量提现交易，单笔提现为空
+	Memo         string            `json:"memo" gorm:"column:memo"`         // 收款方为共享地址时的 memo/destination tag
+	Nonce        uint64            `json:"nonce" gorm:"column:nonce"`       // 签名交易使用的账户 nonce，发送前校验
+	ChainId      string            `json:"chain_id" gorm:"column:chain_id"` // 签名交易的链 id，发送前校验
 	Timestamp    uint64
 }
 
@@ -33,23 +40,35 @@ type WithdrawsView interface {
 	QueryWithdrawsByHash(requestId string, txId string) (*Withdraws, error)
 	UnSendWithdrawsList(requestId string) ([]Withdraws, error)
 	QueryNotifyWithdraws(string) ([]Withdraws, error)
-	SubmitWithdrawFromBusiness(requestId string, fromAddress common.Address, toAddress common.Address, TokenAddress common.Address, amount *big.Int) error
+	SubmitWithdrawFromBusiness(requestId string, fromAddress chainaddr.Address, toAddress chainaddr.Address, TokenAddress chainaddr.Address, amount *big.Int) error
+	QueryWithdrawsByBatchId(requestId string, batchId string) ([]Withdraws, error)
 }
 
 type WithdrawsDB interface {
 	WithdrawsView
 	StoreWithdraw(string, *Withdraws) error
 	UpdateWithdrawTx(requestId string, transactionId string, signedTx string, fee *big.Int, status uint8) error
+	// UpdateWithdrawSigned 写入校验通过的签名交易及其 nonce 和链 id，状态改为已签名
+	UpdateWithdrawSigned(requestId string, transactionId string, signedTx string, nonce uint64, chainId string) error
 	UpdateWithdrawStatus(requestId string, status uint8, withdrawsList []Withdraws) error
+	// UpdateWithdrawsSent 按 guid 写入广播后的交易哈希和状态
+	UpdateWithdrawsSent(requestId string, withdrawsList []Withdraws) error
+	// LockPendingWithdraws 锁定未签名且未加入批量交易的提现，transactionIds 为空时取该地址和代币的全部待处理提现
+	LockPendingWithdraws(requestId string, fromAddress, tokenAddress chainaddr.Address, transactionIds []string, limit int) ([]Withdraws, error)
+	AssignWithdrawBatch(requestId string, batchId string, withdrawsList []Withdraws) error
+	UpdateBatchWithdraws(requestId string, batchId string, status uint8, hash chainaddr.Hash) error
+	// ReleaseBatchWithdraws 批量交易失败时把成员提现退回未签名状态并解除与批次的关联，业务方可以重新提交
+	ReleaseBatchWithdraws(requestId string, batchId string) error
 }
 
 type withdrawsDB struct {
-	gorm *gorm.DB
+	gorm   *gorm.DB
+	router *ReplicaRouter
 }
 
 func (db *withdrawsDB) QueryNotifyWithdraws(requestId string) ([]Withdraws, error) {
 	var notifyWithdraws []Withdraws
-	result := db.gorm.Table("withdraws_"+requestId).Where("status = ?", 3).Find(notifyWithdraws)
+	result := db.gorm.Table("withdraws_"+requestId).Where("status = ?", 3).Find(&notifyWithdraws)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, gorm.ErrRecordNotFound
@@ -61,10 +80,10 @@ func (db *withdrawsDB) QueryNotifyWithdraws(requestId string) ([]Withdraws, erro
 
 /**
 *@Description: select withdraws by transactionId
-*/
+ */
 func (db *withdrawsDB) UnSendWithdrawsList(requestId string) ([]Withdraws, error) {
 	var withdrawsList []Withdraws
-	err := db.gorm.Table("withdraws_"+requestId).Table("withdraws").Where("status = ?", 1).Find(&withdrawsList).Error
+	err := db.gorm.Table("withdraws_"+requestId).Where("status = ? and batch_id = ''", 1).Find(&withdrawsList).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -74,7 +93,6 @@ func (db *withdrawsDB) UnSendWithdrawsList(requestId string) ([]Withdraws, error
 	return withdrawsList, nil
 }
 
-
 func (db *withdrawsDB) QueryWithdrawsByHash(requestId string, txId string) (*Withdraws, error) {
 	var withdrawsEntity Withdraws
 	result := db.gorm.Table("withdraws_"+requestId).Where("guid", txId).Take(&withdrawsEntity)
@@ -87,12 +105,12 @@ func (db *withdrawsDB) QueryWithdrawsByHash(requestId string, txId string) (*Wit
 	return &withdrawsEntity, nil
 }
 
-func (db *withdrawsDB) SubmitWithdrawFromBusiness(requestId string, fromAddress common.Address, toAddress common.Address, TokenAddress common.Address, amount *big.Int) error {
+func (db *withdrawsDB) SubmitWithdrawFromBusiness(requestId string, fromAddress chainaddr.Address, toAddress chainaddr.Address, TokenAddress chainaddr.Address, amount *big.Int) error {
 	withdrawS := Withdraws{
 		GUID:         uuid.New(),
-		BlockHash:    common.Hash{},
+		BlockHash:    "",
 		BlockNumber:  big.NewInt(1),
-		Hash:         common.Hash{},
+		Hash:         "",
 		FromAddress:  fromAddress,
 		ToAddress:    toAddress,
 		TokenAddress: TokenAddress,
@@ -134,12 +152,12 @@ func (db *withdrawsDB) UpdateWithdrawTx(requestId string, transactionId string,
 	return nil
 }
 
-func NewWithdrawsDB(db *gorm.DB) WithdrawsDB {
-	return &withdrawsDB{gorm: db}
+func NewWithdrawsDB(db *gorm.DB, router *ReplicaRouter) WithdrawsDB {
+	return &withdrawsDB{gorm: db, router: router}
 }
 
 func (db *withdrawsDB) StoreWithdraw(requestId string, withdrawsList *Withdraws) error {
-	result := db.gorm.Table("withdraws_"+requestId).Commit().Create(&withdrawsList)
+	result := db.gorm.Table("withdraws_" + requestId).Commit().Create(&withdrawsList)
 	return result.Error
 }
 
@@ -173,3 +191,68 @@ func (db *withdrawsDB) UpdateWithdrawStatus(requestId string, status uint8, with
 	}
 	return nil
 }
+
+func (db *withdrawsDB) UpdateWithdrawsSent(requestId string, withdrawsList []Withdraws) error {
+	for _, withdraw := range withdrawsList {
+		err := db.gorm.Table("withdraws_"+requestId).Where("guid", withdraw.GUID).
+			Updates(map[string]interface{}{"hash": withdraw.Hash.String(), "status": withdraw.Status}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *withdrawsDB) UpdateWithdrawSigned(requestId string, transactionId string, signedTx string, nonce uint64, chainId string) error {
+	return db.gorm.Table("withdraws_"+requestId).Where("guid", transactionId).
+		Updates(map[string]interface{}{"tx_sign_hex": signedTx, "nonce": nonce, "chain_id": chainId, "status": 1}).Error
+}
+
+func (db *withdrawsDB) QueryWithdrawsByBatchId(requestId string, batchId string) ([]Withdraws, error) {
+	var withdrawsList []Withdraws
+	err := db.gorm.Table("withdraws_"+requestId).Where("batch_id = ?", batchId).Order("timestamp asc, guid asc").Find(&withdrawsList).Error
+	if err != nil {
+		return nil, err
+	}
+	return withdrawsList, nil
+}
+
+func (db *withdrawsDB) LockPendingWithdraws(requestId string, fromAddress, tokenAddress chainaddr.Address, transactionIds []string, limit int) ([]Withdraws, error) {
+	var withdrawsList []Withdraws
+	query := db.gorm.Table("withdraws_"+requestId).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("from_address = ? and token_address = ? and status = ? and batch_id = ''", fromAddress.String(), tokenAddress.String(), 0)
+	if len(transactionIds) > 0 {
+		query = query.Where("guid in ?", transactionIds)
+	}
+	err := query.Order("timestamp asc, guid asc").Limit(limit).Find(&withdrawsList).Error
+	if err != nil {
+		return nil, err
+	}
+	return withdrawsList, nil
+}
+
+// AssignWithdrawBatch 记录成员提现所属的批次和分摊的手续费
+func (db *withdrawsDB) AssignWithdrawBatch(requestId string, batchId string, withdrawsList []Withdraws) error {
+	for _, withdraw := range withdrawsList {
+		err := db.gorm.Table("withdraws_"+requestId).Where("guid", withdraw.GUID).
+			Updates(map[string]interface{}{"batch_id": batchId, "fee": withdraw.Fee.String()}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *withdrawsDB) UpdateBatchWithdraws(requestId string, batchId string, status uint8, hash chainaddr.Hash) error {
+	updates := map[string]interface{}{"status": status}
+	if !hash.IsZero() {
+		updates["hash"] = hash.String()
+	}
+	return db.gorm.Table("withdraws_"+requestId).Where("batch_id = ?", batchId).Updates(updates).Error
+}
+
+func (db *withdrawsDB) ReleaseBatchWithdraws(requestId string, batchId string) error {
+	return db.gorm.Table("withdraws_"+requestId).Where("batch_id = ?", batchId).
+		Updates(map[string]interface{}{"status": 0, "batch_id": "", "hash": "", "tx_sign_hex": "", "fee": "0"}).Error
+}
@@ -13,6 +13,11 @@ func prefixEnvVars(name string) []string {
 }
 
 var (
+	ConfigFileFlag = &cli.StringFlag{
+		Name:    "config",
+		Usage:   "path of a yaml or toml config file, flags and env vars override its values",
+		EnvVars: prefixEnvVars("CONFIG"),
+	}
 	MigrationsFlag = &cli.StringFlag{
 		Name:    "migrations-dir",
 		Value:   "./migrations",
@@ -21,24 +26,21 @@ var (
 	}
 
 	ChainIdFlag = &cli.StringFlag{
-		Name:     "chain-id",
-		Usage:    "chain id",
-		EnvVars:  prefixEnvVars("CHAIN_ID"),
-		Required: true,
+		Name:    "chain-id",
+		Usage:   "chain id",
+		EnvVars: prefixEnvVars("CHAIN_ID"),
 	}
 
 	ChainNameFlag = &cli.StringFlag{
-		Name:     "chain-name",
-		Usage:    "chain name",
-		EnvVars:  prefixEnvVars("CHAIN_NAME"),
-		Required: true,
+		Name:    "chain-name",
+		Usage:   "chain name",
+		EnvVars: prefixEnvVars("CHAIN_NAME"),
 	}
 
 	RpcUrlFlag = &cli.StringFlag{
-		Name:     "rpc-url",
-		Usage:    "HTTP provider URL for chain",
-		EnvVars:  prefixEnvVars("RPC_RUL"),
-		Required: true,
+		Name:    "rpc-url",
+		Usage:   "HTTP provider URL for chain",
+		EnvVars: prefixEnvVars("RPC_RUL"),
 	}
 
 	StartingHeightFlag = &cli.UintFlag{
@@ -71,86 +73,203 @@ var (
 		EnvVars: prefixEnvVars("BLOCKS_STEP"),
 		Value:   500,
 	}
+	BusinessRefreshIntervalFlag = &cli.DurationFlag{
+		Name:    "business-refresh-interval",
+		Usage:   "The interval of reloading registered businesses",
+		EnvVars: prefixEnvVars("BUSINESS_REFRESH_INTERVAL"),
+		Value:   time.Second * 10,
+	}
+	AddressPoolSizeFlag = &cli.IntFlag{
+		Name:    "address-pool-size",
+		Usage:   "The number of unallocated addresses derived ahead from each registered xpub",
+		EnvVars: prefixEnvVars("ADDRESS_POOL_SIZE"),
+		Value:   100,
+	}
+	MultisendContractFlag = &cli.StringFlag{
+		Name:    "multisend-contract",
+		Usage:   "The multisend contract used to batch withdrawals on evm chains",
+		EnvVars: prefixEnvVars("MULTISEND_CONTRACT"),
+	}
+	EventFileDirFlag = &cli.StringFlag{
+		Name:    "event-file-dir",
+		Usage:   "The directory of append-only jsonl event files for businesses using the file sink",
+		EnvVars: prefixEnvVars("EVENT_FILE_DIR"),
+	}
+	EventBrokerUrlFlag = &cli.StringFlag{
+		Name:    "event-broker-url",
+		Usage:   "The message broker for businesses using the broker sink, e.g. nats://127.0.0.1:4222",
+		EnvVars: prefixEnvVars("EVENT_BROKER_URL"),
+	}
+	EventVisibilityTimeoutFlag = &cli.DurationFlag{
+		Name:    "event-visibility-timeout",
+		Usage:   "The time after which events fetched from the inbox and not acknowledged are delivered again",
+		EnvVars: prefixEnvVars("EVENT_VISIBILITY_TIMEOUT"),
+		Value:   time.Second * 30,
+	}
+	LeaderLeaseTtlFlag = &cli.DurationFlag{
+		Name:    "leader-lease-ttl",
+		Usage:   "The lease duration of leader election, standby instances take over within this time after the leader dies",
+		EnvVars: prefixEnvVars("LEADER_LEASE_TTL"),
+		Value:   time.Second * 15,
+	}
+	WorkerFailureBudgetFlag = &cli.IntFlag{
+		Name:    "worker-failure-budget",
+		Usage:   "The number of failures a worker may restart from within the failure window before the process shuts down",
+		EnvVars: prefixEnvVars("WORKER_FAILURE_BUDGET"),
+		Value:   5,
+	}
+	WorkerFailureWindowFlag = &cli.DurationFlag{
+		Name:    "worker-failure-window",
+		Usage:   "The sliding window over which worker failures are counted against the failure budget",
+		EnvVars: prefixEnvVars("WORKER_FAILURE_WINDOW"),
+		Value:   time.Minute * 10,
+	}
 
 	// RpcHostFlag rpc api flags
 	RpcHostFlag = &cli.StringFlag{
-		Name:     "rpc-host",
-		Usage:    "The host of the rpc",
-		EnvVars:  prefixEnvVars("RPC_HOST"),
-		Required: true,
+		Name:    "rpc-host",
+		Usage:   "The host of the rpc",
+		EnvVars: prefixEnvVars("RPC_HOST"),
 	}
 	RpcPortFlag = &cli.IntFlag{
-		Name:     "rpc-port",
-		Usage:    "The port of the rpc",
-		EnvVars:  prefixEnvVars("RPC_PORT"),
-		Value:    8987,
-		Required: true,
+		Name:    "rpc-port",
+		Usage:   "The port of the rpc",
+		EnvVars: prefixEnvVars("RPC_PORT"),
+		Value:   8987,
+	}
+	HttpHostFlag = &cli.StringFlag{
+		Name:    "http-host",
+		Usage:   "The host of the http json gateway",
+		EnvVars: prefixEnvVars("HTTP_HOST"),
+		Value:   "127.0.0.1",
+	}
+	HttpPortFlag = &cli.IntFlag{
+		Name:    "http-port",
+		Usage:   "The port of the http json gateway, 0 disables the gateway",
+		EnvVars: prefixEnvVars("HTTP_PORT"),
+		Value:   0,
+	}
+	ConsumerTokensFlag = &cli.StringSliceFlag{
+		Name:    "consumer-tokens",
+		Usage:   "Consumer tokens accepted by the rpc and http api as token:business_id|... or token:*, empty disables the check",
+		EnvVars: prefixEnvVars("CONSUMER_TOKENS"),
+	}
+	SignerTokensFlag = &cli.StringSliceFlag{
+		Name:    "signer-tokens",
+		Usage:   "Tokens of signer clients accepted by the signing queue rpc, empty disables the signing queue",
+		EnvVars: prefixEnvVars("SIGNER_TOKENS"),
+	}
+	SigningJobTimeoutFlag = &cli.DurationFlag{
+		Name:    "signing-job-timeout",
+		Usage:   "The time a signer holds a fetched signing job, signatures submitted later are rejected and the job is handed out again",
+		EnvVars: prefixEnvVars("SIGNING_JOB_TIMEOUT"),
+		Value:   time.Minute * 5,
+	}
+	LocalSignerKeystoreFlag = &cli.StringFlag{
+		Name:    "local-signer-keystore",
+		Usage:   "Encrypted keystore directory used to sign internal transactions without a signer, for devnets and tests only, refused on mainnet chain ids",
+		EnvVars: prefixEnvVars("LOCAL_SIGNER_KEYSTORE"),
+	}
+	LocalSignerPasswordFileFlag = &cli.StringFlag{
+		Name:    "local-signer-password-file",
+		Usage:   "File holding the password of the local signer keystore",
+		EnvVars: prefixEnvVars("LOCAL_SIGNER_PASSWORD_FILE"),
 	}
 	ChainAccountRpcFlag = &cli.StringFlag{
-		Name:     "chain-account-rpc",
-		Usage:    "The host of chain account rpc",
-		EnvVars:  prefixEnvVars("CHAIN_ACCOUNT_RPC"),
-		Required: true,
+		Name:    "chain-account-rpc",
+		Usage:   "The hosts of chain account rpc separated by commas, the first one is preferred and the others are failover backups",
+		EnvVars: prefixEnvVars("CHAIN_ACCOUNT_RPC"),
+	}
+	ChainAccountTimeoutFlag = &cli.DurationFlag{
+		Name:    "chain-account-timeout",
+		Usage:   "The deadline of a single chain account rpc call",
+		EnvVars: prefixEnvVars("CHAIN_ACCOUNT_TIMEOUT"),
+		Value:   time.Second * 10,
+	}
+	ChainAccountRetriesFlag = &cli.IntFlag{
+		Name:    "chain-account-retries",
+		Usage:   "The number of attempts of read-only chain account rpc calls on transport errors",
+		EnvVars: prefixEnvVars("CHAIN_ACCOUNT_RETRIES"),
+		Value:   3,
+	}
+	ChainAccountMaxLagFlag = &cli.Uint64Flag{
+		Name:    "chain-account-max-lag",
+		Usage:   "The number of blocks a chain account endpoint may lag behind the others before it is taken out of rotation",
+		EnvVars: prefixEnvVars("CHAIN_ACCOUNT_MAX_LAG"),
+		Value:   5,
+	}
+	ChainAccountCheckIntervalFlag = &cli.DurationFlag{
+		Name:    "chain-account-check-interval",
+		Usage:   "The interval of comparing chain heads between chain account endpoints, 0 disables the check",
+		EnvVars: prefixEnvVars("CHAIN_ACCOUNT_CHECK_INTERVAL"),
+		Value:   time.Second * 30,
+	}
+	ChainAccountRecordFlag = &cli.StringFlag{
+		Name:    "chain-account-record",
+		Usage:   "Record every chain account rpc request and response to this gzip file for offline replay",
+		EnvVars: prefixEnvVars("CHAIN_ACCOUNT_RECORD"),
+	}
+	ChainAccountRecordWindowFlag = &cli.DurationFlag{
+		Name:    "chain-account-record-window",
+		Usage:   "How long to record chain account rpc calls after start, 0 records until shutdown",
+		EnvVars: prefixEnvVars("CHAIN_ACCOUNT_RECORD_WINDOW"),
+		Value:   time.Minute * 10,
+	}
+	ChainAccountReplayFlag = &cli.StringFlag{
+		Name:    "chain-account-replay",
+		Usage:   "Serve chain account rpc calls from a recorded file instead of the network",
+		EnvVars: prefixEnvVars("CHAIN_ACCOUNT_REPLAY"),
 	}
 
 	// MetricsHostFlag Metrics flags
 	MetricsHostFlag = &cli.StringFlag{
-		Name:     "metrics-host",
-		Usage:    "The host of the metrics",
-		EnvVars:  prefixEnvVars("METRICS_HOST"),
-		Required: true,
+		Name:    "metrics-host",
+		Usage:   "The host of the metrics",
+		EnvVars: prefixEnvVars("METRICS_HOST"),
 	}
 	MetricsPortFlag = &cli.IntFlag{
-		Name:     "metrics-port",
-		Usage:    "The port of the metrics",
-		EnvVars:  prefixEnvVars("METRICS_PORT"),
-		Value:    7214,
-		Required: true,
+		Name:    "metrics-port",
+		Usage:   "The port of the metrics",
+		EnvVars: prefixEnvVars("METRICS_PORT"),
+		Value:   7214,
 	}
 
 	SlaveDbEnableFlag = &cli.BoolFlag{
-		Name:     "slave-db-enable",
-		Usage:    "Whether to use slave db",
-		EnvVars:  prefixEnvVars("SLAVE_DB_ENABLE"),
-		Required: true,
+		Name:    "slave-db-enable",
+		Usage:   "Whether to use slave db",
+		EnvVars: prefixEnvVars("SLAVE_DB_ENABLE"),
 	}
 	ApiCacheEnableFlag = &cli.BoolFlag{
-		Name:     "api-cache-enable",
-		Usage:    "api cache enable",
-		EnvVars:  prefixEnvVars("API_CACHE_ENABLE"),
-		Required: true,
+		Name:    "api-cache-enable",
+		Usage:   "api cache enable",
+		EnvVars: prefixEnvVars("API_CACHE_ENABLE"),
 	}
 
 	// MasterDb Flags
 	MasterDbHostFlag = &cli.StringFlag{
-		Name:     "master-db-host",
-		Usage:    "The host of the master database",
-		EnvVars:  prefixEnvVars("MASTER_DB_HOST"),
-		Required: true,
+		Name:    "master-db-host",
+		Usage:   "The host of the master database",
+		EnvVars: prefixEnvVars("MASTER_DB_HOST"),
 	}
 	MasterDbPortFlag = &cli.IntFlag{
-		Name:     "master-db-port",
-		Usage:    "The port of the master database",
-		EnvVars:  prefixEnvVars("MASTER_DB_PORT"),
-		Required: true,
+		Name:    "master-db-port",
+		Usage:   "The port of the master database",
+		EnvVars: prefixEnvVars("MASTER_DB_PORT"),
 	}
 	MasterDbUserFlag = &cli.StringFlag{
-		Name:     "master-db-user",
-		Usage:    "The user of the master database",
-		EnvVars:  prefixEnvVars("MASTER_DB_USER"),
-		Required: true,
+		Name:    "master-db-user",
+		Usage:   "The user of the master database",
+		EnvVars: prefixEnvVars("MASTER_DB_USER"),
 	}
 	MasterDbPasswordFlag = &cli.StringFlag{
-		Name:     "master-db-password",
-		Usage:    "The host of the master database",
-		EnvVars:  prefixEnvVars("MASTER_DB_PASSWORD"),
-		Required: true,
+		Name:    "master-db-password",
+		Usage:   "The host of the master database",
+		EnvVars: prefixEnvVars("MASTER_DB_PASSWORD"),
 	}
 	MasterDbNameFlag = &cli.StringFlag{
-		Name:     "master-db-name",
-		Usage:    "The db name of the master database",
-		EnvVars:  prefixEnvVars("MASTER_DB_NAME"),
-		Required: true,
+		Name:    "master-db-name",
+		Usage:   "The db name of the master database",
+		EnvVars: prefixEnvVars("MASTER_DB_NAME"),
 	}
 
 	// Slave DB  flags
@@ -179,6 +298,12 @@ var (
 		Usage:   "The db name of the slave database",
 		EnvVars: prefixEnvVars("SLAVE_DB_NAME"),
 	}
+	SlaveDbMaxLagFlag = &cli.DurationFlag{
+		Name:    "slave-db-max-lag",
+		Usage:   "Max replication lag of the slave database before queries fall back to master",
+		EnvVars: prefixEnvVars("SLAVE_DB_MAX_LAG"),
+		Value:   time.Second * 5,
+	}
 
 	// cache flags
 	ApiCacheListSizeFlag = &cli.UintFlag{
@@ -205,6 +330,85 @@ var (
 	}
 )
 
+var (
+	MigrateDryRunFlag = &cli.BoolFlag{
+		Name:  "dry-run",
+		Usage: "Print the migration SQL without executing it",
+	}
+	MigrateTargetFlag = &cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Apply migrations up to and including this version, 0 means latest",
+	}
+	MigrateStepsFlag = &cli.IntFlag{
+		Name:  "steps",
+		Usage: "Number of migrations to roll back",
+		Value: 1,
+	}
+)
+
+var (
+	RescanFromFlag = &cli.Uint64Flag{
+		Name:  "from",
+		Usage: "First block height to rescan",
+	}
+	RescanToFlag = &cli.Uint64Flag{
+		Name:  "to",
+		Usage: "Last block height to rescan (inclusive)",
+	}
+	RescanBusinessFlag = &cli.StringFlag{
+		Name:  "business",
+		Usage: "Only rescan for this business request id, empty means all active businesses",
+	}
+)
+
+var (
+	ColdBundleBusinessFlag = &cli.StringFlag{
+		Name:     "business",
+		Usage:    "Business request id of the cold bundle",
+		Required: true,
+	}
+	ColdBundleTxTypeFlag = &cli.StringFlag{
+		Name:  "tx-type",
+		Usage: "Internal transaction type to export: cold2hot, hot2cold or collection",
+		Value: "cold2hot",
+	}
+	ColdBundleLimitFlag = &cli.UintFlag{
+		Name:  "limit",
+		Usage: "Max transactions in one exported bundle",
+		Value: 20,
+	}
+	ColdBundleFileFlag = &cli.StringFlag{
+		Name:  "file",
+		Usage: "Bundle file written by export and read by import, - means stdout or stdin",
+		Value: "-",
+	}
+	ColdBundleChunkSizeFlag = &cli.IntFlag{
+		Name:  "qr-chunk-size",
+		Usage: "Split the exported bundle into QR code sized chunks of this many bytes, one per line, 0 writes plain json",
+	}
+)
+
+var ColdBundleFlags = []cli.Flag{
+	ColdBundleBusinessFlag,
+	ColdBundleTxTypeFlag,
+	ColdBundleLimitFlag,
+	ColdBundleFileFlag,
+	ColdBundleChunkSizeFlag,
+}
+
+var RescanFlags = []cli.Flag{
+	RescanFromFlag,
+	RescanToFlag,
+	RescanBusinessFlag,
+}
+
+var MigrateFlags = []cli.Flag{
+	MigrateDryRunFlag,
+	MigrateTargetFlag,
+	MigrateStepsFlag,
+}
+
+// requireFlags 可以由配置文件提供，是否缺失由 config.Validate 统一校验
 var requireFlags = []cli.Flag{
 	MigrationsFlag,
 	RpcUrlFlag,
@@ -229,15 +433,40 @@ var requireFlags = []cli.Flag{
 }
 
 var optionalFlags = []cli.Flag{
+	ConfigFileFlag,
 	SlaveDbHostFlag,
 	SlaveDbPortFlag,
 	SlaveDbUserFlag,
 	SlaveDbPasswordFlag,
 	SlaveDbNameFlag,
+	SlaveDbMaxLagFlag,
+	HttpHostFlag,
+	HttpPortFlag,
+	ConsumerTokensFlag,
+	SignerTokensFlag,
+	SigningJobTimeoutFlag,
+	LocalSignerKeystoreFlag,
+	LocalSignerPasswordFileFlag,
 	ApiCacheListSizeFlag,
 	ApiCacheDetailSizeFlag,
 	ApiCacheListExpireTimeFlag,
 	ApiCacheDetailExpireTimeFlag,
+	BusinessRefreshIntervalFlag,
+	AddressPoolSizeFlag,
+	MultisendContractFlag,
+	EventFileDirFlag,
+	EventBrokerUrlFlag,
+	EventVisibilityTimeoutFlag,
+	LeaderLeaseTtlFlag,
+	WorkerFailureBudgetFlag,
+	WorkerFailureWindowFlag,
+	ChainAccountTimeoutFlag,
+	ChainAccountRetriesFlag,
+	ChainAccountMaxLagFlag,
+	ChainAccountCheckIntervalFlag,
+	ChainAccountRecordFlag,
+	ChainAccountRecordWindowFlag,
+	ChainAccountReplayFlag,
 }
 
 func init() {
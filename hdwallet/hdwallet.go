@@ -0,0 +1,171 @@
+package hdwallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+)
+
+const (
+	// HardenedOffset BIP-32 硬化派生的起始索引，扩展公钥只能做非硬化派生
+	HardenedOffset uint32 = 0x80000000
+
+	serializedKeyLen = 78
+)
+
+var (
+	ErrInvalidExtendedKey = errors.New("invalid extended public key")
+	ErrPrivateExtendedKey = errors.New("extended private key is not accepted, register the xpub instead")
+	ErrHardenedDerivation = errors.New("hardened derivation is not possible from an extended public key")
+	ErrInvalidChild       = errors.New("invalid child key, skip to the next index")
+
+	// 主网和测试网的 xpub/xprv 版本号，同时接受 BIP-49/84 的 ypub/zpub
+	publicVersions = [][]byte{
+		{0x04, 0x88, 0xb2, 0x1e}, // xpub
+		{0x04, 0x35, 0x87, 0xcf}, // tpub
+		{0x04, 0x9d, 0x7c, 0xb2}, // ypub
+		{0x04, 0xb2, 0x47, 0x46}, // zpub
+	}
+	privateVersions = [][]byte{
+		{0x04, 0x88, 0xad, 0xe4}, // xprv
+		{0x04, 0x35, 0x83, 0x94}, // tprv
+	}
+)
+
+// ExtendedKey BIP-32 扩展公钥，只保存派生子公钥需要的字段
+type ExtendedKey struct {
+	Depth     uint8
+	ChildNum  uint32
+	chainCode []byte
+	publicKey *ecdsa.PublicKey
+}
+
+// ParseExtendedPublicKey 解析 base58check 编码的扩展公钥
+func ParseExtendedPublicKey(xpub string) (*ExtendedKey, error) {
+	payload, err := chainaddr.Base58CheckDecode(strings.TrimSpace(xpub))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExtendedKey, err)
+	}
+	if len(payload) != serializedKeyLen {
+		return nil, fmt.Errorf("%w: unexpected length %d", ErrInvalidExtendedKey, len(payload))
+	}
+	version := payload[:4]
+	for _, v := range privateVersions {
+		if bytes.Equal(version, v) {
+			return nil, ErrPrivateExtendedKey
+		}
+	}
+	known := false
+	for _, v := range publicVersions {
+		if bytes.Equal(version, v) {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return nil, fmt.Errorf("%w: unknown version %x", ErrInvalidExtendedKey, version)
+	}
+	publicKey, err := crypto.DecompressPubkey(payload[45:78])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExtendedKey, err)
+	}
+	return &ExtendedKey{
+		Depth:     payload[4],
+		ChildNum:  binary.BigEndian.Uint32(payload[9:13]),
+		chainCode: append([]byte(nil), payload[13:45]...),
+		publicKey: publicKey,
+	}, nil
+}
+
+// Child 非硬化派生子公钥 CKDpub
+func (k *ExtendedKey) Child(index uint32) (*ExtendedKey, error) {
+	if index >= HardenedOffset {
+		return nil, ErrHardenedDerivation
+	}
+	data := make([]byte, 0, 37)
+	data = append(data, crypto.CompressPubkey(k.publicKey)...)
+	data = binary.BigEndian.AppendUint32(data, index)
+
+	mac := hmac.New(sha512.New, k.chainCode)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	il, ir := sum[:32], sum[32:]
+
+	curve := crypto.S256()
+	if new(big.Int).SetBytes(il).Cmp(curve.Params().N) >= 0 {
+		return nil, ErrInvalidChild
+	}
+	x, y := curve.ScalarBaseMult(il)
+	x, y = curve.Add(x, y, k.publicKey.X, k.publicKey.Y)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, ErrInvalidChild
+	}
+	return &ExtendedKey{
+		Depth:     k.Depth + 1,
+		ChildNum:  index,
+		chainCode: ir,
+		publicKey: &ecdsa.PublicKey{Curve: curve, X: x, Y: y},
+	}, nil
+}
+
+// Derive 按相对路径依次派生，例如 ParsePath("0/5") 的结果
+func (k *ExtendedKey) Derive(path []uint32) (*ExtendedKey, error) {
+	key := k
+	for _, index := range path {
+		child, err := key.Child(index)
+		if err != nil {
+			return nil, err
+		}
+		key = child
+	}
+	return key, nil
+}
+
+func (k *ExtendedKey) PublicKey() *ecdsa.PublicKey {
+	return k.publicKey
+}
+
+// PublicKeyHex 压缩公钥的十六进制编码，与 ExportAddressesByPublicKeys 入参格式一致
+func (k *ExtendedKey) PublicKeyHex() string {
+	return common.Bytes2Hex(crypto.CompressPubkey(k.publicKey))
+}
+
+// Address 派生地址为 EVM 地址，本地派生目前只支持 EVM 链
+func (k *ExtendedKey) Address() chainaddr.Address {
+	return chainaddr.Address(strings.ToLower(crypto.PubkeyToAddress(*k.publicKey).Hex()))
+}
+
+// ParsePath 解析相对派生路径，例如 "0/12"，可带前缀 "m/"，不允许硬化索引
+func ParsePath(path string) ([]uint32, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "m"), "/")
+	if path == "" {
+		return nil, nil
+	}
+	var indexes []uint32
+	for _, part := range strings.Split(path, "/") {
+		if strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") || strings.HasSuffix(part, "H") {
+			return nil, ErrHardenedDerivation
+		}
+		index, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path element %q: %w", part, err)
+		}
+		if uint32(index) >= HardenedOffset {
+			return nil, ErrHardenedDerivation
+		}
+		indexes = append(indexes, uint32(index))
+	}
+	return indexes, nil
+}
@@ -0,0 +1,65 @@
+package hdwallet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// BIP-32 test vector 1
+const (
+	vector1Xprv       = "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi"
+	vector1Hardened0  = "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw"
+	vector1Hardened01 = "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ"
+)
+
+func TestChildDerivation(t *testing.T) {
+	parent, err := ParseExtendedPublicKey(vector1Hardened0)
+	require.NoError(t, err)
+	require.Equal(t, uint8(1), parent.Depth)
+	require.Equal(t, HardenedOffset, parent.ChildNum)
+
+	expected, err := ParseExtendedPublicKey(vector1Hardened01)
+	require.NoError(t, err)
+
+	child, err := parent.Child(1)
+	require.NoError(t, err)
+	require.Equal(t, expected.PublicKeyHex(), child.PublicKeyHex())
+	require.Equal(t, expected.chainCode, child.chainCode)
+	require.Equal(t, expected.Depth, child.Depth)
+
+	derived, err := parent.Derive([]uint32{1})
+	require.NoError(t, err)
+	require.Equal(t, child.Address(), derived.Address())
+
+	_, err = parent.Child(HardenedOffset)
+	require.ErrorIs(t, err, ErrHardenedDerivation)
+}
+
+func TestParseExtendedPublicKeyErrors(t *testing.T) {
+	_, err := ParseExtendedPublicKey(vector1Xprv)
+	require.ErrorIs(t, err, ErrPrivateExtendedKey)
+
+	corrupted := vector1Hardened0[:len(vector1Hardened0)-1] + "x"
+	_, err = ParseExtendedPublicKey(corrupted)
+	require.ErrorIs(t, err, ErrInvalidExtendedKey)
+
+	_, err = ParseExtendedPublicKey("not-base58-0OIl")
+	require.ErrorIs(t, err, ErrInvalidExtendedKey)
+}
+
+func TestParsePath(t *testing.T) {
+	path, err := ParsePath("m/0/15")
+	require.NoError(t, err)
+	require.Equal(t, []uint32{0, 15}, path)
+
+	path, err = ParsePath("")
+	require.NoError(t, err)
+	require.Empty(t, path)
+
+	_, err = ParsePath("0/1'")
+	require.ErrorIs(t, err, ErrHardenedDerivation)
+
+	_, err = ParsePath("0/abc")
+	require.Error(t, err)
+}
@@ -0,0 +1,192 @@
+package hdwallet
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+)
+
+const defaultPoolSize = 100
+
+var (
+	ErrUnsupportedChain  = errors.New("local address derivation only supports evm chains")
+	ErrXpubNotRegistered = errors.New("extended public key is not registered")
+	ErrXpubConflict      = errors.New("a different extended public key is already registered")
+)
+
+// Pool 按业务方维护由扩展公钥派生的预生成地址池。
+// 派生索引保存在 hd_wallets 表中，派生和推进索引在同一个事务内完成，多实例并发补充不会重复派生
+type Pool struct {
+	db    *database.DB
+	chain string
+	size  int
+}
+
+func NewPool(db *database.DB, chain string, size int) *Pool {
+	if size <= 0 {
+		size = defaultPoolSize
+	}
+	return &Pool{db: db, chain: chain, size: size}
+}
+
+// Register 注册业务方的扩展公钥并立即补满地址池
+func (p *Pool) Register(businessId, xpub string) error {
+	codec, err := chainaddr.Lookup(p.chain)
+	if err != nil {
+		return err
+	}
+	if codec.Family() != chainaddr.FamilyEVM {
+		return ErrUnsupportedChain
+	}
+	if _, err := ParseExtendedPublicKey(xpub); err != nil {
+		return err
+	}
+	existing, err := p.db.HDWallets.QueryHDWallet(businessId, p.chain)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.Xpub != xpub {
+		return ErrXpubConflict
+	}
+	if existing == nil {
+		err := p.db.HDWallets.StoreHDWallet(&database.HDWallets{
+			GUID:        uuid.New(),
+			BusinessUid: businessId,
+			Chain:       p.chain,
+			Xpub:        xpub,
+			Timestamp:   uint64(time.Now().Unix()),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	_, err = p.Fill(businessId)
+	return err
+}
+
+// Fill 派生新地址直到未分配地址数量达到池大小，返回新派生的地址数量
+func (p *Pool) Fill(businessId string) (int, error) {
+	derived := 0
+	err := p.db.Transaction(func(tx *database.DB) error {
+		wallet, err := tx.HDWallets.LockHDWallet(businessId, p.chain)
+		if err != nil {
+			return err
+		}
+		if wallet == nil {
+			return ErrXpubNotRegistered
+		}
+		available, err := tx.Addresses.CountPoolAddresses(businessId)
+		if err != nil {
+			return err
+		}
+		need := p.size - int(available)
+		if need <= 0 {
+			return nil
+		}
+		key, err := ParseExtendedPublicKey(wallet.Xpub)
+		if err != nil {
+			return err
+		}
+		changeKey, err := key.Child(wallet.Change)
+		if err != nil {
+			return err
+		}
+
+		addresses := make([]database.Addresses, 0, need)
+		index := wallet.NextIndex
+		for len(addresses) < need {
+			if index >= HardenedOffset {
+				return fmt.Errorf("derivation index exhausted for business %s", businessId)
+			}
+			child, err := changeKey.Child(index)
+			if errors.Is(err, ErrInvalidChild) {
+				log.Warn("skip invalid derivation index", "businessId", businessId, "index", index)
+				index++
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			derivationIndex := index
+			addresses = append(addresses, database.Addresses{
+				GUID:            uuid.New(),
+				Address:         child.Address(),
+				AddressType:     0,
+				PublicKey:       child.PublicKeyHex(),
+				DerivationIndex: &derivationIndex,
+				Timestamp:       uint64(time.Now().Unix()),
+			})
+			index++
+		}
+		if err := tx.Addresses.StoreAddresses(businessId, addresses); err != nil {
+			return err
+		}
+		derived = len(addresses)
+		return tx.HDWallets.UpdateNextIndex(businessId, p.chain, index)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if derived > 0 {
+		log.Info("fill address pool success", "businessId", businessId, "derived", derived)
+	}
+	return derived, nil
+}
+
+// Allocate 为用户分配一个地址，地址池为空时先同步补充一次
+func (p *Pool) Allocate(businessId, userUid string) (*database.Addresses, error) {
+	address, err := p.allocate(businessId, userUid)
+	if err != nil || address != nil {
+		return address, err
+	}
+	if _, err := p.Fill(businessId); err != nil {
+		return nil, err
+	}
+	address, err = p.allocate(businessId, userUid)
+	if err != nil {
+		return nil, err
+	}
+	if address == nil {
+		return nil, fmt.Errorf("address pool of business %s is empty", businessId)
+	}
+	return address, nil
+}
+
+func (p *Pool) allocate(businessId, userUid string) (*database.Addresses, error) {
+	var address *database.Addresses
+	err := p.db.Transaction(func(tx *database.DB) error {
+		// 锁住扩展公钥记录，避免同一用户的并发请求分配到两个地址
+		wallet, err := tx.HDWallets.LockHDWallet(businessId, p.chain)
+		if err != nil {
+			return err
+		}
+		if wallet == nil {
+			return ErrXpubNotRegistered
+		}
+		address, err = tx.Addresses.AllocateAddress(businessId, userUid)
+		return err
+	})
+	return address, err
+}
+
+// DerivationPath 返回地址相对扩展公钥的派生路径
+func (p *Pool) DerivationPath(businessId string, address *database.Addresses) (string, error) {
+	if address.DerivationIndex == nil {
+		return "", nil
+	}
+	wallet, err := p.db.HDWallets.QueryHDWallet(businessId, p.chain)
+	if err != nil {
+		return "", err
+	}
+	if wallet == nil {
+		return "", ErrXpubNotRegistered
+	}
+	return fmt.Sprintf("m/%d/%d", wallet.Change, *address.DerivationIndex), nil
+}
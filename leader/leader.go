@@ -0,0 +1,173 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/google/uuid"
+
+	"github.com/CavnHan/multichain-sync-account/common/clock"
+	"github.com/CavnHan/multichain-sync-account/database"
+)
+
+const DefaultLeaseTTL = 15 * time.Second
+
+const (
+	RoleSync   = "sync"
+	RoleNotify = "notify"
+)
+
+var ErrLeadershipLost = errors.New("leadership lost")
+
+// LeaseName 每条链的每类任务独立选主
+func LeaseName(role string, chainName string) string {
+	return role + ":" + strings.ToLower(chainName)
+}
+
+// Elector 基于 leader_leases 表选主，每 ttl/3 续约一次。
+// 当选后调用 onElected 启动任务，失去租约后调用 onLost 且不再参与选举，由进程重启后重新作为备机加入
+type Elector struct {
+	leases    database.LeasesDB
+	name      string
+	holder    string
+	ttl       time.Duration
+	clock     clock.Clock
+	onElected func(fence database.Fence) error
+	onLost    func(err error)
+
+	mu        sync.Mutex
+	fence     *database.Fence
+	renewedAt time.Time
+	lost      bool
+
+	worker *clock.LoopFn
+}
+
+func NewElector(leases database.LeasesDB, name string, ttl time.Duration, onElected func(fence database.Fence) error, onLost func(err error)) *Elector {
+	if ttl == 0 {
+		ttl = DefaultLeaseTTL
+	}
+	return &Elector{
+		leases:    leases,
+		name:      name,
+		holder:    newHolderId(),
+		ttl:       ttl,
+		clock:     clock.SystemClock,
+		onElected: onElected,
+		onLost:    onLost,
+	}
+}
+
+// newHolderId 主机名加进程号便于排查，随机后缀保证同一进程重启后是新的持有者
+func newHolderId() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8])
+}
+
+// Start 立即尝试一次选举，单实例部署无需等待一个续约周期
+func (e *Elector) Start() error {
+	if e.worker != nil {
+		return errors.New("already started")
+	}
+	log.Info("start leader election", "lease", e.name, "holder", e.holder, "ttl", e.ttl)
+	e.tick(context.Background())
+	e.worker = clock.NewLoopFn(e.clock, e.tick, nil, e.ttl/3)
+	return nil
+}
+
+// Close 停止续约，不释放租约，调用方停止任务后再调用 Release
+func (e *Elector) Close() error {
+	if e.worker == nil {
+		return nil
+	}
+	return e.worker.Close()
+}
+
+// Release 主动让出租约，备机在下一次选举时即可接管，无需等待过期
+func (e *Elector) Release() error {
+	e.mu.Lock()
+	fence := e.fence
+	e.fence = nil
+	e.mu.Unlock()
+	if fence == nil {
+		return nil
+	}
+	log.Info("release leader lease", "lease", fence.Name, "token", fence.Token)
+	return e.leases.ReleaseLease(*fence)
+}
+
+func (e *Elector) Holder() string {
+	return e.holder
+}
+
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.fence != nil
+}
+
+func (e *Elector) tick(_ context.Context) {
+	e.mu.Lock()
+	fence, renewedAt, lost := e.fence, e.renewedAt, e.lost
+	e.mu.Unlock()
+	if lost {
+		return
+	}
+
+	now := e.clock.Now()
+	token, err := e.leases.AcquireLease(e.name, e.holder, e.ttl)
+	if fence == nil {
+		if err != nil {
+			log.Warn("acquire leader lease fail", "lease", e.name, "err", err)
+			return
+		}
+		if token == 0 {
+			log.Debug("leader lease held by another instance, stay standby", "lease", e.name)
+			return
+		}
+		elected := database.Fence{Name: e.name, Holder: e.holder, Token: token}
+		e.mu.Lock()
+		e.fence, e.renewedAt = &elected, now
+		e.mu.Unlock()
+		log.Info("elected as leader", "lease", e.name, "holder", e.holder, "token", token)
+		if err := e.onElected(elected); err != nil {
+			e.lose(fmt.Errorf("start leader tasks: %w", err))
+		}
+		return
+	}
+
+	switch {
+	case err != nil && e.clock.Since(renewedAt) < e.ttl:
+		// 偶发的续约失败可以容忍，写入仍由 fencing token 把关
+		log.Warn("renew leader lease fail", "lease", e.name, "err", err)
+	case err != nil:
+		e.lose(fmt.Errorf("%w: renew %s fail: %v", ErrLeadershipLost, e.name, err))
+	case token != fence.Token:
+		e.lose(fmt.Errorf("%w: %s taken over, token %d -> %d", ErrLeadershipLost, e.name, fence.Token, token))
+	default:
+		e.mu.Lock()
+		e.renewedAt = now
+		e.mu.Unlock()
+	}
+}
+
+// lose 只触发一次，尽力释放租约以便备机尽快接管
+func (e *Elector) lose(err error) {
+	log.Error("leader lease lost", "lease", e.name, "holder", e.holder, "err", err)
+	e.mu.Lock()
+	e.lost = true
+	e.mu.Unlock()
+	if releaseErr := e.Release(); releaseErr != nil {
+		log.Warn("release leader lease fail", "lease", e.name, "err", releaseErr)
+	}
+	e.onLost(err)
+}
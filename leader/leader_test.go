@@ -0,0 +1,177 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/common/clock"
+	"github.com/CavnHan/multichain-sync-account/database"
+)
+
+// fakeLeases 按 leader_leases 的 SQL 语义在内存中实现租约
+type fakeLeases struct {
+	mu    sync.Mutex
+	clock clock.Clock
+	lease database.LeaderLeases
+	err   error
+}
+
+func (f *fakeLeases) QueryLease(name string) (*database.LeaderLeases, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lease := f.lease
+	return &lease, nil
+}
+
+func (f *fakeLeases) AcquireLease(name string, holder string, ttl time.Duration) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return 0, f.err
+	}
+	now := f.clock.Now()
+	expired := !f.lease.ExpiresAt.After(now)
+	if f.lease.Holder != holder && !expired {
+		return 0, nil
+	}
+	if f.lease.Holder != holder || expired {
+		f.lease.Token++
+	}
+	f.lease.Name, f.lease.Holder, f.lease.ExpiresAt = name, holder, now.Add(ttl)
+	return f.lease.Token, nil
+}
+
+func (f *fakeLeases) ReleaseLease(fence database.Fence) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.lease.Holder == fence.Holder && f.lease.Token == fence.Token {
+		f.lease.ExpiresAt = f.clock.Now()
+	}
+	return nil
+}
+
+func (f *fakeLeases) CheckLease(fence database.Fence) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.lease.Holder != fence.Holder || f.lease.Token != fence.Token || !f.lease.ExpiresAt.After(f.clock.Now()) {
+		return database.ErrLeaseLost
+	}
+	return nil
+}
+
+type recorder struct {
+	elected []database.Fence
+	lost    []error
+}
+
+func newTestElector(leases *fakeLeases, clk clock.Clock, rec *recorder) *Elector {
+	elector := NewElector(leases, LeaseName(RoleSync, "Ethereum"), 15*time.Second, func(fence database.Fence) error {
+		rec.elected = append(rec.elected, fence)
+		return nil
+	}, func(err error) {
+		rec.lost = append(rec.lost, err)
+	})
+	elector.clock = clk
+	return elector
+}
+
+func TestElectorFailover(t *testing.T) {
+	clk := clock.NewDeterministicClock(time.Unix(1_700_000_000, 0))
+	leases := &fakeLeases{clock: clk}
+	var primaryRec, standbyRec recorder
+	primary := newTestElector(leases, clk, &primaryRec)
+	standby := newTestElector(leases, clk, &standbyRec)
+	ctx := context.Background()
+
+	primary.tick(ctx)
+	standby.tick(ctx)
+	require.True(t, primary.IsLeader())
+	require.False(t, standby.IsLeader())
+	require.Len(t, primaryRec.elected, 1)
+	require.Equal(t, "sync:ethereum", primaryRec.elected[0].Name)
+	require.Equal(t, uint64(1), primaryRec.elected[0].Token)
+	require.NoError(t, leases.CheckLease(primaryRec.elected[0]))
+
+	// 续约不换 token
+	clk.AdvanceTime(5 * time.Second)
+	primary.tick(ctx)
+	standby.tick(ctx)
+	require.Len(t, primaryRec.elected, 1)
+	require.Empty(t, primaryRec.lost)
+	require.False(t, standby.IsLeader())
+
+	// 主动释放后备机立即接管，旧 token 失效
+	require.NoError(t, primary.Release())
+	standby.tick(ctx)
+	require.True(t, standby.IsLeader())
+	require.Equal(t, uint64(2), standbyRec.elected[0].Token)
+	require.ErrorIs(t, leases.CheckLease(primaryRec.elected[0]), database.ErrLeaseLost)
+}
+
+func TestElectorLosesLease(t *testing.T) {
+	clk := clock.NewDeterministicClock(time.Unix(1_700_000_000, 0))
+	leases := &fakeLeases{clock: clk}
+	var primaryRec, standbyRec recorder
+	primary := newTestElector(leases, clk, &primaryRec)
+	standby := newTestElector(leases, clk, &standbyRec)
+	ctx := context.Background()
+
+	primary.tick(ctx)
+	require.True(t, primary.IsLeader())
+
+	// 主节点停顿超过 ttl，备机接管
+	clk.AdvanceTime(16 * time.Second)
+	standby.tick(ctx)
+	require.True(t, standby.IsLeader())
+
+	primary.tick(ctx)
+	require.False(t, primary.IsLeader())
+	require.Len(t, primaryRec.lost, 1)
+	require.ErrorIs(t, primaryRec.lost[0], ErrLeadershipLost)
+	// 失去租约后不再参与选举，也不会释放新主的租约
+	primary.tick(ctx)
+	require.Len(t, primaryRec.lost, 1)
+	require.NoError(t, leases.CheckLease(standbyRec.elected[0]))
+}
+
+func TestElectorRenewErrors(t *testing.T) {
+	clk := clock.NewDeterministicClock(time.Unix(1_700_000_000, 0))
+	leases := &fakeLeases{clock: clk}
+	var rec recorder
+	elector := newTestElector(leases, clk, &rec)
+	ctx := context.Background()
+
+	elector.tick(ctx)
+	require.True(t, elector.IsLeader())
+
+	// ttl 内的续约失败可以容忍
+	leases.err = errors.New("connection reset")
+	clk.AdvanceTime(5 * time.Second)
+	elector.tick(ctx)
+	require.True(t, elector.IsLeader())
+	require.Empty(t, rec.lost)
+
+	clk.AdvanceTime(10 * time.Second)
+	elector.tick(ctx)
+	require.False(t, elector.IsLeader())
+	require.Len(t, rec.lost, 1)
+	require.ErrorIs(t, rec.lost[0], ErrLeadershipLost)
+
+	// 当选后启动任务失败时让出租约
+	leases.err = nil
+	var failedRec recorder
+	failing := NewElector(leases, LeaseName(RoleSync, "Ethereum"), 15*time.Second, func(database.Fence) error {
+		return errors.New("connect chain account rpc fail")
+	}, func(err error) {
+		failedRec.lost = append(failedRec.lost, err)
+	})
+	failing.clock = clk
+	failing.tick(ctx)
+	require.False(t, failing.IsLeader())
+	require.Len(t, failedRec.lost, 1)
+}
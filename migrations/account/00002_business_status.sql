@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE business ADD COLUMN IF NOT EXISTS status SMALLINT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS business_status ON business (status);
+
+-- +migrate Down
+DROP INDEX IF EXISTS business_status;
+ALTER TABLE business DROP COLUMN IF EXISTS status;
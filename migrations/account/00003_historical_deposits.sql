@@ -0,0 +1,9 @@
+-- +migrate BusinessUp
+ALTER TABLE addresses${suffix} ADD COLUMN IF NOT EXISTS backfill_status SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE deposits${suffix} ADD COLUMN IF NOT EXISTS historical BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE balances${suffix} ADD COLUMN IF NOT EXISTS historical_balance UINT256;
+
+-- +migrate BusinessDown
+ALTER TABLE balances${suffix} DROP COLUMN IF EXISTS historical_balance;
+ALTER TABLE deposits${suffix} DROP COLUMN IF EXISTS historical;
+ALTER TABLE addresses${suffix} DROP COLUMN IF EXISTS backfill_status;
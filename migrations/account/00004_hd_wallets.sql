@@ -0,0 +1,24 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS hd_wallets (
+    guid         VARCHAR PRIMARY KEY,
+    business_uid VARCHAR NOT NULL,
+    chain        VARCHAR NOT NULL,
+    xpub         VARCHAR NOT NULL,
+    change       INTEGER NOT NULL DEFAULT 0,
+    next_index   BIGINT  NOT NULL DEFAULT 0,
+    timestamp    INTEGER NOT NULL CHECK(timestamp>0),
+    UNIQUE (business_uid, chain)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS hd_wallets;
+
+-- +migrate BusinessUp
+ALTER TABLE addresses${suffix} ADD COLUMN IF NOT EXISTS derivation_index BIGINT;
+ALTER TABLE addresses${suffix} ADD COLUMN IF NOT EXISTS user_uid VARCHAR NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS addresses${suffix}_user_uid ON addresses${suffix}(user_uid);
+
+-- +migrate BusinessDown
+DROP INDEX IF EXISTS addresses${suffix}_user_uid;
+ALTER TABLE addresses${suffix} DROP COLUMN IF EXISTS user_uid;
+ALTER TABLE addresses${suffix} DROP COLUMN IF EXISTS derivation_index;
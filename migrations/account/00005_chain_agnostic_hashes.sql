@@ -0,0 +1,19 @@
+-- +migrate BusinessUp
+UPDATE deposits${suffix} SET block_hash = '' WHERE block_hash = '0x' || repeat('0', 64);
+UPDATE deposits${suffix} SET hash = '' WHERE hash = '0x' || repeat('0', 64);
+UPDATE withdraws${suffix} SET block_hash = '' WHERE block_hash = '0x' || repeat('0', 64);
+UPDATE withdraws${suffix} SET hash = '' WHERE hash = '0x' || repeat('0', 64);
+UPDATE internals${suffix} SET block_hash = '' WHERE block_hash = '0x' || repeat('0', 64);
+UPDATE internals${suffix} SET hash = '' WHERE hash = '0x' || repeat('0', 64);
+UPDATE transactions${suffix} SET block_hash = '' WHERE block_hash = '0x' || repeat('0', 64);
+UPDATE transactions${suffix} SET hash = '' WHERE hash = '0x' || repeat('0', 64);
+
+-- +migrate BusinessDown
+UPDATE transactions${suffix} SET hash = '0x' || repeat('0', 64) WHERE hash = '';
+UPDATE transactions${suffix} SET block_hash = '0x' || repeat('0', 64) WHERE block_hash = '';
+UPDATE internals${suffix} SET hash = '0x' || repeat('0', 64) WHERE hash = '';
+UPDATE internals${suffix} SET block_hash = '0x' || repeat('0', 64) WHERE block_hash = '';
+UPDATE withdraws${suffix} SET hash = '0x' || repeat('0', 64) WHERE hash = '';
+UPDATE withdraws${suffix} SET block_hash = '0x' || repeat('0', 64) WHERE block_hash = '';
+UPDATE deposits${suffix} SET hash = '0x' || repeat('0', 64) WHERE hash = '';
+UPDATE deposits${suffix} SET block_hash = '0x' || repeat('0', 64) WHERE block_hash = '';
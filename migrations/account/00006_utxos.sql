@@ -0,0 +1,20 @@
+-- +migrate BusinessUp
+CREATE TABLE IF NOT EXISTS utxos${suffix} (
+    guid           VARCHAR PRIMARY KEY,
+    tx_hash        VARCHAR NOT NULL,
+    vout           INTEGER NOT NULL,
+    address        VARCHAR NOT NULL,
+    amount         UINT256 NOT NULL,
+    block_number   UINT256 NOT NULL,
+    status         SMALLINT NOT NULL DEFAULT 0,
+    transaction_id VARCHAR NOT NULL DEFAULT '',
+    spent_hash     VARCHAR NOT NULL DEFAULT '',
+    timestamp      INTEGER NOT NULL CHECK(timestamp>0),
+    UNIQUE (tx_hash, vout)
+);
+CREATE INDEX IF NOT EXISTS utxos${suffix}_address_status ON utxos${suffix}(address, status);
+CREATE INDEX IF NOT EXISTS utxos${suffix}_transaction_id ON utxos${suffix}(transaction_id);
+CREATE INDEX IF NOT EXISTS utxos${suffix}_spent_hash ON utxos${suffix}(spent_hash);
+
+-- +migrate BusinessDown
+DROP TABLE IF EXISTS utxos${suffix};
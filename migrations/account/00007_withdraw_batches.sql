@@ -0,0 +1,21 @@
+-- +migrate BusinessUp
+CREATE TABLE IF NOT EXISTS withdraw_batches${suffix} (
+    guid          VARCHAR PRIMARY KEY,
+    hash          VARCHAR NOT NULL DEFAULT '',
+    from_address  VARCHAR NOT NULL,
+    token_address VARCHAR NOT NULL,
+    amount        UINT256 NOT NULL,
+    fee           UINT256 NOT NULL,
+    status        SMALLINT NOT NULL DEFAULT 0,
+    tx_sign_hex   VARCHAR NOT NULL DEFAULT '',
+    timestamp     INTEGER NOT NULL CHECK(timestamp>0)
+);
+CREATE INDEX IF NOT EXISTS withdraw_batches${suffix}_hash ON withdraw_batches${suffix}(hash);
+CREATE INDEX IF NOT EXISTS withdraw_batches${suffix}_status ON withdraw_batches${suffix}(status);
+ALTER TABLE withdraws${suffix} ADD COLUMN IF NOT EXISTS batch_id VARCHAR NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS withdraws${suffix}_batch_id ON withdraws${suffix}(batch_id);
+
+-- +migrate BusinessDown
+DROP INDEX IF EXISTS withdraws${suffix}_batch_id;
+ALTER TABLE withdraws${suffix} DROP COLUMN IF EXISTS batch_id;
+DROP TABLE IF EXISTS withdraw_batches${suffix};
@@ -0,0 +1,15 @@
+-- +migrate BusinessUp
+CREATE TABLE IF NOT EXISTS nft_holdings${suffix} (
+    guid          VARCHAR PRIMARY KEY,
+    address       VARCHAR NOT NULL,
+    token_address VARCHAR NOT NULL,
+    token_id      VARCHAR NOT NULL,
+    standard      VARCHAR NOT NULL,
+    amount        UINT256 NOT NULL,
+    timestamp     INTEGER NOT NULL CHECK(timestamp>0),
+    UNIQUE (address, token_address, token_id)
+);
+CREATE INDEX IF NOT EXISTS nft_holdings${suffix}_address ON nft_holdings${suffix}(address);
+
+-- +migrate BusinessDown
+DROP TABLE IF EXISTS nft_holdings${suffix};
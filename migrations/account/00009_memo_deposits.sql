@@ -0,0 +1,21 @@
+-- +migrate BusinessUp
+CREATE TABLE IF NOT EXISTS memos${suffix} (
+    guid      VARCHAR PRIMARY KEY,
+    address   VARCHAR NOT NULL,
+    memo      VARCHAR NOT NULL,
+    user_uid  VARCHAR NOT NULL,
+    timestamp INTEGER NOT NULL CHECK(timestamp>0),
+    UNIQUE (address, memo),
+    UNIQUE (address, user_uid)
+);
+ALTER TABLE deposits${suffix} ADD COLUMN IF NOT EXISTS memo VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE deposits${suffix} ADD COLUMN IF NOT EXISTS quarantined BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS deposits${suffix}_quarantined ON deposits${suffix}(quarantined) WHERE quarantined;
+ALTER TABLE withdraws${suffix} ADD COLUMN IF NOT EXISTS memo VARCHAR NOT NULL DEFAULT '';
+
+-- +migrate BusinessDown
+ALTER TABLE withdraws${suffix} DROP COLUMN IF EXISTS memo;
+DROP INDEX IF EXISTS deposits${suffix}_quarantined;
+ALTER TABLE deposits${suffix} DROP COLUMN IF EXISTS quarantined;
+ALTER TABLE deposits${suffix} DROP COLUMN IF EXISTS memo;
+DROP TABLE IF EXISTS memos${suffix};
@@ -0,0 +1,25 @@
+-- +migrate Up
+ALTER TABLE business ADD COLUMN IF NOT EXISTS sinks VARCHAR NOT NULL DEFAULT 'webhook';
+ALTER TABLE business ADD COLUMN IF NOT EXISTS event_sequence BIGINT NOT NULL DEFAULT 0;
+
+-- +migrate Down
+ALTER TABLE business DROP COLUMN IF EXISTS event_sequence;
+ALTER TABLE business DROP COLUMN IF EXISTS sinks;
+
+-- +migrate BusinessUp
+CREATE TABLE IF NOT EXISTS events${suffix} (
+    guid      VARCHAR PRIMARY KEY,
+    sequence  BIGINT  NOT NULL UNIQUE,
+    kind      VARCHAR NOT NULL,
+    payload   TEXT    NOT NULL,
+    timestamp INTEGER NOT NULL CHECK(timestamp>0)
+);
+CREATE TABLE IF NOT EXISTS event_cursors${suffix} (
+    sink      VARCHAR PRIMARY KEY,
+    sequence  BIGINT  NOT NULL DEFAULT 0,
+    timestamp INTEGER NOT NULL CHECK(timestamp>0)
+);
+
+-- +migrate BusinessDown
+DROP TABLE IF EXISTS event_cursors${suffix};
+DROP TABLE IF EXISTS events${suffix};
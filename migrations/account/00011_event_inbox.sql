@@ -0,0 +1,11 @@
+-- +migrate BusinessUp
+ALTER TABLE events${suffix} ADD COLUMN IF NOT EXISTS acked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE events${suffix} ADD COLUMN IF NOT EXISTS visible_at INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE events${suffix} ADD COLUMN IF NOT EXISTS deliveries INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS events${suffix}_unacked ON events${suffix}(sequence) WHERE NOT acked;
+
+-- +migrate BusinessDown
+DROP INDEX IF EXISTS events${suffix}_unacked;
+ALTER TABLE events${suffix} DROP COLUMN IF EXISTS deliveries;
+ALTER TABLE events${suffix} DROP COLUMN IF EXISTS visible_at;
+ALTER TABLE events${suffix} DROP COLUMN IF EXISTS acked;
@@ -0,0 +1,11 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS leader_leases
+(
+    name       VARCHAR PRIMARY KEY,
+    holder     VARCHAR     NOT NULL,
+    token      BIGINT      NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS leader_leases;
@@ -0,0 +1,11 @@
+-- +migrate BusinessUp
+ALTER TABLE withdraws${suffix} ADD COLUMN IF NOT EXISTS nonce BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE withdraws${suffix} ADD COLUMN IF NOT EXISTS chain_id VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE internals${suffix} ADD COLUMN IF NOT EXISTS nonce BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE internals${suffix} ADD COLUMN IF NOT EXISTS chain_id VARCHAR NOT NULL DEFAULT '';
+
+-- +migrate BusinessDown
+ALTER TABLE internals${suffix} DROP COLUMN IF EXISTS chain_id;
+ALTER TABLE internals${suffix} DROP COLUMN IF EXISTS nonce;
+ALTER TABLE withdraws${suffix} DROP COLUMN IF EXISTS chain_id;
+ALTER TABLE withdraws${suffix} DROP COLUMN IF EXISTS nonce;
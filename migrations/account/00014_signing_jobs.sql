@@ -0,0 +1,19 @@
+-- +migrate BusinessUp
+CREATE TABLE IF NOT EXISTS signing_jobs${suffix} (
+    transaction_id VARCHAR PRIMARY KEY,
+    tx_type        VARCHAR  NOT NULL,
+    chain_id       VARCHAR  NOT NULL DEFAULT '',
+    job_id         VARCHAR  NOT NULL DEFAULT '',
+    signer         VARCHAR  NOT NULL DEFAULT '',
+    status         SMALLINT NOT NULL DEFAULT 0,
+    nonce          BIGINT   NOT NULL DEFAULT 0,
+    payload        TEXT     NOT NULL DEFAULT '',
+    expires_at     INTEGER  NOT NULL DEFAULT 0,
+    attempts       INTEGER  NOT NULL DEFAULT 0,
+    timestamp      INTEGER  NOT NULL CHECK(timestamp>0)
+);
+CREATE INDEX IF NOT EXISTS signing_jobs${suffix}_job_id ON signing_jobs${suffix}(job_id);
+CREATE INDEX IF NOT EXISTS signing_jobs${suffix}_pending ON signing_jobs${suffix}(expires_at) WHERE status = 0;
+
+-- +migrate BusinessDown
+DROP TABLE IF EXISTS signing_jobs${suffix};
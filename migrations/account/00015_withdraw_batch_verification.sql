@@ -0,0 +1,7 @@
+-- +migrate BusinessUp
+ALTER TABLE withdraw_batches${suffix} ADD COLUMN IF NOT EXISTS nonce BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE withdraw_batches${suffix} ADD COLUMN IF NOT EXISTS chain_id VARCHAR NOT NULL DEFAULT '';
+
+-- +migrate BusinessDown
+ALTER TABLE withdraw_batches${suffix} DROP COLUMN IF EXISTS chain_id;
+ALTER TABLE withdraw_batches${suffix} DROP COLUMN IF EXISTS nonce;
@@ -0,0 +1,7 @@
+-- +migrate BusinessUp
+ALTER TABLE deposits${suffix} ADD COLUMN IF NOT EXISTS notified BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE deposits${suffix} ADD COLUMN IF NOT EXISTS notified_confirms SMALLINT NOT NULL DEFAULT 0;
+
+-- +migrate BusinessDown
+ALTER TABLE deposits${suffix} DROP COLUMN IF EXISTS notified_confirms;
+ALTER TABLE deposits${suffix} DROP COLUMN IF EXISTS notified;
@@ -2,12 +2,20 @@ package multichain_transaction_syncs
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/log"
 
+	"github.com/CavnHan/multichain-sync-account/common/clock"
+	"github.com/CavnHan/multichain-sync-account/common/tasks"
 	"github.com/CavnHan/multichain-sync-account/config"
 	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/leader"
+	"github.com/CavnHan/multichain-sync-account/registry"
+	"github.com/CavnHan/multichain-sync-account/rpcclient"
 	"github.com/CavnHan/multichain-sync-account/worker"
 )
 
@@ -16,65 +24,205 @@ type MultiChainSync struct {
 	Deposit      *worker.Deposit
 	Withdraw     *worker.Withdraw
 	Internal     *worker.Internal
+	History      *worker.History
+	AddressPool  *worker.AddressPool
+	Registry     *registry.Registry
+	Elector      *leader.Elector
 
+	statusServer *tasks.StatusServer
+
+	accountClient *rpcclient.WalletChainAccountClient
+	closeAccount  func() error
+
+	cfg *config.Config
+	db  *database.DB
+
+	workersLock sync.Mutex
+	stopping    bool
 
 	shutdown context.CancelCauseFunc
 	stopped  atomic.Bool
 }
 
+// NewMultiChainSync 同一条链可以部署多个实例，只有选举为主的实例运行同步和发送任务，
+// 备机只维护业务方快照，主节点失去租约后进程退出并作为备机重启
 func NewMultiChainSync(ctx context.Context, cfg *config.Config, shutdown context.CancelCauseFunc) (*MultiChainSync, error) {
-	db, err := database.NewDB(ctx, cfg.MasterDB)
+	db, err := database.NewDB(ctx, cfg)
 	if err != nil {
 		log.Error("init database fail", err)
 		return nil, err
 	}
 
-	deposit, _ := worker.NewDeposit(cfg, db, shutdown)
-	withdraw, _ := worker.NewWithdraw(cfg, db, shutdown)
-	internal, _ := worker.NewInternal(cfg, db, shutdown)
+	businessRegistry, err := registry.NewRegistry(db, cfg.BusinessRefreshInterval)
+	if err != nil {
+		log.Error("init business registry fail", "err", err)
+		return nil, err
+	}
+
+	// 所有 worker 共用一个 chain-account 连接，录制时写入同一个文件
+	accountClient, closeAccount, err := worker.NewAccountClient(cfg)
+	if err != nil {
+		log.Error("new wallet account client fail", "err", err)
+		return nil, err
+	}
 
 	out := &MultiChainSync{
-		Deposit:  deposit,
-		Withdraw: withdraw,
-		Internal: internal,
-		shutdown: shutdown,
+		Registry:      businessRegistry,
+		accountClient: accountClient,
+		closeAccount:  closeAccount,
+		cfg:           cfg,
+		db:            db,
+		shutdown:      shutdown,
 	}
+	out.statusServer = tasks.NewStatusServer(fmt.Sprintf("%s:%d", cfg.MetricsServer.Host, cfg.MetricsServer.Port), out.WorkerStatus)
+	out.Elector = leader.NewElector(db.Leases, leader.LeaseName(leader.RoleSync, cfg.ChainNode.ChainName), cfg.LeaderLeaseTTL, out.startWorkers, func(err error) {
+		shutdown(err)
+	})
 	return out, nil
 }
 
-func (mcs *MultiChainSync) Start(ctx context.Context) error {
-	err := mcs.Deposit.Start()
+// startWorkers 当选后创建任务，同步起点在此时从数据库读取，所有写入都带 fencing 校验
+func (mcs *MultiChainSync) startWorkers(fence database.Fence) error {
+	mcs.workersLock.Lock()
+	defer mcs.workersLock.Unlock()
+	if mcs.stopping {
+		return nil
+	}
+	cfg, db, shutdown := mcs.cfg, mcs.db.WithFence(fence), mcs.shutdown
+
+	clk := clock.SystemClock
+
+	deposit, err := worker.NewDeposit(cfg, db, mcs.Registry, mcs.accountClient, clk, shutdown)
 	if err != nil {
+		log.Error("new deposit fail", "err", err)
 		return err
 	}
-	err = mcs.Withdraw.Start()
+	withdraw, err := worker.NewWithdraw(cfg, db, mcs.Registry, mcs.accountClient, clk, shutdown)
 	if err != nil {
+		log.Error("new withdraw fail", "err", err)
 		return err
 	}
-	err = mcs.Internal.Start()
+	internal, err := worker.NewInternal(cfg, db, mcs.Registry, mcs.accountClient, clk, shutdown)
 	if err != nil {
+		log.Error("new internal fail", "err", err)
 		return err
 	}
-	return nil
-}
+	history, err := worker.NewHistory(cfg, db, mcs.Registry, mcs.accountClient, clk, shutdown)
+	if err != nil {
+		log.Error("new history fail", "err", err)
+		return err
+	}
+	addressPool, _ := worker.NewAddressPool(cfg, db, mcs.Registry, clk, shutdown)
 
+	mcs.Deposit = deposit
+	mcs.Withdraw = withdraw
+	mcs.Internal = internal
+	mcs.History = history
+	mcs.AddressPool = addressPool
 
-func (mcs *MultiChainSync) Stop(ctx context.Context) error {
-	err := mcs.Deposit.Close()
+	err = mcs.Deposit.Start()
 	if err != nil {
 		return err
 	}
-	err = mcs.Withdraw.Close()
+	err = mcs.Withdraw.Start()
 	if err != nil {
 		return err
 	}
-	err = mcs.Internal.Close()
+	err = mcs.Internal.Start()
+	if err != nil {
+		return err
+	}
+	err = mcs.History.Start()
+	if err != nil {
+		return err
+	}
+	err = mcs.AddressPool.Start()
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// WorkerStatus 返回可重启任务的状态，备机没有运行中的任务
+func (mcs *MultiChainSync) WorkerStatus() []tasks.WorkerStatus {
+	mcs.workersLock.Lock()
+	defer mcs.workersLock.Unlock()
+	statuses := []tasks.WorkerStatus{}
+	if mcs.Withdraw != nil {
+		statuses = append(statuses, mcs.Withdraw.Status()...)
+	}
+	if mcs.Internal != nil {
+		statuses = append(statuses, mcs.Internal.Status()...)
+	}
+	return statuses
+}
+
+func (mcs *MultiChainSync) Start(ctx context.Context) error {
+	err := mcs.Registry.Start()
+	if err != nil {
+		return err
+	}
+	// 状态接口只用于观测，端口被占用时不影响同步
+	if err := mcs.statusServer.Start(); err != nil {
+		log.Error("start worker status server fail", "err", err)
+		mcs.statusServer = nil
+	}
+	return mcs.Elector.Start()
+}
+
+// Stop 先停止续约和任务，再释放租约，备机接管时不会与本实例的任务重叠
+func (mcs *MultiChainSync) Stop(ctx context.Context) error {
+	var result error
+	if err := mcs.Elector.Close(); err != nil {
+		result = errors.Join(result, fmt.Errorf("failed to close leader elector: %w", err))
+	}
+	mcs.workersLock.Lock()
+	mcs.stopping = true
+	mcs.workersLock.Unlock()
+
+	if mcs.Deposit != nil {
+		if err := mcs.Deposit.Close(); err != nil {
+			result = errors.Join(result, err)
+		}
+	}
+	if mcs.Withdraw != nil {
+		if err := mcs.Withdraw.Close(); err != nil {
+			result = errors.Join(result, err)
+		}
+	}
+	if mcs.Internal != nil {
+		if err := mcs.Internal.Close(); err != nil {
+			result = errors.Join(result, err)
+		}
+	}
+	if mcs.History != nil {
+		if err := mcs.History.Close(); err != nil {
+			result = errors.Join(result, err)
+		}
+	}
+	if mcs.AddressPool != nil {
+		if err := mcs.AddressPool.Close(); err != nil {
+			result = errors.Join(result, err)
+		}
+	}
+	if err := mcs.closeAccount(); err != nil {
+		result = errors.Join(result, fmt.Errorf("failed to close chain account client: %w", err))
+	}
+	if err := mcs.Elector.Release(); err != nil {
+		result = errors.Join(result, fmt.Errorf("failed to release leader lease: %w", err))
+	}
+	if err := mcs.Registry.Close(); err != nil {
+		result = errors.Join(result, fmt.Errorf("failed to close business registry: %w", err))
+	}
+	if mcs.statusServer != nil {
+		if err := mcs.statusServer.Close(ctx); err != nil {
+			result = errors.Join(result, fmt.Errorf("failed to close worker status server: %w", err))
+		}
+	}
+	mcs.stopped.Store(true)
+	return result
+}
+
 func (mcs *MultiChainSync) Stopped() bool {
 	return mcs.stopped.Load()
 }
@@ -0,0 +1,209 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	brokerSubjectPrefix = "wallet.events."
+	brokerDialTimeout   = 5 * time.Second
+	brokerWriteTimeout  = 10 * time.Second
+)
+
+// Broker 消息队列的发布接口，Publish 返回时消息已被服务端接收
+type Broker interface {
+	Publish(ctx context.Context, subject string, messages [][]byte) error
+	Close() error
+}
+
+// BrokerSink 每个业务方一个 subject，每个事件一条消息
+type BrokerSink struct {
+	broker Broker
+}
+
+func NewBrokerSink(broker Broker) *BrokerSink {
+	return &BrokerSink{broker: broker}
+}
+
+func (s *BrokerSink) Name() string {
+	return SinkBroker
+}
+
+func BrokerSubject(businessId string) string {
+	return brokerSubjectPrefix + businessId
+}
+
+func (s *BrokerSink) Publish(ctx context.Context, businessId string, events []Event) error {
+	messages := make([][]byte, 0, len(events))
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, data)
+	}
+	return s.broker.Publish(ctx, BrokerSubject(businessId), messages)
+}
+
+// NewBroker 按 URL scheme 创建消息队列客户端，memory:// 为进程内队列
+func NewBroker(brokerUrl string) (Broker, error) {
+	parsed, err := url.Parse(brokerUrl)
+	if err != nil {
+		return nil, err
+	}
+	switch parsed.Scheme {
+	case "nats":
+		return NewNatsBroker(parsed.Host), nil
+	case "memory":
+		return NewMemoryBroker(), nil
+	}
+	return nil, fmt.Errorf("unsupported broker url %q", brokerUrl)
+}
+
+// MemoryBroker 进程内消息队列，用于测试和单机调试
+type MemoryBroker struct {
+	mu       sync.Mutex
+	subjects map[string][][]byte
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subjects: make(map[string][][]byte)}
+}
+
+func (b *MemoryBroker) Publish(_ context.Context, subject string, messages [][]byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subjects[subject] = append(b.subjects[subject], messages...)
+	return nil
+}
+
+func (b *MemoryBroker) Messages(subject string) [][]byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([][]byte(nil), b.subjects[subject]...)
+}
+
+func (b *MemoryBroker) Close() error {
+	return nil
+}
+
+// NatsBroker NATS 文本协议的最小发布客户端，批量 PUB 后以 PING/PONG 确认服务端已处理，
+// 连接出错时丢弃连接，下一次发布重新建立
+type NatsBroker struct {
+	address string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func NewNatsBroker(address string) *NatsBroker {
+	return &NatsBroker{address: address}
+}
+
+func (b *NatsBroker) Publish(ctx context.Context, subject string, messages [][]byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.connect(ctx); err != nil {
+		return err
+	}
+	if err := b.publish(ctx, subject, messages); err != nil {
+		b.reset()
+		return err
+	}
+	return nil
+}
+
+func (b *NatsBroker) publish(ctx context.Context, subject string, messages [][]byte) error {
+	deadline := time.Now().Add(brokerWriteTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := b.conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(b.conn)
+	for _, message := range messages {
+		fmt.Fprintf(writer, "PUB %s %d\r\n", subject, len(message))
+		writer.Write(message)
+		writer.WriteString("\r\n")
+	}
+	writer.WriteString("PING\r\n")
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	return b.awaitPong()
+}
+
+func (b *NatsBroker) connect(ctx context.Context) error {
+	if b.conn != nil {
+		return nil
+	}
+	dialer := net.Dialer{Timeout: brokerDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", b.address)
+	if err != nil {
+		return err
+	}
+	b.conn, b.reader = conn, bufio.NewReader(conn)
+	if err := conn.SetDeadline(time.Now().Add(brokerDialTimeout)); err != nil {
+		b.reset()
+		return err
+	}
+	line, err := b.reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO ") {
+		b.reset()
+		return fmt.Errorf("nats handshake with %s fail: %q %v", b.address, line, err)
+	}
+	if _, err := conn.Write([]byte("CONNECT {\"verbose\":false,\"pedantic\":false,\"name\":\"multichain-sync-account\"}\r\nPING\r\n")); err != nil {
+		b.reset()
+		return err
+	}
+	if err := b.awaitPong(); err != nil {
+		b.reset()
+		return err
+	}
+	return nil
+}
+
+func (b *NatsBroker) awaitPong() error {
+	for {
+		line, err := b.reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := b.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New("nats: " + strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (b *NatsBroker) reset() {
+	if b.conn != nil {
+		b.conn.Close()
+	}
+	b.conn, b.reader = nil, nil
+}
+
+func (b *NatsBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reset()
+	return nil
+}
@@ -36,7 +36,7 @@ func NewNotifierClient(baseUrl string) (*NotifyClient, error) {
 func (nc *NotifyClient) BusinessNotify(notifyData *NotifyRequest) (bool, error) {
 	res, err := nc.client.R().
 		SetHeader("Content-Type", "application/json").
-		SetBody(&notifyData).
+		SetBody(notifyData).
 		SetResult(&NotifyResponse{}).Post("dapplink/notify")
 	if err != nil {
 		log.Error("get transaction fee fail", "err", err)
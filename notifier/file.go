@@ -0,0 +1,48 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// FileSink 按业务方追加写入 <dir>/<businessId>.jsonl 用于审计，每行一个事件；
+// 重试可能写入重复事件，按 sequence 去重
+type FileSink struct {
+	dir string
+}
+
+func NewFileSink(dir string) (*FileSink, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &FileSink{dir: dir}, nil
+}
+
+func (s *FileSink) Name() string {
+	return SinkFile
+}
+
+func (s *FileSink) Path(businessId string) string {
+	return filepath.Join(s.dir, businessId+".jsonl")
+}
+
+func (s *FileSink) Publish(_ context.Context, businessId string, events []Event) error {
+	file, err := os.OpenFile(s.Path(businessId), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -4,114 +4,197 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
 
+	"github.com/CavnHan/multichain-sync-account/common/clock"
 	"github.com/CavnHan/multichain-sync-account/common/retry"
 	"github.com/CavnHan/multichain-sync-account/common/tasks"
 	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/leader"
+	"github.com/CavnHan/multichain-sync-account/registry"
 )
 
+const maxEventsPerPublish = 500
+
 type Notifier struct {
 	db             *database.DB
-	businessIds    []string
-	notifyClient   map[string]*NotifyClient
+	registry       *registry.Registry
+	elector        *leader.Elector
+	sinkConfig     SinkConfig
+	broker         Broker
 	resourceCtx    context.Context
 	resourceCancel context.CancelFunc
-	tasks          tasks.Group
-	ticker         *time.Ticker
+	supervisor     *tasks.Supervisor
+	statusServer   *tasks.StatusServer
+	ticker         clock.Ticker
+
+	sinksLock sync.RWMutex
+	sinks     map[string][]Sink
 
 	shutdown context.CancelCauseFunc
 	stopped  atomic.Bool
 }
 
-func NewNotifier(db *database.DB, shutdown context.CancelCauseFunc) (*Notifier, error) {
-	businessList, err := db.Business.QueryBusinessList()
-	if err != nil {
-		log.Error("query business list fail", "err", err)
-		return nil, err
-	}
+type Config struct {
+	Sinks           SinkConfig
+	RefreshInterval time.Duration
+	// LeaseName/LeaseTTL 选主租约，同一条链可以部署多个通知实例，只有主实例投递事件
+	LeaseName  string
+	LeaseTTL   time.Duration
+	Supervisor tasks.SupervisorConfig
+	// StatusAddr 为空时不暴露 worker 状态
+	StatusAddr string
+	// Clock 驱动投递周期和 worker 重启退避，为空时使用系统时钟
+	Clock clock.Clock
+}
 
-	var businessIds []string
-	var notifyClient map[string]*NotifyClient
-	for _, business := range businessList {
-		businessIds = append(businessIds, business.BusinessUid)
-		client, err := NewNotifierClient(business.NotifyUrl)
+func NewNotifier(db *database.DB, cfg Config, shutdown context.CancelCauseFunc) (*Notifier, error) {
+	sinkConfig := cfg.Sinks
+	var broker Broker
+	if sinkConfig.BrokerUrl != "" {
+		var err error
+		broker, err = NewBroker(sinkConfig.BrokerUrl)
 		if err != nil {
-			log.Error("new notify client fail", "err", err)
+			log.Error("new event broker fail", "err", err)
 			return nil, err
 		}
-		notifyClient[business.BusinessUid] = client
 	}
 
+	reg, err := registry.NewRegistry(db, cfg.RefreshInterval)
+	if err != nil {
+		log.Error("new business registry fail", "err", err)
+		return nil, err
+	}
+
+	if cfg.Clock == nil {
+		cfg.Clock = clock.SystemClock
+	}
+	if cfg.Supervisor.Clock == nil {
+		cfg.Supervisor.Clock = cfg.Clock
+	}
 	resCtx, resCancel := context.WithCancel(context.Background())
-	return &Notifier{
+	nf := &Notifier{
 		db:             db,
-		notifyClient:   notifyClient,
-		businessIds:    businessIds,
+		registry:       reg,
+		sinkConfig:     sinkConfig,
+		broker:         broker,
+		sinks:          make(map[string][]Sink),
 		resourceCtx:    resCtx,
 		resourceCancel: resCancel,
-		tasks: tasks.Group{HandleCrit: func(err error) {
-			shutdown(fmt.Errorf("critical error in internals: %w", err))
-		}},
-		ticker: time.NewTicker(time.Second * 5),
-	}, nil
+		supervisor: tasks.NewSupervisor(cfg.Supervisor, func(err error) {
+			shutdown(fmt.Errorf("critical error in notifier: %w", err))
+		}),
+		ticker: cfg.Clock.NewTicker(time.Second * 5),
+	}
+	if cfg.StatusAddr != "" {
+		nf.statusServer = tasks.NewStatusServer(cfg.StatusAddr, nf.supervisor.Status)
+	}
+	nf.elector = leader.NewElector(db.Leases, cfg.LeaseName, cfg.LeaseTTL, nf.startDelivery, func(err error) {
+		shutdown(err)
+	})
+	reg.Subscribe(nf.onBusinessChange)
+	return nf, nil
 }
 
-func (nf *Notifier) Start(ctx context.Context) error {
-	log.Info("start internals......")
-	nf.tasks.Go(func() error {
-		for {
-			select {
-			case <-nf.ticker.C:
-				var txn []Transaction
-				for _, businessId := range nf.businessIds {
-					log.Info("txn and businessId", "txn", txn, "businessId", businessId)
-
-					needNotifyDeposits, err := nf.db.Deposits.QueryNotifyDeposits(businessId)
-					if err != nil {
-						log.Error("Query notify deposits fail", "err", err)
-						return err
-					}
-
-					needNotifyWithdraws, err := nf.db.Withdraws.QueryNotifyWithdraws(businessId)
-					if err != nil {
-						log.Error("Query notify deposits fail", "err", err)
-						return err
-					}
+// onBusinessChange 根据业务方变化增删投递方式，NotifyUrl 或 Sinks 变化时重建
+func (nf *Notifier) onBusinessChange(change registry.Change) {
+	nf.sinksLock.Lock()
+	defer nf.sinksLock.Unlock()
+	for _, business := range append(change.Added, change.Updated...) {
+		nf.sinks[business.BusinessUid] = nf.newSinks(business)
+	}
+	for _, business := range change.Removed {
+		delete(nf.sinks, business.BusinessUid)
+	}
+}
 
-					needNotifyInternals, err := nf.db.Internals.QueryNotifyInternal(businessId)
-					if err != nil {
-						log.Error("Query notify deposits fail", "err", err)
-						return err
-					}
-					notifyRequest, err := nf.BuildNotifyTransaction(needNotifyDeposits, needNotifyWithdraws, needNotifyInternals)
+// newSinks 无法创建的投递方式跳过，其事件保留在发件箱中，配置修复后从游标处继续投递
+func (nf *Notifier) newSinks(business database.Business) []Sink {
+	names, err := ParseSinks(strings.Split(business.Sinks, ","))
+	if err != nil {
+		log.Error("parse business sinks fail", "businessId", business.BusinessUid, "err", err)
+		return nil
+	}
+	var sinks []Sink
+	for _, name := range names {
+		switch name {
+		case SinkWebhook:
+			sink, err := NewWebhookSink(business.NotifyUrl)
+			if err != nil {
+				log.Error("new webhook sink fail", "businessId", business.BusinessUid, "err", err)
+				continue
+			}
+			sinks = append(sinks, sink)
+		case SinkFile:
+			if nf.sinkConfig.FileDir == "" {
+				log.Error("event file dir is not configured, skip file sink", "businessId", business.BusinessUid)
+				continue
+			}
+			sink, err := NewFileSink(nf.sinkConfig.FileDir)
+			if err != nil {
+				log.Error("new file sink fail", "businessId", business.BusinessUid, "err", err)
+				continue
+			}
+			sinks = append(sinks, sink)
+		case SinkBroker:
+			if nf.broker == nil {
+				log.Error("event broker is not configured, skip broker sink", "businessId", business.BusinessUid)
+				continue
+			}
+			sinks = append(sinks, NewBrokerSink(nf.broker))
+		case SinkStream, SinkInbox:
+			// 订阅方通过 AckSubscription/AckEvents 确认
+		}
+	}
+	return sinks
+}
 
-					// BeforeRequest
-					err = nf.BeforeAfterNotify(businessId, true, false, needNotifyDeposits, needNotifyWithdraws, needNotifyInternals)
-					if err != nil {
-						log.Error("Before notify update status  fail", "err", err)
-						return err
-					}
+func (nf *Notifier) businessSinks(businessId string) []Sink {
+	nf.sinksLock.RLock()
+	defer nf.sinksLock.RUnlock()
+	return nf.sinks[businessId]
+}
 
-					notify, err := nf.notifyClient[businessId].BusinessNotify(notifyRequest)
-					if err != nil {
-						log.Error("notify business platform fail", "err")
-						return err
-					}
+func (nf *Notifier) Start(ctx context.Context) error {
+	log.Info("start notify......")
+	if err := nf.registry.Start(); err != nil {
+		return fmt.Errorf("failed to start business registry: %w", err)
+	}
+	if nf.statusServer != nil {
+		// 状态接口只用于观测，端口被占用时不影响投递
+		if err := nf.statusServer.Start(); err != nil {
+			log.Error("start worker status server fail", "err", err)
+			nf.statusServer = nil
+		}
+	}
+	return nf.elector.Start()
+}
 
-					// AfterRequest
-					err = nf.BeforeAfterNotify(businessId, true, notify, needNotifyDeposits, needNotifyWithdraws, needNotifyInternals)
-					if err != nil {
-						log.Error("After notify update status fail", "err", err)
+// startDelivery 当选后开始写发件箱和投递，发件箱和游标的写入都带 fencing 校验
+func (nf *Notifier) startDelivery(fence database.Fence) error {
+	nf.db = nf.db.WithFence(fence)
+	nf.supervisor.Go("notifier", func(ctx context.Context) error {
+		for {
+			select {
+			case <-nf.ticker.Ch():
+				for _, businessId := range nf.registry.BusinessIds() {
+					if err := nf.emitEvents(businessId); err != nil {
+						log.Error("emit business events fail", "businessId", businessId, "err", err)
+						// 失去租约无法通过重启恢复
+						if errors.Is(err, database.ErrLeaseLost) {
+							return tasks.Fatal(err)
+						}
 						return err
 					}
-
+					nf.deliverEvents(businessId)
 				}
-			case <-nf.resourceCtx.Done():
-				log.Info("stop internals in worker")
+			case <-ctx.Done():
+				log.Info("stop notifier in worker")
 				return nil
 			}
 		}
@@ -121,75 +204,159 @@ func (nf *Notifier) Start(ctx context.Context) error {
 
 func (nf *Notifier) Stop(ctx context.Context) error {
 	var result error
+	if err := nf.elector.Close(); err != nil {
+		result = errors.Join(result, fmt.Errorf("failed to close leader elector: %w", err))
+	}
 	nf.resourceCancel()
 	nf.ticker.Stop()
-	if err := nf.tasks.Wait(); err != nil {
-		result = errors.Join(result, fmt.Errorf("failed to await notify %w"), err)
+	if err := nf.registry.Close(); err != nil {
+		result = errors.Join(result, fmt.Errorf("failed to close business registry: %w", err))
+	}
+	if err := nf.supervisor.Close(); err != nil {
+		result = errors.Join(result, fmt.Errorf("failed to await notify %w", err))
 		return result
 	}
+	if nf.statusServer != nil {
+		if err := nf.statusServer.Close(ctx); err != nil {
+			result = errors.Join(result, fmt.Errorf("failed to close worker status server: %w", err))
+		}
+	}
+	if err := nf.elector.Release(); err != nil {
+		result = errors.Join(result, fmt.Errorf("failed to release leader lease: %w", err))
+	}
+	if nf.broker != nil {
+		if err := nf.broker.Close(); err != nil {
+			result = errors.Join(result, fmt.Errorf("failed to close event broker: %w", err))
+		}
+	}
+	nf.stopped.Store(true)
 	log.Info("stop notify success")
-	return nil
+	return result
 }
 
 func (nf *Notifier) Stopped() bool {
 	return nf.stopped.Load()
 }
 
-func (nf *Notifier) BeforeAfterNotify(businessId string, isBefore bool, notifySuccess bool, deposits []database.Deposits, withdraws []database.Withdraws, internals []database.Internals) error {
-	var depositsNotifyStatus uint8
-	var withdrawNotifyStatus uint8
-	var internalNotifyStatus uint8
-	if isBefore {
-		depositsNotifyStatus = 2
-		withdrawNotifyStatus = 4
-		internalNotifyStatus = 4
-	} else {
-		if notifySuccess {
-			depositsNotifyStatus = 3
-			withdrawNotifyStatus = 5
-			internalNotifyStatus = 5
-		} else {
-			depositsNotifyStatus = 1
-			withdrawNotifyStatus = 3
-			internalNotifyStatus = 3
+// emitEvents 把待通知的交易写入发件箱并在同一事务内更新通知状态，之后的投递只依赖发件箱。
+// 写入发件箱即视为已通知，交易表的状态不等待任何投递方式确认
+func (nf *Notifier) emitEvents(businessId string) error {
+	needNotifyDeposits, err := nf.db.Deposits.QueryNotifyDeposits(businessId)
+	if err != nil {
+		log.Error("Query notify deposits fail", "err", err)
+		return err
+	}
+
+	needNotifyWithdraws, err := nf.db.Withdraws.QueryNotifyWithdraws(businessId)
+	if err != nil {
+		log.Error("Query notify withdraws fail", "err", err)
+		return err
+	}
+
+	needNotifyInternals, err := nf.db.Internals.QueryNotifyInternal(businessId)
+	if err != nil {
+		log.Error("Query notify internals fail", "err", err)
+		return err
+	}
+	notifyRequest, err := nf.BuildNotifyTransaction(needNotifyDeposits, needNotifyWithdraws, needNotifyInternals)
+	if err != nil {
+		return err
+	}
+	if len(notifyRequest.Txn) == 0 {
+		return nil
+	}
+	events := make([]database.Events, 0, len(notifyRequest.Txn))
+	for _, txn := range notifyRequest.Txn {
+		event, err := NewTransactionEvent(txn)
+		if err != nil {
+			return err
 		}
+		events = append(events, event)
 	}
-	// 过滤状态为 0 的交易
-	var updateStutusDepositTxn []database.Deposits
-	for _, deposit := range deposits {
+
+	// 已到账的充值更新为已通知，确认中的充值记录本次通知的确认数，确认数不变时不再重复通知
+	var updateStutusDepositTxn, confirmingDeposits []database.Deposits
+	for _, deposit := range needNotifyDeposits {
 		if deposit.Status != 0 {
 			updateStutusDepositTxn = append(updateStutusDepositTxn, deposit)
+		} else {
+			confirmingDeposits = append(confirmingDeposits, deposit)
 		}
 	}
 	retryStrategy := &retry.ExponentialStrategy{Min: 1000, Max: 20_000, MaxJitter: 250}
-	if _, err := retry.Do[interface{}](nf.resourceCtx, 10, retryStrategy, func() (interface{}, error) {
+	_, err = retry.Do[interface{}](nf.resourceCtx, 10, retryStrategy, func() (interface{}, error) {
 		if err := nf.db.Transaction(func(tx *database.DB) error {
-			if len(deposits) > 0 {
-				if err := tx.Deposits.UpdateDepositsNotifyStatus(businessId, depositsNotifyStatus, updateStutusDepositTxn); err != nil {
+			if err := tx.Events.StoreEvents(businessId, events); err != nil {
+				return err
+			}
+			if len(updateStutusDepositTxn) > 0 {
+				if err := tx.Deposits.UpdateDepositsNotifyStatus(businessId, 3, updateStutusDepositTxn); err != nil {
 					return err
 				}
 			}
-			if len(withdraws) > 0 {
-				if err := tx.Withdraws.UpdateWithdrawStatus(businessId, withdrawNotifyStatus, withdraws); err != nil {
+			if len(confirmingDeposits) > 0 {
+				if err := tx.Deposits.UpdateDepositsNotifiedConfirms(businessId, confirmingDeposits); err != nil {
 					return err
 				}
 			}
-
-			if len(internals) > 0 {
-				if err := tx.Internals.UpdateInternalstatus(businessId, internalNotifyStatus, internals); err != nil {
+			if len(needNotifyWithdraws) > 0 {
+				if err := tx.Withdraws.UpdateWithdrawStatus(businessId, 5, needNotifyWithdraws); err != nil {
+					return err
+				}
+			}
+			if len(needNotifyInternals) > 0 {
+				if err := tx.Internals.UpdateInternalstatus(businessId, 5, needNotifyInternals); err != nil {
 					return err
 				}
 			}
 			return nil
 		}); err != nil {
-			log.Error("unable to persist batch", "err", err)
+			log.Error("unable to persist events", "err", err)
 			return nil, err
 		}
 		return nil, nil
-	}); err != nil {
-		return err
+	})
+	return err
+}
+
+// deliverEvents 各投递方式按自己的游标消费发件箱，单个方式失败不影响其他方式，下一轮重试
+func (nf *Notifier) deliverEvents(businessId string) {
+	for _, sink := range nf.businessSinks(businessId) {
+		cursor, err := nf.db.Events.QueryEventCursor(businessId, sink.Name())
+		if err != nil {
+			log.Error("query event cursor fail", "businessId", businessId, "sink", sink.Name(), "err", err)
+			continue
+		}
+		records, err := nf.db.Events.QueryEventsAfter(businessId, cursor, maxEventsPerPublish)
+		if err != nil {
+			log.Error("query events fail", "businessId", businessId, "sink", sink.Name(), "err", err)
+			continue
+		}
+		if len(records) == 0 {
+			continue
+		}
+		events := make([]Event, 0, len(records))
+		for _, record := range records {
+			event, err := DecodeEvent(businessId, record)
+			if err != nil {
+				log.Error("decode event fail, skip", "businessId", businessId, "sequence", record.Sequence, "err", err)
+				continue
+			}
+			events = append(events, event)
+		}
+		if err := sink.Publish(nf.resourceCtx, businessId, events); err != nil {
+			log.Error("publish events fail", "businessId", businessId, "sink", sink.Name(), "err", err)
+			continue
+		}
+		last := records[len(records)-1].Sequence
+		if err := nf.db.Transaction(func(tx *database.DB) error {
+			return tx.Events.UpdateEventCursor(businessId, sink.Name(), last)
+		}); err != nil {
+			log.Error("update event cursor fail", "businessId", businessId, "sink", sink.Name(), "err", err)
+			continue
+		}
+		log.Info("publish events success", "businessId", businessId, "sink", sink.Name(), "events", len(events), "sequence", last)
 	}
-	return nil
 }
 
 func (nf *Notifier) BuildNotifyTransaction(deposits []database.Deposits, withdraws []database.Withdraws, internals []database.Internals) (*NotifyRequest, error) {
@@ -208,6 +375,8 @@ func (nf *Notifier) BuildNotifyTransaction(deposits []database.Deposits, withdra
 			TokenAddress: deposit.TokenAddress.String(),
 			TokenId:      deposit.TokenId,
 			TokenMeta:    deposit.TokenMeta,
+			Memo:         deposit.Memo,
+			Historical:   deposit.Historical,
 		}
 		notifyTransactions = append(notifyTransactions, txItem)
 	}
@@ -226,6 +395,7 @@ func (nf *Notifier) BuildNotifyTransaction(deposits []database.Deposits, withdra
 			TokenAddress: withdraw.TokenAddress.String(),
 			TokenId:      withdraw.TokenId,
 			TokenMeta:    withdraw.TokenMeta,
+			Memo:         withdraw.Memo,
 		}
 		notifyTransactions = append(notifyTransactions, txItem)
 	}
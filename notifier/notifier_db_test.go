@@ -0,0 +1,119 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/config"
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/database/dynamic"
+)
+
+// openTestDB 连接 WALLET_TEST_DB_* 指定的 postgres 并执行迁移，未配置时跳过
+func openTestDB(t *testing.T) *database.DB {
+	host := os.Getenv("WALLET_TEST_DB_HOST")
+	if host == "" {
+		t.Skip("WALLET_TEST_DB_HOST not set")
+	}
+	port, err := strconv.Atoi(os.Getenv("WALLET_TEST_DB_PORT"))
+	if err != nil {
+		port = 5432
+	}
+	db, err := database.NewDB(context.Background(), &config.Config{
+		MasterDB: config.DBConfig{
+			Host:     host,
+			Port:     port,
+			Name:     os.Getenv("WALLET_TEST_DB_NAME"),
+			User:     os.Getenv("WALLET_TEST_DB_USER"),
+			Password: os.Getenv("WALLET_TEST_DB_PASSWORD"),
+		},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	require.NoError(t, db.NewMigrator("../migrations").Up(0))
+	return db
+}
+
+func TestEmitEvents(t *testing.T) {
+	db := openTestDB(t)
+	businessId := fmt.Sprintf("emit%d", time.Now().UnixNano())
+	dynamic.CreateTableFromTemplate(businessId, db)
+
+	now := uint64(time.Now().Unix())
+	withdrawId, internalId := uuid.New(), uuid.New()
+	require.NoError(t, db.Deposits.StoreDeposits(businessId, []database.Deposits{{
+		GUID:        uuid.New(),
+		BlockNumber: big.NewInt(100),
+		Hash:        chainaddr.Hash("0xdeposit"),
+		Fee:         big.NewInt(1),
+		Amount:      big.NewInt(10),
+		Status:      1,
+		Timestamp:   now,
+	}, {
+		GUID:        uuid.New(),
+		BlockNumber: big.NewInt(100),
+		Hash:        chainaddr.Hash("0xconfirming"),
+		Fee:         big.NewInt(1),
+		Amount:      big.NewInt(10),
+		Status:      0,
+		Timestamp:   now,
+	}}, 2))
+	require.NoError(t, db.Withdraws.StoreWithdraw(businessId, &database.Withdraws{
+		GUID:        withdrawId,
+		BlockNumber: big.NewInt(101),
+		Hash:        chainaddr.Hash("0xwithdraw"),
+		Fee:         big.NewInt(1),
+		Amount:      big.NewInt(20),
+		Status:      3,
+		Timestamp:   now,
+	}))
+	require.NoError(t, db.Internals.StoreInternal(businessId, &database.Internals{
+		GUID:        internalId,
+		BlockNumber: big.NewInt(102),
+		Hash:        chainaddr.Hash("0xinternal"),
+		Fee:         big.NewInt(1),
+		Amount:      big.NewInt(30),
+		Status:      3,
+		TxType:      "collection",
+		Timestamp:   now,
+	}))
+
+	nf := &Notifier{db: db, resourceCtx: context.Background()}
+	require.NoError(t, nf.emitEvents(businessId))
+
+	events, err := db.Events.QueryEventsAfter(businessId, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+
+	deposits, err := db.Deposits.QueryNotifyDeposits(businessId)
+	require.NoError(t, err)
+	require.Empty(t, deposits)
+	withdraw, err := db.Withdraws.QueryWithdrawsByHash(businessId, withdrawId.String())
+	require.NoError(t, err)
+	require.Equal(t, uint8(5), withdraw.Status)
+	internal, err := db.Internals.QueryInternalsByHash(businessId, internalId.String())
+	require.NoError(t, err)
+	require.Equal(t, uint8(5), internal.Status)
+
+	// 状态和确认数都没有变化，再次执行不会重复写入事件
+	require.NoError(t, nf.emitEvents(businessId))
+	events, err = db.Events.QueryEventsAfter(businessId, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+
+	// 确认数变化后再通知一次
+	require.NoError(t, db.Deposits.UpdateDepositsComfirms(businessId, 102, 10))
+	require.NoError(t, nf.emitEvents(businessId))
+	events, err = db.Events.QueryEventsAfter(businessId, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 5)
+}
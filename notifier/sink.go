@@ -0,0 +1,143 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/CavnHan/multichain-sync-account/database"
+)
+
+const (
+	SinkWebhook = "webhook"
+	SinkFile    = "file"
+	SinkBroker  = "broker"
+	// SinkStream 由 gRPC SubscribeEvents 订阅方拉取，确认后推进游标，notifier 不主动投递
+	SinkStream = "stream"
+	// SinkInbox 由 FetchEvents/AckEvents 拉取并逐条确认，notifier 不主动投递
+	SinkInbox = "inbox"
+)
+
+var errNotifyRejected = errors.New("business platform rejected notify")
+
+// Sink 事件投递方式，Publish 返回成功后该批事件不再重复投递，失败时下一轮从游标处重试
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, businessId string, events []Event) error
+}
+
+// SinkConfig 文件和消息队列投递的全局配置，未配置时选择该方式的业务方事件保留在发件箱中
+type SinkConfig struct {
+	FileDir   string
+	BrokerUrl string
+}
+
+// ParseSinks 解析业务方注册时选择的投递方式，为空时默认 webhook
+func ParseSinks(names []string) ([]string, error) {
+	var sinks []string
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		switch name {
+		case SinkWebhook, SinkFile, SinkBroker, SinkStream, SinkInbox:
+		default:
+			return nil, fmt.Errorf("unknown event sink %q", name)
+		}
+		seen[name] = true
+		sinks = append(sinks, name)
+	}
+	if len(sinks) == 0 {
+		sinks = []string{SinkWebhook}
+	}
+	return sinks, nil
+}
+
+// NewTransactionEvent 交易事件的 payload 为不含序号的 Transaction
+func NewTransactionEvent(tx Transaction) (database.Events, error) {
+	return newEvent(tx.TxType, tx)
+}
+
+func NewReorgEvent(reorg Reorg) (database.Events, error) {
+	return newEvent(database.EventKindReorg, reorg)
+}
+
+func newEvent(kind string, payload any) (database.Events, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return database.Events{}, err
+	}
+	return database.Events{
+		GUID:      uuid.New(),
+		Kind:      kind,
+		Payload:   string(data),
+		Timestamp: uint64(time.Now().Unix()),
+	}, nil
+}
+
+// DecodeEvent 把发件箱记录还原为投递用的事件，序号回填到 payload
+func DecodeEvent(businessId string, record database.Events) (Event, error) {
+	event := Event{
+		Sequence:   record.Sequence,
+		BusinessId: businessId,
+		Kind:       record.Kind,
+		Timestamp:  record.Timestamp,
+	}
+	if record.Kind == database.EventKindReorg {
+		event.Reorg = &Reorg{}
+		if err := json.Unmarshal([]byte(record.Payload), event.Reorg); err != nil {
+			return Event{}, err
+		}
+		event.Reorg.Sequence = record.Sequence
+		return event, nil
+	}
+	event.Transaction = &Transaction{}
+	if err := json.Unmarshal([]byte(record.Payload), event.Transaction); err != nil {
+		return Event{}, err
+	}
+	event.Transaction.Sequence = record.Sequence
+	return event, nil
+}
+
+// WebhookSink 沿用原有的 HTTP 回调格式，交易和 reorg 分别放在 txn 和 reorgs 中
+type WebhookSink struct {
+	client *NotifyClient
+}
+
+func NewWebhookSink(notifyUrl string) (*WebhookSink, error) {
+	client, err := NewNotifierClient(notifyUrl)
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookSink{client: client}, nil
+}
+
+func (s *WebhookSink) Name() string {
+	return SinkWebhook
+}
+
+func (s *WebhookSink) Publish(_ context.Context, _ string, events []Event) error {
+	request := &NotifyRequest{}
+	for _, event := range events {
+		if event.Reorg != nil {
+			request.Reorgs = append(request.Reorgs, *event.Reorg)
+		} else if event.Transaction != nil {
+			request.Txn = append(request.Txn, *event.Transaction)
+		}
+	}
+	success, err := s.client.BusinessNotify(request)
+	if err != nil {
+		return err
+	}
+	if !success {
+		return errNotifyRejected
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/database"
+)
+
+func TestParseSinks(t *testing.T) {
+	sinks, err := ParseSinks(nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{SinkWebhook}, sinks)
+
+	sinks, err = ParseSinks([]string{" File", "broker", "file", ""})
+	require.NoError(t, err)
+	require.Equal(t, []string{SinkFile, SinkBroker}, sinks)
+
+	_, err = ParseSinks([]string{"kafka"})
+	require.Error(t, err)
+}
+
+func testEvents(t *testing.T) []Event {
+	deposit, err := NewTransactionEvent(Transaction{Hash: "0x01", TxType: database.EventKindDeposit, Value: "10"})
+	require.NoError(t, err)
+	reorg, err := NewReorgEvent(Reorg{BlockNumber: 9, ParentHash: "0xaa", ExpectedParentHash: "0xbb"})
+	require.NoError(t, err)
+	deposit.Sequence, reorg.Sequence = 1, 2
+
+	var events []Event
+	for _, record := range []database.Events{deposit, reorg} {
+		event, err := DecodeEvent("b", record)
+		require.NoError(t, err)
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestDecodeEvent(t *testing.T) {
+	events := testEvents(t)
+	require.Equal(t, uint64(1), events[0].Transaction.Sequence)
+	require.Equal(t, "0x01", events[0].Transaction.Hash)
+	require.Nil(t, events[0].Reorg)
+	require.Equal(t, database.EventKindReorg, events[1].Kind)
+	require.Equal(t, uint64(2), events[1].Reorg.Sequence)
+	require.Equal(t, "0xbb", events[1].Reorg.ExpectedParentHash)
+}
+
+func TestFileSink(t *testing.T) {
+	sink, err := NewFileSink(t.TempDir())
+	require.NoError(t, err)
+	events := testEvents(t)
+	require.NoError(t, sink.Publish(context.Background(), "b", events[:1]))
+	require.NoError(t, sink.Publish(context.Background(), "b", events[1:]))
+
+	data, err := os.ReadFile(sink.Path("b"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var event Event
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+	require.Equal(t, uint64(2), event.Sequence)
+	require.Equal(t, "b", event.BusinessId)
+}
+
+func TestMemoryBrokerSink(t *testing.T) {
+	broker := NewMemoryBroker()
+	sink := NewBrokerSink(broker)
+	require.NoError(t, sink.Publish(context.Background(), "b", testEvents(t)))
+	messages := broker.Messages(BrokerSubject("b"))
+	require.Len(t, messages, 2)
+	require.Empty(t, broker.Messages(BrokerSubject("c")))
+}
+
+// fakeNats 只实现 INFO/CONNECT/PUB/PING 的嵌入式 NATS 服务端
+func fakeNats(t *testing.T, published chan<- string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "PING"):
+				fmt.Fprint(conn, "PONG\r\n")
+			case strings.HasPrefix(line, "PUB "):
+				var subject string
+				var size int
+				fmt.Sscanf(line, "PUB %s %d", &subject, &size)
+				payload := make([]byte, size+2)
+				if _, err := io.ReadFull(reader, payload); err != nil {
+					return
+				}
+				published <- subject + " " + string(payload[:size])
+			}
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestNatsBroker(t *testing.T) {
+	published := make(chan string, 4)
+	broker, err := NewBroker("nats://" + fakeNats(t, published))
+	require.NoError(t, err)
+	defer broker.Close()
+
+	require.NoError(t, NewBrokerSink(broker).Publish(context.Background(), "b", testEvents(t)))
+	require.Len(t, published, 2)
+	first := <-published
+	require.True(t, strings.HasPrefix(first, BrokerSubject("b")+" {"))
+	require.Contains(t, first, `"sequence":1`)
+}
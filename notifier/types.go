@@ -1,10 +1,12 @@
 package notifier
 
 type NotifyRequest struct {
-	Txn []Transaction `json:"txn"`
+	Txn    []Transaction `json:"txn"`
+	Reorgs []Reorg       `json:"reorgs,omitempty"`
 }
 
 type Transaction struct {
+	Sequence     uint64 `json:"sequence"` // 业务方内单调递增的事件序号
 	BlockHash    string `json:"block_hash"`
 	BlockNumber  uint64 `json:"block_number"`
 	Hash         string `json:"hash"`
@@ -15,8 +17,29 @@ type Transaction struct {
 	TxType       string `json:"tx_type"` // 0: 充值，1:提现；2:归集，3:热转冷；4:冷转热
 	Confirms     uint8  `json:"confirms"`
 	TokenAddress string `json:"token_address"`
-	TokenId      string `json:"token_id"`
-	TokenMeta    string `json:"token_meta"`
+	TokenId      string `json:"token_id"`       // NFT 为十进制 token id，同质化代币为 0x00
+	TokenMeta    string `json:"token_meta"`     // NFT 为代币标准 erc721/erc1155
+	Memo         string `json:"memo,omitempty"` // 共享地址充值或提现的 memo/destination tag
+	Historical   bool   `json:"historical"`     // 地址注册前的历史充值，由业务方决定是否入账
+}
+
+// Reorg 同步时发现新区块的父哈希与上一个已同步区块不一致
+type Reorg struct {
+	Sequence           uint64 `json:"sequence"`
+	BlockNumber        uint64 `json:"block_number"`
+	BlockHash          string `json:"block_hash"`
+	ParentHash         string `json:"parent_hash"`
+	ExpectedParentHash string `json:"expected_parent_hash"`
+}
+
+// Event 投递给 Sink 的事件，Transaction 和 Reorg 按 Kind 二选一
+type Event struct {
+	Sequence    uint64       `json:"sequence"`
+	BusinessId  string       `json:"business_id"`
+	Kind        string       `json:"kind"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+	Reorg       *Reorg       `json:"reorg,omitempty"`
+	Timestamp   uint64       `json:"timestamp"`
 }
 
 type NotifyResponse struct {
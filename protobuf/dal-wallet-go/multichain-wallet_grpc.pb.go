@@ -19,11 +19,28 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	BusinessMiddleWireServices_BusinessRegister_FullMethodName            = "/proto.multichain.BusinessMiddleWireServices/businessRegister"
-	BusinessMiddleWireServices_ExportAddressesByPublicKeys_FullMethodName = "/proto.multichain.BusinessMiddleWireServices/exportAddressesByPublicKeys"
-	BusinessMiddleWireServices_CreateUnSignTransaction_FullMethodName     = "/proto.multichain.BusinessMiddleWireServices/createUnSignTransaction"
-	BusinessMiddleWireServices_BuildSignedTransaction_FullMethodName      = "/proto.multichain.BusinessMiddleWireServices/buildSignedTransaction"
-	BusinessMiddleWireServices_SetTokenAddress_FullMethodName             = "/proto.multichain.BusinessMiddleWireServices/setTokenAddress"
+	BusinessMiddleWireServices_BusinessRegister_FullMethodName                    = "/proto.multichain.BusinessMiddleWireServices/businessRegister"
+	BusinessMiddleWireServices_UpdateBusinessStatus_FullMethodName                = "/proto.multichain.BusinessMiddleWireServices/updateBusinessStatus"
+	BusinessMiddleWireServices_RemoveBusiness_FullMethodName                      = "/proto.multichain.BusinessMiddleWireServices/removeBusiness"
+	BusinessMiddleWireServices_RescanBlocks_FullMethodName                        = "/proto.multichain.BusinessMiddleWireServices/rescanBlocks"
+	BusinessMiddleWireServices_ExportAddressesByPublicKeys_FullMethodName         = "/proto.multichain.BusinessMiddleWireServices/exportAddressesByPublicKeys"
+	BusinessMiddleWireServices_RegisterXpub_FullMethodName                        = "/proto.multichain.BusinessMiddleWireServices/registerXpub"
+	BusinessMiddleWireServices_AllocateAddress_FullMethodName                     = "/proto.multichain.BusinessMiddleWireServices/allocateAddress"
+	BusinessMiddleWireServices_AllocateMemo_FullMethodName                        = "/proto.multichain.BusinessMiddleWireServices/allocateMemo"
+	BusinessMiddleWireServices_ResolveQuarantinedDeposit_FullMethodName           = "/proto.multichain.BusinessMiddleWireServices/resolveQuarantinedDeposit"
+	BusinessMiddleWireServices_SubscribeEvents_FullMethodName                     = "/proto.multichain.BusinessMiddleWireServices/subscribeEvents"
+	BusinessMiddleWireServices_AckSubscription_FullMethodName                     = "/proto.multichain.BusinessMiddleWireServices/ackSubscription"
+	BusinessMiddleWireServices_FetchEvents_FullMethodName                         = "/proto.multichain.BusinessMiddleWireServices/fetchEvents"
+	BusinessMiddleWireServices_AckEvents_FullMethodName                           = "/proto.multichain.BusinessMiddleWireServices/ackEvents"
+	BusinessMiddleWireServices_CreateUnSignTransaction_FullMethodName             = "/proto.multichain.BusinessMiddleWireServices/createUnSignTransaction"
+	BusinessMiddleWireServices_BuildSignedTransaction_FullMethodName              = "/proto.multichain.BusinessMiddleWireServices/buildSignedTransaction"
+	BusinessMiddleWireServices_CreateBatchWithdrawTransaction_FullMethodName      = "/proto.multichain.BusinessMiddleWireServices/createBatchWithdrawTransaction"
+	BusinessMiddleWireServices_BuildSignedBatchWithdrawTransaction_FullMethodName = "/proto.multichain.BusinessMiddleWireServices/buildSignedBatchWithdrawTransaction"
+	BusinessMiddleWireServices_SetTokenAddress_FullMethodName                     = "/proto.multichain.BusinessMiddleWireServices/setTokenAddress"
+	BusinessMiddleWireServices_FetchSigningJobs_FullMethodName                    = "/proto.multichain.BusinessMiddleWireServices/fetchSigningJobs"
+	BusinessMiddleWireServices_SubmitSignature_FullMethodName                     = "/proto.multichain.BusinessMiddleWireServices/submitSignature"
+	BusinessMiddleWireServices_ExportColdBundle_FullMethodName                    = "/proto.multichain.BusinessMiddleWireServices/exportColdBundle"
+	BusinessMiddleWireServices_ImportColdBundle_FullMethodName                    = "/proto.multichain.BusinessMiddleWireServices/importColdBundle"
 )
 
 // BusinessMiddleWireServicesClient is the client API for BusinessMiddleWireServices service.
@@ -31,10 +48,27 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type BusinessMiddleWireServicesClient interface {
 	BusinessRegister(ctx context.Context, in *BusinessRegisterRequest, opts ...grpc.CallOption) (*BusinessRegisterResponse, error)
+	UpdateBusinessStatus(ctx context.Context, in *UpdateBusinessStatusRequest, opts ...grpc.CallOption) (*UpdateBusinessStatusResponse, error)
+	RemoveBusiness(ctx context.Context, in *RemoveBusinessRequest, opts ...grpc.CallOption) (*RemoveBusinessResponse, error)
+	RescanBlocks(ctx context.Context, in *RescanBlocksRequest, opts ...grpc.CallOption) (*RescanBlocksResponse, error)
 	ExportAddressesByPublicKeys(ctx context.Context, in *ExportAddressesRequest, opts ...grpc.CallOption) (*ExportAddressesResponse, error)
+	RegisterXpub(ctx context.Context, in *RegisterXpubRequest, opts ...grpc.CallOption) (*RegisterXpubResponse, error)
+	AllocateAddress(ctx context.Context, in *AllocateAddressRequest, opts ...grpc.CallOption) (*AllocateAddressResponse, error)
+	AllocateMemo(ctx context.Context, in *AllocateMemoRequest, opts ...grpc.CallOption) (*AllocateMemoResponse, error)
+	ResolveQuarantinedDeposit(ctx context.Context, in *ResolveQuarantinedDepositRequest, opts ...grpc.CallOption) (*ResolveQuarantinedDepositResponse, error)
+	SubscribeEvents(ctx context.Context, in *SubscribeEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WalletEvent], error)
+	AckSubscription(ctx context.Context, in *AckSubscriptionRequest, opts ...grpc.CallOption) (*AckSubscriptionResponse, error)
+	FetchEvents(ctx context.Context, in *FetchEventsRequest, opts ...grpc.CallOption) (*FetchEventsResponse, error)
+	AckEvents(ctx context.Context, in *AckEventsRequest, opts ...grpc.CallOption) (*AckEventsResponse, error)
 	CreateUnSignTransaction(ctx context.Context, in *UnSignWithdrawTransactionRequest, opts ...grpc.CallOption) (*UnSignWithdrawTransactionResponse, error)
 	BuildSignedTransaction(ctx context.Context, in *SignedWithdrawTransactionRequest, opts ...grpc.CallOption) (*SignedWithdrawTransactionResponse, error)
+	CreateBatchWithdrawTransaction(ctx context.Context, in *BatchWithdrawRequest, opts ...grpc.CallOption) (*BatchWithdrawResponse, error)
+	BuildSignedBatchWithdrawTransaction(ctx context.Context, in *SignedBatchWithdrawRequest, opts ...grpc.CallOption) (*SignedBatchWithdrawResponse, error)
 	SetTokenAddress(ctx context.Context, in *SetTokenAddressRequest, opts ...grpc.CallOption) (*SetTokenAddressResponse, error)
+	FetchSigningJobs(ctx context.Context, in *FetchSigningJobsRequest, opts ...grpc.CallOption) (*FetchSigningJobsResponse, error)
+	SubmitSignature(ctx context.Context, in *SubmitSignatureRequest, opts ...grpc.CallOption) (*SubmitSignatureResponse, error)
+	ExportColdBundle(ctx context.Context, in *ExportColdBundleRequest, opts ...grpc.CallOption) (*ExportColdBundleResponse, error)
+	ImportColdBundle(ctx context.Context, in *ImportColdBundleRequest, opts ...grpc.CallOption) (*ImportColdBundleResponse, error)
 }
 
 type businessMiddleWireServicesClient struct {
@@ -55,6 +89,36 @@ func (c *businessMiddleWireServicesClient) BusinessRegister(ctx context.Context,
 	return out, nil
 }
 
+func (c *businessMiddleWireServicesClient) UpdateBusinessStatus(ctx context.Context, in *UpdateBusinessStatusRequest, opts ...grpc.CallOption) (*UpdateBusinessStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateBusinessStatusResponse)
+	err := c.cc.Invoke(ctx, BusinessMiddleWireServices_UpdateBusinessStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *businessMiddleWireServicesClient) RemoveBusiness(ctx context.Context, in *RemoveBusinessRequest, opts ...grpc.CallOption) (*RemoveBusinessResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveBusinessResponse)
+	err := c.cc.Invoke(ctx, BusinessMiddleWireServices_RemoveBusiness_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *businessMiddleWireServicesClient) RescanBlocks(ctx context.Context, in *RescanBlocksRequest, opts ...grpc.CallOption) (*RescanBlocksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RescanBlocksResponse)
+	err := c.cc.Invoke(ctx, BusinessMiddleWireServices_RescanBlocks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *businessMiddleWireServicesClient) ExportAddressesByPublicKeys(ctx context.Context, in *ExportAddressesRequest, opts ...grpc.CallOption) (*ExportAddressesResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ExportAddressesResponse)
@@ -65,6 +129,95 @@ func (c *businessMiddleWireServicesClient) ExportAddressesByPublicKeys(ctx conte
 	return out, nil
 }
 
+func (c *businessMiddleWireServicesClient) RegisterXpub(ctx context.Context, in *RegisterXpubRequest, opts ...grpc.CallOption) (*RegisterXpubResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RegisterXpubResponse)
+	err := c.cc.Invoke(ctx, BusinessMiddleWireServices_RegisterXpub_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *businessMiddleWireServicesClient) AllocateAddress(ctx context.Context, in *AllocateAddressRequest, opts ...grpc.CallOption) (*AllocateAddressResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AllocateAddressResponse)
+	err := c.cc.Invoke(ctx, BusinessMiddleWireServices_AllocateAddress_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *businessMiddleWireServicesClient) AllocateMemo(ctx context.Context, in *AllocateMemoRequest, opts ...grpc.CallOption) (*AllocateMemoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AllocateMemoResponse)
+	err := c.cc.Invoke(ctx, BusinessMiddleWireServices_AllocateMemo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *businessMiddleWireServicesClient) ResolveQuarantinedDeposit(ctx context.Context, in *ResolveQuarantinedDepositRequest, opts ...grpc.CallOption) (*ResolveQuarantinedDepositResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResolveQuarantinedDepositResponse)
+	err := c.cc.Invoke(ctx, BusinessMiddleWireServices_ResolveQuarantinedDeposit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *businessMiddleWireServicesClient) SubscribeEvents(ctx context.Context, in *SubscribeEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WalletEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BusinessMiddleWireServices_ServiceDesc.Streams[0], BusinessMiddleWireServices_SubscribeEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SubscribeEventsRequest, WalletEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BusinessMiddleWireServices_SubscribeEventsClient = grpc.ServerStreamingClient[WalletEvent]
+
+func (c *businessMiddleWireServicesClient) AckSubscription(ctx context.Context, in *AckSubscriptionRequest, opts ...grpc.CallOption) (*AckSubscriptionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AckSubscriptionResponse)
+	err := c.cc.Invoke(ctx, BusinessMiddleWireServices_AckSubscription_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *businessMiddleWireServicesClient) FetchEvents(ctx context.Context, in *FetchEventsRequest, opts ...grpc.CallOption) (*FetchEventsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FetchEventsResponse)
+	err := c.cc.Invoke(ctx, BusinessMiddleWireServices_FetchEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *businessMiddleWireServicesClient) AckEvents(ctx context.Context, in *AckEventsRequest, opts ...grpc.CallOption) (*AckEventsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AckEventsResponse)
+	err := c.cc.Invoke(ctx, BusinessMiddleWireServices_AckEvents_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *businessMiddleWireServicesClient) CreateUnSignTransaction(ctx context.Context, in *UnSignWithdrawTransactionRequest, opts ...grpc.CallOption) (*UnSignWithdrawTransactionResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(UnSignWithdrawTransactionResponse)
@@ -85,6 +238,26 @@ func (c *businessMiddleWireServicesClient) BuildSignedTransaction(ctx context.Co
 	return out, nil
 }
 
+func (c *businessMiddleWireServicesClient) CreateBatchWithdrawTransaction(ctx context.Context, in *BatchWithdrawRequest, opts ...grpc.CallOption) (*BatchWithdrawResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchWithdrawResponse)
+	err := c.cc.Invoke(ctx, BusinessMiddleWireServices_CreateBatchWithdrawTransaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *businessMiddleWireServicesClient) BuildSignedBatchWithdrawTransaction(ctx context.Context, in *SignedBatchWithdrawRequest, opts ...grpc.CallOption) (*SignedBatchWithdrawResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SignedBatchWithdrawResponse)
+	err := c.cc.Invoke(ctx, BusinessMiddleWireServices_BuildSignedBatchWithdrawTransaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *businessMiddleWireServicesClient) SetTokenAddress(ctx context.Context, in *SetTokenAddressRequest, opts ...grpc.CallOption) (*SetTokenAddressResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(SetTokenAddressResponse)
@@ -95,15 +268,72 @@ func (c *businessMiddleWireServicesClient) SetTokenAddress(ctx context.Context,
 	return out, nil
 }
 
+func (c *businessMiddleWireServicesClient) FetchSigningJobs(ctx context.Context, in *FetchSigningJobsRequest, opts ...grpc.CallOption) (*FetchSigningJobsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FetchSigningJobsResponse)
+	err := c.cc.Invoke(ctx, BusinessMiddleWireServices_FetchSigningJobs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *businessMiddleWireServicesClient) SubmitSignature(ctx context.Context, in *SubmitSignatureRequest, opts ...grpc.CallOption) (*SubmitSignatureResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SubmitSignatureResponse)
+	err := c.cc.Invoke(ctx, BusinessMiddleWireServices_SubmitSignature_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *businessMiddleWireServicesClient) ExportColdBundle(ctx context.Context, in *ExportColdBundleRequest, opts ...grpc.CallOption) (*ExportColdBundleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExportColdBundleResponse)
+	err := c.cc.Invoke(ctx, BusinessMiddleWireServices_ExportColdBundle_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *businessMiddleWireServicesClient) ImportColdBundle(ctx context.Context, in *ImportColdBundleRequest, opts ...grpc.CallOption) (*ImportColdBundleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ImportColdBundleResponse)
+	err := c.cc.Invoke(ctx, BusinessMiddleWireServices_ImportColdBundle_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BusinessMiddleWireServicesServer is the server API for BusinessMiddleWireServices service.
 // All implementations should embed UnimplementedBusinessMiddleWireServicesServer
 // for forward compatibility.
 type BusinessMiddleWireServicesServer interface {
 	BusinessRegister(context.Context, *BusinessRegisterRequest) (*BusinessRegisterResponse, error)
+	UpdateBusinessStatus(context.Context, *UpdateBusinessStatusRequest) (*UpdateBusinessStatusResponse, error)
+	RemoveBusiness(context.Context, *RemoveBusinessRequest) (*RemoveBusinessResponse, error)
+	RescanBlocks(context.Context, *RescanBlocksRequest) (*RescanBlocksResponse, error)
 	ExportAddressesByPublicKeys(context.Context, *ExportAddressesRequest) (*ExportAddressesResponse, error)
+	RegisterXpub(context.Context, *RegisterXpubRequest) (*RegisterXpubResponse, error)
+	AllocateAddress(context.Context, *AllocateAddressRequest) (*AllocateAddressResponse, error)
+	AllocateMemo(context.Context, *AllocateMemoRequest) (*AllocateMemoResponse, error)
+	ResolveQuarantinedDeposit(context.Context, *ResolveQuarantinedDepositRequest) (*ResolveQuarantinedDepositResponse, error)
+	SubscribeEvents(*SubscribeEventsRequest, grpc.ServerStreamingServer[WalletEvent]) error
+	AckSubscription(context.Context, *AckSubscriptionRequest) (*AckSubscriptionResponse, error)
+	FetchEvents(context.Context, *FetchEventsRequest) (*FetchEventsResponse, error)
+	AckEvents(context.Context, *AckEventsRequest) (*AckEventsResponse, error)
 	CreateUnSignTransaction(context.Context, *UnSignWithdrawTransactionRequest) (*UnSignWithdrawTransactionResponse, error)
 	BuildSignedTransaction(context.Context, *SignedWithdrawTransactionRequest) (*SignedWithdrawTransactionResponse, error)
+	CreateBatchWithdrawTransaction(context.Context, *BatchWithdrawRequest) (*BatchWithdrawResponse, error)
+	BuildSignedBatchWithdrawTransaction(context.Context, *SignedBatchWithdrawRequest) (*SignedBatchWithdrawResponse, error)
 	SetTokenAddress(context.Context, *SetTokenAddressRequest) (*SetTokenAddressResponse, error)
+	FetchSigningJobs(context.Context, *FetchSigningJobsRequest) (*FetchSigningJobsResponse, error)
+	SubmitSignature(context.Context, *SubmitSignatureRequest) (*SubmitSignatureResponse, error)
+	ExportColdBundle(context.Context, *ExportColdBundleRequest) (*ExportColdBundleResponse, error)
+	ImportColdBundle(context.Context, *ImportColdBundleRequest) (*ImportColdBundleResponse, error)
 }
 
 // UnimplementedBusinessMiddleWireServicesServer should be embedded to have
@@ -116,18 +346,69 @@ type UnimplementedBusinessMiddleWireServicesServer struct{}
 func (UnimplementedBusinessMiddleWireServicesServer) BusinessRegister(context.Context, *BusinessRegisterRequest) (*BusinessRegisterResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method BusinessRegister not implemented")
 }
+func (UnimplementedBusinessMiddleWireServicesServer) UpdateBusinessStatus(context.Context, *UpdateBusinessStatusRequest) (*UpdateBusinessStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateBusinessStatus not implemented")
+}
+func (UnimplementedBusinessMiddleWireServicesServer) RemoveBusiness(context.Context, *RemoveBusinessRequest) (*RemoveBusinessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveBusiness not implemented")
+}
+func (UnimplementedBusinessMiddleWireServicesServer) RescanBlocks(context.Context, *RescanBlocksRequest) (*RescanBlocksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RescanBlocks not implemented")
+}
 func (UnimplementedBusinessMiddleWireServicesServer) ExportAddressesByPublicKeys(context.Context, *ExportAddressesRequest) (*ExportAddressesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ExportAddressesByPublicKeys not implemented")
 }
+func (UnimplementedBusinessMiddleWireServicesServer) RegisterXpub(context.Context, *RegisterXpubRequest) (*RegisterXpubResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterXpub not implemented")
+}
+func (UnimplementedBusinessMiddleWireServicesServer) AllocateAddress(context.Context, *AllocateAddressRequest) (*AllocateAddressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AllocateAddress not implemented")
+}
+func (UnimplementedBusinessMiddleWireServicesServer) AllocateMemo(context.Context, *AllocateMemoRequest) (*AllocateMemoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AllocateMemo not implemented")
+}
+func (UnimplementedBusinessMiddleWireServicesServer) ResolveQuarantinedDeposit(context.Context, *ResolveQuarantinedDepositRequest) (*ResolveQuarantinedDepositResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResolveQuarantinedDeposit not implemented")
+}
+func (UnimplementedBusinessMiddleWireServicesServer) SubscribeEvents(*SubscribeEventsRequest, grpc.ServerStreamingServer[WalletEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeEvents not implemented")
+}
+func (UnimplementedBusinessMiddleWireServicesServer) AckSubscription(context.Context, *AckSubscriptionRequest) (*AckSubscriptionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AckSubscription not implemented")
+}
+func (UnimplementedBusinessMiddleWireServicesServer) FetchEvents(context.Context, *FetchEventsRequest) (*FetchEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FetchEvents not implemented")
+}
+func (UnimplementedBusinessMiddleWireServicesServer) AckEvents(context.Context, *AckEventsRequest) (*AckEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AckEvents not implemented")
+}
 func (UnimplementedBusinessMiddleWireServicesServer) CreateUnSignTransaction(context.Context, *UnSignWithdrawTransactionRequest) (*UnSignWithdrawTransactionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateUnSignTransaction not implemented")
 }
 func (UnimplementedBusinessMiddleWireServicesServer) BuildSignedTransaction(context.Context, *SignedWithdrawTransactionRequest) (*SignedWithdrawTransactionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method BuildSignedTransaction not implemented")
 }
+func (UnimplementedBusinessMiddleWireServicesServer) CreateBatchWithdrawTransaction(context.Context, *BatchWithdrawRequest) (*BatchWithdrawResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateBatchWithdrawTransaction not implemented")
+}
+func (UnimplementedBusinessMiddleWireServicesServer) BuildSignedBatchWithdrawTransaction(context.Context, *SignedBatchWithdrawRequest) (*SignedBatchWithdrawResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BuildSignedBatchWithdrawTransaction not implemented")
+}
 func (UnimplementedBusinessMiddleWireServicesServer) SetTokenAddress(context.Context, *SetTokenAddressRequest) (*SetTokenAddressResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SetTokenAddress not implemented")
 }
+func (UnimplementedBusinessMiddleWireServicesServer) FetchSigningJobs(context.Context, *FetchSigningJobsRequest) (*FetchSigningJobsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FetchSigningJobs not implemented")
+}
+func (UnimplementedBusinessMiddleWireServicesServer) SubmitSignature(context.Context, *SubmitSignatureRequest) (*SubmitSignatureResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitSignature not implemented")
+}
+func (UnimplementedBusinessMiddleWireServicesServer) ExportColdBundle(context.Context, *ExportColdBundleRequest) (*ExportColdBundleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExportColdBundle not implemented")
+}
+func (UnimplementedBusinessMiddleWireServicesServer) ImportColdBundle(context.Context, *ImportColdBundleRequest) (*ImportColdBundleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportColdBundle not implemented")
+}
 func (UnimplementedBusinessMiddleWireServicesServer) testEmbeddedByValue() {}
 
 // UnsafeBusinessMiddleWireServicesServer may be embedded to opt out of forward compatibility for this service.
@@ -166,6 +447,60 @@ func _BusinessMiddleWireServices_BusinessRegister_Handler(srv interface{}, ctx c
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BusinessMiddleWireServices_UpdateBusinessStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateBusinessStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusinessMiddleWireServicesServer).UpdateBusinessStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BusinessMiddleWireServices_UpdateBusinessStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusinessMiddleWireServicesServer).UpdateBusinessStatus(ctx, req.(*UpdateBusinessStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BusinessMiddleWireServices_RemoveBusiness_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveBusinessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusinessMiddleWireServicesServer).RemoveBusiness(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BusinessMiddleWireServices_RemoveBusiness_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusinessMiddleWireServicesServer).RemoveBusiness(ctx, req.(*RemoveBusinessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BusinessMiddleWireServices_RescanBlocks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RescanBlocksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusinessMiddleWireServicesServer).RescanBlocks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BusinessMiddleWireServices_RescanBlocks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusinessMiddleWireServicesServer).RescanBlocks(ctx, req.(*RescanBlocksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _BusinessMiddleWireServices_ExportAddressesByPublicKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ExportAddressesRequest)
 	if err := dec(in); err != nil {
@@ -184,6 +519,143 @@ func _BusinessMiddleWireServices_ExportAddressesByPublicKeys_Handler(srv interfa
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BusinessMiddleWireServices_RegisterXpub_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterXpubRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusinessMiddleWireServicesServer).RegisterXpub(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BusinessMiddleWireServices_RegisterXpub_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusinessMiddleWireServicesServer).RegisterXpub(ctx, req.(*RegisterXpubRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BusinessMiddleWireServices_AllocateAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllocateAddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusinessMiddleWireServicesServer).AllocateAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BusinessMiddleWireServices_AllocateAddress_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusinessMiddleWireServicesServer).AllocateAddress(ctx, req.(*AllocateAddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BusinessMiddleWireServices_AllocateMemo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AllocateMemoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusinessMiddleWireServicesServer).AllocateMemo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BusinessMiddleWireServices_AllocateMemo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusinessMiddleWireServicesServer).AllocateMemo(ctx, req.(*AllocateMemoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BusinessMiddleWireServices_ResolveQuarantinedDeposit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveQuarantinedDepositRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusinessMiddleWireServicesServer).ResolveQuarantinedDeposit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BusinessMiddleWireServices_ResolveQuarantinedDeposit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusinessMiddleWireServicesServer).ResolveQuarantinedDeposit(ctx, req.(*ResolveQuarantinedDepositRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BusinessMiddleWireServices_SubscribeEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BusinessMiddleWireServicesServer).SubscribeEvents(m, &grpc.GenericServerStream[SubscribeEventsRequest, WalletEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BusinessMiddleWireServices_SubscribeEventsServer = grpc.ServerStreamingServer[WalletEvent]
+
+func _BusinessMiddleWireServices_AckSubscription_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AckSubscriptionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusinessMiddleWireServicesServer).AckSubscription(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BusinessMiddleWireServices_AckSubscription_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusinessMiddleWireServicesServer).AckSubscription(ctx, req.(*AckSubscriptionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BusinessMiddleWireServices_FetchEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FetchEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusinessMiddleWireServicesServer).FetchEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BusinessMiddleWireServices_FetchEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusinessMiddleWireServicesServer).FetchEvents(ctx, req.(*FetchEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BusinessMiddleWireServices_AckEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AckEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusinessMiddleWireServicesServer).AckEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BusinessMiddleWireServices_AckEvents_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusinessMiddleWireServicesServer).AckEvents(ctx, req.(*AckEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _BusinessMiddleWireServices_CreateUnSignTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(UnSignWithdrawTransactionRequest)
 	if err := dec(in); err != nil {
@@ -220,6 +692,42 @@ func _BusinessMiddleWireServices_BuildSignedTransaction_Handler(srv interface{},
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BusinessMiddleWireServices_CreateBatchWithdrawTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchWithdrawRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusinessMiddleWireServicesServer).CreateBatchWithdrawTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BusinessMiddleWireServices_CreateBatchWithdrawTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusinessMiddleWireServicesServer).CreateBatchWithdrawTransaction(ctx, req.(*BatchWithdrawRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BusinessMiddleWireServices_BuildSignedBatchWithdrawTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignedBatchWithdrawRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusinessMiddleWireServicesServer).BuildSignedBatchWithdrawTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BusinessMiddleWireServices_BuildSignedBatchWithdrawTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusinessMiddleWireServicesServer).BuildSignedBatchWithdrawTransaction(ctx, req.(*SignedBatchWithdrawRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _BusinessMiddleWireServices_SetTokenAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(SetTokenAddressRequest)
 	if err := dec(in); err != nil {
@@ -238,6 +746,78 @@ func _BusinessMiddleWireServices_SetTokenAddress_Handler(srv interface{}, ctx co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BusinessMiddleWireServices_FetchSigningJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FetchSigningJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusinessMiddleWireServicesServer).FetchSigningJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BusinessMiddleWireServices_FetchSigningJobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusinessMiddleWireServicesServer).FetchSigningJobs(ctx, req.(*FetchSigningJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BusinessMiddleWireServices_SubmitSignature_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitSignatureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusinessMiddleWireServicesServer).SubmitSignature(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BusinessMiddleWireServices_SubmitSignature_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusinessMiddleWireServicesServer).SubmitSignature(ctx, req.(*SubmitSignatureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BusinessMiddleWireServices_ExportColdBundle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportColdBundleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusinessMiddleWireServicesServer).ExportColdBundle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BusinessMiddleWireServices_ExportColdBundle_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusinessMiddleWireServicesServer).ExportColdBundle(ctx, req.(*ExportColdBundleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BusinessMiddleWireServices_ImportColdBundle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportColdBundleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BusinessMiddleWireServicesServer).ImportColdBundle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BusinessMiddleWireServices_ImportColdBundle_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BusinessMiddleWireServicesServer).ImportColdBundle(ctx, req.(*ImportColdBundleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BusinessMiddleWireServices_ServiceDesc is the grpc.ServiceDesc for BusinessMiddleWireServices service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -249,10 +829,50 @@ var BusinessMiddleWireServices_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "businessRegister",
 			Handler:    _BusinessMiddleWireServices_BusinessRegister_Handler,
 		},
+		{
+			MethodName: "updateBusinessStatus",
+			Handler:    _BusinessMiddleWireServices_UpdateBusinessStatus_Handler,
+		},
+		{
+			MethodName: "removeBusiness",
+			Handler:    _BusinessMiddleWireServices_RemoveBusiness_Handler,
+		},
+		{
+			MethodName: "rescanBlocks",
+			Handler:    _BusinessMiddleWireServices_RescanBlocks_Handler,
+		},
 		{
 			MethodName: "exportAddressesByPublicKeys",
 			Handler:    _BusinessMiddleWireServices_ExportAddressesByPublicKeys_Handler,
 		},
+		{
+			MethodName: "registerXpub",
+			Handler:    _BusinessMiddleWireServices_RegisterXpub_Handler,
+		},
+		{
+			MethodName: "allocateAddress",
+			Handler:    _BusinessMiddleWireServices_AllocateAddress_Handler,
+		},
+		{
+			MethodName: "allocateMemo",
+			Handler:    _BusinessMiddleWireServices_AllocateMemo_Handler,
+		},
+		{
+			MethodName: "resolveQuarantinedDeposit",
+			Handler:    _BusinessMiddleWireServices_ResolveQuarantinedDeposit_Handler,
+		},
+		{
+			MethodName: "ackSubscription",
+			Handler:    _BusinessMiddleWireServices_AckSubscription_Handler,
+		},
+		{
+			MethodName: "fetchEvents",
+			Handler:    _BusinessMiddleWireServices_FetchEvents_Handler,
+		},
+		{
+			MethodName: "ackEvents",
+			Handler:    _BusinessMiddleWireServices_AckEvents_Handler,
+		},
 		{
 			MethodName: "createUnSignTransaction",
 			Handler:    _BusinessMiddleWireServices_CreateUnSignTransaction_Handler,
@@ -261,11 +881,41 @@ var BusinessMiddleWireServices_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "buildSignedTransaction",
 			Handler:    _BusinessMiddleWireServices_BuildSignedTransaction_Handler,
 		},
+		{
+			MethodName: "createBatchWithdrawTransaction",
+			Handler:    _BusinessMiddleWireServices_CreateBatchWithdrawTransaction_Handler,
+		},
+		{
+			MethodName: "buildSignedBatchWithdrawTransaction",
+			Handler:    _BusinessMiddleWireServices_BuildSignedBatchWithdrawTransaction_Handler,
+		},
 		{
 			MethodName: "setTokenAddress",
 			Handler:    _BusinessMiddleWireServices_SetTokenAddress_Handler,
 		},
+		{
+			MethodName: "fetchSigningJobs",
+			Handler:    _BusinessMiddleWireServices_FetchSigningJobs_Handler,
+		},
+		{
+			MethodName: "submitSignature",
+			Handler:    _BusinessMiddleWireServices_SubmitSignature_Handler,
+		},
+		{
+			MethodName: "exportColdBundle",
+			Handler:    _BusinessMiddleWireServices_ExportColdBundle_Handler,
+		},
+		{
+			MethodName: "importColdBundle",
+			Handler:    _BusinessMiddleWireServices_ImportColdBundle_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "subscribeEvents",
+			Handler:       _BusinessMiddleWireServices_SubscribeEvents_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/multichain-wallet.proto",
 }
@@ -0,0 +1,176 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/common/clock"
+	"github.com/CavnHan/multichain-sync-account/database"
+)
+
+const defaultRefreshInterval = 10 * time.Second
+
+// Change 描述两次刷新之间 business 表的变化，暂停的业务方视为被移除
+type Change struct {
+	Added   []database.Business
+	Updated []database.Business
+	Removed []database.Business
+}
+
+func (c Change) Empty() bool {
+	return len(c.Added) == 0 && len(c.Updated) == 0 && len(c.Removed) == 0
+}
+
+type Listener func(change Change)
+
+// Registry 定时轮询 business 表，维护当前处于激活状态的业务方快照，
+// 新注册、暂停或删除的业务方无需重启进程即可生效
+type Registry struct {
+	db       *database.DB
+	interval time.Duration
+
+	mu         sync.RWMutex
+	businesses map[string]database.Business
+	listeners  []Listener
+
+	worker *clock.LoopFn
+}
+
+func NewRegistry(db *database.DB, interval time.Duration) (*Registry, error) {
+	if interval == 0 {
+		interval = defaultRefreshInterval
+	}
+	reg := &Registry{
+		db:         db,
+		interval:   interval,
+		businesses: make(map[string]database.Business),
+	}
+	if err := reg.Refresh(); err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+func (r *Registry) Start() error {
+	if r.worker != nil {
+		return errors.New("already started")
+	}
+	r.worker = clock.NewLoopFn(clock.SystemClock, r.tick, nil, r.interval)
+	return nil
+}
+
+func (r *Registry) Close() error {
+	if r.worker == nil {
+		return nil
+	}
+	return r.worker.Close()
+}
+
+// Subscribe 注册变更回调，回调会立即收到一次当前全部业务方作为 Added
+func (r *Registry) Subscribe(listener Listener) {
+	r.mu.Lock()
+	r.listeners = append(r.listeners, listener)
+	current := make([]database.Business, 0, len(r.businesses))
+	for _, business := range r.businesses {
+		current = append(current, business)
+	}
+	r.mu.Unlock()
+
+	sortBusinesses(current)
+	if len(current) > 0 {
+		listener(Change{Added: current})
+	}
+}
+
+func (r *Registry) BusinessIds() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	businessIds := make([]string, 0, len(r.businesses))
+	for businessId := range r.businesses {
+		businessIds = append(businessIds, businessId)
+	}
+	sort.Strings(businessIds)
+	return businessIds
+}
+
+func (r *Registry) Business(businessId string) (database.Business, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	business, ok := r.businesses[businessId]
+	return business, ok
+}
+
+func (r *Registry) IsActive(businessId string) bool {
+	_, ok := r.Business(businessId)
+	return ok
+}
+
+// Refresh 重新加载 business 表并通知订阅者，Start 之后由定时任务调用
+func (r *Registry) Refresh() error {
+	businessList, err := r.db.Business.QueryBusinessList()
+	if err != nil {
+		log.Error("query business list fail", "err", err)
+		return err
+	}
+	next := make(map[string]database.Business, len(businessList))
+	for _, business := range businessList {
+		if business.Status != database.BusinessStatusActive {
+			continue
+		}
+		next[business.BusinessUid] = business
+	}
+
+	r.mu.Lock()
+	change := diffBusinesses(r.businesses, next)
+	r.businesses = next
+	listeners := make([]Listener, len(r.listeners))
+	copy(listeners, r.listeners)
+	r.mu.Unlock()
+
+	if change.Empty() {
+		return nil
+	}
+	log.Info("business registry changed", "added", len(change.Added), "updated", len(change.Updated), "removed", len(change.Removed))
+	for _, listener := range listeners {
+		listener(change)
+	}
+	return nil
+}
+
+func (r *Registry) tick(_ context.Context) {
+	if err := r.Refresh(); err != nil {
+		log.Warn("refresh business registry fail, keep previous snapshot", "err", err)
+	}
+}
+
+func diffBusinesses(prev, next map[string]database.Business) Change {
+	var change Change
+	for businessId, business := range next {
+		old, ok := prev[businessId]
+		if !ok {
+			change.Added = append(change.Added, business)
+		} else if old.NotifyUrl != business.NotifyUrl || old.Sinks != business.Sinks {
+			change.Updated = append(change.Updated, business)
+		}
+	}
+	for businessId, business := range prev {
+		if _, ok := next[businessId]; !ok {
+			change.Removed = append(change.Removed, business)
+		}
+	}
+	sortBusinesses(change.Added)
+	sortBusinesses(change.Updated)
+	sortBusinesses(change.Removed)
+	return change
+}
+
+func sortBusinesses(businesses []database.Business) {
+	sort.Slice(businesses, func(i, j int) bool {
+		return businesses[i].BusinessUid < businesses[j].BusinessUid
+	})
+}
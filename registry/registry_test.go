@@ -0,0 +1,30 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/database"
+)
+
+func TestDiffBusinesses(t *testing.T) {
+	prev := map[string]database.Business{
+		"a": {BusinessUid: "a", NotifyUrl: "http://a"},
+		"b": {BusinessUid: "b", NotifyUrl: "http://b"},
+	}
+	next := map[string]database.Business{
+		"b": {BusinessUid: "b", NotifyUrl: "http://b2"},
+		"c": {BusinessUid: "c", NotifyUrl: "http://c"},
+	}
+
+	change := diffBusinesses(prev, next)
+	require.Len(t, change.Added, 1)
+	require.Equal(t, "c", change.Added[0].BusinessUid)
+	require.Len(t, change.Updated, 1)
+	require.Equal(t, "http://b2", change.Updated[0].NotifyUrl)
+	require.Len(t, change.Removed, 1)
+	require.Equal(t, "a", change.Removed[0].BusinessUid)
+
+	require.True(t, diffBusinesses(next, next).Empty())
+}
@@ -2,30 +2,83 @@ package rpcclient
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math/big"
 	"strconv"
+	"strings"
 
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/common/retry"
 	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
 	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/common"
-	ethcommon "github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 )
 
-
 type WalletChainAccountClient struct {
 	Ctx             context.Context
 	ChainName       string
 	AccountRpClient account.WalletAccountServiceClient
+	// 只读调用遇到传输错误时的重试次数和间隔，发送交易不重试
+	Retries       int
+	RetryStrategy retry.Strategy
 }
 
 func NewWalletChainAccountClient(ctx context.Context, rpc account.WalletAccountServiceClient, chainName string) (*WalletChainAccountClient, error) {
-	return &WalletChainAccountClient{Ctx: ctx, AccountRpClient: rpc, ChainName: chainName}, nil
+	return &WalletChainAccountClient{Ctx: ctx, AccountRpClient: rpc, ChainName: chainName, Retries: 3, RetryStrategy: retry.Exponential()}, nil
+}
+
+// read 只读调用按 RetryStrategy 重试传输错误，业务错误直接返回
+func read[T any](wac *WalletChainAccountClient, op func() (T, error)) (T, error) {
+	var final error
+	out, err := retry.Do(wac.Ctx, max(wac.Retries, 1), wac.RetryStrategy, func() (T, error) {
+		out, err := op()
+		if err != nil && !IsTransportError(err) {
+			final = err
+			return out, nil
+		}
+		return out, err
+	})
+	if err != nil {
+		return out, err
+	}
+	return out, final
+}
+
+// ErrBroadcastUnknown 发送交易时调用本身失败，交易可能已被节点接收，调用方不能当作拒绝处理
+var ErrBroadcastUnknown = errors.New("broadcast outcome unknown")
+
+// alreadyKnownMessages 节点因交易已在交易池或链上而拒绝时的错误信息
+var alreadyKnownMessages = []string{"already known", "known transaction", "txn-already-in-mempool", "txn-already-known", "already in block chain"}
+
+// IsAlreadyKnown 节点拒绝的原因是同一笔交易已经被接收，说明之前的广播已经成功
+func IsAlreadyKnown(err error) bool {
+	if err == nil || errors.Is(err, ErrBroadcastUnknown) {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, known := range alreadyKnownMessages {
+		if strings.Contains(msg, known) {
+			return true
+		}
+	}
+	return false
+}
+
+// responseError 把返回码为失败的响应转为错误
+func responseError(code common.ReturnCode, msg string) error {
+	if code == common.ReturnCode_ERROR {
+		return errors.New(msg)
+	}
+	return nil
 }
 
 func (wac *WalletChainAccountClient) ExportAddressByPubKey(method, publicKey string) string {
 	log.Info("method:", method, "publicKey:", publicKey)
 	req := &account.ConvertAddressRequest{
-		Chain:     wac.ChainName,
+		Chain: wac.ChainName,
 		//TODO fix bug
 		// Type:      method,
 		PublicKey: publicKey,
@@ -34,16 +87,18 @@ func (wac *WalletChainAccountClient) ExportAddressByPubKey(method, publicKey str
 	log.Info("========wac is :", wac.AccountRpClient)
 	log.Info("========wac.Ctx is :", wac.Ctx)
 	log.Info("========wac.ChainName is :", wac.ChainName)
-	address, err := wac.AccountRpClient.ConvertAddress(wac.Ctx, req)
-	log.Info("ConvertAddress", "address", address.Address, "error", err)
+	address, err := read(wac, func() (*account.ConvertAddressResponse, error) {
+		return wac.AccountRpClient.ConvertAddress(wac.Ctx, req)
+	})
 	if err != nil {
 		log.Error("ConvertAddress error", "error", err)
 		return ""
 	}
+	log.Info("ConvertAddress", "address", address.Address)
 
 	//TODO  fix bug
 
-	if address.Code == 1{
+	if address.Code == 1 {
 		log.Error("ConvertAddress error", "error", err)
 		return ""
 	}
@@ -62,15 +117,29 @@ func (wac *WalletChainAccountClient) GetBlockHeader(number *big.Int) (*BlockHead
 		Network: "mainnet",
 		Height:  height,
 	}
-	blockHeader, err := wac.AccountRpClient.GetBlockHeaderByNumber(wac.Ctx, req)
-	if blockHeader.Code == common.ReturnCode_ERROR {
+	blockHeader, err := read(wac, func() (*account.BlockHeaderResponse, error) {
+		resp, err := wac.AccountRpClient.GetBlockHeaderByNumber(wac.Ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return resp, responseError(resp.Code, resp.Msg)
+	})
+	if err != nil {
 		log.Error("get latest block fail", "err", err)
 		return nil, err
 	}
 	blockNumber, _ := new(big.Int).SetString(blockHeader.BlockHeader.Number, 10)
+	hash, err := chainaddr.ParseHash(blockHeader.BlockHeader.Hash)
+	if err != nil {
+		return nil, err
+	}
+	parentHash, err := chainaddr.ParseHash(blockHeader.BlockHeader.ParentHash)
+	if err != nil {
+		return nil, err
+	}
 	header := &BlockHeader{
-		Hash:       ethcommon.HexToHash(blockHeader.BlockHeader.Hash),
-		ParentHash: ethcommon.HexToHash(blockHeader.BlockHeader.ParentHash),
+		Hash:       hash,
+		ParentHash: parentHash,
 		Number:     blockNumber,
 		Timestamp:  blockHeader.BlockHeader.Time,
 	}
@@ -83,8 +152,14 @@ func (wac *WalletChainAccountClient) GetBlockInfo(blockNumber *big.Int) ([]*acco
 		Height: blockNumber.Int64(),
 		ViewTx: true,
 	}
-	blockInfo, err := wac.AccountRpClient.GetBlockByNumber(wac.Ctx, req)
-	if blockInfo.Code == common.ReturnCode_ERROR {
+	blockInfo, err := read(wac, func() (*account.BlockResponse, error) {
+		resp, err := wac.AccountRpClient.GetBlockByNumber(wac.Ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return resp, responseError(resp.Code, resp.Msg)
+	})
+	if err != nil {
 		log.Error("get block info fail", "err", err)
 		return nil, err
 	}
@@ -97,23 +172,58 @@ func (wac *WalletChainAccountClient) GetTransactionByHash(hash string) (*account
 		Network: "mainnet",
 		Hash:    hash,
 	}
-	txInfo, err := wac.AccountRpClient.GetTxByHash(wac.Ctx, req)
-	if txInfo.Code == common.ReturnCode_ERROR {
-		log.Error("get block info fail", "err", err)
+	txInfo, err := read(wac, func() (*account.TxHashResponse, error) {
+		resp, err := wac.AccountRpClient.GetTxByHash(wac.Ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return resp, responseError(resp.Code, resp.Msg)
+	})
+	if err != nil {
+		log.Error("get transaction by hash fail", "err", err)
 		return nil, err
 	}
 	return txInfo.Tx, nil
 }
 
+// GetTxByAddress 分页查询地址的历史交易，page 从 1 开始
+func (wac *WalletChainAccountClient) GetTxByAddress(address string, page, pageSize uint32) ([]*account.TxMessage, error) {
+	req := &account.TxAddressRequest{
+		Chain:    wac.ChainName,
+		Network:  "mainnet",
+		Address:  address,
+		Page:     page,
+		Pagesize: pageSize,
+	}
+	txList, err := read(wac, func() (*account.TxAddressResponse, error) {
+		resp, err := wac.AccountRpClient.GetTxByAddress(wac.Ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return resp, responseError(resp.Code, resp.Msg)
+	})
+	if err != nil {
+		log.Error("get tx by address fail", "err", err)
+		return nil, err
+	}
+	return txList.Tx, nil
+}
+
 func (wac *WalletChainAccountClient) GetAccount(address string) (int, error) {
 	req := &account.AccountRequest{
 		Chain:   wac.ChainName,
 		Network: "mainnet",
 		Address: address,
 	}
-	accountInfo, err := wac.AccountRpClient.GetAccount(wac.Ctx, req)
-	if accountInfo.Code == common.ReturnCode_ERROR {
-		log.Error("get block info fail", "err", err)
+	accountInfo, err := read(wac, func() (*account.AccountResponse, error) {
+		resp, err := wac.AccountRpClient.GetAccount(wac.Ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return resp, responseError(resp.Code, resp.Msg)
+	})
+	if err != nil {
+		log.Error("get account fail", "err", err)
 		return 0, err
 	}
 	return strconv.Atoi(accountInfo.AccountNumber)
@@ -125,10 +235,67 @@ func (wac *WalletChainAccountClient) SendTx(rawTx string) (string, error) {
 		Network: "mainnet",
 		RawTx:   rawTx,
 	}
+	// 广播不重试，调用失败时结果未知，由发送任务下一轮用同一笔签名交易重新广播；
+	// 只有节点返回的失败响应才是明确拒绝
 	txInfo, err := wac.AccountRpClient.SendTx(wac.Ctx, req)
-	if txInfo.Code == common.ReturnCode_ERROR {
-		log.Error("get block info fail", "err", err)
+	if err != nil {
+		log.Error("send tx fail", "err", err)
+		return "", fmt.Errorf("%w: %v", ErrBroadcastUnknown, err)
+	}
+	if err := responseError(txInfo.Code, txInfo.Msg); err != nil {
+		log.Error("send tx fail", "err", err)
 		return "", err
 	}
 	return txInfo.TxHash, nil
 }
+
+// DecodeTransaction 由 chain-account 解析签名交易，返回的 base64 json 与构建交易时的结构一致
+func (wac *WalletChainAccountClient) DecodeTransaction(rawTx string) (*DecodedTx, error) {
+	req := &account.DecodeTransactionRequest{
+		Chain:   wac.ChainName,
+		Network: "mainnet",
+		RawTx:   rawTx,
+	}
+	resp, err := read(wac, func() (*account.DecodeTransactionResponse, error) {
+		resp, err := wac.AccountRpClient.DecodeTransaction(wac.Ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return resp, responseError(resp.Code, resp.Msg)
+	})
+	if err != nil {
+		log.Error("decode transaction fail", "err", err)
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(resp.Base64Tx)
+	if err != nil {
+		return nil, fmt.Errorf("decode transaction base64: %w", err)
+	}
+	var decoded DecodedTx
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("decode transaction json: %w", err)
+	}
+	return &decoded, nil
+}
+
+// VerifySignedTransaction 校验签名交易是否由 publicKey 对应的私钥签名
+func (wac *WalletChainAccountClient) VerifySignedTransaction(publicKey, signedTx string) (bool, error) {
+	req := &account.VerifyTransactionRequest{
+		Chain:     wac.ChainName,
+		Network:   "mainnet",
+		PublicKey: publicKey,
+		Signature: signedTx,
+	}
+	resp, err := read(wac, func() (*account.VerifyTransactionResponse, error) {
+		resp, err := wac.AccountRpClient.VerifySignedTransaction(wac.Ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return resp, responseError(resp.Code, resp.Msg)
+	})
+	if err != nil {
+		log.Error("verify signed transaction fail", "err", err)
+		return false, err
+	}
+	return resp.Verify, nil
+}
@@ -0,0 +1,377 @@
+package rpcclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/CavnHan/multichain-sync-account/common/clock"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/common"
+)
+
+// Endpoint 一个 chain-account 服务节点
+type Endpoint struct {
+	Name   string
+	Client account.WalletAccountServiceClient
+}
+
+type EndpointsConfig struct {
+	Chain            string
+	Timeout          time.Duration // 调用方没有设置 deadline 时的单次调用超时
+	BroadcastTimeout time.Duration // 发送交易的超时，不受 Timeout 限制，默认 2 分钟
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	MaxLag           uint64        // 落后最高节点超过该块数视为不健康
+	CheckInterval    time.Duration // 节点间链头比对周期，0 不比对
+	Clock            clock.Clock
+	OnDivergence     func(Divergence) // 默认打印错误日志
+}
+
+const (
+	DivergenceLag  = "lag"
+	DivergenceFork = "fork"
+)
+
+// Divergence 节点间链头不一致，Lag 为节点落后过多，Fork 为同一高度区块哈希不同
+type Divergence struct {
+	Reason string
+	Height uint64            // Lag 时为最高节点的高度，Fork 时为比较哈希的高度
+	Heads  map[string]uint64 // 节点 -> 最新高度
+	Hashes map[string]string // 节点 -> Height 高度的区块哈希，只在 Fork 时填充
+}
+
+type endpoint struct {
+	Endpoint
+	breaker *breaker
+	lagging atomic.Bool
+}
+
+// Endpoints 多个 chain-account 节点，调用固定发往当前节点，传输错误后切换到下一个健康节点。
+// 熔断中或落后过多的节点被跳过，所有节点都不可用时直接返回 Unavailable
+type Endpoints struct {
+	cfg       EndpointsConfig
+	endpoints []*endpoint
+	current   atomic.Int32
+
+	mu   sync.Mutex
+	loop *clock.LoopFn
+}
+
+func NewEndpoints(endpoints []Endpoint, cfg EndpointsConfig) (*Endpoints, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no chain account endpoint")
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.SystemClock
+	}
+	if cfg.BreakerThreshold <= 0 {
+		cfg.BreakerThreshold = 5
+	}
+	if cfg.BreakerCooldown <= 0 {
+		cfg.BreakerCooldown = 30 * time.Second
+	}
+	if cfg.BroadcastTimeout <= 0 {
+		cfg.BroadcastTimeout = 2 * time.Minute
+	}
+	if cfg.OnDivergence == nil {
+		cfg.OnDivergence = func(d Divergence) {
+			log.Error("chain account endpoints diverged", "reason", d.Reason, "height", d.Height, "heads", d.Heads, "hashes", d.Hashes)
+		}
+	}
+	e := &Endpoints{cfg: cfg}
+	for _, ep := range endpoints {
+		e.endpoints = append(e.endpoints, &endpoint{
+			Endpoint: ep,
+			breaker:  &breaker{clock: cfg.Clock, threshold: cfg.BreakerThreshold, cooldown: cfg.BreakerCooldown},
+		})
+	}
+	return e, nil
+}
+
+// Start 多于一个节点时定期比对链头
+func (e *Endpoints) Start() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.loop != nil || e.cfg.CheckInterval <= 0 || len(e.endpoints) < 2 {
+		return
+	}
+	e.loop = clock.NewLoopFn(e.cfg.Clock, e.CheckDivergence, nil, e.cfg.CheckInterval)
+}
+
+func (e *Endpoints) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.loop == nil {
+		return nil
+	}
+	err := e.loop.Close()
+	e.loop = nil
+	return err
+}
+
+// pick 从当前节点开始找第一个可用节点，优先不落后的节点
+func (e *Endpoints) pick() (int, *endpoint, error) {
+	start := int(e.current.Load())
+	for _, allowLagging := range []bool{false, true} {
+		for k := range e.endpoints {
+			i := (start + k) % len(e.endpoints)
+			ep := e.endpoints[i]
+			if (!allowLagging && ep.lagging.Load()) || !ep.breaker.allow() {
+				continue
+			}
+			if i != start && e.current.CompareAndSwap(int32(start), int32(i)) {
+				log.Warn("chain account failover", "from", e.endpoints[start].Name, "to", ep.Name)
+			}
+			return i, ep, nil
+		}
+	}
+	return 0, nil, status.Error(codes.Unavailable, "all chain account endpoints are unavailable")
+}
+
+// report 传输错误计入熔断并把当前节点切到下一个，其余结果视为节点健康
+func (e *Endpoints) report(i int, err error) {
+	ep := e.endpoints[i]
+	if !IsTransportError(err) {
+		ep.breaker.success()
+		return
+	}
+	log.Warn("chain account endpoint call fail", "endpoint", ep.Name, "err", err)
+	if ep.breaker.failure() {
+		log.Error("chain account endpoint circuit open", "endpoint", ep.Name, "cooldown", e.cfg.BreakerCooldown)
+	}
+	e.current.CompareAndSwap(int32(i), int32((i+1)%len(e.endpoints)))
+}
+
+func (e *Endpoints) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || e.cfg.Timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, e.cfg.Timeout)
+}
+
+func invoke[Resp any](e *Endpoints, ctx context.Context, call func(ctx context.Context, c account.WalletAccountServiceClient) (Resp, error)) (Resp, error) {
+	i, ep, err := e.pick()
+	if err != nil {
+		var zero Resp
+		return zero, err
+	}
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	resp, err := call(ctx, ep.Client)
+	e.report(i, err)
+	return resp, err
+}
+
+// header 直接访问指定节点，不经过节点选择
+func (e *Endpoints) header(ctx context.Context, i int, height uint64) (uint64, string, error) {
+	ctx, cancel := e.withTimeout(ctx)
+	defer cancel()
+	resp, err := e.endpoints[i].Client.GetBlockHeaderByNumber(ctx, &account.BlockHeaderNumberRequest{
+		Chain:   e.cfg.Chain,
+		Network: "mainnet",
+		Height:  int64(height),
+	})
+	e.report(i, err)
+	if err != nil {
+		return 0, "", err
+	}
+	if resp.Code == common.ReturnCode_ERROR || resp.BlockHeader == nil {
+		return 0, "", fmt.Errorf("get block header fail: %s", resp.Msg)
+	}
+	number, err := strconv.ParseUint(resp.BlockHeader.Number, 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid block number %q: %w", resp.BlockHeader.Number, err)
+	}
+	return number, resp.BlockHeader.Hash, nil
+}
+
+// CheckDivergence 比对各节点最新高度，落后超过 MaxLag 的节点标记为不健康；
+// 再在所有节点都有的最低高度上比对区块哈希，不一致说明有节点在分叉上
+func (e *Endpoints) CheckDivergence(ctx context.Context) {
+	heads := make(map[string]uint64)
+	hashes := make(map[int]string)
+	heights := make(map[int]uint64)
+	var best, lowest uint64
+	for i, ep := range e.endpoints {
+		number, hash, err := e.header(ctx, i, 0)
+		if err != nil {
+			log.Warn("get chain head fail", "endpoint", ep.Name, "err", err)
+			continue
+		}
+		heads[ep.Name], heights[i], hashes[i] = number, number, hash
+		if number > best {
+			best = number
+		}
+		if len(heights) == 1 || number < lowest {
+			lowest = number
+		}
+	}
+	if len(heights) < 2 {
+		return
+	}
+
+	lagging := false
+	for i, number := range heights {
+		ep := e.endpoints[i]
+		lag := best-number > e.cfg.MaxLag
+		if ep.lagging.Swap(lag) != lag {
+			log.Info("chain account endpoint health changed", "endpoint", ep.Name, "head", number, "best", best, "lagging", lag)
+		}
+		lagging = lagging || lag
+	}
+	if lagging {
+		e.cfg.OnDivergence(Divergence{Reason: DivergenceLag, Height: best, Heads: heads})
+	}
+
+	forkHashes := make(map[string]string)
+	distinct := make(map[string]struct{})
+	for i, number := range heights {
+		ep := e.endpoints[i]
+		hash := hashes[i]
+		if number != lowest {
+			var err error
+			if _, hash, err = e.header(ctx, i, lowest); err != nil {
+				log.Warn("get block header fail", "endpoint", ep.Name, "height", lowest, "err", err)
+				continue
+			}
+		}
+		forkHashes[ep.Name] = hash
+		distinct[hash] = struct{}{}
+	}
+	if len(distinct) > 1 {
+		e.cfg.OnDivergence(Divergence{Reason: DivergenceFork, Height: lowest, Heads: heads, Hashes: forkHashes})
+	}
+}
+
+func (e *Endpoints) GetSupportChains(ctx context.Context, in *account.SupportChainsRequest, opts ...grpc.CallOption) (*account.SupportChainsResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.SupportChainsResponse, error) {
+		return c.GetSupportChains(ctx, in, opts...)
+	})
+}
+
+func (e *Endpoints) ConvertAddress(ctx context.Context, in *account.ConvertAddressRequest, opts ...grpc.CallOption) (*account.ConvertAddressResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.ConvertAddressResponse, error) {
+		return c.ConvertAddress(ctx, in, opts...)
+	})
+}
+
+func (e *Endpoints) ValidAddress(ctx context.Context, in *account.ValidAddressRequest, opts ...grpc.CallOption) (*account.ValidAddressResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.ValidAddressResponse, error) {
+		return c.ValidAddress(ctx, in, opts...)
+	})
+}
+
+func (e *Endpoints) GetBlockByNumber(ctx context.Context, in *account.BlockNumberRequest, opts ...grpc.CallOption) (*account.BlockResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.BlockResponse, error) {
+		return c.GetBlockByNumber(ctx, in, opts...)
+	})
+}
+
+func (e *Endpoints) GetBlockByHash(ctx context.Context, in *account.BlockHashRequest, opts ...grpc.CallOption) (*account.BlockResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.BlockResponse, error) {
+		return c.GetBlockByHash(ctx, in, opts...)
+	})
+}
+
+func (e *Endpoints) GetBlockHeaderByHash(ctx context.Context, in *account.BlockHeaderHashRequest, opts ...grpc.CallOption) (*account.BlockHeaderResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.BlockHeaderResponse, error) {
+		return c.GetBlockHeaderByHash(ctx, in, opts...)
+	})
+}
+
+func (e *Endpoints) GetBlockHeaderByNumber(ctx context.Context, in *account.BlockHeaderNumberRequest, opts ...grpc.CallOption) (*account.BlockHeaderResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.BlockHeaderResponse, error) {
+		return c.GetBlockHeaderByNumber(ctx, in, opts...)
+	})
+}
+
+func (e *Endpoints) GetBlockHeaderByRange(ctx context.Context, in *account.BlockByRangeRequest, opts ...grpc.CallOption) (*account.BlockByRangeResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.BlockByRangeResponse, error) {
+		return c.GetBlockHeaderByRange(ctx, in, opts...)
+	})
+}
+
+func (e *Endpoints) GetAccount(ctx context.Context, in *account.AccountRequest, opts ...grpc.CallOption) (*account.AccountResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.AccountResponse, error) {
+		return c.GetAccount(ctx, in, opts...)
+	})
+}
+
+func (e *Endpoints) GetFee(ctx context.Context, in *account.FeeRequest, opts ...grpc.CallOption) (*account.FeeResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.FeeResponse, error) {
+		return c.GetFee(ctx, in, opts...)
+	})
+}
+
+// SendTx 广播交易使用 BroadcastTimeout，传输错误时交易可能已被节点接收，
+// 不计入熔断也不切换节点，由调用方按广播结果未知处理
+func (e *Endpoints) SendTx(ctx context.Context, in *account.SendTxRequest, opts ...grpc.CallOption) (*account.SendTxResponse, error) {
+	i, ep, err := e.pick()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.cfg.BroadcastTimeout)
+		defer cancel()
+	}
+	resp, err := ep.Client.SendTx(ctx, in, opts...)
+	if IsTransportError(err) {
+		log.Warn("chain account broadcast outcome unknown", "endpoint", ep.Name, "err", err)
+		return resp, err
+	}
+	e.report(i, err)
+	return resp, err
+}
+
+func (e *Endpoints) GetTxByAddress(ctx context.Context, in *account.TxAddressRequest, opts ...grpc.CallOption) (*account.TxAddressResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.TxAddressResponse, error) {
+		return c.GetTxByAddress(ctx, in, opts...)
+	})
+}
+
+func (e *Endpoints) GetTxByHash(ctx context.Context, in *account.TxHashRequest, opts ...grpc.CallOption) (*account.TxHashResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.TxHashResponse, error) {
+		return c.GetTxByHash(ctx, in, opts...)
+	})
+}
+
+func (e *Endpoints) CreateUnSignTransaction(ctx context.Context, in *account.UnSignTransactionRequest, opts ...grpc.CallOption) (*account.UnSignTransactionResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.UnSignTransactionResponse, error) {
+		return c.CreateUnSignTransaction(ctx, in, opts...)
+	})
+}
+
+func (e *Endpoints) BuildSignedTransaction(ctx context.Context, in *account.SignedTransactionRequest, opts ...grpc.CallOption) (*account.SignedTransactionResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.SignedTransactionResponse, error) {
+		return c.BuildSignedTransaction(ctx, in, opts...)
+	})
+}
+
+func (e *Endpoints) DecodeTransaction(ctx context.Context, in *account.DecodeTransactionRequest, opts ...grpc.CallOption) (*account.DecodeTransactionResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.DecodeTransactionResponse, error) {
+		return c.DecodeTransaction(ctx, in, opts...)
+	})
+}
+
+func (e *Endpoints) VerifySignedTransaction(ctx context.Context, in *account.VerifyTransactionRequest, opts ...grpc.CallOption) (*account.VerifyTransactionResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.VerifyTransactionResponse, error) {
+		return c.VerifySignedTransaction(ctx, in, opts...)
+	})
+}
+
+func (e *Endpoints) GetExtraData(ctx context.Context, in *account.ExtraDataRequest, opts ...grpc.CallOption) (*account.ExtraDataResponse, error) {
+	return invoke(e, ctx, func(ctx context.Context, c account.WalletAccountServiceClient) (*account.ExtraDataResponse, error) {
+		return c.GetExtraData(ctx, in, opts...)
+	})
+}
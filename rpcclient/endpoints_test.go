@@ -0,0 +1,243 @@
+package rpcclient
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/CavnHan/multichain-sync-account/common/clock"
+	"github.com/CavnHan/multichain-sync-account/common/retry"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/common"
+)
+
+// fakeNode 链头高度为 head，down 时返回 Unavailable，forkFrom 之后的区块哈希与其他节点不同
+type fakeNode struct {
+	account.WalletAccountServiceClient
+
+	mu       sync.Mutex
+	head     int64
+	down     bool
+	forkFrom int64
+	calls    int
+	sent     []time.Duration // SendTx 调用时剩余的超时
+}
+
+func (n *fakeNode) setDown(down bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.down = down
+}
+
+func (n *fakeNode) callCount() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls
+}
+
+func (n *fakeNode) GetBlockHeaderByNumber(_ context.Context, in *account.BlockHeaderNumberRequest, _ ...grpc.CallOption) (*account.BlockHeaderResponse, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls++
+	if n.down {
+		return nil, status.Error(codes.Unavailable, "connection refused")
+	}
+	height := in.Height
+	if height == 0 {
+		height = n.head
+	}
+	if height > n.head {
+		return &account.BlockHeaderResponse{Code: common.ReturnCode_ERROR, Msg: "block not found"}, nil
+	}
+	hash := fmt.Sprintf("0x%064x", height)
+	if n.forkFrom > 0 && height >= n.forkFrom {
+		hash = fmt.Sprintf("0x%064x", height<<32)
+	}
+	return &account.BlockHeaderResponse{
+		Code:        common.ReturnCode_SUCCESS,
+		BlockHeader: &account.BlockHeader{Number: fmt.Sprint(height), Hash: hash, ParentHash: fmt.Sprintf("0x%064x", height-1)},
+	}, nil
+}
+
+func (n *fakeNode) SendTx(ctx context.Context, in *account.SendTxRequest, _ ...grpc.CallOption) (*account.SendTxResponse, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	deadline, _ := ctx.Deadline()
+	n.sent = append(n.sent, time.Until(deadline))
+	if n.down {
+		return nil, status.Error(codes.DeadlineExceeded, "context deadline exceeded")
+	}
+	switch in.RawTx {
+	case "0xbad":
+		return &account.SendTxResponse{Code: common.ReturnCode_ERROR, Msg: "invalid transaction"}, nil
+	case "0xknown":
+		return &account.SendTxResponse{Code: common.ReturnCode_ERROR, Msg: "already known"}, nil
+	}
+	return &account.SendTxResponse{Code: common.ReturnCode_SUCCESS, TxHash: "0x01"}, nil
+}
+
+func newTestEndpoints(t *testing.T, clk clock.Clock, onDivergence func(Divergence), nodes ...*fakeNode) *Endpoints {
+	var endpoints []Endpoint
+	for i, node := range nodes {
+		endpoints = append(endpoints, Endpoint{Name: fmt.Sprintf("node-%d", i), Client: node})
+	}
+	e, err := NewEndpoints(endpoints, EndpointsConfig{
+		Chain:            "Ethereum",
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Minute,
+		MaxLag:           5,
+		Clock:            clk,
+		OnDivergence:     onDivergence,
+	})
+	require.NoError(t, err)
+	return e
+}
+
+func newTestClient(t *testing.T, rpc account.WalletAccountServiceClient) *WalletChainAccountClient {
+	client, err := NewWalletChainAccountClient(context.Background(), rpc, "Ethereum")
+	require.NoError(t, err)
+	client.RetryStrategy = retry.Fixed(0)
+	return client
+}
+
+func TestEndpointsFailover(t *testing.T) {
+	clk := clock.NewDeterministicClock(time.Unix(1_700_000_000, 0))
+	primary, backup := &fakeNode{head: 100}, &fakeNode{head: 100}
+	client := newTestClient(t, newTestEndpoints(t, clk, nil, primary, backup))
+
+	// 首选节点故障时重试切到备用节点
+	primary.setDown(true)
+	header, err := client.GetBlockHeader(nil)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(100), header.Number)
+	require.Equal(t, 1, primary.callCount())
+
+	// 切换后固定使用备用节点
+	_, err = client.GetBlockHeader(big.NewInt(99))
+	require.NoError(t, err)
+	require.Equal(t, 1, primary.callCount())
+
+	// 业务错误不重试也不切换节点
+	_, err = client.GetBlockHeader(big.NewInt(101))
+	require.ErrorContains(t, err, "block not found")
+	require.Equal(t, 3, backup.callCount())
+}
+
+func TestEndpointsCircuitBreaker(t *testing.T) {
+	clk := clock.NewDeterministicClock(time.Unix(1_700_000_000, 0))
+	node := &fakeNode{head: 100}
+	client := newTestClient(t, newTestEndpoints(t, clk, nil, node))
+
+	// 传输错误返回错误而不是 panic，连续失败达到阈值后熔断
+	node.setDown(true)
+	_, err := client.GetBlockHeader(nil)
+	require.Error(t, err)
+	require.Equal(t, 2, node.callCount())
+	_, err = client.GetBlockHeader(nil)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Equal(t, 2, node.callCount())
+
+	// 冷却结束后放行试探，成功即恢复
+	node.setDown(false)
+	clk.AdvanceTime(time.Minute)
+	header, err := client.GetBlockHeader(nil)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(100), header.Number)
+}
+
+func TestEndpointsSendTx(t *testing.T) {
+	primary, backup := &fakeNode{head: 100}, &fakeNode{head: 100}
+	e, err := NewEndpoints([]Endpoint{{Name: "node-0", Client: primary}, {Name: "node-1", Client: backup}}, EndpointsConfig{
+		Chain:            "Ethereum",
+		Timeout:          time.Second,
+		BreakerThreshold: 1,
+	})
+	require.NoError(t, err)
+	client := newTestClient(t, e)
+
+	// 广播不使用普通调用的短超时
+	hash, err := client.SendTx("0x02")
+	require.NoError(t, err)
+	require.Equal(t, "0x01", hash)
+	require.Greater(t, primary.sent[0], time.Minute)
+
+	// 超时后结果未知，不熔断也不切换节点
+	primary.setDown(true)
+	_, err = client.SendTx("0x02")
+	require.ErrorIs(t, err, ErrBroadcastUnknown)
+	primary.setDown(false)
+	_, err = client.SendTx("0x02")
+	require.NoError(t, err)
+	require.Len(t, primary.sent, 3)
+	require.Empty(t, backup.sent)
+
+	// 节点明确拒绝不是结果未知
+	_, err = client.SendTx("0xbad")
+	require.ErrorContains(t, err, "invalid transaction")
+	require.NotErrorIs(t, err, ErrBroadcastUnknown)
+	require.False(t, IsAlreadyKnown(err))
+	_, err = client.SendTx("0xknown")
+	require.True(t, IsAlreadyKnown(err))
+}
+
+func TestEndpointsDivergence(t *testing.T) {
+	clk := clock.NewDeterministicClock(time.Unix(1_700_000_000, 0))
+	var alarms []Divergence
+	primary, lagging := &fakeNode{head: 100}, &fakeNode{head: 90}
+	e := newTestEndpoints(t, clk, func(d Divergence) { alarms = append(alarms, d) }, primary, lagging)
+
+	e.CheckDivergence(context.Background())
+	require.Len(t, alarms, 1)
+	require.Equal(t, DivergenceLag, alarms[0].Reason)
+	require.Equal(t, map[string]uint64{"node-0": 100, "node-1": 90}, alarms[0].Heads)
+
+	// 落后的节点不再被选中，首选节点故障时仍然可以兜底
+	primary.setDown(true)
+	_, err := newTestClient(t, e).GetBlockHeader(nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, lagging.callCount())
+
+	// 高度追上后在共同高度比对哈希
+	alarms = nil
+	primary.setDown(false)
+	lagging.head, lagging.forkFrom = 99, 95
+	e.CheckDivergence(context.Background())
+	require.Len(t, alarms, 1)
+	require.Equal(t, DivergenceFork, alarms[0].Reason)
+	require.Equal(t, uint64(99), alarms[0].Height)
+	require.NotEqual(t, alarms[0].Hashes["node-0"], alarms[0].Hashes["node-1"])
+
+	alarms = nil
+	lagging.forkFrom = 0
+	e.CheckDivergence(context.Background())
+	require.Empty(t, alarms)
+}
+
+func TestEndpointsCloseStopsDivergenceLoop(t *testing.T) {
+	clk := clock.NewDeterministicClock(time.Unix(1_700_000_000, 0))
+	primary, backup := &fakeNode{head: 100}, &fakeNode{head: 100}
+	e, err := NewEndpoints([]Endpoint{{Name: "node-0", Client: primary}, {Name: "node-1", Client: backup}}, EndpointsConfig{
+		Chain:         "Ethereum",
+		CheckInterval: time.Second,
+		Clock:         clk,
+	})
+	require.NoError(t, err)
+	e.Start()
+	clk.AdvanceTime(time.Second)
+	require.Eventually(t, func() bool { return primary.callCount() > 0 }, time.Second, time.Millisecond)
+
+	// Close 等待比对协程退出，之后不再访问节点
+	require.NoError(t, e.Close())
+	calls := primary.callCount()
+	clk.AdvanceTime(time.Minute)
+	require.Equal(t, calls, primary.callCount())
+	require.NoError(t, e.Close())
+}
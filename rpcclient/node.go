@@ -0,0 +1,93 @@
+package rpcclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const defaultNodeTimeout = 30 * time.Second
+
+// Log 链节点返回的合约事件日志
+type Log struct {
+	Address     string   `json:"address"`
+	Topics      []string `json:"topics"`
+	Data        string   `json:"data"`
+	BlockNumber string   `json:"blockNumber"`
+	TxHash      string   `json:"transactionHash"`
+	LogIndex    string   `json:"logIndex"`
+	Removed     bool     `json:"removed"`
+}
+
+// NodeClient 直接访问 EVM 链节点的 JSON-RPC，chain-account 不提供事件日志查询时使用
+type NodeClient struct {
+	url    string
+	client *http.Client
+}
+
+func NewNodeClient(url string) *NodeClient {
+	return &NodeClient{url: url, client: &http.Client{Timeout: defaultNodeTimeout}}
+}
+
+type nodeRequest struct {
+	JsonRpc string        `json:"jsonrpc"`
+	Id      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type nodeResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// FilterLogs 查询 [fromBlock, toBlock] 区间内 topic0 命中任一 topics 的日志
+func (nc *NodeClient) FilterLogs(ctx context.Context, fromBlock, toBlock *big.Int, topics []string) ([]Log, error) {
+	filter := map[string]interface{}{
+		"fromBlock": hexutil.EncodeBig(fromBlock),
+		"toBlock":   hexutil.EncodeBig(toBlock),
+		"topics":    []interface{}{topics},
+	}
+	var logs []Log
+	if err := nc.call(ctx, "eth_getLogs", []interface{}{filter}, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+func (nc *NodeClient) call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	body, err := json.Marshal(nodeRequest{JsonRpc: "2.0", Id: 1, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, nc.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := nc.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected http status %d", method, resp.StatusCode)
+	}
+	var response nodeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return err
+	}
+	if response.Error != nil {
+		return fmt.Errorf("%s: %s (code %d)", method, response.Error.Message, response.Error.Code)
+	}
+	return json.Unmarshal(response.Result, result)
+}
@@ -0,0 +1,3 @@
+// Package replay 录制 chain-account 服务的请求和响应，并在离线时按录制文件回放，
+// 用于在本地复现生产环境同步时看到的链上数据
+package replay
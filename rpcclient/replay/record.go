@@ -0,0 +1,236 @@
+package replay
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/CavnHan/multichain-sync-account/common/clock"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+)
+
+// entry 录制文件中的一行，文件整体为 gzip 压缩的 json lines
+type entry struct {
+	Time     int64           `json:"t"`
+	Method   string          `json:"m"`
+	Request  json.RawMessage `json:"req"`
+	Response json.RawMessage `json:"resp,omitempty"`
+	Code     uint32          `json:"code,omitempty"`
+	Error    string          `json:"err,omitempty"`
+}
+
+// Recorder 透传所有调用并把请求和响应写入录制文件，超过录制窗口后只透传不再写入
+type Recorder struct {
+	inner  account.WalletAccountServiceClient
+	clock  clock.Clock
+	window time.Duration
+
+	mu      sync.Mutex
+	started time.Time
+	file    *os.File
+	zw      *gzip.Writer
+	count   int
+}
+
+// NewRecorder window 为 0 时一直录制到 Close
+func NewRecorder(inner account.WalletAccountServiceClient, path string, window time.Duration, clk clock.Clock) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open record file: %w", err)
+	}
+	log.Info("recording chain account calls", "path", path, "window", window)
+	return &Recorder{
+		inner:   inner,
+		clock:   clk,
+		window:  window,
+		started: clk.Now(),
+		file:    file,
+		zw:      gzip.NewWriter(file),
+	}, nil
+}
+
+// Close 结束录制，已录制的内容全部落盘
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.finish()
+}
+
+func (r *Recorder) finish() error {
+	if r.file == nil {
+		return nil
+	}
+	err := errors.Join(r.zw.Close(), r.file.Close())
+	log.Info("chain account recording finished", "path", r.file.Name(), "calls", r.count)
+	r.file, r.zw = nil, nil
+	return err
+}
+
+func (r *Recorder) record(method string, in proto.Message, out proto.Message, callErr error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return
+	}
+	now := r.clock.Now()
+	if r.window > 0 && now.Sub(r.started) > r.window {
+		if err := r.finish(); err != nil {
+			log.Error("close record file fail", "err", err)
+		}
+		return
+	}
+
+	e := entry{Time: now.UnixMilli(), Method: method}
+	var err error
+	if e.Request, err = protojson.Marshal(in); err != nil {
+		log.Error("marshal recorded request fail", "method", method, "err", err)
+		return
+	}
+	if callErr != nil {
+		st := status.Convert(callErr)
+		e.Code, e.Error = uint32(st.Code()), st.Message()
+	} else if e.Response, err = protojson.Marshal(out); err != nil {
+		log.Error("marshal recorded response fail", "method", method, "err", err)
+		return
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		log.Error("marshal record entry fail", "method", method, "err", err)
+		return
+	}
+	// 每条都 flush，进程异常退出时最多丢失最后一条
+	if _, err := r.zw.Write(append(line, '\n')); err != nil {
+		log.Error("write record file fail", "err", err)
+		return
+	}
+	if err := r.zw.Flush(); err != nil {
+		log.Error("flush record file fail", "err", err)
+		return
+	}
+	r.count++
+}
+
+func call[Req, Resp proto.Message](r *Recorder, method string, in Req, invoke func() (Resp, error)) (Resp, error) {
+	out, err := invoke()
+	r.record(method, in, out, err)
+	return out, err
+}
+
+func (r *Recorder) GetSupportChains(ctx context.Context, in *account.SupportChainsRequest, opts ...grpc.CallOption) (*account.SupportChainsResponse, error) {
+	return call(r, account.WalletAccountService_GetSupportChains_FullMethodName, in, func() (*account.SupportChainsResponse, error) {
+		return r.inner.GetSupportChains(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) ConvertAddress(ctx context.Context, in *account.ConvertAddressRequest, opts ...grpc.CallOption) (*account.ConvertAddressResponse, error) {
+	return call(r, account.WalletAccountService_ConvertAddress_FullMethodName, in, func() (*account.ConvertAddressResponse, error) {
+		return r.inner.ConvertAddress(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) ValidAddress(ctx context.Context, in *account.ValidAddressRequest, opts ...grpc.CallOption) (*account.ValidAddressResponse, error) {
+	return call(r, account.WalletAccountService_ValidAddress_FullMethodName, in, func() (*account.ValidAddressResponse, error) {
+		return r.inner.ValidAddress(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) GetBlockByNumber(ctx context.Context, in *account.BlockNumberRequest, opts ...grpc.CallOption) (*account.BlockResponse, error) {
+	return call(r, account.WalletAccountService_GetBlockByNumber_FullMethodName, in, func() (*account.BlockResponse, error) {
+		return r.inner.GetBlockByNumber(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) GetBlockByHash(ctx context.Context, in *account.BlockHashRequest, opts ...grpc.CallOption) (*account.BlockResponse, error) {
+	return call(r, account.WalletAccountService_GetBlockByHash_FullMethodName, in, func() (*account.BlockResponse, error) {
+		return r.inner.GetBlockByHash(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) GetBlockHeaderByHash(ctx context.Context, in *account.BlockHeaderHashRequest, opts ...grpc.CallOption) (*account.BlockHeaderResponse, error) {
+	return call(r, account.WalletAccountService_GetBlockHeaderByHash_FullMethodName, in, func() (*account.BlockHeaderResponse, error) {
+		return r.inner.GetBlockHeaderByHash(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) GetBlockHeaderByNumber(ctx context.Context, in *account.BlockHeaderNumberRequest, opts ...grpc.CallOption) (*account.BlockHeaderResponse, error) {
+	return call(r, account.WalletAccountService_GetBlockHeaderByNumber_FullMethodName, in, func() (*account.BlockHeaderResponse, error) {
+		return r.inner.GetBlockHeaderByNumber(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) GetBlockHeaderByRange(ctx context.Context, in *account.BlockByRangeRequest, opts ...grpc.CallOption) (*account.BlockByRangeResponse, error) {
+	return call(r, account.WalletAccountService_GetBlockHeaderByRange_FullMethodName, in, func() (*account.BlockByRangeResponse, error) {
+		return r.inner.GetBlockHeaderByRange(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) GetAccount(ctx context.Context, in *account.AccountRequest, opts ...grpc.CallOption) (*account.AccountResponse, error) {
+	return call(r, account.WalletAccountService_GetAccount_FullMethodName, in, func() (*account.AccountResponse, error) {
+		return r.inner.GetAccount(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) GetFee(ctx context.Context, in *account.FeeRequest, opts ...grpc.CallOption) (*account.FeeResponse, error) {
+	return call(r, account.WalletAccountService_GetFee_FullMethodName, in, func() (*account.FeeResponse, error) {
+		return r.inner.GetFee(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) SendTx(ctx context.Context, in *account.SendTxRequest, opts ...grpc.CallOption) (*account.SendTxResponse, error) {
+	return call(r, account.WalletAccountService_SendTx_FullMethodName, in, func() (*account.SendTxResponse, error) {
+		return r.inner.SendTx(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) GetTxByAddress(ctx context.Context, in *account.TxAddressRequest, opts ...grpc.CallOption) (*account.TxAddressResponse, error) {
+	return call(r, account.WalletAccountService_GetTxByAddress_FullMethodName, in, func() (*account.TxAddressResponse, error) {
+		return r.inner.GetTxByAddress(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) GetTxByHash(ctx context.Context, in *account.TxHashRequest, opts ...grpc.CallOption) (*account.TxHashResponse, error) {
+	return call(r, account.WalletAccountService_GetTxByHash_FullMethodName, in, func() (*account.TxHashResponse, error) {
+		return r.inner.GetTxByHash(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) CreateUnSignTransaction(ctx context.Context, in *account.UnSignTransactionRequest, opts ...grpc.CallOption) (*account.UnSignTransactionResponse, error) {
+	return call(r, account.WalletAccountService_CreateUnSignTransaction_FullMethodName, in, func() (*account.UnSignTransactionResponse, error) {
+		return r.inner.CreateUnSignTransaction(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) BuildSignedTransaction(ctx context.Context, in *account.SignedTransactionRequest, opts ...grpc.CallOption) (*account.SignedTransactionResponse, error) {
+	return call(r, account.WalletAccountService_BuildSignedTransaction_FullMethodName, in, func() (*account.SignedTransactionResponse, error) {
+		return r.inner.BuildSignedTransaction(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) DecodeTransaction(ctx context.Context, in *account.DecodeTransactionRequest, opts ...grpc.CallOption) (*account.DecodeTransactionResponse, error) {
+	return call(r, account.WalletAccountService_DecodeTransaction_FullMethodName, in, func() (*account.DecodeTransactionResponse, error) {
+		return r.inner.DecodeTransaction(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) VerifySignedTransaction(ctx context.Context, in *account.VerifyTransactionRequest, opts ...grpc.CallOption) (*account.VerifyTransactionResponse, error) {
+	return call(r, account.WalletAccountService_VerifySignedTransaction_FullMethodName, in, func() (*account.VerifyTransactionResponse, error) {
+		return r.inner.VerifySignedTransaction(ctx, in, opts...)
+	})
+}
+
+func (r *Recorder) GetExtraData(ctx context.Context, in *account.ExtraDataRequest, opts ...grpc.CallOption) (*account.ExtraDataResponse, error) {
+	return call(r, account.WalletAccountService_GetExtraData_FullMethodName, in, func() (*account.ExtraDataResponse, error) {
+		return r.inner.GetExtraData(ctx, in, opts...)
+	})
+}
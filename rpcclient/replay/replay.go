@@ -0,0 +1,190 @@
+package replay
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+)
+
+// Replayer 按录制文件应答，不访问网络。相同请求按录制顺序依次返回响应，
+// 用完后重复最后一个，没有录制过的请求返回 NotFound
+type Replayer struct {
+	mu      sync.Mutex
+	entries map[string][]entry             // method -> 录制顺序的调用
+	index   map[string]map[string][]*entry // method -> 请求 -> 待返回的响应
+}
+
+// Load 读取录制文件，文件末尾不完整的一行（录制进程异常退出）会被忽略
+func Load(path string) (*Replayer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open replay file: %w", err)
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("read replay file: %w", err)
+	}
+	r := &Replayer{entries: make(map[string][]entry), index: make(map[string]map[string][]*entry)}
+	scanner := bufio.NewScanner(zr)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	count := 0
+	for scanner.Scan() {
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			log.Warn("stop at broken replay entry", "line", count+1, "err", err)
+			break
+		}
+		r.entries[e.Method] = append(r.entries[e.Method], e)
+		count++
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("read replay file: %w", err)
+	}
+	log.Info("loaded chain account recording", "path", path, "calls", count)
+	return r, nil
+}
+
+// canonical 请求的确定性编码，作为匹配录制请求的 key
+func canonical(msg proto.Message) (string, error) {
+	key, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	return string(key), err
+}
+
+// lookup 首次调用某个方法时用请求的具体类型解析该方法的录制请求并建立索引
+func (r *Replayer) lookup(method string, in proto.Message) (*entry, error) {
+	key, err := canonical(in)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	byRequest, ok := r.index[method]
+	if !ok {
+		byRequest = make(map[string][]*entry)
+		for i := range r.entries[method] {
+			e := &r.entries[method][i]
+			req := in.ProtoReflect().New().Interface()
+			if err := protojson.Unmarshal(e.Request, req); err != nil {
+				return nil, fmt.Errorf("decode recorded %s request: %w", method, err)
+			}
+			k, err := canonical(req)
+			if err != nil {
+				return nil, err
+			}
+			byRequest[k] = append(byRequest[k], e)
+		}
+		r.index[method] = byRequest
+	}
+	pending := byRequest[key]
+	if len(pending) == 0 {
+		return nil, status.Errorf(codes.NotFound, "replay: no recorded %s call for %s", method, protojson.Format(in))
+	}
+	if len(pending) > 1 {
+		byRequest[key] = pending[1:]
+	}
+	return pending[0], nil
+}
+
+func serve[Resp proto.Message](r *Replayer, method string, in proto.Message, out Resp) (Resp, error) {
+	var zero Resp
+	e, err := r.lookup(method, in)
+	if err != nil {
+		return zero, err
+	}
+	if e.Error != "" || e.Code != 0 {
+		return zero, status.Error(codes.Code(e.Code), e.Error)
+	}
+	if err := protojson.Unmarshal(e.Response, out); err != nil {
+		return zero, fmt.Errorf("decode recorded %s response: %w", method, err)
+	}
+	return out, nil
+}
+
+func (r *Replayer) GetSupportChains(_ context.Context, in *account.SupportChainsRequest, _ ...grpc.CallOption) (*account.SupportChainsResponse, error) {
+	return serve(r, account.WalletAccountService_GetSupportChains_FullMethodName, in, &account.SupportChainsResponse{})
+}
+
+func (r *Replayer) ConvertAddress(_ context.Context, in *account.ConvertAddressRequest, _ ...grpc.CallOption) (*account.ConvertAddressResponse, error) {
+	return serve(r, account.WalletAccountService_ConvertAddress_FullMethodName, in, &account.ConvertAddressResponse{})
+}
+
+func (r *Replayer) ValidAddress(_ context.Context, in *account.ValidAddressRequest, _ ...grpc.CallOption) (*account.ValidAddressResponse, error) {
+	return serve(r, account.WalletAccountService_ValidAddress_FullMethodName, in, &account.ValidAddressResponse{})
+}
+
+func (r *Replayer) GetBlockByNumber(_ context.Context, in *account.BlockNumberRequest, _ ...grpc.CallOption) (*account.BlockResponse, error) {
+	return serve(r, account.WalletAccountService_GetBlockByNumber_FullMethodName, in, &account.BlockResponse{})
+}
+
+func (r *Replayer) GetBlockByHash(_ context.Context, in *account.BlockHashRequest, _ ...grpc.CallOption) (*account.BlockResponse, error) {
+	return serve(r, account.WalletAccountService_GetBlockByHash_FullMethodName, in, &account.BlockResponse{})
+}
+
+func (r *Replayer) GetBlockHeaderByHash(_ context.Context, in *account.BlockHeaderHashRequest, _ ...grpc.CallOption) (*account.BlockHeaderResponse, error) {
+	return serve(r, account.WalletAccountService_GetBlockHeaderByHash_FullMethodName, in, &account.BlockHeaderResponse{})
+}
+
+func (r *Replayer) GetBlockHeaderByNumber(_ context.Context, in *account.BlockHeaderNumberRequest, _ ...grpc.CallOption) (*account.BlockHeaderResponse, error) {
+	return serve(r, account.WalletAccountService_GetBlockHeaderByNumber_FullMethodName, in, &account.BlockHeaderResponse{})
+}
+
+func (r *Replayer) GetBlockHeaderByRange(_ context.Context, in *account.BlockByRangeRequest, _ ...grpc.CallOption) (*account.BlockByRangeResponse, error) {
+	return serve(r, account.WalletAccountService_GetBlockHeaderByRange_FullMethodName, in, &account.BlockByRangeResponse{})
+}
+
+func (r *Replayer) GetAccount(_ context.Context, in *account.AccountRequest, _ ...grpc.CallOption) (*account.AccountResponse, error) {
+	return serve(r, account.WalletAccountService_GetAccount_FullMethodName, in, &account.AccountResponse{})
+}
+
+func (r *Replayer) GetFee(_ context.Context, in *account.FeeRequest, _ ...grpc.CallOption) (*account.FeeResponse, error) {
+	return serve(r, account.WalletAccountService_GetFee_FullMethodName, in, &account.FeeResponse{})
+}
+
+// SendTx 只返回录制时的结果，回放时不会广播任何交易
+func (r *Replayer) SendTx(_ context.Context, in *account.SendTxRequest, _ ...grpc.CallOption) (*account.SendTxResponse, error) {
+	return serve(r, account.WalletAccountService_SendTx_FullMethodName, in, &account.SendTxResponse{})
+}
+
+func (r *Replayer) GetTxByAddress(_ context.Context, in *account.TxAddressRequest, _ ...grpc.CallOption) (*account.TxAddressResponse, error) {
+	return serve(r, account.WalletAccountService_GetTxByAddress_FullMethodName, in, &account.TxAddressResponse{})
+}
+
+func (r *Replayer) GetTxByHash(_ context.Context, in *account.TxHashRequest, _ ...grpc.CallOption) (*account.TxHashResponse, error) {
+	return serve(r, account.WalletAccountService_GetTxByHash_FullMethodName, in, &account.TxHashResponse{})
+}
+
+func (r *Replayer) CreateUnSignTransaction(_ context.Context, in *account.UnSignTransactionRequest, _ ...grpc.CallOption) (*account.UnSignTransactionResponse, error) {
+	return serve(r, account.WalletAccountService_CreateUnSignTransaction_FullMethodName, in, &account.UnSignTransactionResponse{})
+}
+
+func (r *Replayer) BuildSignedTransaction(_ context.Context, in *account.SignedTransactionRequest, _ ...grpc.CallOption) (*account.SignedTransactionResponse, error) {
+	return serve(r, account.WalletAccountService_BuildSignedTransaction_FullMethodName, in, &account.SignedTransactionResponse{})
+}
+
+func (r *Replayer) DecodeTransaction(_ context.Context, in *account.DecodeTransactionRequest, _ ...grpc.CallOption) (*account.DecodeTransactionResponse, error) {
+	return serve(r, account.WalletAccountService_DecodeTransaction_FullMethodName, in, &account.DecodeTransactionResponse{})
+}
+
+func (r *Replayer) VerifySignedTransaction(_ context.Context, in *account.VerifyTransactionRequest, _ ...grpc.CallOption) (*account.VerifyTransactionResponse, error) {
+	return serve(r, account.WalletAccountService_VerifySignedTransaction_FullMethodName, in, &account.VerifyTransactionResponse{})
+}
+
+func (r *Replayer) GetExtraData(_ context.Context, in *account.ExtraDataRequest, _ ...grpc.CallOption) (*account.ExtraDataResponse, error) {
+	return serve(r, account.WalletAccountService_GetExtraData_FullMethodName, in, &account.ExtraDataResponse{})
+}
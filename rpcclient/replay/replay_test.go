@@ -0,0 +1,96 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/CavnHan/multichain-sync-account/common/clock"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+)
+
+// stubClient 最新区块高度每次调用加一，其余方法未实现
+type stubClient struct {
+	account.WalletAccountServiceClient
+	latest int64
+	sent   int
+}
+
+func (c *stubClient) GetBlockHeaderByNumber(_ context.Context, in *account.BlockHeaderNumberRequest, _ ...grpc.CallOption) (*account.BlockHeaderResponse, error) {
+	if in.Height == 0 {
+		c.latest++
+		return &account.BlockHeaderResponse{BlockHeader: &account.BlockHeader{Number: itoa(c.latest)}}, nil
+	}
+	return &account.BlockHeaderResponse{BlockHeader: &account.BlockHeader{Number: itoa(in.Height)}}, nil
+}
+
+func (c *stubClient) SendTx(_ context.Context, in *account.SendTxRequest, _ ...grpc.CallOption) (*account.SendTxResponse, error) {
+	c.sent++
+	return &account.SendTxResponse{TxHash: "0x" + in.RawTx}, nil
+}
+
+func (c *stubClient) GetTxByHash(context.Context, *account.TxHashRequest, ...grpc.CallOption) (*account.TxHashResponse, error) {
+	return nil, status.Error(codes.Unavailable, "node down")
+}
+
+func itoa(v int64) string {
+	return fmt.Sprintf("%02d", v)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calls.jsonl.gz")
+	clk := clock.NewDeterministicClock(time.Unix(1_700_000_000, 0))
+	stub := &stubClient{}
+	recorder, err := NewRecorder(stub, path, time.Minute, clk)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	latest := &account.BlockHeaderNumberRequest{Chain: "Ethereum"}
+	for i := 0; i < 2; i++ {
+		_, err := recorder.GetBlockHeaderByNumber(ctx, latest)
+		require.NoError(t, err)
+	}
+	_, err = recorder.GetBlockHeaderByNumber(ctx, &account.BlockHeaderNumberRequest{Chain: "Ethereum", Height: 7})
+	require.NoError(t, err)
+	_, err = recorder.SendTx(ctx, &account.SendTxRequest{Chain: "Ethereum", RawTx: "ab"})
+	require.NoError(t, err)
+	_, err = recorder.GetTxByHash(ctx, &account.TxHashRequest{Chain: "Ethereum", Hash: "0xab"})
+	require.Error(t, err)
+
+	// 录制窗口结束后的调用只透传
+	clk.AdvanceTime(2 * time.Minute)
+	_, err = recorder.GetBlockHeaderByNumber(ctx, &account.BlockHeaderNumberRequest{Chain: "Ethereum", Height: 8})
+	require.NoError(t, err)
+	require.NoError(t, recorder.Close())
+
+	replayer, err := Load(path)
+	require.NoError(t, err)
+
+	// 相同请求按录制顺序应答，用完后重复最后一个
+	for _, want := range []string{"01", "02", "02"} {
+		resp, err := replayer.GetBlockHeaderByNumber(ctx, &account.BlockHeaderNumberRequest{Chain: "Ethereum"})
+		require.NoError(t, err)
+		require.Equal(t, want, resp.BlockHeader.Number)
+	}
+	resp, err := replayer.GetBlockHeaderByNumber(ctx, &account.BlockHeaderNumberRequest{Chain: "Ethereum", Height: 7})
+	require.NoError(t, err)
+	require.Equal(t, "07", resp.BlockHeader.Number)
+
+	sent, err := replayer.SendTx(ctx, &account.SendTxRequest{Chain: "Ethereum", RawTx: "ab"})
+	require.NoError(t, err)
+	require.Equal(t, "0xab", sent.TxHash)
+	require.Equal(t, 1, stub.sent)
+
+	_, err = replayer.GetTxByHash(ctx, &account.TxHashRequest{Chain: "Ethereum", Hash: "0xab"})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	_, err = replayer.GetBlockHeaderByNumber(ctx, &account.BlockHeaderNumberRequest{Chain: "Ethereum", Height: 8})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -1,13 +1,26 @@
 package rpcclient
 
 import (
-	"github.com/ethereum/go-ethereum/common"
 	"math/big"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
 )
 
 type BlockHeader struct {
-	Hash       common.Hash
-	ParentHash common.Hash
+	Hash       chainaddr.Hash
+	ParentHash chainaddr.Hash
 	Number     *big.Int
 	Timestamp  uint64
 }
+
+// DecodedTx chain-account 解析出的签名交易，Hash 和 FromAddress 由签名计算得出
+type DecodedTx struct {
+	Hash            string `json:"hash"`
+	ChainId         string `json:"chain_id"`
+	Nonce           uint64 `json:"nonce"`
+	FromAddress     string `json:"from_address"`
+	ToAddress       string `json:"to_address"`
+	ContractAddress string `json:"contract_address"`
+	TokenId         string `json:"token_id"`
+	Value           string `json:"value"`
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/config"
+	dal_wallet_go "github.com/CavnHan/multichain-sync-account/protobuf/dal-wallet-go"
+)
+
+const (
+	ConsumerTokenHeader = "x-consumer-token"
+	SignerTokenHeader   = "x-signer-token"
+)
+
+var (
+	errInvalidConsumerToken = errors.New("invalid consumer token")
+	errBusinessNotAllowed   = errors.New("consumer token is not allowed for this business")
+	errInvalidSignerToken   = errors.New("invalid signer token")
+	errSigningQueueDisabled = errors.New("signing queue is disabled")
+)
+
+// signerMethods 签名队列接口只接受签名机 token，业务方 token 无权调用
+var signerMethods = map[string]bool{
+	dal_wallet_go.BusinessMiddleWireServices_FetchSigningJobs_FullMethodName: true,
+	dal_wallet_go.BusinessMiddleWireServices_SubmitSignature_FullMethodName:  true,
+}
+
+type consumerTokenRequest interface {
+	GetConsumerToken() string
+}
+
+type requestIdRequest interface {
+	GetRequestId() string
+}
+
+type signerTokenRequest interface {
+	GetSignerToken() string
+}
+
+// adminRequest 运维接口只允许 * 作用域的 token 调用，不按请求体中的 request_id 放行
+func adminRequest(request any) bool {
+	switch request.(type) {
+	case *dal_wallet_go.UpdateBusinessStatusRequest, *dal_wallet_go.RemoveBusinessRequest, *dal_wallet_go.RescanBlocksRequest:
+		return true
+	}
+	return false
+}
+
+// ConsumerAuth 校验业务方的 consumer token 及其可访问的业务方，gRPC 和 HTTP 网关共用同一套规则。
+// 未配置 token 时不做校验，保持与旧版本一致。
+type ConsumerAuth struct {
+	tokens []consumerToken
+}
+
+type consumerToken struct {
+	token       []byte
+	all         bool
+	businessIds map[string]bool
+}
+
+// NewConsumerAuth 配置格式见 config.ParseConsumerToken，格式错误的 token 在配置校验时已拒绝，这里直接忽略
+func NewConsumerAuth(tokens []string) *ConsumerAuth {
+	auth := &ConsumerAuth{}
+	for _, entry := range tokens {
+		parsed, err := config.ParseConsumerToken(entry)
+		if err != nil {
+			log.Error("ignore invalid consumer token", "err", err)
+			continue
+		}
+		token := consumerToken{token: []byte(parsed.Token), businessIds: make(map[string]bool)}
+		for _, businessId := range parsed.BusinessIds {
+			if businessId == config.AllBusinesses {
+				token.all = true
+			}
+			token.businessIds[businessId] = true
+		}
+		auth.tokens = append(auth.tokens, token)
+	}
+	return auth
+}
+
+func (a *ConsumerAuth) Enabled() bool {
+	return a != nil && len(a.tokens) > 0
+}
+
+// Check 优先使用 header/metadata 中的 token，其次使用请求体中的 consumer_token 字段，业务方取请求体中的 request_id。
+// 运维接口按空 request_id 校验，只有 * 作用域的 token 通过
+func (a *ConsumerAuth) Check(headerToken string, request any) error {
+	if !a.Enabled() {
+		return nil
+	}
+	token := headerToken
+	if token == "" {
+		if req, ok := request.(consumerTokenRequest); ok {
+			token = req.GetConsumerToken()
+		}
+	}
+	var requestId string
+	if req, ok := request.(requestIdRequest); ok && !adminRequest(request) {
+		requestId = req.GetRequestId()
+	}
+	return a.CheckBusiness(token, requestId)
+}
+
+// CheckBusiness 校验 token 可以访问 requestId 对应的业务方，requestId 为空时只有 * 作用域的 token 通过
+func (a *ConsumerAuth) CheckBusiness(token string, requestId string) error {
+	if !a.Enabled() {
+		return nil
+	}
+	for _, allowed := range a.tokens {
+		if subtle.ConstantTimeCompare(allowed.token, []byte(token)) != 1 {
+			continue
+		}
+		if allowed.all || (requestId != "" && allowed.businessIds[requestId]) {
+			return nil
+		}
+		return errBusinessNotAllowed
+	}
+	return errInvalidConsumerToken
+}
+
+// UnaryInterceptor 签名队列接口由 SignerAuth 校验
+func (a *ConsumerAuth) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if signerMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		if err := a.Check(metadataToken(ctx, ConsumerTokenHeader), req); err != nil {
+			return nil, status.Error(consumerAuthCode(err), err.Error())
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor 流式接口的请求体在 RecvMsg 时才解码，在首个请求到达后校验
+func (a *ConsumerAuth) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &authServerStream{ServerStream: ss, auth: a})
+	}
+}
+
+type authServerStream struct {
+	grpc.ServerStream
+	auth *ConsumerAuth
+}
+
+func (s *authServerStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if err := s.auth.Check(metadataToken(s.Context(), ConsumerTokenHeader), m); err != nil {
+		return status.Error(consumerAuthCode(err), err.Error())
+	}
+	return nil
+}
+
+// consumerAuthCode token 有效但无权访问该业务方时返回 PermissionDenied
+func consumerAuthCode(err error) codes.Code {
+	if errors.Is(err, errBusinessNotAllowed) {
+		return codes.PermissionDenied
+	}
+	return codes.Unauthenticated
+}
+
+// SignerAuth 校验签名机的 signer token，与业务方 token 分开配置。未配置 token 时签名队列关闭
+type SignerAuth struct {
+	tokens [][]byte
+}
+
+func NewSignerAuth(tokens []string) *SignerAuth {
+	return &SignerAuth{tokens: tokenBytes(tokens)}
+}
+
+func (a *SignerAuth) Enabled() bool {
+	return a != nil && len(a.tokens) > 0
+}
+
+// Check 优先使用 metadata 中的 token，其次使用请求体中的 signer_token 字段
+func (a *SignerAuth) Check(headerToken string, request any) error {
+	if !a.Enabled() {
+		return errSigningQueueDisabled
+	}
+	token := headerToken
+	if token == "" {
+		if req, ok := request.(signerTokenRequest); ok {
+			token = req.GetSignerToken()
+		}
+	}
+	if !matchToken(a.tokens, token) {
+		return errInvalidSignerToken
+	}
+	return nil
+}
+
+// UnaryInterceptor 只校验签名队列接口，其余接口由 ConsumerAuth 校验
+func (a *SignerAuth) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !signerMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		if err := a.Check(metadataToken(ctx, SignerTokenHeader), req); err != nil {
+			if errors.Is(err, errSigningQueueDisabled) {
+				return nil, status.Error(codes.FailedPrecondition, err.Error())
+			}
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return handler(ctx, req)
+	}
+}
+
+func tokenBytes(tokens []string) [][]byte {
+	var result [][]byte
+	for _, token := range tokens {
+		if token != "" {
+			result = append(result, []byte(token))
+		}
+	}
+	return result
+}
+
+func matchToken(tokens [][]byte, token string) bool {
+	for _, allowed := range tokens {
+		if subtle.ConstantTimeCompare(allowed, []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func metadataToken(ctx context.Context, header string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(header)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	dal_wallet_go "github.com/CavnHan/multichain-sync-account/protobuf/dal-wallet-go"
+)
+
+func TestSignerAuthSeparatedFromConsumerAuth(t *testing.T) {
+	consumer, signer := NewConsumerAuth([]string{"consumer:*"}), NewSignerAuth([]string{"signer"})
+	chain := func(method string, md metadata.MD) error {
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		_, err := signer.UnaryInterceptor()(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+			return consumer.UnaryInterceptor()(ctx, req, info, func(context.Context, any) (any, error) {
+				return nil, nil
+			})
+		})
+		return err
+	}
+	fetch := dal_wallet_go.BusinessMiddleWireServices_FetchSigningJobs_FullMethodName
+	register := dal_wallet_go.BusinessMiddleWireServices_BusinessRegister_FullMethodName
+
+	require.NoError(t, chain(fetch, metadata.Pairs(SignerTokenHeader, "signer")))
+	require.Equal(t, codes.Unauthenticated, status.Code(chain(fetch, metadata.Pairs(ConsumerTokenHeader, "consumer"))))
+	require.NoError(t, chain(register, metadata.Pairs(ConsumerTokenHeader, "consumer")))
+	require.Equal(t, codes.Unauthenticated, status.Code(chain(register, metadata.Pairs(ConsumerTokenHeader, "signer"))))
+
+	// 未配置签名机 token 时签名队列关闭
+	signer = NewSignerAuth(nil)
+	require.Equal(t, codes.FailedPrecondition, status.Code(chain(fetch, metadata.Pairs(SignerTokenHeader, ""))))
+}
+
+func TestConsumerAuthScopedByBusiness(t *testing.T) {
+	auth := NewConsumerAuth([]string{"token-a:a|c", "admin:*", "unscoped"})
+	require.NoError(t, auth.CheckBusiness("token-a", "a"))
+	require.NoError(t, auth.CheckBusiness("token-a", "c"))
+	require.ErrorIs(t, auth.CheckBusiness("token-a", "b"), errBusinessNotAllowed)
+	require.ErrorIs(t, auth.CheckBusiness("token-a", ""), errBusinessNotAllowed)
+	require.NoError(t, auth.CheckBusiness("admin", "b"))
+	require.NoError(t, auth.CheckBusiness("admin", ""))
+	// 未限定业务方的 token 不生效
+	require.ErrorIs(t, auth.CheckBusiness("unscoped", "a"), errInvalidConsumerToken)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ConsumerTokenHeader, "token-a"))
+	info := &grpc.UnaryServerInfo{FullMethod: dal_wallet_go.BusinessMiddleWireServices_AckEvents_FullMethodName}
+	call := func(req any) error {
+		_, err := auth.UnaryInterceptor()(ctx, req, info, func(context.Context, any) (any, error) {
+			return nil, nil
+		})
+		return err
+	}
+	require.NoError(t, call(&dal_wallet_go.AckEventsRequest{RequestId: "a"}))
+	require.Equal(t, codes.PermissionDenied, status.Code(call(&dal_wallet_go.AckEventsRequest{RequestId: "b"})))
+	// 停用、删除业务方和重扫区块只允许 * 作用域的 token，即使是自己的业务方
+	require.Equal(t, codes.PermissionDenied, status.Code(call(&dal_wallet_go.UpdateBusinessStatusRequest{RequestId: "a"})))
+	require.Equal(t, codes.PermissionDenied, status.Code(call(&dal_wallet_go.RemoveBusinessRequest{RequestId: "a"})))
+	require.Equal(t, codes.PermissionDenied, status.Code(call(&dal_wallet_go.RescanBlocksRequest{RequestId: "a"})))
+	require.NoError(t, auth.Check("admin", &dal_wallet_go.RescanBlocksRequest{RequestId: "a"}))
+	require.NoError(t, auth.Check("admin", &dal_wallet_go.RemoveBusinessRequest{RequestId: "a"}))
+
+	stream := &authServerStream{ServerStream: &recvStream{ctx: ctx}, auth: auth}
+	require.NoError(t, stream.RecvMsg(&dal_wallet_go.SubscribeEventsRequest{RequestId: "a"}))
+	require.Equal(t, codes.PermissionDenied, status.Code(stream.RecvMsg(&dal_wallet_go.SubscribeEventsRequest{RequestId: "b"})))
+}
+
+// recvStream RecvMsg 不做解码，请求体由调用方直接传入
+type recvStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *recvStream) Context() context.Context { return s.ctx }
+
+func (s *recvStream) RecvMsg(any) error { return nil }
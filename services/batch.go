@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/google/uuid"
+
+	"github.com/CavnHan/multichain-sync-account/common/bigint"
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+	dal_wallet_go "github.com/CavnHan/multichain-sync-account/protobuf/dal-wallet-go"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/common"
+	"github.com/CavnHan/multichain-sync-account/txverify"
+)
+
+const (
+	maxBatchWithdraws = 200
+
+	// multisend 合约调用的 gas 估算：基础开销加每个收款方的转账开销
+	batchBaseGas             uint64 = 50000
+	batchNativeGasPerPayee   uint64 = 10000
+	batchTokenGasPerPayee    uint64 = 35000
+	batchMinWithdrawsPerCall        = 2
+)
+
+var errBatchMembers = errors.New("some withdraws are not pending or already batched")
+
+type BatchRecipient struct {
+	ToAddress string `json:"to_address"`
+	Value     string `json:"value"`
+}
+
+// BatchTxStructure EVM 链通过 multisend 合约一次转给多个收款方
+type BatchTxStructure struct {
+	ChainId           string           `json:"chain_id"`
+	Nonce             uint64           `json:"nonce"`
+	GasPrice          string           `json:"gas_price"`
+	GasTipCap         string           `json:"gas_tip_cap"`
+	GasFeeCap         string           `json:"gas_fee_cap"`
+	Gas               uint64           `json:"gas"`
+	MultisendContract string           `json:"multisend_contract"`
+	ContractAddress   string           `json:"contract_address"`
+	FromAddress       string           `json:"from_address"`
+	Recipients        []BatchRecipient `json:"recipients"`
+}
+
+func batchGasLimit(tokenAddress chainaddr.Address, recipients int) uint64 {
+	perPayee := batchTokenGasPerPayee
+	if tokenAddress == chainaddr.NativeToken() {
+		perPayee = batchNativeGasPerPayee
+	}
+	return batchBaseGas + perPayee*uint64(recipients)
+}
+
+// CreateBatchWithdrawTransaction 把同一热钱包同一代币的多笔待处理提现合并成一笔链上交易，
+// 手续费在成员提现之间平均分摊
+func (bws *BusinessMiddleWireServices) CreateBatchWithdrawTransaction(ctx context.Context, request *dal_wallet_go.BatchWithdrawRequest) (*dal_wallet_go.BatchWithdrawResponse, error) {
+	errorResponse := func(msg string) *dal_wallet_go.BatchWithdrawResponse {
+		return &dal_wallet_go.BatchWithdrawResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  msg,
+		}
+	}
+	if request.RequestId == "" || request.From == "" || len(request.TransactionIds) > maxBatchWithdraws {
+		return errorResponse("invalid params"), nil
+	}
+	if reject := bws.checkChainId(request.ChainId); reject != "" && !chainaddr.IsUTXO() {
+		return errorResponse(reject), nil
+	}
+	fromAddress, err := chainaddr.ParseAddress(request.From)
+	if err != nil {
+		return errorResponse("invalid address: " + err.Error()), nil
+	}
+	tokenAddress, err := parseTokenAddress(request.ContractAddress)
+	if err != nil {
+		return errorResponse("invalid address: " + err.Error()), nil
+	}
+	feeRate := bws.feePolicy.UtxoFeeRate
+	if request.FeeRate != "" {
+		feeRate, err = strconv.ParseInt(request.FeeRate, 10, 64)
+		if err != nil || feeRate <= 0 {
+			return errorResponse("invalid fee rate"), nil
+		}
+	}
+	if !chainaddr.IsUTXO() && bws.MultisendContract == "" {
+		return errorResponse("multisend contract is not configured"), nil
+	}
+
+	// 短事务内锁定并认领成员提现，构建未签名交易需要访问节点，放在事务外进行
+	batch := &database.WithdrawBatches{
+		GUID:         uuid.New(),
+		FromAddress:  fromAddress,
+		TokenAddress: tokenAddress,
+		Status:       database.WithdrawBatchStatusUnSigned,
+	}
+	batchId := batch.GUID.String()
+	var members []database.Withdraws
+	err = bws.db.Transaction(func(tx *database.DB) error {
+		members, err = tx.Withdraws.LockPendingWithdraws(request.RequestId, fromAddress, tokenAddress, request.TransactionIds, maxBatchWithdraws)
+		if err != nil {
+			return err
+		}
+		if len(request.TransactionIds) > 0 && len(members) != len(request.TransactionIds) {
+			return errBatchMembers
+		}
+		if len(members) < batchMinWithdrawsPerCall {
+			return fmt.Errorf("%w: batch needs at least %d pending withdraws, got %d", errBatchMembers, batchMinWithdrawsPerCall, len(members))
+		}
+		batch.Amount = big.NewInt(0)
+		for _, member := range members {
+			batch.Amount.Add(batch.Amount, member.Amount)
+		}
+
+		if chainaddr.IsUTXO() {
+			// 单笔构建时锁定的输出先释放，再按合并后的金额统一选币
+			for _, member := range members {
+				if err := tx.Utxos.ReleaseUtxos(request.RequestId, member.GUID.String()); err != nil {
+					return err
+				}
+			}
+			utxos, err := tx.Utxos.LockUnspentUtxos(request.RequestId, fromAddress)
+			if err != nil {
+				return err
+			}
+			selection, err := SelectCoins(utxos, batch.Amount, len(members), feeRate)
+			if err != nil {
+				return err
+			}
+			guids := make([]uuid.UUID, 0, len(selection.Inputs))
+			for _, input := range selection.Inputs {
+				guids = append(guids, input.GUID)
+			}
+			if err := tx.Utxos.MarkUtxosLocked(request.RequestId, batchId, guids); err != nil {
+				return err
+			}
+			batch.Fee = selection.Fee
+		} else {
+			gasPrice, _ := new(big.Int).SetString(bws.feePolicy.MaxFeePerGas, 10)
+			batch.Fee = new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(batchGasLimit(tokenAddress, len(members))))
+		}
+
+		for i, share := range bigint.Split(batch.Fee, len(members)) {
+			members[i].Fee = share
+		}
+		return tx.Withdraws.AssignWithdrawBatch(request.RequestId, batchId, members)
+	})
+	if errors.Is(err, errBatchMembers) || errors.Is(err, ErrInsufficientUtxos) {
+		return errorResponse(err.Error()), nil
+	}
+	if err != nil {
+		log.Error("create batch withdraw transaction fail", "err", err)
+		return nil, err
+	}
+
+	unSignTx, err := bws.buildUnSignBatch(ctx, request.RequestId, batch, members)
+	if err == nil {
+		batch.Timestamp = uint64(time.Now().Unix())
+		err = bws.db.WithdrawBatches.StoreWithdrawBatch(request.RequestId, batch)
+	}
+	if err != nil {
+		// 未签名交易构建或批次写入失败时退回认领的成员提现和 utxo，成员提现保持可重试状态
+		log.Error("create batch withdraw transaction fail", "batchId", batchId, "err", err)
+		releaseErr := bws.db.Transaction(func(tx *database.DB) error {
+			if err := tx.Withdraws.ReleaseBatchWithdraws(request.RequestId, batchId); err != nil {
+				return err
+			}
+			return tx.Utxos.ReleaseUtxos(request.RequestId, batchId)
+		})
+		if releaseErr != nil {
+			log.Error("release batch withdraws fail", "batchId", batchId, "err", releaseErr)
+		}
+		return nil, err
+	}
+
+	transactionIds := make([]string, 0, len(members))
+	for _, member := range members {
+		transactionIds = append(transactionIds, member.GUID.String())
+	}
+	log.Info("create batch withdraw success", "requestId", request.RequestId, "batchId", batchId, "withdraws", len(members))
+	return &dal_wallet_go.BatchWithdrawResponse{
+		Code:           dal_wallet_go.ReturnCode_SUCCESS,
+		Msg:            "build batch withdraw transaction success",
+		BatchId:        batchId,
+		UnSignTx:       unSignTx,
+		TransactionIds: transactionIds,
+	}, nil
+}
+
+func (bws *BusinessMiddleWireServices) BuildSignedBatchWithdrawTransaction(ctx context.Context, request *dal_wallet_go.SignedBatchWithdrawRequest) (*dal_wallet_go.SignedBatchWithdrawResponse, error) {
+	errorResponse := func(msg string) *dal_wallet_go.SignedBatchWithdrawResponse {
+		return &dal_wallet_go.SignedBatchWithdrawResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  msg,
+		}
+	}
+	if reject := bws.checkChainId(request.ChainId); reject != "" && !chainaddr.IsUTXO() {
+		return errorResponse(reject), nil
+	}
+	batch, err := bws.db.WithdrawBatches.QueryWithdrawBatch(request.RequestId, request.BatchId)
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil || batch.Status != database.WithdrawBatchStatusUnSigned {
+		return errorResponse("batch not found or already signed"), nil
+	}
+	members, err := bws.db.Withdraws.QueryWithdrawsByBatchId(request.RequestId, request.BatchId)
+	if err != nil {
+		return nil, err
+	}
+	payload, nonce, err := bws.batchTxPayload(request.RequestId, batch, members)
+	if err != nil {
+		log.Error("rebuild batch transaction fail", "err", err)
+		return errorResponse("rebuild batch transaction fail: " + err.Error()), nil
+	}
+	returnTx, err := bws.accountClient.AccountRpClient.BuildSignedTransaction(ctx, &account.SignedTransactionRequest{
+		Chain:     bws.ChainName,
+		Network:   Network,
+		Signature: request.Signature,
+		Base64Tx:  payload,
+	})
+	if err != nil {
+		log.Error("build signed batch transaction fail", "err", err)
+		return nil, err
+	}
+	if returnTx.Code == common.ReturnCode_ERROR {
+		log.Error("build signed batch transaction fail", "msg", returnTx.Msg)
+		return errorResponse("build signed batch transaction fail: " + returnTx.Msg), nil
+	}
+	// EVM 链的签名交易必须与批次成员、本次构造时取到的 nonce 和配置的链 id 一致，否则整批拦截
+	if !chainaddr.IsUTXO() {
+		contract, err := chainaddr.ParseAddress(bws.MultisendContract)
+		if err != nil {
+			return errorResponse("invalid multisend contract: " + err.Error()), nil
+		}
+		expected := txverify.FromBatch(batch, members, contract)
+		expected.Nonce, expected.ChainId = nonce, bws.chainId()
+		if _, err := bws.verifier.VerifyBatch(request.RequestId, expected, returnTx.SignedTx); err != nil {
+			if !errors.Is(err, txverify.ErrMismatch) {
+				log.Error("verify signed batch transaction fail", "err", err)
+				return nil, err
+			}
+			txverify.Alert(request.RequestId, "batch", request.BatchId, err)
+			if err := bws.db.Transaction(func(tx *database.DB) error {
+				return txverify.BlockBatch(tx, request.RequestId, request.BatchId)
+			}); err != nil {
+				log.Error("block mismatched batch fail", "err", err)
+				return nil, err
+			}
+			return errorResponse("signed transaction does not match the stored batch, batch blocked"), nil
+		}
+	}
+	err = bws.db.Transaction(func(tx *database.DB) error {
+		if err := tx.WithdrawBatches.UpdateWithdrawBatchSigned(request.RequestId, request.BatchId, returnTx.SignedTx, nonce, bws.chainId()); err != nil {
+			return err
+		}
+		return tx.Withdraws.UpdateBatchWithdraws(request.RequestId, request.BatchId, 1, "") // 1:交易已经签名
+	})
+	if err != nil {
+		log.Error("update signed batch tx to db fail", "err", err)
+		return nil, err
+	}
+	return &dal_wallet_go.SignedBatchWithdrawResponse{
+		Code:     dal_wallet_go.ReturnCode_SUCCESS,
+		Msg:      "build signed batch tx success",
+		SignedTx: returnTx.SignedTx,
+	}, nil
+}
+
+// buildUnSignBatch 由 chain-account 构建批次的未签名交易
+func (bws *BusinessMiddleWireServices) buildUnSignBatch(ctx context.Context, requestId string, batch *database.WithdrawBatches, members []database.Withdraws) (string, error) {
+	payload, _, err := bws.batchTxPayload(requestId, batch, members)
+	if err != nil {
+		return "", err
+	}
+	returnTx, err := bws.accountClient.AccountRpClient.CreateUnSignTransaction(ctx, &account.UnSignTransactionRequest{
+		Chain:    bws.ChainName,
+		Network:  Network,
+		Base64Tx: payload,
+	})
+	if err != nil {
+		return "", err
+	}
+	return returnTx.UnSignTx, nil
+}
+
+// batchTxPayload 按批次和成员还原交易结构并编码为 chain-account 需要的 base64 json，同时返回 EVM 链使用的 nonce
+func (bws *BusinessMiddleWireServices) batchTxPayload(requestId string, batch *database.WithdrawBatches, members []database.Withdraws) (string, uint64, error) {
+	var payload any
+	var nonce uint64
+	if chainaddr.IsUTXO() {
+		inputs, err := bws.db.Utxos.QueryUtxosByTransactionId(requestId, batch.GUID.String())
+		if err != nil {
+			return "", 0, err
+		}
+		outputs := make([]UtxoOutput, 0, len(members))
+		for _, member := range members {
+			outputs = append(outputs, UtxoOutput{Address: member.ToAddress.String(), Amount: member.Amount.String()})
+		}
+		utxoTx, err := buildUtxoTxStructure(inputs, batch.FromAddress, outputs, batch.Fee)
+		if err != nil {
+			return "", 0, err
+		}
+		payload = utxoTx
+	} else {
+		accountInfo, err := bws.accountClient.AccountRpClient.GetAccount(context.Background(), &account.AccountRequest{
+			Chain:   bws.ChainName,
+			Network: Network,
+			Address: batch.FromAddress.String(),
+		})
+		if err != nil {
+			return "", 0, err
+		}
+		sequence, _ := strconv.Atoi(accountInfo.Sequence)
+		nonce = uint64(sequence)
+		batchTx := &BatchTxStructure{
+			ChainId:           bws.chainId(),
+			Nonce:             nonce,
+			GasPrice:          bws.feePolicy.MaxFeePerGas,
+			GasTipCap:         bws.feePolicy.MaxFeePerGas,
+			GasFeeCap:         bws.feePolicy.MaxPriorityFeePerGas,
+			Gas:               batchGasLimit(batch.TokenAddress, len(members)),
+			MultisendContract: bws.MultisendContract,
+			ContractAddress:   batch.TokenAddress.String(),
+			FromAddress:       batch.FromAddress.String(),
+		}
+		for _, member := range members {
+			batchTx.Recipients = append(batchTx.Recipients, BatchRecipient{ToAddress: member.ToAddress.String(), Value: member.Amount.String()})
+		}
+		payload = batchTx
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", 0, err
+	}
+	return base64.StdEncoding.EncodeToString(data), nonce, nil
+}
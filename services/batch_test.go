@@ -0,0 +1,31 @@
+package services
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+)
+
+func TestBatchGasLimit(t *testing.T) {
+	token := chainaddr.Address("0x0000000000000000000000000000000000000002")
+	require.Equal(t, batchBaseGas+3*batchNativeGasPerPayee, batchGasLimit(chainaddr.NativeToken(), 3))
+	require.Equal(t, batchBaseGas+3*batchTokenGasPerPayee, batchGasLimit(token, 3))
+}
+
+func TestBatchUtxoTxStructure(t *testing.T) {
+	selection, err := SelectCoins(testUtxos(30_000, 50_000), big.NewInt(60_000), 2, 10)
+	require.NoError(t, err)
+	require.Len(t, selection.Inputs, 2)
+	require.Equal(t, estimateUtxoFee(2, 3, 10), selection.Fee)
+
+	outputs := []UtxoOutput{{Address: "a", Amount: "20000"}, {Address: "b", Amount: "40000"}}
+	txStructure, err := buildUtxoTxStructure(selection.Inputs, "from", outputs, selection.Fee)
+	require.NoError(t, err)
+	require.Len(t, txStructure.Vout, 3)
+	require.Equal(t, outputs, txStructure.Vout[:2])
+	change := new(big.Int).Sub(big.NewInt(20_000), selection.Fee)
+	require.Equal(t, UtxoOutput{Address: "from", Amount: change.String()}, txStructure.Vout[2])
+}
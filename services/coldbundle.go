@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/coldbundle"
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	dal_wallet_go "github.com/CavnHan/multichain-sync-account/protobuf/dal-wallet-go"
+	"github.com/CavnHan/multichain-sync-account/txverify"
+)
+
+const (
+	defaultColdBundleLimit = 20
+	maxColdBundleLimit     = 200
+)
+
+// ExportColdBundle 导出未签名的内部交易(默认冷转热)供离线签名，不改变交易状态，重复导出会按最新 nonce 重新构建
+func (bws *BusinessMiddleWireServices) ExportColdBundle(ctx context.Context, request *dal_wallet_go.ExportColdBundleRequest) (*dal_wallet_go.ExportColdBundleResponse, error) {
+	errorResponse := func(msg string) *dal_wallet_go.ExportColdBundleResponse {
+		return &dal_wallet_go.ExportColdBundleResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  msg,
+		}
+	}
+	txType := request.TxType
+	if txType == "" {
+		txType = "cold2hot"
+	}
+	if request.RequestId == "" || !isInternalTxType(txType) || request.Limit > maxColdBundleLimit {
+		return errorResponse("invalid params"), nil
+	}
+	if chainaddr.IsUTXO() {
+		return errorResponse("cold bundle is not supported on utxo chains"), nil
+	}
+	if reject := bws.checkChainId(request.ChainId); reject != "" {
+		return errorResponse(reject), nil
+	}
+	limit := int(request.Limit)
+	if limit == 0 {
+		limit = defaultColdBundleLimit
+	}
+	internals, err := bws.db.Internals.QueryUnsignedInternals(request.RequestId, txType, limit)
+	if err != nil {
+		log.Error("query unsigned internals fail", "err", err)
+		return nil, err
+	}
+
+	bundle := &coldbundle.Bundle{
+		Kind:      coldbundle.KindUnsigned,
+		Chain:     bws.ChainName,
+		ChainId:   bws.chainId(),
+		RequestId: request.RequestId,
+		CreatedAt: uint64(time.Now().Unix()),
+		Items:     []coldbundle.Item{},
+	}
+	// 同一冷钱包地址的多笔交易离线签名后依次广播，nonce 在链上 nonce 的基础上递增
+	nonces := make(map[chainaddr.Address]uint64)
+	for i := range internals {
+		internal := &internals[i]
+		pending := &pendingTx{status: internal.Status, expected: txverify.FromInternal(internal), tokenMeta: internal.TokenMeta}
+		txStructure, err := bws.accountTxStructure(bws.chainId(), pending)
+		if err != nil {
+			return nil, err
+		}
+		if next, ok := nonces[internal.FromAddress]; ok {
+			txStructure.Nonce = next
+		}
+		nonces[internal.FromAddress] = txStructure.Nonce + 1
+		payload, err := encodeTxPayload(txStructure)
+		if err != nil {
+			return nil, err
+		}
+		unSignTx, err := bws.createUnSignTx(payload)
+		if err != nil {
+			log.Error("create un sign transaction fail", "transactionId", internal.GUID, "err", err)
+			return nil, err
+		}
+		bundle.Items = append(bundle.Items, coldbundle.Item{
+			TransactionId:   internal.GUID.String(),
+			TxType:          internal.TxType,
+			From:            txStructure.FromAddress,
+			To:              txStructure.ToAddress,
+			Value:           txStructure.Value,
+			ContractAddress: txStructure.ContractAddress,
+			TokenId:         txStructure.TokenId,
+			Nonce:           txStructure.Nonce,
+			Payload:         payload,
+			UnSignTx:        unSignTx,
+		})
+	}
+	data, err := coldbundle.Marshal(bundle)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("export cold bundle", "requestId", request.RequestId, "txType", txType, "count", len(bundle.Items), "checksum", bundle.Checksum)
+	return &dal_wallet_go.ExportColdBundleResponse{
+		Code:   dal_wallet_go.ReturnCode_SUCCESS,
+		Msg:    "export cold bundle success",
+		Bundle: string(data),
+		Count:  uint32(len(bundle.Items)),
+	}, nil
+}
+
+// ImportColdBundle 导入离线签名后的交易包，bundle 可以是完整 JSON 或按行分隔的二维码分段。
+// 每笔签名交易与库中记录比对，通过后入库等待广播，不通过的交易被拦截
+func (bws *BusinessMiddleWireServices) ImportColdBundle(ctx context.Context, request *dal_wallet_go.ImportColdBundleRequest) (*dal_wallet_go.ImportColdBundleResponse, error) {
+	errorResponse := func(msg string) *dal_wallet_go.ImportColdBundleResponse {
+		return &dal_wallet_go.ImportColdBundleResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  msg,
+		}
+	}
+	if request.RequestId == "" || request.Bundle == "" {
+		return errorResponse("invalid params"), nil
+	}
+	data := []byte(request.Bundle)
+	if coldbundle.IsChunk(request.Bundle) {
+		var err error
+		if data, err = coldbundle.Join(strings.Split(request.Bundle, "\n")); err != nil {
+			return errorResponse(err.Error()), nil
+		}
+	}
+	bundle, err := coldbundle.Unmarshal(data)
+	if err != nil {
+		return errorResponse(err.Error()), nil
+	}
+	if bundle.Kind != coldbundle.KindSigned || bundle.RequestId != request.RequestId || bundle.Chain != bws.ChainName {
+		return errorResponse("not a signed cold bundle of this business and chain"), nil
+	}
+	if !txverify.SameChainId(bundle.ChainId, bws.chainId()) {
+		return errorResponse(fmt.Sprintf("cold bundle chain id %s does not match configured chain id %d", bundle.ChainId, bws.ChainId)), nil
+	}
+
+	var results []*dal_wallet_go.ColdBundleResult
+	accepted := 0
+	for _, item := range bundle.Items {
+		result := &dal_wallet_go.ColdBundleResult{TransactionId: item.TransactionId}
+		results = append(results, result)
+		if !isInternalTxType(item.TxType) || item.Signature == "" {
+			result.Msg = "missing signature or unsupported transaction type"
+			continue
+		}
+		pending, err := bws.queryPendingTx(request.RequestId, item.TxType, item.TransactionId)
+		if err != nil {
+			log.Error("query pending transaction fail", "err", err)
+			return nil, err
+		}
+		if pending == nil || pending.status != 0 {
+			result.Msg = "transaction is no longer waiting for signature"
+			continue
+		}
+		// 交易包中的内容不可信，按库中记录和配置的链 id 重新构造交易，只沿用离线签名时的 nonce 和手续费
+		var signed TxStructure
+		if err := decodeTxPayload(item.Payload, &signed); err != nil {
+			result.Msg = "invalid payload: " + err.Error()
+			continue
+		}
+		txStructure := bws.buildTxStructure(bws.chainId(), item.Nonce, pending)
+		txStructure.GasPrice, txStructure.GasTipCap, txStructure.GasFeeCap, txStructure.Gas = signed.GasPrice, signed.GasTipCap, signed.GasFeeCap, signed.Gas
+		payload, err := encodeTxPayload(txStructure)
+		if err != nil {
+			return nil, err
+		}
+		expected := pending.expected
+		expected.Nonce, expected.ChainId = txStructure.Nonce, txStructure.ChainId
+		_, reject, err := bws.signAccountTx(request.RequestId, item.TxType, item.TransactionId, "", expected, payload, item.Signature)
+		if err != nil {
+			return nil, err
+		}
+		if reject != "" {
+			result.Msg = reject
+			continue
+		}
+		result.Accepted, result.Msg = true, "signed transaction queued for broadcast"
+		accepted++
+	}
+	log.Info("import cold bundle", "requestId", request.RequestId, "checksum", bundle.Checksum, "accepted", accepted, "total", len(bundle.Items))
+	return &dal_wallet_go.ImportColdBundleResponse{
+		Code:    dal_wallet_go.ReturnCode_SUCCESS,
+		Msg:     fmt.Sprintf("imported %d of %d transactions", accepted, len(bundle.Items)),
+		Results: results,
+	}, nil
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/coldbundle"
+	"github.com/CavnHan/multichain-sync-account/database"
+	dal_wallet_go "github.com/CavnHan/multichain-sync-account/protobuf/dal-wallet-go"
+)
+
+type memInternals struct {
+	database.InternalsDB
+	internals map[string]database.Internals
+}
+
+func (db *memInternals) QueryInternalsByHash(_ string, txId string) (*database.Internals, error) {
+	internal, ok := db.internals[txId]
+	if !ok {
+		return nil, nil
+	}
+	return &internal, nil
+}
+
+func TestImportColdBundleRejects(t *testing.T) {
+	sent, pending := uuid.New(), uuid.New()
+	internals := &memInternals{internals: map[string]database.Internals{
+		sent.String():    {GUID: sent, TxType: "cold2hot", Status: 2},
+		pending.String(): {GUID: pending, TxType: "cold2hot"},
+	}}
+	bws, err := NewBusinessMiddleWireServices(&database.DB{Internals: internals}, &BusinessMiddleConfig{ChainName: "Ethereum", ChainId: 11155111}, nil)
+	require.NoError(t, err)
+	importBundle := func(bundle string) *dal_wallet_go.ImportColdBundleResponse {
+		resp, err := bws.ImportColdBundle(context.Background(), &dal_wallet_go.ImportColdBundleRequest{RequestId: "biz", Bundle: bundle})
+		require.NoError(t, err)
+		return resp
+	}
+	bundle := &coldbundle.Bundle{
+		Kind:      coldbundle.KindSigned,
+		Chain:     "Ethereum",
+		ChainId:   "11155111",
+		RequestId: "biz",
+		Items: []coldbundle.Item{
+			{TransactionId: sent.String(), TxType: "cold2hot", Signature: "0x01"},
+			{TransactionId: uuid.NewString(), TxType: "cold2hot"},
+			{TransactionId: pending.String(), TxType: "cold2hot", Payload: "not base64", Signature: "0x01"},
+		},
+	}
+	data, err := coldbundle.Marshal(bundle)
+	require.NoError(t, err)
+
+	// 已广播的交易和缺少签名的交易逐笔拒绝，不影响整个交易包
+	resp := importBundle(strings.Join(coldbundle.Split(data, 64), "\n"))
+	require.Equal(t, dal_wallet_go.ReturnCode_SUCCESS, resp.Code)
+	require.Equal(t, "imported 0 of 3 transactions", resp.Msg)
+	require.Contains(t, resp.Results[0].Msg, "no longer waiting")
+	require.Contains(t, resp.Results[1].Msg, "missing signature")
+	require.Contains(t, resp.Results[2].Msg, "invalid payload")
+
+	tampered := strings.Replace(string(data), `"chain_id": "11155111"`, `"chain_id": "1"`, 1)
+	require.Equal(t, coldbundle.ErrChecksum.Error(), importBundle(tampered).Msg)
+
+	// 链 id 以配置为准，交易包中的链 id 不一致时整个交易包拒绝
+	bundle.ChainId = "1"
+	data, err = coldbundle.Marshal(bundle)
+	require.NoError(t, err)
+	require.Contains(t, importBundle(string(data)).Msg, "does not match configured chain id 11155111")
+
+	bundle.ChainId = "0xaa36a7"
+	bundle.Kind = coldbundle.KindUnsigned
+	data, err = coldbundle.Marshal(bundle)
+	require.NoError(t, err)
+	require.Contains(t, importBundle(string(data)).Msg, "not a signed cold bundle")
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/notifier"
+	dal_wallet_go "github.com/CavnHan/multichain-sync-account/protobuf/dal-wallet-go"
+)
+
+const (
+	subscribeBatchSize    = 100
+	subscribePollInterval = time.Second
+
+	defaultFetchLimit      = 100
+	maxFetchLimit          = 1000
+	defaultEventVisibility = 30 * time.Second
+	maxAckEventsPerRequest = 1000
+)
+
+// SubscribeEvents 从游标之后推送业务方事件发件箱中的事件，cursor 为 0 时从上次确认的位置开始。
+// 只有 AckSubscription 确认过的事件才算送达，断线重连后未确认的事件会重新推送
+func (bws *BusinessMiddleWireServices) SubscribeEvents(request *dal_wallet_go.SubscribeEventsRequest, stream grpc.ServerStreamingServer[dal_wallet_go.WalletEvent]) error {
+	if request.RequestId == "" {
+		return status.Error(codes.InvalidArgument, "invalid params")
+	}
+	if _, err := bws.db.Business.QueryBusinessByUuid(request.RequestId); err != nil {
+		return status.Error(codes.NotFound, "business not found")
+	}
+	cursor := request.Cursor
+	if cursor == 0 {
+		acked, err := bws.db.Events.QueryEventCursor(request.RequestId, notifier.SinkStream)
+		if err != nil {
+			log.Error("query subscription cursor fail", "err", err)
+			return err
+		}
+		cursor = acked
+	}
+	log.Info("subscribe events", "requestId", request.RequestId, "cursor", cursor)
+	return streamEvents(stream.Context(), bws.db.Events, request.RequestId, cursor, subscribePollInterval, stream.Send)
+}
+
+// streamEvents 按序号顺序推送事件，没有新事件时按 poll 间隔轮询，直到连接断开
+func streamEvents(ctx context.Context, events database.EventsView, businessId string, cursor uint64, poll time.Duration, send func(*dal_wallet_go.WalletEvent) error) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+		records, err := events.QueryEventsAfter(businessId, cursor, subscribeBatchSize)
+		if err != nil {
+			log.Error("query events fail", "businessId", businessId, "err", err)
+			return err
+		}
+		for _, record := range records {
+			if err := send(walletEvent(businessId, record)); err != nil {
+				return err
+			}
+			cursor = record.Sequence
+		}
+		if len(records) == subscribeBatchSize {
+			timer.Reset(0)
+		} else {
+			timer.Reset(poll)
+		}
+	}
+}
+
+func walletEvent(businessId string, record database.Events) *dal_wallet_go.WalletEvent {
+	return &dal_wallet_go.WalletEvent{
+		Sequence:   record.Sequence,
+		BusinessId: businessId,
+		Kind:       record.Kind,
+		Payload:    record.Payload,
+		Timestamp:  record.Timestamp,
+		Id:         record.GUID.String(),
+		Deliveries: record.Deliveries,
+	}
+}
+
+// AckSubscription 确认 sequence 及之前的事件已处理，游标只前进不后退。
+// 只移动 stream 游标，交易表在写入发件箱时已更新为已通知
+func (bws *BusinessMiddleWireServices) AckSubscription(ctx context.Context, request *dal_wallet_go.AckSubscriptionRequest) (*dal_wallet_go.AckSubscriptionResponse, error) {
+	if request.RequestId == "" || request.Sequence == 0 {
+		return &dal_wallet_go.AckSubscriptionResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  "invalid params",
+		}, nil
+	}
+	cursor, err := bws.db.Events.QueryEventCursor(request.RequestId, notifier.SinkStream)
+	if err != nil {
+		log.Error("query subscription cursor fail", "err", err)
+		return nil, err
+	}
+	if request.Sequence > cursor {
+		records, err := bws.db.Events.QueryEventsAfter(request.RequestId, request.Sequence-1, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) == 0 || records[0].Sequence != request.Sequence {
+			return &dal_wallet_go.AckSubscriptionResponse{
+				Code:   dal_wallet_go.ReturnCode_ERROR,
+				Msg:    "event not found",
+				Cursor: cursor,
+			}, nil
+		}
+		if err := bws.db.Events.UpdateEventCursor(request.RequestId, notifier.SinkStream, request.Sequence); err != nil {
+			log.Error("update subscription cursor fail", "err", err)
+			return nil, err
+		}
+		cursor = request.Sequence
+	}
+	return &dal_wallet_go.AckSubscriptionResponse{
+		Code:   dal_wallet_go.ReturnCode_SUCCESS,
+		Msg:    "ack events success",
+		Cursor: cursor,
+	}, nil
+}
+
+// FetchEvents 拉取 cursor 之后未确认的事件，返回的事件在可见超时内不会被再次拉取，
+// 超时仍未通过 AckEvents 确认的事件会重新返回
+func (bws *BusinessMiddleWireServices) FetchEvents(ctx context.Context, request *dal_wallet_go.FetchEventsRequest) (*dal_wallet_go.FetchEventsResponse, error) {
+	if request.RequestId == "" || request.Limit > maxFetchLimit {
+		return &dal_wallet_go.FetchEventsResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  "invalid params",
+		}, nil
+	}
+	limit := int(request.Limit)
+	if limit == 0 {
+		limit = defaultFetchLimit
+	}
+	visibility := bws.EventVisibilityTimeout
+	if visibility <= 0 {
+		visibility = defaultEventVisibility
+	}
+	records, err := bws.db.Events.LeaseEvents(request.RequestId, request.Cursor, limit, visibility)
+	if err != nil {
+		log.Error("lease events fail", "err", err)
+		return nil, err
+	}
+	response := &dal_wallet_go.FetchEventsResponse{
+		Code:       dal_wallet_go.ReturnCode_SUCCESS,
+		Msg:        "fetch events success",
+		NextCursor: request.Cursor,
+	}
+	for _, record := range records {
+		response.Events = append(response.Events, walletEvent(request.RequestId, record))
+		response.NextCursor = record.Sequence
+	}
+	return response, nil
+}
+
+// AckEvents 按事件 id 逐条确认，重复确认不报错
+func (bws *BusinessMiddleWireServices) AckEvents(ctx context.Context, request *dal_wallet_go.AckEventsRequest) (*dal_wallet_go.AckEventsResponse, error) {
+	if request.RequestId == "" || len(request.Ids) == 0 || len(request.Ids) > maxAckEventsPerRequest {
+		return &dal_wallet_go.AckEventsResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  "invalid params",
+		}, nil
+	}
+	for _, id := range request.Ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return &dal_wallet_go.AckEventsResponse{
+				Code: dal_wallet_go.ReturnCode_ERROR,
+				Msg:  "invalid event id " + id,
+			}, nil
+		}
+	}
+	acked, err := bws.db.Events.AckEvents(request.RequestId, request.Ids)
+	if err != nil {
+		log.Error("ack events fail", "err", err)
+		return nil, err
+	}
+	return &dal_wallet_go.AckEventsResponse{
+		Code:  dal_wallet_go.ReturnCode_SUCCESS,
+		Msg:   "ack events success",
+		Acked: uint64(acked),
+	}, nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/database"
+	dal_wallet_go "github.com/CavnHan/multichain-sync-account/protobuf/dal-wallet-go"
+)
+
+type fakeEvents struct {
+	mu     sync.Mutex
+	events []database.Events
+}
+
+func (f *fakeEvents) append(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := 0; i < n; i++ {
+		f.events = append(f.events, database.Events{Sequence: uint64(len(f.events) + 1), Kind: database.EventKindDeposit})
+	}
+}
+
+func (f *fakeEvents) QueryEventsAfter(_ string, sequence uint64, limit int) ([]database.Events, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []database.Events
+	for _, event := range f.events {
+		if event.Sequence > sequence && len(out) < limit {
+			out = append(out, event)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeEvents) QueryEventCursor(string, string) (uint64, error) {
+	return 0, nil
+}
+
+func TestStreamEvents(t *testing.T) {
+	source := &fakeEvents{}
+	source.append(subscribeBatchSize + 5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var received []uint64
+	done := make(chan error)
+	go func() {
+		done <- streamEvents(ctx, source, "b", 3, time.Millisecond, func(event *dal_wallet_go.WalletEvent) error {
+			received = append(received, event.Sequence)
+			if event.Sequence == subscribeBatchSize+7 {
+				cancel()
+			} else if event.Sequence == subscribeBatchSize+5 {
+				// 追上之后新写入的事件在下一次轮询时推送
+				source.append(2)
+			}
+			return nil
+		})
+	}()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not stop")
+	}
+	require.Len(t, received, subscribeBatchSize+4)
+	for i, sequence := range received {
+		require.Equal(t, uint64(i+4), sequence)
+	}
+}
@@ -4,34 +4,51 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"math/big"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/google/uuid"
 
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/config"
 	"github.com/CavnHan/multichain-sync-account/database"
 	"github.com/CavnHan/multichain-sync-account/database/dynamic"
+	"github.com/CavnHan/multichain-sync-account/notifier"
 	dal_wallet_go "github.com/CavnHan/multichain-sync-account/protobuf/dal-wallet-go"
 	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/common"
+	"github.com/CavnHan/multichain-sync-account/txverify"
+	"github.com/CavnHan/multichain-sync-account/worker"
 )
 
-const (
-	ChainName = "Ethereum"
-	Network   = "mainnet"
-)
+const Network = "mainnet"
 
 var (
-	EthGasLimit          uint64 = 21000
-	TokenGasLimit        uint64 = 120000
-	maxFeePerGas                = "2900000000"
-	maxPriorityFeePerGas        = "2600000000"
+	EthGasLimit   uint64 = 21000
+	TokenGasLimit uint64 = 120000
+)
+
+// 链配置没有设置 fee_policy 时的默认手续费
+const (
+	defaultMaxFeePerGas         = "2900000000"
+	defaultMaxPriorityFeePerGas = "2600000000"
 )
 
 func (bws *BusinessMiddleWireServices) BusinessRegister(ctx context.Context, request *dal_wallet_go.BusinessRegisterRequest) (*dal_wallet_go.BusinessRegisterResponse, error) {
-	if request.RequestId == "" || request.NotifyUrl == "" {
+	sinks, err := notifier.ParseSinks(request.Sinks)
+	if err != nil {
+		return &dal_wallet_go.BusinessRegisterResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  err.Error(),
+		}, nil
+	}
+	// 只有选择 webhook 投递时才需要回调地址
+	if request.RequestId == "" || (request.NotifyUrl == "" && slices.Contains(sinks, notifier.SinkWebhook)) {
 		return &dal_wallet_go.BusinessRegisterResponse{
 			Code: dal_wallet_go.ReturnCode_ERROR,
 			Msg:  "invalid params",
@@ -41,10 +58,11 @@ func (bws *BusinessMiddleWireServices) BusinessRegister(ctx context.Context, req
 		GUID:        uuid.New(),
 		BusinessUid: request.RequestId,
 		NotifyUrl:   request.NotifyUrl,
+		Sinks:       strings.Join(sinks, ","),
 		Timestamp:   uint64(time.Now().Unix()),
 	}
 
-	err := bws.db.Business.StoreBusiness(business)
+	err = bws.db.Business.StoreBusiness(business)
 	if err != nil {
 		log.Error("store business fail", "err", err)
 		return &dal_wallet_go.BusinessRegisterResponse{
@@ -55,6 +73,11 @@ func (bws *BusinessMiddleWireServices) BusinessRegister(ctx context.Context, req
 
 	//create business table
 	dynamic.CreateTableFromTemplate(request.RequestId, bws.db)
+	if tokenList := defaultTokenList(bws.Tokens); len(tokenList) > 0 {
+		if err := bws.db.Tokens.StoreTokens(request.RequestId, tokenList); err != nil {
+			log.Error("store default tokens fail", "business", request.RequestId, "err", err)
+		}
+	}
 
 	return &dal_wallet_go.BusinessRegisterResponse{
 		Code: dal_wallet_go.ReturnCode_SUCCESS,
@@ -62,6 +85,84 @@ func (bws *BusinessMiddleWireServices) BusinessRegister(ctx context.Context, req
 	}, nil
 }
 
+func (bws *BusinessMiddleWireServices) UpdateBusinessStatus(ctx context.Context, request *dal_wallet_go.UpdateBusinessStatusRequest) (*dal_wallet_go.UpdateBusinessStatusResponse, error) {
+	status := uint8(request.Status)
+	if request.RequestId == "" || (status != database.BusinessStatusActive && status != database.BusinessStatusSuspended) {
+		return &dal_wallet_go.UpdateBusinessStatusResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  "invalid params",
+		}, nil
+	}
+	err := bws.db.Business.UpdateBusinessStatus(request.RequestId, status)
+	if err != nil {
+		log.Error("update business status fail", "err", err)
+		return &dal_wallet_go.UpdateBusinessStatusResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  "update business status fail",
+		}, nil
+	}
+	return &dal_wallet_go.UpdateBusinessStatusResponse{
+		Code: dal_wallet_go.ReturnCode_SUCCESS,
+		Msg:  "update business status success",
+	}, nil
+}
+
+func (bws *BusinessMiddleWireServices) RemoveBusiness(ctx context.Context, request *dal_wallet_go.RemoveBusinessRequest) (*dal_wallet_go.RemoveBusinessResponse, error) {
+	if request.RequestId == "" {
+		return &dal_wallet_go.RemoveBusinessResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  "invalid params",
+		}, nil
+	}
+	err := bws.db.Business.RemoveBusiness(request.RequestId)
+	if err != nil {
+		log.Error("remove business fail", "err", err)
+		return &dal_wallet_go.RemoveBusinessResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  "remove business fail",
+		}, nil
+	}
+	return &dal_wallet_go.RemoveBusinessResponse{
+		Code: dal_wallet_go.ReturnCode_SUCCESS,
+		Msg:  "remove business success",
+	}, nil
+}
+
+func (bws *BusinessMiddleWireServices) RescanBlocks(ctx context.Context, request *dal_wallet_go.RescanBlocksRequest) (*dal_wallet_go.RescanBlocksResponse, error) {
+	report, err := bws.rescanner.Rescan(ctx, request.RequestId, request.FromHeight, request.ToHeight)
+	if err != nil {
+		log.Error("rescan blocks fail", "from", request.FromHeight, "to", request.ToHeight, "err", err)
+		resp := &dal_wallet_go.RescanBlocksResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  "rescan blocks fail: " + err.Error(),
+		}
+		if report != nil {
+			resp.BlocksScanned = report.BlocksScanned
+			resp.Results = rescanResults(report)
+		}
+		return resp, nil
+	}
+	return &dal_wallet_go.RescanBlocksResponse{
+		Code:          dal_wallet_go.ReturnCode_SUCCESS,
+		Msg:           "rescan blocks success",
+		BlocksScanned: report.BlocksScanned,
+		Results:       rescanResults(report),
+	}, nil
+}
+
+func rescanResults(report *worker.RescanReport) []*dal_wallet_go.RescanBusinessResult {
+	results := make([]*dal_wallet_go.RescanBusinessResult, 0, len(report.Businesses))
+	for _, business := range report.Businesses {
+		results = append(results, &dal_wallet_go.RescanBusinessResult{
+			RequestId: business.BusinessId,
+			Found:     uint64(business.Found),
+			Inserted:  uint64(business.Inserted),
+			Skipped:   uint64(business.Skipped),
+		})
+	}
+	return results
+}
+
 func (bws *BusinessMiddleWireServices) ExportAddressesByPublicKeys(ctx context.Context, request *dal_wallet_go.ExportAddressesRequest) (*dal_wallet_go.ExportAddressesResponse, error) {
 	var retAddresses []*dal_wallet_go.Address
 	var dbAddresses []database.Addresses
@@ -70,17 +171,29 @@ func (bws *BusinessMiddleWireServices) ExportAddressesByPublicKeys(ctx context.C
 		log.Info("public key", "public key", value.PublicKey)
 		log.Info("type", "type", value.Type)
 		address := bws.accountClient.ExportAddressByPubKey(strconv.Itoa(int(value.Type)), value.PublicKey)
+		parsedAddress, err := chainaddr.ParseAddress(address)
+		if err != nil || parsedAddress.IsZero() {
+			log.Error("export address is invalid", "publicKey", value.PublicKey, "address", address, "err", err)
+			return &dal_wallet_go.ExportAddressesResponse{
+				Code: dal_wallet_go.ReturnCode_ERROR,
+				Msg:  "export address fail",
+			}, nil
+		}
 		item := &dal_wallet_go.Address{
 			Type:    value.Type,
 			Address: address,
 		}
 		dbAddress := database.Addresses{
 			GUID:        uuid.New(),
-			Address:     common.HexToAddress(address),
+			Address:     parsedAddress,
 			AddressType: uint8(value.Type),
 			PublicKey:   value.PublicKey,
 			Timestamp:   uint64(time.Now().Unix()),
 		}
+		// 只有用户地址需要回溯注册前的历史充值
+		if request.BackfillHistory && value.Type == 0 {
+			dbAddress.BackfillStatus = database.BackfillStatusPending
+		}
 		dbAddresses = append(dbAddresses, dbAddress)
 		retAddresses = append(retAddresses, item)
 	}
@@ -99,54 +212,155 @@ func (bws *BusinessMiddleWireServices) ExportAddressesByPublicKeys(ctx context.C
 	}, nil
 }
 
+func (bws *BusinessMiddleWireServices) RegisterXpub(ctx context.Context, request *dal_wallet_go.RegisterXpubRequest) (*dal_wallet_go.RegisterXpubResponse, error) {
+	if request.RequestId == "" || request.Xpub == "" {
+		return &dal_wallet_go.RegisterXpubResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  "invalid params",
+		}, nil
+	}
+	if err := bws.addressPool.Register(request.RequestId, request.Xpub); err != nil {
+		log.Error("register xpub fail", "err", err)
+		return &dal_wallet_go.RegisterXpubResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  "register xpub fail: " + err.Error(),
+		}, nil
+	}
+	return &dal_wallet_go.RegisterXpubResponse{
+		Code: dal_wallet_go.ReturnCode_SUCCESS,
+		Msg:  "register xpub success",
+	}, nil
+}
+
+func (bws *BusinessMiddleWireServices) AllocateAddress(ctx context.Context, request *dal_wallet_go.AllocateAddressRequest) (*dal_wallet_go.AllocateAddressResponse, error) {
+	if request.RequestId == "" || request.UserUid == "" {
+		return &dal_wallet_go.AllocateAddressResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  "invalid params",
+		}, nil
+	}
+	address, err := bws.addressPool.Allocate(request.RequestId, request.UserUid)
+	if err != nil {
+		log.Error("allocate address fail", "err", err)
+		return &dal_wallet_go.AllocateAddressResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  "allocate address fail: " + err.Error(),
+		}, nil
+	}
+	path, err := bws.addressPool.DerivationPath(request.RequestId, address)
+	if err != nil {
+		log.Error("query derivation path fail", "err", err)
+	}
+	return &dal_wallet_go.AllocateAddressResponse{
+		Code:           dal_wallet_go.ReturnCode_SUCCESS,
+		Msg:            "allocate address success",
+		Address:        address.Address.String(),
+		PublicKey:      address.PublicKey,
+		DerivationPath: path,
+	}, nil
+}
+
 func (bws *BusinessMiddleWireServices) CreateUnSignTransaction(ctx context.Context, request *dal_wallet_go.UnSignWithdrawTransactionRequest) (*dal_wallet_go.UnSignWithdrawTransactionResponse, error) {
 	amountBig, _ := new(big.Int).SetString(request.Value, 10)
 	transactionId := uuid.New()
-	if request.TxType == "withdraw" {
-		withdraw := &database.Withdraws{
-			GUID:         transactionId,
-			BlockHash:    common.Hash{},
-			BlockNumber:  big.NewInt(0),
-			Hash:         common.Hash{},
-			FromAddress:  common.HexToAddress(request.From),
-			ToAddress:    common.HexToAddress(request.To),
-			TokenAddress: common.HexToAddress(request.ContractAddress),
-			TokenId:      request.TokenId,
-			TokenMeta:    request.TokenMeta,
-			Fee:          big.NewInt(0),
-			Amount:       amountBig,
-			Status:       0,
-			TxSignHex:    "",
-			Timestamp:    uint64(time.Now().Unix()),
+	if request.SigningQueue && (!bws.signerAuth.Enabled() || chainaddr.IsUTXO()) {
+		return &dal_wallet_go.UnSignWithdrawTransactionResponse{
+			Code:          dal_wallet_go.ReturnCode_ERROR,
+			Msg:           "signing queue is disabled or not supported on utxo chains",
+			TransactionId: transactionId.String(),
+			UnSignTx:      "0x00",
+		}, nil
+	}
+	if reject := bws.checkChainId(request.ChainId); reject != "" && !chainaddr.IsUTXO() {
+		return &dal_wallet_go.UnSignWithdrawTransactionResponse{
+			Code:          dal_wallet_go.ReturnCode_ERROR,
+			Msg:           reject,
+			TransactionId: transactionId.String(),
+			UnSignTx:      "0x00",
+		}, nil
+	}
+	fromAddress, toAddress, tokenAddress, err := parseTransferAddresses(request.From, request.To, request.ContractAddress)
+	if err != nil {
+		log.Error("invalid transfer address", "err", err)
+		return &dal_wallet_go.UnSignWithdrawTransactionResponse{
+			Code:          dal_wallet_go.ReturnCode_ERROR,
+			Msg:           "invalid address: " + err.Error(),
+			TransactionId: transactionId.String(),
+			UnSignTx:      "0x00",
+		}, nil
+	}
+	// NFT 转账的 token_meta 记录代币标准，数量以持仓校验结果为准
+	tokenId, tokenMeta, value := request.TokenId, request.TokenMeta, request.Value
+	if isNftTokenId(request.TokenId) {
+		holding, amount, err := bws.checkNftHolding(request.RequestId, fromAddress, tokenAddress, request.TokenId, request.Value)
+		if errors.Is(err, ErrInvalidNftTransfer) {
+			return &dal_wallet_go.UnSignWithdrawTransactionResponse{
+				Code:          dal_wallet_go.ReturnCode_ERROR,
+				Msg:           err.Error(),
+				TransactionId: transactionId.String(),
+				UnSignTx:      "0x00",
+			}, nil
 		}
-		//store withdraw
-		err := bws.db.Withdraws.StoreWithdraw(request.RequestId, withdraw)
 		if err != nil {
-			log.Error("store withdraw fail", "err", err)
+			log.Error("query nft holding fail", "err", err)
 			return nil, err
 		}
-	} else if request.TxType == "collection" || request.TxType == "hot2cold" {
-		internal := &database.Internals{
-			GUID:         transactionId,
-			BlockHash:    common.Hash{},
-			BlockNumber:  big.NewInt(0),
-			Hash:         common.Hash{},
-			FromAddress:  common.HexToAddress(request.From),
-			ToAddress:    common.HexToAddress(request.To),
-			TokenAddress: common.HexToAddress(request.ContractAddress),
-			TokenId:      request.TokenId,
-			TokenMeta:    request.TokenMeta,
-			Fee:          big.NewInt(0),
-			Amount:       amountBig,
-			Status:       0,
-			TxType:       request.TxType,
-			TxSignHex:    "",
-			Timestamp:    uint64(time.Now().Unix()),
+		tokenId, tokenMeta, value, amountBig = holding.TokenId, holding.Standard, amount.String(), amount
+	}
+	// store 写入提现或内部交易记录，UTXO 链在选币事务内写入并带上预估手续费
+	var store func(db *database.DB, fee *big.Int) error
+	if request.TxType == "withdraw" {
+		store = func(db *database.DB, fee *big.Int) error {
+			withdraw := &database.Withdraws{
+				GUID:         transactionId,
+				BlockHash:    "",
+				BlockNumber:  big.NewInt(0),
+				Hash:         "",
+				FromAddress:  fromAddress,
+				ToAddress:    toAddress,
+				TokenAddress: tokenAddress,
+				TokenId:      tokenId,
+				TokenMeta:    tokenMeta,
+				Fee:          fee,
+				Amount:       amountBig,
+				Status:       0,
+				TxSignHex:    "",
+				Memo:         request.Memo,
+				Timestamp:    uint64(time.Now().Unix()),
+			}
+			//store withdraw
+			err := db.Withdraws.StoreWithdraw(request.RequestId, withdraw)
+			if err != nil {
+				log.Error("store withdraw fail", "err", err)
+				return err
+			}
+			return nil
 		}
-		err := bws.db.Internals.StoreInternal(request.RequestId, internal)
-		if err != nil {
-			log.Error("store internal business transaction fail", "err", err)
-			return nil, err
+	} else if isInternalTxType(request.TxType) {
+		store = func(db *database.DB, fee *big.Int) error {
+			internal := &database.Internals{
+				GUID:         transactionId,
+				BlockHash:    "",
+				BlockNumber:  big.NewInt(0),
+				Hash:         "",
+				FromAddress:  fromAddress,
+				ToAddress:    toAddress,
+				TokenAddress: tokenAddress,
+				TokenId:      tokenId,
+				TokenMeta:    tokenMeta,
+				Fee:          fee,
+				Amount:       amountBig,
+				Status:       0,
+				TxType:       request.TxType,
+				TxSignHex:    "",
+				Timestamp:    uint64(time.Now().Unix()),
+			}
+			err := db.Internals.StoreInternal(request.RequestId, internal)
+			if err != nil {
+				log.Error("store internal business transaction fail", "err", err)
+				return err
+			}
+			return nil
 		}
 	} else {
 		return &dal_wallet_go.UnSignWithdrawTransactionResponse{
@@ -157,8 +371,32 @@ func (bws *BusinessMiddleWireServices) CreateUnSignTransaction(ctx context.Conte
 		}, nil
 	}
 
+	if chainaddr.IsUTXO() {
+		return bws.createUtxoUnSignTransaction(request, transactionId, fromAddress, toAddress, amountBig, store)
+	}
+	// 进入签名队列的交易由签名机通过 FetchSigningJobs 拉取
+	err = bws.db.Transaction(func(tx *database.DB) error {
+		if err := store(tx, big.NewInt(0)); err != nil {
+			return err
+		}
+		if !request.SigningQueue {
+			return nil
+		}
+		return tx.SigningJobs.StoreSigningJob(request.RequestId, &database.SigningJobs{
+			TransactionId: transactionId.String(),
+			TxType:        request.TxType,
+			ChainId:       bws.chainId(),
+			Status:        database.SigningJobStatusPending,
+			Timestamp:     uint64(time.Now().Unix()),
+		})
+	})
+	if err != nil {
+		log.Error("store transaction fail", "err", err)
+		return nil, err
+	}
+
 	accountReq := &account.AccountRequest{
-		Chain:   ChainName,
+		Chain:   bws.ChainName,
 		Network: Network,
 		Address: request.From,
 	}
@@ -169,25 +407,21 @@ func (bws *BusinessMiddleWireServices) CreateUnSignTransaction(ctx context.Conte
 	}
 	//str to int
 	nonce, _ := strconv.Atoi(accountInfo.Sequence)
-	var gasLimit uint64
-	if request.ContractAddress == "0x00" {
-		gasLimit = EthGasLimit
-	} else {
-		gasLimit = TokenGasLimit
-	}
 	//build tx
 	txStructure := TxStructure{
-		ChainId:         request.ChainId,
+		ChainId:         bws.chainId(),
 		Nonce:           uint64(nonce),
-		GasPrice:        maxFeePerGas,
-		GasTipCap:       maxFeePerGas,
-		GasFeeCap:       maxPriorityFeePerGas,
-		Gas:             gasLimit,
+		GasPrice:        bws.feePolicy.MaxFeePerGas,
+		GasTipCap:       bws.feePolicy.MaxFeePerGas,
+		GasFeeCap:       bws.feePolicy.MaxPriorityFeePerGas,
+		Gas:             transferGasLimit(tokenAddress, tokenId),
 		ContractAddress: request.ContractAddress,
 		FromAddress:     request.From,
 		ToAddress:       request.To,
-		TokenId:         request.TokenId,
-		Value:           request.Value,
+		TokenId:         tokenId,
+		TokenMeta:       nftTokenMeta(tokenId, tokenMeta),
+		Memo:            request.Memo,
+		Value:           value,
 	}
 	//conv struct to json
 	data, err := json.Marshal(txStructure)
@@ -199,7 +433,7 @@ func (bws *BusinessMiddleWireServices) CreateUnSignTransaction(ctx context.Conte
 	base64Data := base64.StdEncoding.EncodeToString(data)
 	//get tx hash by grpc
 	unSingTx := &account.UnSignTransactionRequest{
-		Chain:    ChainName,
+		Chain:    bws.ChainName,
 		Network:  Network,
 		Base64Tx: base64Data,
 	}
@@ -208,6 +442,28 @@ func (bws *BusinessMiddleWireServices) CreateUnSignTransaction(ctx context.Conte
 		log.Error("create un sign transaction fail", "err", err)
 		return nil, err
 	}
+	// 配置了本地签名时内部交易直接签名入库，由 worker 广播
+	if bws.LocalSigner != nil && isInternalTxType(request.TxType) && !request.SigningQueue && returnTx.Code == common.ReturnCode_SUCCESS {
+		expected := txverify.Expected{ChainId: bws.chainId(), Nonce: uint64(nonce), From: fromAddress, To: toAddress, Token: tokenAddress, TokenId: tokenId, Amount: amountBig}
+		reject, err := bws.signLocally(request.RequestId, request.TxType, transactionId.String(), expected, base64Data, returnTx.UnSignTx)
+		if err != nil {
+			return nil, err
+		}
+		if reject != "" {
+			return &dal_wallet_go.UnSignWithdrawTransactionResponse{
+				Code:          dal_wallet_go.ReturnCode_ERROR,
+				Msg:           reject,
+				TransactionId: transactionId.String(),
+				UnSignTx:      returnTx.UnSignTx,
+			}, nil
+		}
+		return &dal_wallet_go.UnSignWithdrawTransactionResponse{
+			Code:          dal_wallet_go.ReturnCode_SUCCESS,
+			Msg:           "submit internal transaction and sign with local signer success",
+			TransactionId: transactionId.String(),
+			UnSignTx:      returnTx.UnSignTx,
+		}, nil
+	}
 	return &dal_wallet_go.UnSignWithdrawTransactionResponse{
 		Code:          dal_wallet_go.ReturnCode_SUCCESS,
 		Msg:           "submit withdraw and build un sign tranaction success",
@@ -217,112 +473,98 @@ func (bws *BusinessMiddleWireServices) CreateUnSignTransaction(ctx context.Conte
 }
 
 func (bws *BusinessMiddleWireServices) BuildSignedTransaction(ctx context.Context, request *dal_wallet_go.SignedWithdrawTransactionRequest) (*dal_wallet_go.SignedWithdrawTransactionResponse, error) {
-	var txStructure TxStructure
-	if request.TxType == "withdraw" {
-		tx, err := bws.db.Withdraws.QueryWithdrawsByHash(request.RequestId, request.TransactionId)
-		if err != nil {
-			return nil, err
-		}
-		accountReq := &account.AccountRequest{
-			Chain:   ChainName,
-			Network: Network,
-			Address: tx.FromAddress.String(),
-		}
-		accountInfo, err := bws.accountClient.AccountRpClient.GetAccount(context.Background(), accountReq)
-		if err != nil {
-			return nil, err
-		}
-		nonce, _ := strconv.Atoi(accountInfo.Sequence)
-		var gasLimit uint64
-		if tx.TokenAddress.String() == "0x00" {
-			gasLimit = EthGasLimit
-		} else {
-			gasLimit = TokenGasLimit
-		}
-		txStructure = TxStructure{
-			ChainId:         request.ChainId,
-			Nonce:           uint64(nonce),
-			GasPrice:        maxFeePerGas,
-			GasTipCap:       maxFeePerGas,
-			GasFeeCap:       maxPriorityFeePerGas,
-			Gas:             gasLimit,
-			ContractAddress: tx.TokenAddress.String(),
-			FromAddress:     tx.FromAddress.String(),
-			ToAddress:       tx.ToAddress.String(),
-			TokenId:         tx.TokenId,
-			Value:           tx.Amount.String(),
-		}
-	} else if request.TxType == "collection" || request.TxType == "hot2cold" {
-		tx, err := bws.db.Internals.QueryInternalsByHash(request.RequestId, request.TransactionId)
-		if err != nil {
-			return nil, err
-		}
-		accountReq := &account.AccountRequest{
-			Chain:   ChainName,
-			Network: Network,
-			Address: tx.FromAddress.String(),
-		}
-		accountInfo, err := bws.accountClient.AccountRpClient.GetAccount(context.Background(), accountReq)
-		if err != nil {
-			return nil, err
-		}
-		nonce, _ := strconv.Atoi(accountInfo.Sequence)
-		var gasLimit uint64
-		if tx.TokenAddress.String() == "0x00" {
-			gasLimit = EthGasLimit
-		} else {
-			gasLimit = TokenGasLimit
-		}
-		txStructure = TxStructure{
-			ChainId:         request.ChainId,
-			Nonce:           uint64(nonce),
-			GasPrice:        maxFeePerGas,
-			GasTipCap:       maxFeePerGas,
-			GasFeeCap:       maxPriorityFeePerGas,
-			Gas:             gasLimit,
-			ContractAddress: tx.TokenAddress.String(),
-			FromAddress:     tx.FromAddress.String(),
-			ToAddress:       tx.ToAddress.String(),
-			TokenId:         tx.TokenId,
-			Value:           tx.Amount.String(),
+	errorResponse := func(msg string) *dal_wallet_go.SignedWithdrawTransactionResponse {
+		return &dal_wallet_go.SignedWithdrawTransactionResponse{
+			Code:     dal_wallet_go.ReturnCode_ERROR,
+			Msg:      msg,
+			SignedTx: "",
 		}
-	} else {
+	}
+	if request.TxType != "withdraw" && !isInternalTxType(request.TxType) {
+		return errorResponse("Un support transaction type"), nil
+	}
+	if chainaddr.IsUTXO() {
+		return bws.buildSignedUtxoTransaction(request)
+	}
+	if reject := bws.checkChainId(request.ChainId); reject != "" {
+		return errorResponse(reject), nil
+	}
+	pending, err := bws.queryPendingTx(request.RequestId, request.TxType, request.TransactionId)
+	if err != nil {
+		return nil, err
+	}
+	if pending == nil {
+		return errorResponse("transaction not found"), nil
+	}
+	// 已签名、已发送或已拦截的交易不能重新签名，否则状态会被改回已签名并再次广播
+	if pending.status != 0 {
+		return errorResponse("transaction is no longer waiting for signature"), nil
+	}
+	txStructure, err := bws.accountTxStructure(bws.chainId(), pending)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := encodeTxPayload(txStructure)
+	if err != nil {
+		return nil, err
+	}
+	// 签名交易的要素必须与库中记录和本次构造时取到的 nonce 一致，否则拦截
+	expected := pending.expected
+	expected.Nonce, expected.ChainId = txStructure.Nonce, txStructure.ChainId
+	signedTx, reject, err := bws.signAccountTx(request.RequestId, request.TxType, request.TransactionId, "", expected, payload, request.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if reject != "" {
+		return errorResponse(reject), nil
+	}
+	return &dal_wallet_go.SignedWithdrawTransactionResponse{
+		Code:     1,
+		Msg:      "build signed tx success",
+		SignedTx: signedTx,
+	}, nil
+}
+
+// buildSignedUtxoTransaction UTXO 链按锁定的 utxo 重建交易，没有单一收款方，不做签名交易校验
+func (bws *BusinessMiddleWireServices) buildSignedUtxoTransaction(request *dal_wallet_go.SignedWithdrawTransactionRequest) (*dal_wallet_go.SignedWithdrawTransactionResponse, error) {
+	utxoTx, err := bws.lockedUtxoTxStructure(request.RequestId, request.TxType, request.TransactionId)
+	if err != nil {
+		log.Error("rebuild utxo transaction fail", "err", err)
 		return &dal_wallet_go.SignedWithdrawTransactionResponse{
 			Code:     dal_wallet_go.ReturnCode_ERROR,
-			Msg:      "Un support transaction type",
+			Msg:      "rebuild utxo transaction fail: " + err.Error(),
 			SignedTx: "",
 		}, nil
 	}
-	data, err := json.Marshal(txStructure)
+	payload, err := encodeTxPayload(utxoTx)
 	if err != nil {
-		log.Error("parse json fail", "err", err)
 		return nil, err
 	}
-	base64Str := base64.StdEncoding.EncodeToString(data)
-	signedTx := &account.SignedTransactionRequest{
-		Chain:     ChainName,
+	returnTx, err := bws.accountClient.AccountRpClient.BuildSignedTransaction(context.Background(), &account.SignedTransactionRequest{
+		Chain:     bws.ChainName,
 		Network:   Network,
 		Signature: request.Signature,
-		Base64Tx:  base64Str,
-	}
-	returnTx, err := bws.accountClient.AccountRpClient.BuildSignedTransaction(context.Background(), signedTx)
+		Base64Tx:  payload,
+	})
 	if err != nil {
 		log.Error("create un sign transaction fail", "err", err)
 		return nil, err
 	}
-
+	if returnTx.Code == common.ReturnCode_ERROR {
+		log.Error("build signed transaction fail", "msg", returnTx.Msg)
+		return &dal_wallet_go.SignedWithdrawTransactionResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  "build signed transaction fail: " + returnTx.Msg,
+		}, nil
+	}
 	if request.TxType == "withdraw" {
 		err = bws.db.Withdraws.UpdateWithdrawTx(request.RequestId, request.TransactionId, returnTx.SignedTx, nil, 1) // 1:交易已经签名
-		if err != nil {
-			log.Error("update signed tx to db fail", "err", err)
-			return nil, err
-		}
 	} else {
 		err = bws.db.Internals.UpdateInternalTx(request.RequestId, request.TransactionId, returnTx.SignedTx, nil, 1) // 1:交易已经签名
-		if err != nil {
-			log.Error("update signed tx to db fail", "err", err)
-			return nil, err
-		}
+	}
+	if err != nil {
+		log.Error("update signed tx to db fail", "err", err)
+		return nil, err
 	}
 	return &dal_wallet_go.SignedWithdrawTransactionResponse{
 		Code:     1,
@@ -336,9 +578,17 @@ func (bws *BusinessMiddleWireServices) SetTokenAddress(ctx context.Context, requ
 	for _, value := range request.TokenList {
 		CollectAmountBigInt, _ := new(big.Int).SetString(value.CollectAmount, 10)
 		ColdAmountBigInt, _ := new(big.Int).SetString(value.ColdAmount, 10)
+		tokenAddress, err := chainaddr.ParseAddress(value.Address)
+		if err != nil {
+			log.Error("invalid token address", "address", value.Address, "err", err)
+			return &dal_wallet_go.SetTokenAddressResponse{
+				Code: dal_wallet_go.ReturnCode_ERROR,
+				Msg:  "invalid token address: " + value.Address,
+			}, nil
+		}
 		token := database.Tokens{
 			GUID:          uuid.New(),
-			TokenAddress:  common.HexToAddress(value.Address),
+			TokenAddress:  tokenAddress,
 			Decimals:      uint8(value.Decimals),
 			TokenName:     value.TokenName,
 			CollectAmount: CollectAmountBigInt,
@@ -359,3 +609,66 @@ func (bws *BusinessMiddleWireServices) SetTokenAddress(ctx context.Context, requ
 	}, nil
 
 }
+
+// withFeeDefaults 链配置中为空的手续费参数取内置默认值，配置已在加载时校验
+func withFeeDefaults(policy config.FeePolicy) config.FeePolicy {
+	if policy.MaxFeePerGas == "" {
+		policy.MaxFeePerGas = defaultMaxFeePerGas
+	}
+	if policy.MaxPriorityFeePerGas == "" {
+		policy.MaxPriorityFeePerGas = defaultMaxPriorityFeePerGas
+	}
+	if policy.UtxoFeeRate <= 0 {
+		policy.UtxoFeeRate = defaultUtxoFeeRate
+	}
+	return policy
+}
+
+// defaultTokenList 把链配置中的代币列表转换为业务方代币表记录，地址已在加载配置时校验
+func defaultTokenList(tokens []config.TokenConfig) []database.Tokens {
+	var tokenList []database.Tokens
+	for _, value := range tokens {
+		tokenAddress, err := chainaddr.ParseAddress(value.Address)
+		if err != nil {
+			log.Error("invalid default token address", "address", value.Address, "err", err)
+			continue
+		}
+		collectAmount, _ := new(big.Int).SetString(value.CollectAmount, 10)
+		coldAmount, _ := new(big.Int).SetString(value.ColdAmount, 10)
+		tokenList = append(tokenList, database.Tokens{
+			GUID:          uuid.New(),
+			TokenAddress:  tokenAddress,
+			Decimals:      value.Decimals,
+			TokenName:     value.Symbol,
+			CollectAmount: collectAmount,
+			ColdAmount:    coldAmount,
+			Timestamp:     uint64(time.Now().Unix()),
+		})
+	}
+	return tokenList
+}
+
+// parseTransferAddresses 按当前链规范化转账地址，合约地址为空表示原生币
+func parseTransferAddresses(from, to, contract string) (chainaddr.Address, chainaddr.Address, chainaddr.Address, error) {
+	fromAddress, err := chainaddr.ParseAddress(from)
+	if err != nil {
+		return "", "", "", err
+	}
+	toAddress, err := chainaddr.ParseAddress(to)
+	if err != nil {
+		return "", "", "", err
+	}
+	tokenAddress, err := parseTokenAddress(contract)
+	if err != nil {
+		return "", "", "", err
+	}
+	return fromAddress, toAddress, tokenAddress, nil
+}
+
+// parseTokenAddress 合约地址为空表示原生币，兼容旧版客户端用 0x00 表示原生币
+func parseTokenAddress(contract string) (chainaddr.Address, error) {
+	if contract == "" || contract == "0x00" {
+		return chainaddr.NativeToken(), nil
+	}
+	return chainaddr.ParseAddress(contract)
+}
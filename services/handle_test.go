@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/config"
+	"github.com/CavnHan/multichain-sync-account/database"
+	dal_wallet_go "github.com/CavnHan/multichain-sync-account/protobuf/dal-wallet-go"
+	"github.com/CavnHan/multichain-sync-account/txverify"
+)
+
+type memWithdraws struct {
+	database.WithdrawsDB
+	withdraws map[string]database.Withdraws
+}
+
+func (db *memWithdraws) QueryWithdrawsByHash(_ string, txId string) (*database.Withdraws, error) {
+	withdraw, ok := db.withdraws[txId]
+	if !ok {
+		return nil, nil
+	}
+	return &withdraw, nil
+}
+
+func TestBuildSignedTransactionRejects(t *testing.T) {
+	blocked, sent := uuid.New(), uuid.New()
+	withdraws := &memWithdraws{withdraws: map[string]database.Withdraws{
+		blocked.String(): {GUID: blocked, Status: txverify.StatusBlocked},
+		sent.String():    {GUID: sent, Status: 2},
+	}}
+	bws, err := NewBusinessMiddleWireServices(&database.DB{Withdraws: withdraws}, &BusinessMiddleConfig{ChainName: "Ethereum", ChainId: 11155111}, nil)
+	require.NoError(t, err)
+	build := func(transactionId string, chainId string) *dal_wallet_go.SignedWithdrawTransactionResponse {
+		resp, err := bws.BuildSignedTransaction(context.Background(), &dal_wallet_go.SignedWithdrawTransactionRequest{
+			RequestId:     "biz",
+			TransactionId: transactionId,
+			ChainId:       chainId,
+			Signature:     "0x01",
+			TxType:        "withdraw",
+		})
+		require.NoError(t, err)
+		require.Equal(t, dal_wallet_go.ReturnCode_ERROR, resp.Code)
+		return resp
+	}
+
+	// 已拦截和已发送的提现不能重新签名
+	require.Contains(t, build(blocked.String(), "11155111").Msg, "no longer waiting")
+	require.Contains(t, build(sent.String(), "0xaa36a7").Msg, "no longer waiting")
+	require.Contains(t, build(uuid.NewString(), "").Msg, "not found")
+	// 业务方传入的链 id 与配置不一致时直接拒绝
+	require.Contains(t, build(blocked.String(), "1").Msg, "does not match configured chain id 11155111")
+}
+
+func TestFeePolicyPerService(t *testing.T) {
+	custom, err := NewBusinessMiddleWireServices(&database.DB{}, &BusinessMiddleConfig{FeePolicy: config.FeePolicy{MaxFeePerGas: "3000000000", UtxoFeeRate: 20}}, nil)
+	require.NoError(t, err)
+	defaults, err := NewBusinessMiddleWireServices(&database.DB{}, &BusinessMiddleConfig{}, nil)
+	require.NoError(t, err)
+
+	// 每个实例使用自己的手续费配置，未配置的项取默认值，互不影响
+	tx := &pendingTx{}
+	require.Equal(t, "3000000000", custom.buildTxStructure("1", 0, tx).GasPrice)
+	require.Equal(t, defaultMaxPriorityFeePerGas, custom.buildTxStructure("1", 0, tx).GasFeeCap)
+	require.Equal(t, int64(20), custom.feePolicy.UtxoFeeRate)
+	require.Equal(t, defaultMaxFeePerGas, defaults.buildTxStructure("1", 0, tx).GasPrice)
+	require.Equal(t, defaultUtxoFeeRate, defaults.feePolicy.UtxoFeeRate)
+}
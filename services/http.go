@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gorm.io/gorm"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	dal_wallet_go "github.com/CavnHan/multichain-sync-account/protobuf/dal-wallet-go"
+)
+
+const (
+	maxHttpBodySize   = 10 * 1024 * 1024
+	httpReadTimeout   = 30 * time.Second
+	httpWriteTimeout  = 60 * time.Second
+	httpShutdownGrace = 10 * time.Second
+)
+
+var (
+	jsonMarshaler   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	jsonUnmarshaler = protojson.UnmarshalOptions{DiscardUnknown: false}
+)
+
+// ErrorResponse 是网关层错误的统一返回体，字段与 gRPC 响应中的 code/msg 保持一致
+type ErrorResponse struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// QueryResponse 是查询类接口的返回体
+type QueryResponse struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+	Data any    `json:"data"`
+}
+
+type queryParam struct {
+	Name     string
+	Required bool
+	Usage    string
+}
+
+type route struct {
+	Method   string
+	Path     string
+	Summary  string
+	Request  protoreflect.MessageDescriptor
+	Response protoreflect.MessageDescriptor
+	Params   []queryParam
+	handler  http.HandlerFunc
+}
+
+// unaryRoute 把 HTTP JSON 请求转换为 proto 请求，直接调用对应的 gRPC 实现
+func unaryRoute[Req, Resp proto.Message](bws *BusinessMiddleWireServices, path, summary string, call func(context.Context, Req) (Resp, error)) route {
+	var reqZero Req
+	var respZero Resp
+	return route{
+		Method:   http.MethodPost,
+		Path:     path,
+		Summary:  summary,
+		Request:  reqZero.ProtoReflect().Descriptor(),
+		Response: respZero.ProtoReflect().Descriptor(),
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxHttpBodySize))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "read request body fail")
+				return
+			}
+			req := reqZero.ProtoReflect().New().Interface().(Req)
+			if err := jsonUnmarshaler.Unmarshal(body, req); err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+				return
+			}
+			if err := bws.auth.Check(r.Header.Get(ConsumerTokenHeader), req); err != nil {
+				writeError(w, consumerAuthStatus(err), err.Error())
+				return
+			}
+			resp, err := call(r.Context(), req)
+			if err != nil {
+				log.Error("http gateway call fail", "path", path, "err", err)
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			data, err := jsonMarshaler.Marshal(resp)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "marshal response fail")
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(data)
+		},
+	}
+}
+
+func queryRoute(bws *BusinessMiddleWireServices, path, summary string, params []queryParam, query func(values map[string]string) (any, error)) route {
+	return route{
+		Method:  http.MethodGet,
+		Path:    path,
+		Summary: summary,
+		Params:  params,
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			if err := bws.auth.CheckBusiness(r.Header.Get(ConsumerTokenHeader), r.URL.Query().Get("request_id")); err != nil {
+				writeError(w, consumerAuthStatus(err), err.Error())
+				return
+			}
+			values := make(map[string]string, len(params))
+			for _, param := range params {
+				value := r.URL.Query().Get(param.Name)
+				if param.Required && value == "" {
+					writeError(w, http.StatusBadRequest, fmt.Sprintf("missing query param %s", param.Name))
+					return
+				}
+				values[param.Name] = value
+			}
+			data, err := query(values)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					writeError(w, http.StatusNotFound, "record not found")
+					return
+				}
+				if errors.Is(err, chainaddr.ErrInvalidAddress) || errors.Is(err, chainaddr.ErrInvalidHash) {
+					writeError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+				log.Error("http gateway query fail", "path", path, "err", err)
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			if data == nil {
+				writeError(w, http.StatusNotFound, "record not found")
+				return
+			}
+			writeJson(w, http.StatusOK, QueryResponse{
+				Code: dal_wallet_go.ReturnCode_SUCCESS.String(),
+				Msg:  "query success",
+				Data: data,
+			})
+		},
+	}
+}
+
+// consumerAuthStatus token 有效但无权访问该业务方时返回 403
+func consumerAuthStatus(err error) int {
+	if errors.Is(err, errBusinessNotAllowed) {
+		return http.StatusForbidden
+	}
+	return http.StatusUnauthorized
+}
+
+func (bws *BusinessMiddleWireServices) routes() []route {
+	requestId := queryParam{Name: "request_id", Required: true, Usage: "business request id"}
+	return []route{
+		unaryRoute(bws, "/api/v1/business/register", "Register a business", bws.BusinessRegister),
+		unaryRoute(bws, "/api/v1/business/status", "Suspend or resume a business", bws.UpdateBusinessStatus),
+		unaryRoute(bws, "/api/v1/business/remove", "Remove a business", bws.RemoveBusiness),
+		unaryRoute(bws, "/api/v1/blocks/rescan", "Rescan a block range and backfill missed transactions", bws.RescanBlocks),
+		unaryRoute(bws, "/api/v1/addresses/export", "Export addresses by public keys", bws.ExportAddressesByPublicKeys),
+		unaryRoute(bws, "/api/v1/addresses/xpub", "Register an extended public key for local address derivation", bws.RegisterXpub),
+		unaryRoute(bws, "/api/v1/addresses/allocate", "Allocate a pooled address to a user", bws.AllocateAddress),
+		unaryRoute(bws, "/api/v1/addresses/memo", "Allocate a memo on a shared deposit address", bws.AllocateMemo),
+		unaryRoute(bws, "/api/v1/events/fetch", "Fetch unacknowledged events from the business inbox", bws.FetchEvents),
+		unaryRoute(bws, "/api/v1/events/ack", "Acknowledge fetched events by id", bws.AckEvents),
+		unaryRoute(bws, "/api/v1/events/subscription/ack", "Acknowledge events received from SubscribeEvents", bws.AckSubscription),
+		unaryRoute(bws, "/api/v1/deposits/quarantined/resolve", "Assign a memo to a quarantined deposit", bws.ResolveQuarantinedDeposit),
+		unaryRoute(bws, "/api/v1/transactions/unsigned", "Create an unsigned transaction", bws.CreateUnSignTransaction),
+		unaryRoute(bws, "/api/v1/transactions/signed", "Build a signed transaction", bws.BuildSignedTransaction),
+		unaryRoute(bws, "/api/v1/withdraws/batch", "Merge pending withdraws into one unsigned batch transaction", bws.CreateBatchWithdrawTransaction),
+		unaryRoute(bws, "/api/v1/withdraws/batch/signed", "Build a signed batch withdraw transaction", bws.BuildSignedBatchWithdrawTransaction),
+		unaryRoute(bws, "/api/v1/cold-bundles/export", "Export pending internal transactions as an offline signing bundle", bws.ExportColdBundle),
+		unaryRoute(bws, "/api/v1/cold-bundles/import", "Import a signed offline bundle and queue verified transactions for broadcast", bws.ImportColdBundle),
+		unaryRoute(bws, "/api/v1/tokens", "Set token addresses", bws.SetTokenAddress),
+
+		queryRoute(bws, "/api/v1/business", "Query business info", []queryParam{requestId}, func(values map[string]string) (any, error) {
+			return bws.db.Business.QueryBusinessByUuid(values["request_id"])
+		}),
+		queryRoute(bws, "/api/v1/deposits/quarantined", "Query deposits quarantined for missing or unknown memo", []queryParam{requestId}, func(values map[string]string) (any, error) {
+			return bws.db.Deposits.QueryQuarantinedDeposits(values["request_id"])
+		}),
+		queryRoute(bws, "/api/v1/withdraws", "Query withdraw by transaction id", []queryParam{
+			requestId,
+			{Name: "transaction_id", Required: true, Usage: "transaction id returned by createUnSignTransaction"},
+		}, func(values map[string]string) (any, error) {
+			withdraw, err := bws.db.Withdraws.QueryWithdrawsByHash(values["request_id"], values["transaction_id"])
+			if withdraw == nil {
+				return nil, err
+			}
+			return withdraw, err
+		}),
+		queryRoute(bws, "/api/v1/transactions", "Query transaction by hash", []queryParam{
+			requestId,
+			{Name: "hash", Required: true, Usage: "transaction hash"},
+		}, func(values map[string]string) (any, error) {
+			hash, err := chainaddr.ParseHash(values["hash"])
+			if err != nil {
+				return nil, err
+			}
+			tx, err := bws.db.Transactions.QueryTransactionByHash(values["request_id"], hash)
+			if tx == nil {
+				return nil, err
+			}
+			return tx, err
+		}),
+		queryRoute(bws, "/api/v1/balances", "Query balance by address and token", []queryParam{
+			requestId,
+			{Name: "address", Required: true, Usage: "wallet address"},
+			{Name: "token_address", Required: true, Usage: "token contract address"},
+		}, func(values map[string]string) (any, error) {
+			address, err := chainaddr.ParseAddress(values["address"])
+			if err != nil {
+				return nil, err
+			}
+			tokenAddress, err := chainaddr.ParseAddress(values["token_address"])
+			if err != nil {
+				return nil, err
+			}
+			balance, err := bws.db.Balances.QueryWalletBalanceByTokenAndAddress(values["request_id"], address, tokenAddress)
+			if balance == nil {
+				return nil, err
+			}
+			return balance, err
+		}),
+	}
+}
+
+func (bws *BusinessMiddleWireServices) httpHandler() http.Handler {
+	mux := http.NewServeMux()
+	for _, r := range bws.routes() {
+		mux.HandleFunc(r.Method+" "+r.Path, r.handler)
+	}
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJson(w, http.StatusOK, bws.OpenAPISpec())
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "route not found")
+	})
+	return mux
+}
+
+func (bws *BusinessMiddleWireServices) startHttp() error {
+	addr := fmt.Sprintf("%s:%d", bws.HttpHostname, bws.HttpPort)
+	bws.httpServer = &http.Server{
+		Addr:         addr,
+		Handler:      bws.httpHandler(),
+		ReadTimeout:  httpReadTimeout,
+		WriteTimeout: httpWriteTimeout,
+	}
+	go func() {
+		log.Info("start http gateway", "addr", addr)
+		if err := bws.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("Could not start http gateway", "err", err)
+		}
+	}()
+	return nil
+}
+
+func (bws *BusinessMiddleWireServices) stopHttp(ctx context.Context) error {
+	if bws.httpServer == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, httpShutdownGrace)
+	defer cancel()
+	return bws.httpServer.Shutdown(ctx)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJson(w, status, ErrorResponse{
+		Code: dal_wallet_go.ReturnCode_ERROR.String(),
+		Msg:  msg,
+	})
+}
+
+func writeJson(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error("write http response fail", "err", err)
+	}
+}
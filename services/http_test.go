@@ -0,0 +1,66 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpGatewayErrors(t *testing.T) {
+	bws, err := NewBusinessMiddleWireServices(nil, &BusinessMiddleConfig{ConsumerTokens: []string{"secret:*", "scoped:a"}}, nil)
+	require.NoError(t, err)
+	handler := bws.httpHandler()
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		token  string
+		status int
+	}{
+		{"missing token", http.MethodPost, "/api/v1/business/register", `{"request_id":"a","notify_url":"http://a"}`, "", http.StatusUnauthorized},
+		{"wrong token", http.MethodPost, "/api/v1/business/register", `{"request_id":"a","consumer_token":"nope"}`, "", http.StatusUnauthorized},
+		{"invalid body", http.MethodPost, "/api/v1/business/register", `{"request_id":`, "secret", http.StatusBadRequest},
+		{"other business body", http.MethodPost, "/api/v1/business/register", `{"request_id":"b"}`, "scoped", http.StatusForbidden},
+		{"other business query", http.MethodGet, "/api/v1/business?request_id=b", "", "scoped", http.StatusForbidden},
+		{"scoped token updates status", http.MethodPost, "/api/v1/business/status", `{"request_id":"a","status":1}`, "scoped", http.StatusForbidden},
+		{"scoped token removes business", http.MethodPost, "/api/v1/business/remove", `{"request_id":"a"}`, "scoped", http.StatusForbidden},
+		{"scoped token rescans blocks", http.MethodPost, "/api/v1/blocks/rescan", `{"request_id":"a","from_height":1,"to_height":2}`, "scoped", http.StatusForbidden},
+		{"missing query param", http.MethodGet, "/api/v1/business", "", "secret", http.StatusBadRequest},
+		{"unknown route", http.MethodGet, "/api/v1/unknown", "", "secret", http.StatusNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if tc.token != "" {
+				req.Header.Set(ConsumerTokenHeader, tc.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			require.Equal(t, tc.status, rec.Code)
+
+			var body ErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			require.Equal(t, "ERROR", body.Code)
+			require.NotEmpty(t, body.Msg)
+		})
+	}
+}
+
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	bws, err := NewBusinessMiddleWireServices(nil, &BusinessMiddleConfig{}, nil)
+	require.NoError(t, err)
+	spec := bws.OpenAPISpec()
+	paths := spec["paths"].(map[string]any)
+	for _, r := range bws.routes() {
+		require.Contains(t, paths, r.Path)
+	}
+	schemas := spec["components"].(map[string]any)["schemas"].(map[string]any)
+	require.Contains(t, schemas, "UnSignWithdrawTransactionRequest")
+	require.Contains(t, schemas, "Token")
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	dal_wallet_go "github.com/CavnHan/multichain-sync-account/protobuf/dal-wallet-go"
+)
+
+// AllocateMemo 在共享充值地址上为用户分配 memo，地址必须是已导入的用户地址
+func (bws *BusinessMiddleWireServices) AllocateMemo(ctx context.Context, request *dal_wallet_go.AllocateMemoRequest) (*dal_wallet_go.AllocateMemoResponse, error) {
+	errorResponse := func(msg string) *dal_wallet_go.AllocateMemoResponse {
+		return &dal_wallet_go.AllocateMemoResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  msg,
+		}
+	}
+	if request.RequestId == "" || request.UserUid == "" || request.Address == "" {
+		return errorResponse("invalid params"), nil
+	}
+	address, err := chainaddr.ParseAddress(request.Address)
+	if err != nil {
+		return errorResponse("invalid address: " + err.Error()), nil
+	}
+	if exist, addressType := bws.db.Addresses.AddressExist(request.RequestId, address); !exist || addressType != 0 {
+		return errorResponse("address is not a user deposit address of the business"), nil
+	}
+	memo, err := bws.db.Memos.AllocateMemo(request.RequestId, address, request.UserUid)
+	if err != nil {
+		log.Error("allocate memo fail", "err", err)
+		return nil, err
+	}
+	return &dal_wallet_go.AllocateMemoResponse{
+		Code:    dal_wallet_go.ReturnCode_SUCCESS,
+		Msg:     "allocate memo success",
+		Address: memo.Address.String(),
+		Memo:    memo.Memo,
+	}, nil
+}
+
+// ResolveQuarantinedDeposit 人工确认隔离充值所属的 memo，memo 必须已在该充值地址上分配
+func (bws *BusinessMiddleWireServices) ResolveQuarantinedDeposit(ctx context.Context, request *dal_wallet_go.ResolveQuarantinedDepositRequest) (*dal_wallet_go.ResolveQuarantinedDepositResponse, error) {
+	errorResponse := func(msg string) *dal_wallet_go.ResolveQuarantinedDepositResponse {
+		return &dal_wallet_go.ResolveQuarantinedDepositResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  msg,
+		}
+	}
+	if request.RequestId == "" || request.TransactionId == "" || request.Memo == "" {
+		return errorResponse("invalid params"), nil
+	}
+	deposits, err := bws.db.Deposits.QueryQuarantinedDeposits(request.RequestId)
+	if err != nil {
+		return nil, err
+	}
+	for _, deposit := range deposits {
+		if deposit.GUID.String() != request.TransactionId {
+			continue
+		}
+		memo, err := bws.db.Memos.QueryMemo(request.RequestId, deposit.ToAddress, request.Memo)
+		if err != nil {
+			return nil, err
+		}
+		if memo == nil {
+			return errorResponse("memo is not allocated on the deposit address"), nil
+		}
+		if err := bws.db.Deposits.ResolveQuarantinedDeposit(request.RequestId, request.TransactionId, request.Memo); err != nil {
+			log.Error("resolve quarantined deposit fail", "err", err)
+			return nil, err
+		}
+		log.Info("resolve quarantined deposit", "requestId", request.RequestId, "deposit", request.TransactionId, "memo", request.Memo, "userUid", memo.UserUid)
+		return &dal_wallet_go.ResolveQuarantinedDepositResponse{
+			Code: dal_wallet_go.ReturnCode_SUCCESS,
+			Msg:  "resolve quarantined deposit success",
+		}, nil
+	}
+	return errorResponse("quarantined deposit not found"), nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+)
+
+var NftGasLimit uint64 = 200000
+
+var ErrInvalidNftTransfer = errors.New("invalid nft transfer")
+
+// isNftTokenId token_id 为空或 "0x00" 表示同质化代币，NFT 的 token id 统一使用十进制
+func isNftTokenId(tokenId string) bool {
+	return tokenId != "" && tokenId != "0x00"
+}
+
+// transferGasLimit 原生币、同质化代币和 NFT 转账的 gas 上限
+func transferGasLimit(tokenAddress chainaddr.Address, tokenId string) uint64 {
+	if isNftTokenId(tokenId) {
+		return NftGasLimit
+	}
+	if tokenAddress == chainaddr.NativeToken() {
+		return EthGasLimit
+	}
+	return TokenGasLimit
+}
+
+// nftTokenMeta 只有 NFT 转账需要告知 chain-account 代币标准
+func nftTokenMeta(tokenId, tokenMeta string) string {
+	if !isNftTokenId(tokenId) {
+		return ""
+	}
+	return tokenMeta
+}
+
+// checkNftHolding 校验转出地址持有足够数量的 NFT，value 为空时按 1 个处理，ERC-721 只能转 1 个
+func (bws *BusinessMiddleWireServices) checkNftHolding(requestId string, from, tokenAddress chainaddr.Address, tokenId, value string) (*database.NftHoldings, *big.Int, error) {
+	if tokenAddress == chainaddr.NativeToken() {
+		return nil, nil, fmt.Errorf("%w: contract address is required", ErrInvalidNftTransfer)
+	}
+	id, ok := new(big.Int).SetString(tokenId, 10)
+	if !ok || id.Sign() < 0 {
+		return nil, nil, fmt.Errorf("%w: token id %s", ErrInvalidNftTransfer, tokenId)
+	}
+	amount := big.NewInt(1)
+	if value != "" {
+		amount, ok = new(big.Int).SetString(value, 10)
+		if !ok || amount.Sign() <= 0 {
+			return nil, nil, fmt.Errorf("%w: amount %s", ErrInvalidNftTransfer, value)
+		}
+	}
+	holding, err := bws.db.NftHoldings.QueryNftHolding(requestId, from, tokenAddress, id.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	if holding == nil || holding.Amount.Cmp(amount) < 0 {
+		return nil, nil, fmt.Errorf("%w: token %s not held by %s", ErrInvalidNftTransfer, id, from)
+	}
+	if holding.Standard == database.NftStandardERC721 && amount.Cmp(big.NewInt(1)) != 0 {
+		return nil, nil, fmt.Errorf("%w: erc721 amount must be 1", ErrInvalidNftTransfer)
+	}
+	return holding, amount, nil
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// OpenAPISpec 根据网关路由和 proto 消息描述生成 OpenAPI 3 文档，
+// proto 变更后无需手工维护接口文档
+func (bws *BusinessMiddleWireServices) OpenAPISpec() map[string]any {
+	schemas := map[string]any{
+		"ErrorResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"code": map[string]any{"type": "string"},
+				"msg":  map[string]any{"type": "string"},
+			},
+		},
+		"QueryResponse": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"code": map[string]any{"type": "string"},
+				"msg":  map[string]any{"type": "string"},
+				"data": map[string]any{"type": "object"},
+			},
+		},
+	}
+	errorResponse := map[string]any{
+		"description": "error",
+		"content":     jsonContent(schemaRef("ErrorResponse")),
+	}
+
+	paths := map[string]any{}
+	for _, r := range bws.routes() {
+		operation := map[string]any{
+			"summary":     r.Summary,
+			"operationId": operationId(r.Method, r.Path),
+			"parameters": []any{
+				map[string]any{
+					"name":     ConsumerTokenHeader,
+					"in":       "header",
+					"required": false,
+					"schema":   map[string]any{"type": "string"},
+				},
+			},
+		}
+		if r.Request != nil {
+			addMessageSchema(schemas, r.Request)
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(schemaRef(string(r.Request.Name()))),
+			}
+		}
+		for _, param := range r.Params {
+			operation["parameters"] = append(operation["parameters"].([]any), map[string]any{
+				"name":        param.Name,
+				"in":          "query",
+				"required":    param.Required,
+				"description": param.Usage,
+				"schema":      map[string]any{"type": "string"},
+			})
+		}
+		okSchema := schemaRef("QueryResponse")
+		if r.Response != nil {
+			addMessageSchema(schemas, r.Response)
+			okSchema = schemaRef(string(r.Response.Name()))
+		}
+		operation["responses"] = map[string]any{
+			"200": map[string]any{
+				"description": "success",
+				"content":     jsonContent(okSchema),
+			},
+			"400":     errorResponse,
+			"401":     errorResponse,
+			"default": errorResponse,
+		}
+		item, ok := paths[r.Path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[r.Path] = item
+		}
+		item[strings.ToLower(r.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "multichain sync account business api",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+		},
+	}
+}
+
+func addMessageSchema(schemas map[string]any, md protoreflect.MessageDescriptor) {
+	name := string(md.Name())
+	if _, ok := schemas[name]; ok {
+		return
+	}
+	properties := map[string]any{}
+	schemas[name] = map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		schema := fieldSchema(schemas, field)
+		if field.IsList() {
+			schema = map[string]any{"type": "array", "items": schema}
+		}
+		properties[string(field.Name())] = schema
+	}
+}
+
+func fieldSchema(schemas map[string]any, field protoreflect.FieldDescriptor) map[string]any {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// protojson 把 64 位整数编码为字符串
+		return map[string]any{"type": "string", "format": "int64"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return map[string]any{"type": "number"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		names := make([]any, 0, values.Len())
+		for i := 0; i < values.Len(); i++ {
+			names = append(names, string(values.Get(i).Name()))
+		}
+		return map[string]any{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		addMessageSchema(schemas, field.Message())
+		return schemaRef(string(field.Message().Name()))
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+func schemaRef(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{
+		"application/json": map[string]any{"schema": schema},
+	}
+}
+
+func operationId(method, path string) string {
+	parts := strings.Split(strings.TrimPrefix(path, "/api/v1/"), "/")
+	for i := range parts {
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+	return strings.ToLower(method) + strings.Join(parts, "")
+}
@@ -4,36 +4,76 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"sync/atomic"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
 	"github.com/ethereum/go-ethereum/log"
 
+	"github.com/CavnHan/multichain-sync-account/config"
 	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/hdwallet"
 	"github.com/CavnHan/multichain-sync-account/protobuf/dal-wallet-go"
 	"github.com/CavnHan/multichain-sync-account/rpcclient"
+	"github.com/CavnHan/multichain-sync-account/signer"
+	"github.com/CavnHan/multichain-sync-account/txverify"
+	"github.com/CavnHan/multichain-sync-account/worker"
 )
 
-
 const MaxRecvMessageSize = 1024 * 1024 * 300
 
-type BusinessMiddleConfig struct{
-	GrpcHostname string
-	GrpcPort int
+type BusinessMiddleConfig struct {
+	GrpcHostname   string
+	GrpcPort       int
+	HttpHostname   string
+	HttpPort       int // 0 表示不启动 HTTP 网关
+	ConsumerTokens []string
+	Confirmations  uint
+	ChainName      string
+	ChainId        uint64 // 配置的链 id，构造和校验交易都使用它，请求中的 chain_id 只能与之一致
+	RpcUrl         string // 链节点地址，EVM 链补扫 NFT 转账日志
+	PoolSize       int
+	// MultisendContract EVM 链批量提现使用的 multisend 合约地址
+	MultisendContract string
+	// EventVisibilityTimeout FetchEvents 返回的事件未确认时重新可见的时间
+	EventVisibilityTimeout time.Duration
+	// SignerTokens 签名机调用签名队列接口的 token，为空时签名队列关闭
+	SignerTokens []string
+	// SigningJobTimeout 签名机拉取的任务在该时间内独占，超时后提交的签名被拒绝
+	SigningJobTimeout time.Duration
+	// LocalSigner 开发网和测试中代替签名机签名内部交易，为空时关闭
+	LocalSigner signer.Signer
+	// FeePolicy 链配置中的手续费参数，为空的项保留内置默认值
+	FeePolicy config.FeePolicy
+	// Tokens 新注册业务方默认导入的代币
+	Tokens []config.TokenConfig
 }
 
-type BusinessMiddleWireServices struct{
+type BusinessMiddleWireServices struct {
 	*BusinessMiddleConfig
 	accountClient *rpcclient.WalletChainAccountClient
-	db *database.DB
-	stopped atomic.Bool
+	db            *database.DB
+	auth          *ConsumerAuth
+	signerAuth    *SignerAuth
+	rescanner     *worker.Rescanner
+	verifier      *txverify.Verifier
+	feePolicy     config.FeePolicy // 补全默认值后的手续费参数
+	addressPool   *hdwallet.Pool
+	grpcServer    *grpc.Server
+	httpServer    *http.Server
+	stopped       atomic.Bool
 }
 
 func (bws *BusinessMiddleWireServices) Stop(ctx context.Context) error {
+	err := bws.stopHttp(ctx)
+	if bws.grpcServer != nil {
+		bws.grpcServer.GracefulStop()
+	}
 	bws.stopped.Store(true)
-	return nil
+	return err
 }
 
 func (bws *BusinessMiddleWireServices) Stopped() bool {
@@ -45,31 +85,46 @@ func NewBusinessMiddleWireServices(db *database.DB, config *BusinessMiddleConfig
 		BusinessMiddleConfig: config,
 		accountClient:        accountClient,
 		db:                   db,
+		auth:                 NewConsumerAuth(config.ConsumerTokens),
+		signerAuth:           NewSignerAuth(config.SignerTokens),
+		rescanner:            worker.NewRescanner(accountClient, config.RpcUrl, db, uint8(config.Confirmations)),
+		verifier:             txverify.NewVerifier(accountClient, db),
+		feePolicy:            withFeeDefaults(config.FeePolicy),
+		addressPool:          hdwallet.NewPool(db, config.ChainName, config.PoolSize),
 	}, nil
 }
 
 func (bws *BusinessMiddleWireServices) Start(ctx context.Context) error {
-	go func(bws *BusinessMiddleWireServices) {
-		addr := fmt.Sprintf("%s:%d", bws.GrpcHostname, bws.GrpcPort)
-		log.Info("start rpc server", "addr", addr)
-		listener, err := net.Listen("tcp", addr)
-		if err != nil {
-			log.Error("Could not start tcp listener. ")
-		}
-		gs := grpc.NewServer(
-			grpc.MaxRecvMsgSize(MaxRecvMessageSize),
-			grpc.ChainUnaryInterceptor(
-				nil,
-			),
-		)
-		reflection.Register(gs)
+	addr := fmt.Sprintf("%s:%d", bws.GrpcHostname, bws.GrpcPort)
+	log.Info("start rpc server", "addr", addr)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Error("Could not start tcp listener. ")
+		return err
+	}
+	bws.grpcServer = grpc.NewServer(
+		grpc.MaxRecvMsgSize(MaxRecvMessageSize),
+		grpc.ChainUnaryInterceptor(
+			bws.signerAuth.UnaryInterceptor(),
+			bws.auth.UnaryInterceptor(),
+		),
+		grpc.ChainStreamInterceptor(
+			bws.auth.StreamInterceptor(),
+		),
+	)
+	reflection.Register(bws.grpcServer)
 
-		dal_wallet_go.RegisterBusinessMiddleWireServicesServer(gs, bws)
+	dal_wallet_go.RegisterBusinessMiddleWireServicesServer(bws.grpcServer, bws)
 
+	go func(bws *BusinessMiddleWireServices) {
 		log.Info("Grpc info", "port", bws.GrpcPort, "address", listener.Addr())
-		if err := gs.Serve(listener); err != nil {
+		if err := bws.grpcServer.Serve(listener); err != nil {
 			log.Error("Could not GRPC server")
 		}
 	}(bws)
+
+	if bws.HttpPort != 0 {
+		return bws.startHttp()
+	}
 	return nil
 }
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/database"
+	dal_wallet_go "github.com/CavnHan/multichain-sync-account/protobuf/dal-wallet-go"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/common"
+	"github.com/CavnHan/multichain-sync-account/signer"
+	"github.com/CavnHan/multichain-sync-account/txverify"
+)
+
+const (
+	defaultSigningJobLimit   = 10
+	maxSigningJobLimit       = 100
+	defaultSigningJobTimeout = 5 * time.Minute
+)
+
+var errSigningJobExpired = errors.New("signing job expired, fetch it again")
+
+// pendingTx 等待签名的提现或内部交易
+type pendingTx struct {
+	status    uint8
+	expected  txverify.Expected
+	tokenMeta string
+	memo      string
+}
+
+func (bws *BusinessMiddleWireServices) queryPendingTx(requestId, txType, transactionId string) (*pendingTx, error) {
+	if txType == "withdraw" {
+		tx, err := bws.db.Withdraws.QueryWithdrawsByHash(requestId, transactionId)
+		if err != nil || tx == nil {
+			return nil, err
+		}
+		return &pendingTx{status: tx.Status, expected: txverify.FromWithdraw(tx), tokenMeta: tx.TokenMeta, memo: tx.Memo}, nil
+	}
+	tx, err := bws.db.Internals.QueryInternalsByHash(requestId, transactionId)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	return &pendingTx{status: tx.Status, expected: txverify.FromInternal(tx), tokenMeta: tx.TokenMeta}, nil
+}
+
+// chainId 交易使用配置的链 id，不采用业务方或签名机传入的值
+func (bws *BusinessMiddleWireServices) chainId() string {
+	return strconv.FormatUint(bws.ChainId, 10)
+}
+
+// checkChainId 请求中的 chain_id 可以为空，不为空时必须与配置的链 id 一致
+func (bws *BusinessMiddleWireServices) checkChainId(chainId string) string {
+	if chainId == "" || txverify.SameChainId(chainId, bws.chainId()) {
+		return ""
+	}
+	return fmt.Sprintf("chain id %s does not match configured chain id %d", chainId, bws.ChainId)
+}
+
+// accountTxStructure 按发送地址当前的 nonce 构造账户模型链的交易结构
+func (bws *BusinessMiddleWireServices) accountTxStructure(chainId string, tx *pendingTx) (TxStructure, error) {
+	accountInfo, err := bws.accountClient.AccountRpClient.GetAccount(context.Background(), &account.AccountRequest{
+		Chain:   bws.ChainName,
+		Network: Network,
+		Address: tx.expected.From.String(),
+	})
+	if err != nil {
+		return TxStructure{}, err
+	}
+	nonce, _ := strconv.Atoi(accountInfo.Sequence)
+	return bws.buildTxStructure(chainId, uint64(nonce), tx), nil
+}
+
+// buildTxStructure 按库中记录构造交易结构，手续费取配置
+func (bws *BusinessMiddleWireServices) buildTxStructure(chainId string, nonce uint64, tx *pendingTx) TxStructure {
+	expected := tx.expected
+	return TxStructure{
+		ChainId:         chainId,
+		Nonce:           nonce,
+		GasPrice:        bws.feePolicy.MaxFeePerGas,
+		GasTipCap:       bws.feePolicy.MaxFeePerGas,
+		GasFeeCap:       bws.feePolicy.MaxPriorityFeePerGas,
+		Gas:             transferGasLimit(expected.Token, expected.TokenId),
+		ContractAddress: expected.Token.String(),
+		FromAddress:     expected.From.String(),
+		ToAddress:       expected.To.String(),
+		TokenId:         expected.TokenId,
+		TokenMeta:       nftTokenMeta(expected.TokenId, tx.tokenMeta),
+		Memo:            tx.memo,
+		Value:           expected.Amount.String(),
+	}
+}
+
+// createUnSignTx 返回 chain-account 按交易结构计算的待签名哈希
+func (bws *BusinessMiddleWireServices) createUnSignTx(payload string) (string, error) {
+	unSignTx, err := bws.accountClient.AccountRpClient.CreateUnSignTransaction(context.Background(), &account.UnSignTransactionRequest{
+		Chain:    bws.ChainName,
+		Network:  Network,
+		Base64Tx: payload,
+	})
+	if err != nil {
+		return "", err
+	}
+	if unSignTx.Code == common.ReturnCode_ERROR {
+		return "", fmt.Errorf("create un sign transaction fail: %s", unSignTx.Msg)
+	}
+	return unSignTx.UnSignTx, nil
+}
+
+// decodeTxPayload 解码 encodeTxPayload 生成的 base64 json
+func decodeTxPayload(payload string, out any) error {
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// encodeTxPayload 编码为 chain-account 需要的 base64 json
+func encodeTxPayload(payload any) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Error("parse json fail", "err", err)
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// signAccountTx 用签名组装签名交易，校验通过后入库等待广播；jobId 非空时在同一事务内结束签名任务。
+// reject 非空表示请求被拒绝，内容返回给调用方
+func (bws *BusinessMiddleWireServices) signAccountTx(requestId, txType, transactionId, jobId string, expected txverify.Expected, payload, signature string) (string, string, error) {
+	returnTx, err := bws.accountClient.AccountRpClient.BuildSignedTransaction(context.Background(), &account.SignedTransactionRequest{
+		Chain:     bws.ChainName,
+		Network:   Network,
+		Signature: signature,
+		Base64Tx:  payload,
+	})
+	if err != nil {
+		log.Error("create un sign transaction fail", "err", err)
+		return "", "", err
+	}
+	if returnTx.Code == common.ReturnCode_ERROR {
+		log.Error("build signed transaction fail", "msg", returnTx.Msg)
+		return "", "build signed transaction fail: " + returnTx.Msg, nil
+	}
+
+	_, err = bws.verifier.Verify(requestId, expected, returnTx.SignedTx)
+	if errors.Is(err, txverify.ErrMismatch) {
+		txverify.Alert(requestId, txType, transactionId, err)
+		err = bws.db.Transaction(func(tx *database.DB) error {
+			if jobId != "" {
+				if _, err := tx.SigningJobs.CompleteSigningJob(requestId, jobId, database.SigningJobStatusCancelled); err != nil {
+					return err
+				}
+			}
+			if txType == "withdraw" {
+				return tx.Withdraws.UpdateWithdrawTx(requestId, transactionId, "", nil, txverify.StatusBlocked)
+			}
+			return tx.Internals.UpdateInternalTx(requestId, transactionId, "", nil, txverify.StatusBlocked)
+		})
+		if err != nil {
+			log.Error("block mismatched transaction fail", "err", err)
+			return "", "", err
+		}
+		return "", "signed transaction does not match the stored record, transaction blocked", nil
+	}
+	if err != nil {
+		log.Error("verify signed transaction fail", "err", err)
+		return "", "", err
+	}
+
+	err = bws.db.Transaction(func(tx *database.DB) error {
+		if jobId != "" {
+			ok, err := tx.SigningJobs.CompleteSigningJob(requestId, jobId, database.SigningJobStatusSigned)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return errSigningJobExpired
+			}
+		}
+		if txType == "withdraw" {
+			return tx.Withdraws.UpdateWithdrawSigned(requestId, transactionId, returnTx.SignedTx, expected.Nonce, expected.ChainId)
+		}
+		return tx.Internals.UpdateInternalSigned(requestId, transactionId, returnTx.SignedTx, expected.Nonce, expected.ChainId)
+	})
+	if errors.Is(err, errSigningJobExpired) {
+		return "", err.Error(), nil
+	}
+	if err != nil {
+		log.Error("update signed tx to db fail", "err", err)
+		return "", "", err
+	}
+	return returnTx.SignedTx, "", nil
+}
+
+// signLocally 用本地签名器签名内部交易，签名失败时返回原因，不影响已入库的交易记录
+func (bws *BusinessMiddleWireServices) signLocally(requestId, txType, transactionId string, expected txverify.Expected, payload, unSignTx string) (string, error) {
+	signature, err := bws.LocalSigner.Sign(signer.Request{
+		RequestId:     requestId,
+		TxType:        txType,
+		TransactionId: transactionId,
+		ChainId:       expected.ChainId,
+		From:          expected.From,
+		UnSignTx:      unSignTx,
+	})
+	if err != nil {
+		log.Error("local signer sign fail", "transactionId", transactionId, "err", err)
+		return "local signer sign fail: " + err.Error(), nil
+	}
+	_, reject, err := bws.signAccountTx(requestId, txType, transactionId, "", expected, payload, signature)
+	return reject, err
+}
+
+// isInternalTxType 归集、热转冷和冷转热(热钱包补充)都记录为内部交易
+func isInternalTxType(txType string) bool {
+	return txType == "collection" || txType == "hot2cold" || txType == "cold2hot"
+}
+
+// FetchSigningJobs 签名机拉取待签名交易，任务在 SigningJobTimeout 内由该签名机独占，
+// 超时未提交的任务按最新 nonce 重新构建后交给下一次拉取
+func (bws *BusinessMiddleWireServices) FetchSigningJobs(ctx context.Context, request *dal_wallet_go.FetchSigningJobsRequest) (*dal_wallet_go.FetchSigningJobsResponse, error) {
+	if request.RequestId == "" || request.Signer == "" || request.Limit > maxSigningJobLimit {
+		return &dal_wallet_go.FetchSigningJobsResponse{
+			Code: dal_wallet_go.ReturnCode_ERROR,
+			Msg:  "invalid params",
+		}, nil
+	}
+	limit := int(request.Limit)
+	if limit == 0 {
+		limit = defaultSigningJobLimit
+	}
+	timeout := bws.SigningJobTimeout
+	if timeout <= 0 {
+		timeout = defaultSigningJobTimeout
+	}
+	jobs, err := bws.db.SigningJobs.LeaseSigningJobs(request.RequestId, request.Signer, limit, timeout)
+	if err != nil {
+		log.Error("lease signing jobs fail", "err", err)
+		return nil, err
+	}
+	response := &dal_wallet_go.FetchSigningJobsResponse{
+		Code: dal_wallet_go.ReturnCode_SUCCESS,
+		Msg:  "fetch signing jobs success",
+	}
+	for i := range jobs {
+		job, err := bws.prepareSigningJob(request.RequestId, &jobs[i])
+		if err != nil {
+			log.Error("prepare signing job fail", "transactionId", jobs[i].TransactionId, "err", err)
+			if err := bws.db.SigningJobs.ReleaseSigningJob(request.RequestId, jobs[i].JobId); err != nil {
+				log.Error("release signing job fail", "err", err)
+				return nil, err
+			}
+			continue
+		}
+		if job != nil {
+			response.Jobs = append(response.Jobs, job)
+		}
+	}
+	log.Info("fetch signing jobs", "requestId", request.RequestId, "signer", request.Signer, "jobs", len(response.Jobs))
+	return response, nil
+}
+
+// prepareSigningJob 构建待签名交易并保存交易结构，提交签名时按同一结构组装。交易已不在待签名状态时结束任务并返回 nil
+func (bws *BusinessMiddleWireServices) prepareSigningJob(requestId string, job *database.SigningJobs) (*dal_wallet_go.SigningJob, error) {
+	pending, err := bws.queryPendingTx(requestId, job.TxType, job.TransactionId)
+	if err != nil {
+		return nil, err
+	}
+	if pending == nil || pending.status != 0 {
+		// 已通过 BuildSignedTransaction 签名或已被拦截
+		_, err := bws.db.SigningJobs.CompleteSigningJob(requestId, job.JobId, database.SigningJobStatusCancelled)
+		return nil, err
+	}
+	txStructure, err := bws.accountTxStructure(bws.chainId(), pending)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := encodeTxPayload(txStructure)
+	if err != nil {
+		return nil, err
+	}
+	unSignTx, err := bws.createUnSignTx(payload)
+	if err != nil {
+		return nil, err
+	}
+	if err := bws.db.SigningJobs.UpdateSigningJobPayload(requestId, job.JobId, txStructure.Nonce, payload); err != nil {
+		return nil, err
+	}
+
+	// 签名机据此执行自己的策略，例如只允许热钱包向外部地址提现、归集只能转入热钱包
+	_, fromType := bws.db.Addresses.AddressExist(requestId, pending.expected.From)
+	toWallet, toType := bws.db.Addresses.AddressExist(requestId, pending.expected.To)
+	return &dal_wallet_go.SigningJob{
+		JobId:           job.JobId,
+		TransactionId:   job.TransactionId,
+		ChainId:         txStructure.ChainId,
+		From:            txStructure.FromAddress,
+		To:              txStructure.ToAddress,
+		Value:           txStructure.Value,
+		ContractAddress: txStructure.ContractAddress,
+		TokenId:         txStructure.TokenId,
+		Nonce:           txStructure.Nonce,
+		UnSignTx:        unSignTx,
+		ExpiresAt:       job.ExpiresAt,
+		Policy: &dal_wallet_go.SigningPolicy{
+			TxType:          job.TxType,
+			FromAddressType: uint32(fromType),
+			ToWalletAddress: toWallet,
+			ToAddressType:   uint32(toType),
+			Attempts:        job.Attempts,
+			CreatedAt:       job.Timestamp,
+		},
+	}, nil
+}
+
+// SubmitSignature 签名机提交签名，校验通过后交易进入广播队列
+func (bws *BusinessMiddleWireServices) SubmitSignature(ctx context.Context, request *dal_wallet_go.SubmitSignatureRequest) (*dal_wallet_go.SubmitSignatureResponse, error) {
+	errorResponse := func(msg string, transactionId string) *dal_wallet_go.SubmitSignatureResponse {
+		return &dal_wallet_go.SubmitSignatureResponse{
+			Code:          dal_wallet_go.ReturnCode_ERROR,
+			Msg:           msg,
+			TransactionId: transactionId,
+		}
+	}
+	if request.RequestId == "" || request.Signer == "" || request.JobId == "" || request.Signature == "" {
+		return errorResponse("invalid params", ""), nil
+	}
+	job, err := bws.db.SigningJobs.QuerySigningJob(request.RequestId, request.JobId)
+	if err != nil {
+		log.Error("query signing job fail", "err", err)
+		return nil, err
+	}
+	if job == nil || job.Status != database.SigningJobStatusPending {
+		return errorResponse("signing job not found or already completed", ""), nil
+	}
+	if job.Signer != request.Signer {
+		return errorResponse("signing job is held by another signer", job.TransactionId), nil
+	}
+	if uint64(time.Now().Unix()) >= job.ExpiresAt || job.Payload == "" {
+		return errorResponse(errSigningJobExpired.Error(), job.TransactionId), nil
+	}
+	pending, err := bws.queryPendingTx(request.RequestId, job.TxType, job.TransactionId)
+	if err != nil {
+		return nil, err
+	}
+	if pending == nil || pending.status != 0 {
+		if _, err := bws.db.SigningJobs.CompleteSigningJob(request.RequestId, job.JobId, database.SigningJobStatusCancelled); err != nil {
+			log.Error("cancel signing job fail", "err", err)
+			return nil, err
+		}
+		return errorResponse("transaction is no longer waiting for signature", job.TransactionId), nil
+	}
+
+	// 链 id 以配置为准，不使用任务中保存的值
+	expected := pending.expected
+	expected.Nonce, expected.ChainId = job.Nonce, bws.chainId()
+	signedTx, reject, err := bws.signAccountTx(request.RequestId, job.TxType, job.TransactionId, job.JobId, expected, job.Payload, request.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if reject != "" {
+		return errorResponse(reject, job.TransactionId), nil
+	}
+	log.Info("signing job signed", "requestId", request.RequestId, "signer", request.Signer, "transactionId", job.TransactionId)
+	return &dal_wallet_go.SubmitSignatureResponse{
+		Code:          dal_wallet_go.ReturnCode_SUCCESS,
+		Msg:           "submit signature success",
+		TransactionId: job.TransactionId,
+		SignedTx:      signedTx,
+	}, nil
+}
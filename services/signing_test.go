@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+	dal_wallet_go "github.com/CavnHan/multichain-sync-account/protobuf/dal-wallet-go"
+	"github.com/CavnHan/multichain-sync-account/rpcclient"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/common"
+)
+
+type memSigningJobs struct {
+	database.SigningJobsDB
+	jobs map[string]database.SigningJobs
+}
+
+func (db *memSigningJobs) QuerySigningJob(_ string, jobId string) (*database.SigningJobs, error) {
+	job, ok := db.jobs[jobId]
+	if !ok {
+		return nil, nil
+	}
+	return &job, nil
+}
+
+func (db *memSigningJobs) UpdateSigningJobPayload(_ string, jobId string, nonce uint64, payload string) error {
+	job := db.jobs[jobId]
+	job.Nonce, job.Payload = nonce, payload
+	db.jobs[jobId] = job
+	return nil
+}
+
+type memAddresses struct {
+	database.AddressesDB
+}
+
+func (db *memAddresses) AddressExist(string, chainaddr.Address) (bool, uint8) {
+	return false, 0
+}
+
+// fakeAccountRpc 返回固定的 nonce 和待签名哈希
+type fakeAccountRpc struct {
+	account.WalletAccountServiceClient
+}
+
+func (f *fakeAccountRpc) GetAccount(context.Context, *account.AccountRequest, ...grpc.CallOption) (*account.AccountResponse, error) {
+	return &account.AccountResponse{Code: common.ReturnCode_SUCCESS, Sequence: "7"}, nil
+}
+
+func (f *fakeAccountRpc) CreateUnSignTransaction(context.Context, *account.UnSignTransactionRequest, ...grpc.CallOption) (*account.UnSignTransactionResponse, error) {
+	return &account.UnSignTransactionResponse{Code: common.ReturnCode_SUCCESS, UnSignTx: "0xhash"}, nil
+}
+
+func TestPrepareSigningJobUsesConfiguredChainId(t *testing.T) {
+	transactionId := uuid.New()
+	withdraws := &memWithdraws{withdraws: map[string]database.Withdraws{
+		transactionId.String(): {GUID: transactionId, Amount: big.NewInt(1)},
+	}}
+	jobs := &memSigningJobs{jobs: map[string]database.SigningJobs{
+		"job": {JobId: "job", TransactionId: transactionId.String(), TxType: "withdraw", ChainId: "1"},
+	}}
+	accountClient, err := rpcclient.NewWalletChainAccountClient(context.Background(), &fakeAccountRpc{}, "Ethereum")
+	require.NoError(t, err)
+	db := &database.DB{Withdraws: withdraws, SigningJobs: jobs, Addresses: &memAddresses{}}
+	bws, err := NewBusinessMiddleWireServices(db, &BusinessMiddleConfig{ChainName: "Ethereum", ChainId: 11155111}, accountClient)
+	require.NoError(t, err)
+
+	// 任务中保存的链 id 与配置不一致时以配置为准
+	job := jobs.jobs["job"]
+	signingJob, err := bws.prepareSigningJob("biz", &job)
+	require.NoError(t, err)
+	require.Equal(t, "11155111", signingJob.ChainId)
+	require.Equal(t, uint64(7), signingJob.Nonce)
+
+	data, err := base64.StdEncoding.DecodeString(jobs.jobs["job"].Payload)
+	require.NoError(t, err)
+	var payload TxStructure
+	require.NoError(t, json.Unmarshal(data, &payload))
+	require.Equal(t, "11155111", payload.ChainId)
+}
+
+func TestSubmitSignatureRejectsStaleJobs(t *testing.T) {
+	now := uint64(time.Now().Unix())
+	jobs := &memSigningJobs{jobs: map[string]database.SigningJobs{
+		"expired":  {JobId: "expired", TransactionId: "tx-1", Signer: "hsm-1", Payload: "e30=", ExpiresAt: now - 1},
+		"held":     {JobId: "held", TransactionId: "tx-2", Signer: "hsm-2", Payload: "e30=", ExpiresAt: now + 60},
+		"finished": {JobId: "finished", TransactionId: "tx-3", Signer: "hsm-1", Status: database.SigningJobStatusSigned, ExpiresAt: now + 60},
+	}}
+	bws, err := NewBusinessMiddleWireServices(&database.DB{SigningJobs: jobs}, &BusinessMiddleConfig{SignerTokens: []string{"signer"}}, nil)
+	require.NoError(t, err)
+
+	for jobId, want := range map[string]string{
+		"expired":  errSigningJobExpired.Error(),
+		"held":     "held by another signer",
+		"finished": "already completed",
+		"unknown":  "not found",
+	} {
+		resp, err := bws.SubmitSignature(context.Background(), &dal_wallet_go.SubmitSignatureRequest{
+			RequestId: "biz",
+			Signer:    "hsm-1",
+			JobId:     jobId,
+			Signature: "0x01",
+		})
+		require.NoError(t, err)
+		require.Equal(t, dal_wallet_go.ReturnCode_ERROR, resp.Code, jobId)
+		require.Contains(t, resp.Msg, want, jobId)
+	}
+}
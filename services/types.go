@@ -11,5 +11,7 @@ type TxStructure struct {
 	FromAddress     string `json:"from_address"`
 	ToAddress       string `json:"to_address"`
 	TokenId         string `json:"token_id"`
+	TokenMeta       string `json:"token_meta,omitempty"` // NFT 的代币标准 erc721/erc1155
+	Memo            string `json:"memo,omitempty"`       // 收款方为共享地址时的 memo/destination tag
 	Value           string `json:"value"`
 }
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/google/uuid"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+	dal_wallet_go "github.com/CavnHan/multichain-sync-account/protobuf/dal-wallet-go"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+)
+
+// defaultUtxoFeeRate 链配置没有设置 fee_policy.utxo_fee_rate 时的默认费率
+const defaultUtxoFeeRate int64 = 10 // sat/vB
+
+const (
+	utxoDustLimit = 546 // 低于该金额的找零不单独输出，并入手续费
+
+	// P2WPKH 交易的估算虚拟大小
+	utxoTxOverheadVBytes = 11
+	utxoInputVBytes      = 68
+	utxoOutputVBytes     = 31
+)
+
+var ErrInsufficientUtxos = errors.New("insufficient utxos to cover amount and fee")
+
+type UtxoInput struct {
+	TxHash  string `json:"tx_hash"`
+	Vout    uint32 `json:"vout"`
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+}
+
+type UtxoOutput struct {
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+}
+
+// UtxoTxStructure UTXO 链构建交易的参数，最后一个输出为找零
+type UtxoTxStructure struct {
+	Vin  []UtxoInput  `json:"vin"`
+	Vout []UtxoOutput `json:"vout"`
+	Fee  string       `json:"fee"`
+}
+
+type CoinSelection struct {
+	Inputs []database.Utxos
+	Fee    *big.Int
+}
+
+func estimateUtxoFee(inputs, outputs int, feeRate int64) *big.Int {
+	vsize := utxoTxOverheadVBytes + inputs*utxoInputVBytes + outputs*utxoOutputVBytes
+	return big.NewInt(int64(vsize) * feeRate)
+}
+
+// SelectCoins 为提现选择输入，recipients 为收款输出个数：优先使用能单独覆盖金额的最小输出，避免拆散大额输出；
+// 否则按金额从大到小累加。找零低于粉尘阈值时并入手续费
+func SelectCoins(utxos []database.Utxos, amount *big.Int, recipients int, feeRate int64) (*CoinSelection, error) {
+	if amount == nil || amount.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid amount %v", amount)
+	}
+	if feeRate <= 0 {
+		feeRate = defaultUtxoFeeRate
+	}
+	if recipients <= 0 {
+		recipients = 1
+	}
+	candidates := make([]database.Utxos, len(utxos))
+	copy(candidates, utxos)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Amount.Cmp(candidates[j].Amount) > 0
+	})
+
+	for i := len(candidates) - 1; i >= 0; i-- {
+		if selection := settleSelection(candidates[i:i+1], amount, recipients, feeRate); selection != nil {
+			return selection, nil
+		}
+	}
+	for i := range candidates {
+		if selection := settleSelection(candidates[:i+1], amount, recipients, feeRate); selection != nil {
+			return selection, nil
+		}
+	}
+	return nil, ErrInsufficientUtxos
+}
+
+// settleSelection 输入不足以支付金额和手续费时返回 nil
+func settleSelection(inputs []database.Utxos, amount *big.Int, recipients int, feeRate int64) *CoinSelection {
+	total := sumUtxos(inputs)
+	withChange := estimateUtxoFee(len(inputs), recipients+1, feeRate)
+	change := new(big.Int).Sub(total, new(big.Int).Add(amount, withChange))
+	if change.Cmp(big.NewInt(utxoDustLimit)) >= 0 {
+		return &CoinSelection{Inputs: append([]database.Utxos(nil), inputs...), Fee: withChange}
+	}
+	noChange := estimateUtxoFee(len(inputs), recipients, feeRate)
+	if total.Cmp(new(big.Int).Add(amount, noChange)) < 0 {
+		return nil
+	}
+	return &CoinSelection{Inputs: append([]database.Utxos(nil), inputs...), Fee: new(big.Int).Sub(total, amount)}
+}
+
+// buildUtxoTxStructure 由已锁定的输入还原交易结构，构建未签名交易和组装签名交易时结果一致
+func buildUtxoTxStructure(inputs []database.Utxos, from chainaddr.Address, outputs []UtxoOutput, fee *big.Int) (*UtxoTxStructure, error) {
+	amount := big.NewInt(0)
+	for _, output := range outputs {
+		value, ok := new(big.Int).SetString(output.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid output amount %s", output.Amount)
+		}
+		amount.Add(amount, value)
+	}
+	total := sumUtxos(inputs)
+	change := new(big.Int).Sub(total, new(big.Int).Add(amount, fee))
+	if change.Sign() < 0 {
+		return nil, ErrInsufficientUtxos
+	}
+	txStructure := &UtxoTxStructure{Fee: fee.String()}
+	for _, input := range inputs {
+		txStructure.Vin = append(txStructure.Vin, UtxoInput{
+			TxHash:  input.TxHash.String(),
+			Vout:    input.Vout,
+			Address: input.Address.String(),
+			Amount:  input.Amount.String(),
+		})
+	}
+	txStructure.Vout = append(txStructure.Vout, outputs...)
+	if change.Sign() > 0 {
+		txStructure.Vout = append(txStructure.Vout, UtxoOutput{Address: from.String(), Amount: change.String()})
+	}
+	return txStructure, nil
+}
+
+func sumUtxos(utxos []database.Utxos) *big.Int {
+	total := big.NewInt(0)
+	for _, utxo := range utxos {
+		total.Add(total, utxo.Amount)
+	}
+	return total
+}
+
+// createUtxoUnSignTransaction 在同一个事务内锁定可用输出、选币并写入交易记录，并发提现不会选中同一个输出
+func (bws *BusinessMiddleWireServices) createUtxoUnSignTransaction(request *dal_wallet_go.UnSignWithdrawTransactionRequest, transactionId uuid.UUID, from, to chainaddr.Address, amount *big.Int, store func(db *database.DB, fee *big.Int) error) (*dal_wallet_go.UnSignWithdrawTransactionResponse, error) {
+	errorResponse := func(msg string) *dal_wallet_go.UnSignWithdrawTransactionResponse {
+		return &dal_wallet_go.UnSignWithdrawTransactionResponse{
+			Code:          dal_wallet_go.ReturnCode_ERROR,
+			Msg:           msg,
+			TransactionId: transactionId.String(),
+			UnSignTx:      "0x00",
+		}
+	}
+	if amount == nil || amount.Sign() <= 0 || to.IsZero() {
+		return errorResponse("invalid params"), nil
+	}
+	feeRate := bws.feePolicy.UtxoFeeRate
+	if request.FeeRate != "" {
+		rate, err := strconv.ParseInt(request.FeeRate, 10, 64)
+		if err != nil || rate <= 0 {
+			return errorResponse("invalid fee rate"), nil
+		}
+		feeRate = rate
+	}
+
+	var selection *CoinSelection
+	err := bws.db.Transaction(func(tx *database.DB) error {
+		utxos, err := tx.Utxos.LockUnspentUtxos(request.RequestId, from)
+		if err != nil {
+			return err
+		}
+		selection, err = SelectCoins(utxos, amount, 1, feeRate)
+		if err != nil {
+			return err
+		}
+		guids := make([]uuid.UUID, 0, len(selection.Inputs))
+		for _, input := range selection.Inputs {
+			guids = append(guids, input.GUID)
+		}
+		if err := tx.Utxos.MarkUtxosLocked(request.RequestId, transactionId.String(), guids); err != nil {
+			return err
+		}
+		return store(tx, selection.Fee)
+	})
+	if errors.Is(err, ErrInsufficientUtxos) {
+		return errorResponse(err.Error()), nil
+	}
+	if err != nil {
+		log.Error("select utxos fail", "err", err)
+		return nil, err
+	}
+
+	txStructure, err := buildUtxoTxStructure(selection.Inputs, from, []UtxoOutput{{Address: to.String(), Amount: amount.String()}}, selection.Fee)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(txStructure)
+	if err != nil {
+		log.Error("parse json fail", "err", err)
+		return nil, err
+	}
+	unSignTx := &account.UnSignTransactionRequest{
+		Chain:    bws.ChainName,
+		Network:  Network,
+		Base64Tx: base64.StdEncoding.EncodeToString(data),
+	}
+	returnTx, err := bws.accountClient.AccountRpClient.CreateUnSignTransaction(context.Background(), unSignTx)
+	if err != nil {
+		log.Error("create un sign transaction fail", "err", err)
+		return nil, err
+	}
+	return &dal_wallet_go.UnSignWithdrawTransactionResponse{
+		Code:          dal_wallet_go.ReturnCode_SUCCESS,
+		Msg:           "submit withdraw and build un sign tranaction success",
+		TransactionId: transactionId.String(),
+		UnSignTx:      returnTx.UnSignTx,
+	}, nil
+}
+
+// lockedUtxoTxStructure 用构建未签名交易时锁定的输入还原交易结构
+func (bws *BusinessMiddleWireServices) lockedUtxoTxStructure(requestId, txType, transactionId string) (*UtxoTxStructure, error) {
+	var from, to chainaddr.Address
+	var amount, fee *big.Int
+	if txType == "withdraw" {
+		tx, err := bws.db.Withdraws.QueryWithdrawsByHash(requestId, transactionId)
+		if err != nil {
+			return nil, err
+		}
+		from, to, amount, fee = tx.FromAddress, tx.ToAddress, tx.Amount, tx.Fee
+	} else if isInternalTxType(txType) {
+		tx, err := bws.db.Internals.QueryInternalsByHash(requestId, transactionId)
+		if err != nil {
+			return nil, err
+		}
+		from, to, amount, fee = tx.FromAddress, tx.ToAddress, tx.Amount, tx.Fee
+	} else {
+		return nil, fmt.Errorf("unsupported transaction type %s", txType)
+	}
+	inputs, err := bws.db.Utxos.QueryUtxosByTransactionId(requestId, transactionId)
+	if err != nil {
+		return nil, err
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no utxo locked by transaction %s", transactionId)
+	}
+	return buildUtxoTxStructure(inputs, from, []UtxoOutput{{Address: to.String(), Amount: amount.String()}}, fee)
+}
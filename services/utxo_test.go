@@ -0,0 +1,61 @@
+package services
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/database"
+)
+
+func testUtxos(amounts ...int64) []database.Utxos {
+	var utxos []database.Utxos
+	for i, amount := range amounts {
+		utxos = append(utxos, database.Utxos{GUID: uuid.New(), Vout: uint32(i), Amount: big.NewInt(amount)})
+	}
+	return utxos
+}
+
+func TestSelectCoins(t *testing.T) {
+	// 单个输出足够时选择能覆盖的最小输出
+	selection, err := SelectCoins(testUtxos(100_000, 20_000, 5_000), big.NewInt(10_000), 1, 10)
+	require.NoError(t, err)
+	require.Len(t, selection.Inputs, 1)
+	require.Equal(t, int64(20_000), selection.Inputs[0].Amount.Int64())
+	require.Equal(t, estimateUtxoFee(1, 2, 10), selection.Fee)
+
+	// 单个输出不够时按金额从大到小累加
+	selection, err = SelectCoins(testUtxos(6_000, 8_000, 7_000), big.NewInt(12_000), 1, 10)
+	require.NoError(t, err)
+	require.Len(t, selection.Inputs, 2)
+	require.Equal(t, int64(8_000), selection.Inputs[0].Amount.Int64())
+	require.Equal(t, int64(7_000), selection.Inputs[1].Amount.Int64())
+
+	_, err = SelectCoins(testUtxos(1_000, 2_000), big.NewInt(10_000), 1, 10)
+	require.ErrorIs(t, err, ErrInsufficientUtxos)
+}
+
+func TestSelectCoinsDustChange(t *testing.T) {
+	amount := int64(10_000)
+	noChangeFee := estimateUtxoFee(1, 1, 10).Int64()
+	selection, err := SelectCoins(testUtxos(amount+noChangeFee+100), big.NewInt(amount), 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, noChangeFee+100, selection.Fee.Int64())
+
+	txStructure, err := buildUtxoTxStructure(selection.Inputs, "from", []UtxoOutput{{Address: "to", Amount: "10000"}}, selection.Fee)
+	require.NoError(t, err)
+	require.Len(t, txStructure.Vout, 1)
+}
+
+func TestBuildUtxoTxStructure(t *testing.T) {
+	inputs := testUtxos(50_000)
+	txStructure, err := buildUtxoTxStructure(inputs, "from", []UtxoOutput{{Address: "to", Amount: "30000"}}, big.NewInt(2_000))
+	require.NoError(t, err)
+	require.Len(t, txStructure.Vin, 1)
+	require.Equal(t, []UtxoOutput{{Address: "to", Amount: "30000"}, {Address: "from", Amount: "18000"}}, txStructure.Vout)
+
+	_, err = buildUtxoTxStructure(inputs, "from", []UtxoOutput{{Address: "to", Amount: "49000"}}, big.NewInt(2_000))
+	require.ErrorIs(t, err, ErrInsufficientUtxos)
+}
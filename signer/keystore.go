@@ -0,0 +1,108 @@
+package signer
+
+import (
+	"crypto/aes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+)
+
+// KeystoreSigner 用 go-ethereum 加密 keystore 目录中的私钥签名，启动时用同一个密码解密全部私钥
+type KeystoreSigner struct {
+	chainId uint64
+	keys    map[chainaddr.Address]*ecdsa.PrivateKey
+}
+
+// NewKeystoreSigner 只支持 EVM 链，配置的链 id 不是已知的开发网或测试网时拒绝启动
+func NewKeystoreSigner(dir, passwordFile string, chainId uint64) (*KeystoreSigner, error) {
+	if !IsDevChain(chainId) {
+		return nil, fmt.Errorf("%w: chain id %d", ErrNotDevChain, chainId)
+	}
+	if chainaddr.Default().Family() != chainaddr.FamilyEVM {
+		return nil, fmt.Errorf("local signer only supports evm chains")
+	}
+	password, err := os.ReadFile(passwordFile)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := &KeystoreSigner{chainId: chainId, keys: make(map[chainaddr.Address]*ecdsa.PrivateKey)}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		key, err := decryptKey(data, strings.TrimRight(string(password), "\r\n"))
+		if err != nil {
+			return nil, fmt.Errorf("keystore %s: %w", entry.Name(), err)
+		}
+		address, err := chainaddr.ParseAddress(crypto.PubkeyToAddress(key.PublicKey).Hex())
+		if err != nil {
+			return nil, err
+		}
+		s.keys[address] = key
+	}
+	if len(s.keys) == 0 {
+		return nil, fmt.Errorf("no keys in keystore %s", dir)
+	}
+	log.Warn("local signer enabled, do not use it with real funds", "chainId", chainId, "keys", len(s.keys))
+	return s, nil
+}
+
+func (s *KeystoreSigner) Sign(req Request) (string, error) {
+	chainId, ok := new(big.Int).SetString(req.ChainId, 0)
+	if !ok || !chainId.IsUint64() || chainId.Uint64() != s.chainId {
+		return "", fmt.Errorf("chain id %q differs from the configured chain id %d", req.ChainId, s.chainId)
+	}
+	key, ok := s.keys[req.From]
+	if !ok {
+		return "", fmt.Errorf("no key for %s in keystore", req.From)
+	}
+	hash := common.FromHex(req.UnSignTx)
+	if len(hash) != common.HashLength {
+		return "", fmt.Errorf("un sign tx is not a 32 byte hash: %s", req.UnSignTx)
+	}
+	signature, err := crypto.Sign(hash, key)
+	if err != nil {
+		return "", err
+	}
+	log.Info("local signer signed transaction", "business", req.RequestId, "type", req.TxType, "transactionId", req.TransactionId, "from", req.From, "chainId", req.ChainId, "hash", common.BytesToHash(hash))
+	return hex.EncodeToString(signature), nil
+}
+
+// decryptKey 用 go-ethereum 解密 v3 keystore。go-ethereum 在 MAC 校验通过后直接用 iv 做 AES-CTR，
+// iv 长度不对时会 panic，解密前先检查
+func decryptKey(data []byte, password string) (*ecdsa.PrivateKey, error) {
+	var k struct {
+		Crypto keystore.CryptoJSON `json:"crypto"`
+	}
+	if err := json.Unmarshal(data, &k); err != nil {
+		return nil, err
+	}
+	if iv, err := hex.DecodeString(k.Crypto.CipherParams.IV); err != nil || len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid keystore iv %q", k.Crypto.CipherParams.IV)
+	}
+	key, err := keystore.DecryptKey(data, password)
+	if err != nil {
+		return nil, err
+	}
+	return key.PrivateKey, nil
+}
@@ -0,0 +1,108 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+)
+
+// writeKeystore 按 v3 格式加密私钥写入 dir，scrypt 参数取小值加快测试
+func writeKeystore(t *testing.T, dir, password string, key *ecdsa.PrivateKey) string {
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	data, err := keystore.EncryptKey(&keystore.Key{Id: uuid.New(), Address: address, PrivateKey: key}, password, keystore.LightScryptN, keystore.LightScryptP)
+	require.NoError(t, err)
+	path := filepath.Join(dir, "UTC--"+address.Hex())
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func newTestSigner(t *testing.T, chainId uint64) (*KeystoreSigner, chainaddr.Address, error) {
+	dir := t.TempDir()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	writeKeystore(t, dir, "devnet", key)
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("devnet\n"), 0o600))
+
+	address, err := chainaddr.ParseAddress(crypto.PubkeyToAddress(key.PublicKey).Hex())
+	require.NoError(t, err)
+	s, err := NewKeystoreSigner(dir, passwordFile, chainId)
+	return s, address, err
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	s, from, err := newTestSigner(t, 11155111)
+	require.NoError(t, err)
+
+	hash := crypto.Keccak256Hash([]byte("tx"))
+	req := Request{RequestId: "biz", TxType: "collection", TransactionId: "tx-1", ChainId: "11155111", From: from, UnSignTx: hash.Hex()}
+	signature, err := s.Sign(req)
+	require.NoError(t, err)
+	pub, err := crypto.SigToPub(hash.Bytes(), common.FromHex(signature))
+	require.NoError(t, err)
+	signer, err := chainaddr.ParseAddress(crypto.PubkeyToAddress(*pub).Hex())
+	require.NoError(t, err)
+	require.Equal(t, from, signer)
+
+	other := req
+	other.ChainId = "1"
+	_, err = s.Sign(other)
+	require.ErrorContains(t, err, "differs from the configured chain id")
+
+	other = req
+	other.From = chainaddr.Address("0x0000000000000000000000000000000000000001")
+	_, err = s.Sign(other)
+	require.ErrorContains(t, err, "no key")
+}
+
+func TestKeystoreSignerRefusesNonDevChains(t *testing.T) {
+	// 主网、未配置和未知的链 id 都拒绝
+	for _, chainId := range []uint64{1, 0, 999999} {
+		_, _, err := newTestSigner(t, chainId)
+		require.ErrorIs(t, err, ErrNotDevChain, chainId)
+	}
+}
+
+func TestKeystoreSignerWrongPassword(t *testing.T) {
+	dir := t.TempDir()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	writeKeystore(t, dir, "devnet", key)
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("wrong"), 0o600))
+
+	_, err = NewKeystoreSigner(dir, passwordFile, 11155111)
+	require.ErrorContains(t, err, "could not decrypt key")
+}
+
+func TestKeystoreSignerInvalidIV(t *testing.T) {
+	dir := t.TempDir()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	path := writeKeystore(t, dir, "devnet", key)
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("devnet"), 0o600))
+
+	// MAC 不覆盖 iv，改短 iv 后密码仍能通过校验
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var k map[string]any
+	require.NoError(t, json.Unmarshal(data, &k))
+	k["crypto"].(map[string]any)["cipherparams"] = map[string]string{"iv": "00"}
+	data, err = json.Marshal(k)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	_, err = NewKeystoreSigner(dir, passwordFile, 11155111)
+	require.ErrorContains(t, err, "invalid keystore iv")
+}
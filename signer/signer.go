@@ -0,0 +1,46 @@
+// Package signer 服务自身对内部交易签名，只用于开发网和自动化测试，生产环境由签名机签名
+package signer
+
+import (
+	"errors"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+)
+
+// ErrNotDevChain 本地签名只允许在开发网和测试网使用
+var ErrNotDevChain = errors.New("local signer is only allowed on dev and test chains")
+
+// Request 待签名交易和它对应的提现/内部交易记录，签名时写入日志
+type Request struct {
+	RequestId     string
+	TxType        string
+	TransactionId string
+	ChainId       string
+	From          chainaddr.Address
+	// UnSignTx chain-account 返回的待签名哈希
+	UnSignTx string
+}
+
+// Signer 返回 chain-account BuildSignedTransaction 需要的十六进制签名
+type Signer interface {
+	Sign(req Request) (string, error)
+}
+
+// devChainIds 允许本地签名的开发网和测试网链 id，未列出的链 id(包括 0)一律视为可能是主网
+var devChainIds = map[uint64]string{
+	97:       "bsc-testnet",
+	1337:     "geth-dev",
+	17000:    "holesky",
+	31337:    "hardhat",
+	80002:    "polygon-amoy",
+	84532:    "base-sepolia",
+	421614:   "arbitrum-sepolia",
+	560048:   "hoodi",
+	11155111: "sepolia",
+	11155420: "optimism-sepolia",
+}
+
+func IsDevChain(chainId uint64) bool {
+	_, ok := devChainIds[chainId]
+	return ok
+}
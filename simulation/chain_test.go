@@ -0,0 +1,253 @@
+package simulation
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/CavnHan/multichain-sync-account/common/clock"
+	"github.com/CavnHan/multichain-sync-account/rpcclient"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/common"
+)
+
+const (
+	genesisHeight = 100
+	blockTime     = 10 * time.Second
+	chainId       = 1
+)
+
+type chainTx struct {
+	hash   string
+	from   string
+	to     string
+	amount string
+	height uint64
+}
+
+// fakeChain 链高度由时钟推导，每 blockTime 出一个块，新交易打包进下一个块
+type fakeChain struct {
+	account.UnimplementedWalletAccountServiceServer
+
+	clock   clock.Clock
+	genesis time.Time
+
+	mu        sync.Mutex
+	txs       map[string]*chainTx
+	sent      []string
+	fetchedAt map[uint64]uint64 // 区块第一次被拉取时的链高度
+}
+
+func newFakeChain(t *testing.T, clk clock.Clock) (*fakeChain, string) {
+	chain := &fakeChain{
+		clock:     clk,
+		genesis:   clk.Now(),
+		txs:       make(map[string]*chainTx),
+		fetchedAt: make(map[uint64]uint64),
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	account.RegisterWalletAccountServiceServer(server, chain)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+	return chain, listener.Addr().String()
+}
+
+func (c *fakeChain) latest() uint64 {
+	return genesisHeight + uint64(c.clock.Since(c.genesis)/blockTime)
+}
+
+// signedTx 模拟签名机输出已签名的 EVM 原生币转账
+func signedTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, to, amount string) string {
+	value, _ := new(big.Int).SetString(amount, 10)
+	toAddress := gethcommon.HexToAddress(to)
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(chainId)), &types.DynamicFeeTx{
+		ChainID:   big.NewInt(chainId),
+		Nonce:     nonce,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(1),
+		Gas:       21000,
+		To:        &toAddress,
+		Value:     value,
+	})
+	require.NoError(t, err)
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	return hexutil.Encode(raw)
+}
+
+func decodeSignedTx(rawTx string) (*types.Transaction, gethcommon.Address, error) {
+	raw, err := hexutil.Decode(rawTx)
+	if err != nil {
+		return nil, gethcommon.Address{}, err
+	}
+	var tx types.Transaction
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, gethcommon.Address{}, err
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), &tx)
+	return &tx, from, err
+}
+
+func lowerHex(v interface{ Hex() string }) string {
+	return strings.ToLower(v.Hex())
+}
+
+// transfer 提交一笔转账，返回交易哈希
+func (c *fakeChain) transfer(from, to, amount string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.include(fmt.Sprintf("0x%064x", len(c.txs)+1), from, to, amount)
+}
+
+// include 把交易打包进下一个块，调用方持有锁
+func (c *fakeChain) include(hash, from, to, amount string) string {
+	c.txs[hash] = &chainTx{
+		hash:   hash,
+		from:   from,
+		to:     to,
+		amount: amount,
+		height: c.latest() + 1,
+	}
+	return hash
+}
+
+func (c *fakeChain) tx(hash string) *chainTx {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.txs[hash]
+}
+
+func (c *fakeChain) sentTxs() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.sent...)
+}
+
+func (c *fakeChain) firstFetchedAt(height uint64) (uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	latest, ok := c.fetchedAt[height]
+	return latest, ok
+}
+
+func blockHash(height uint64) string {
+	return fmt.Sprintf("0x%064x", height<<32)
+}
+
+func (c *fakeChain) GetBlockHeaderByNumber(_ context.Context, req *account.BlockHeaderNumberRequest) (*account.BlockHeaderResponse, error) {
+	height := uint64(req.Height)
+	if height == 0 {
+		height = c.latest()
+	}
+	if height > c.latest() {
+		return &account.BlockHeaderResponse{Code: common.ReturnCode_ERROR, Msg: "block not found"}, nil
+	}
+	return &account.BlockHeaderResponse{
+		Code: common.ReturnCode_SUCCESS,
+		BlockHeader: &account.BlockHeader{
+			Hash:       blockHash(height),
+			ParentHash: blockHash(height - 1),
+			Number:     strconv.FormatUint(height, 10),
+			Time:       uint64(c.genesis.Add(time.Duration(height-genesisHeight) * blockTime).Unix()),
+		},
+	}, nil
+}
+
+func (c *fakeChain) GetBlockByNumber(_ context.Context, req *account.BlockNumberRequest) (*account.BlockResponse, error) {
+	height := uint64(req.Height)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.fetchedAt[height]; !ok {
+		c.fetchedAt[height] = c.latest()
+	}
+	var txs []*account.BlockInfoTransactionList
+	for _, tx := range c.txs {
+		if tx.height == height {
+			txs = append(txs, &account.BlockInfoTransactionList{From: tx.from, To: tx.to, Hash: tx.hash, Amount: tx.amount})
+		}
+	}
+	return &account.BlockResponse{Code: common.ReturnCode_SUCCESS, Height: req.Height, Hash: blockHash(height), Transactions: txs}, nil
+}
+
+func (c *fakeChain) GetTxByHash(_ context.Context, req *account.TxHashRequest) (*account.TxHashResponse, error) {
+	tx := c.tx(req.Hash)
+	if tx == nil {
+		return &account.TxHashResponse{Code: common.ReturnCode_ERROR, Msg: "tx not found"}, nil
+	}
+	return &account.TxHashResponse{
+		Code: common.ReturnCode_SUCCESS,
+		Tx: &account.TxMessage{
+			Hash:     tx.hash,
+			Froms:    []*account.Address{{Address: tx.from}},
+			Tos:      []*account.Address{{Address: tx.to}},
+			Values:   []*account.Value{{Value: tx.amount}},
+			Fee:      "21000",
+			Status:   account.TxStatus_Success,
+			Height:   strconv.FormatUint(tx.height, 10),
+			Datetime: strconv.FormatInt(c.clock.Now().Unix(), 10),
+		},
+	}, nil
+}
+
+func (c *fakeChain) SendTx(_ context.Context, req *account.SendTxRequest) (*account.SendTxResponse, error) {
+	tx, from, err := decodeSignedTx(req.RawTx)
+	if err != nil {
+		return &account.SendTxResponse{Code: common.ReturnCode_ERROR, Msg: "invalid raw tx: " + err.Error()}, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	hash := c.include(lowerHex(tx.Hash()), lowerHex(from), lowerHex(tx.To()), tx.Value().String())
+	c.sent = append(c.sent, hash)
+	return &account.SendTxResponse{Code: common.ReturnCode_SUCCESS, TxHash: hash}, nil
+}
+
+func (c *fakeChain) DecodeTransaction(_ context.Context, req *account.DecodeTransactionRequest) (*account.DecodeTransactionResponse, error) {
+	tx, from, err := decodeSignedTx(req.RawTx)
+	if err != nil {
+		return &account.DecodeTransactionResponse{Code: common.ReturnCode_ERROR, Msg: "invalid raw tx: " + err.Error()}, nil
+	}
+	data, err := json.Marshal(rpcclient.DecodedTx{
+		Hash:        lowerHex(tx.Hash()),
+		ChainId:     tx.ChainId().String(),
+		Nonce:       tx.Nonce(),
+		FromAddress: lowerHex(from),
+		ToAddress:   lowerHex(tx.To()),
+		Value:       tx.Value().String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &account.DecodeTransactionResponse{Code: common.ReturnCode_SUCCESS, Base64Tx: base64.StdEncoding.EncodeToString(data)}, nil
+}
+
+func (c *fakeChain) VerifySignedTransaction(_ context.Context, req *account.VerifyTransactionRequest) (*account.VerifyTransactionResponse, error) {
+	_, from, err := decodeSignedTx(req.Signature)
+	if err != nil {
+		return &account.VerifyTransactionResponse{Code: common.ReturnCode_ERROR, Msg: "invalid raw tx: " + err.Error()}, nil
+	}
+	publicKey, err := crypto.DecompressPubkey(gethcommon.FromHex(req.PublicKey))
+	if err != nil {
+		return &account.VerifyTransactionResponse{Code: common.ReturnCode_ERROR, Msg: "invalid public key: " + err.Error()}, nil
+	}
+	return &account.VerifyTransactionResponse{Code: common.ReturnCode_SUCCESS, Verify: crypto.PubkeyToAddress(*publicKey) == from}, nil
+}
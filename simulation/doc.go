@@ -0,0 +1,3 @@
+// Package simulation 在确定性时钟下驱动真实的同步、发送和通知 worker，
+// 链上数据来自内存中的 chain-account 服务，数据库为内存实现，测试不依赖真实时间流逝
+package simulation
@@ -0,0 +1,130 @@
+package simulation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/common/clock"
+	"github.com/CavnHan/multichain-sync-account/config"
+	"github.com/CavnHan/multichain-sync-account/notifier"
+	"github.com/CavnHan/multichain-sync-account/registry"
+	"github.com/CavnHan/multichain-sync-account/worker"
+)
+
+const (
+	businessId    = "sim"
+	confirmations = 3
+)
+
+// webhook 记录业务方收到的通知
+type webhook struct {
+	mu  sync.Mutex
+	txs []notifier.Transaction
+}
+
+func newWebhook(t *testing.T) (*webhook, string) {
+	hook := &webhook{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request notifier.NotifyRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		hook.mu.Lock()
+		hook.txs = append(hook.txs, request.Txn...)
+		hook.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(notifier.NotifyResponse{Success: true})
+	}))
+	t.Cleanup(server.Close)
+	return hook, server.URL
+}
+
+func (h *webhook) received(txType, hash string) []notifier.Transaction {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var txs []notifier.Transaction
+	for _, tx := range h.txs {
+		if tx.TxType == txType && tx.Hash == hash {
+			txs = append(txs, tx)
+		}
+	}
+	return txs
+}
+
+type harness struct {
+	t       *testing.T
+	clock   *clock.DeterministicClock
+	chain   *fakeChain
+	store   *memStore
+	webhook *webhook
+}
+
+// newHarness 启动充值同步、提现发送和通知 worker，全部由同一个确定性时钟驱动
+func newHarness(t *testing.T, setup func(store *memStore)) *harness {
+	clk := clock.NewDeterministicClock(time.Unix(1_700_000_000, 0))
+	chain, chainAccountRpc := newFakeChain(t, clk)
+	hook, notifyUrl := newWebhook(t)
+	store, db := newMemoryDB()
+	store.addBusiness(businessId, notifyUrl)
+	setup(store)
+
+	cfg := &config.Config{
+		ChainAccountRpc: chainAccountRpc,
+		ChainNode: config.ChainNodeConfig{
+			ChainName:            "Ethereum",
+			SynchronizerInterval: 5 * time.Second,
+			WorkerInterval:       5 * time.Second,
+			BlocksStep:           10,
+			Confirmations:        confirmations,
+		},
+		// 不允许重启，任何 worker 错误都会触发 shutdown 使测试失败
+		WorkerFailureBudget: 0,
+	}
+	shutdown := func(cause error) {
+		t.Errorf("unexpected shutdown: %v", cause)
+	}
+	reg, err := registry.NewRegistry(db, 0)
+	require.NoError(t, err)
+
+	accountClient, closeAccount, err := worker.NewAccountClient(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, closeAccount())
+	})
+
+	deposit, err := worker.NewDeposit(cfg, db, reg, accountClient, clk, shutdown)
+	require.NoError(t, err)
+	withdraw, err := worker.NewWithdraw(cfg, db, reg, accountClient, clk, shutdown)
+	require.NoError(t, err)
+	nf, err := notifier.NewNotifier(db, notifier.Config{LeaseName: "notify:sim", Clock: clk}, shutdown)
+	require.NoError(t, err)
+
+	require.NoError(t, deposit.Start())
+	require.NoError(t, withdraw.Start())
+	require.NoError(t, nf.Start(context.Background()))
+	t.Cleanup(func() {
+		require.NoError(t, deposit.Close())
+		require.NoError(t, withdraw.Close())
+		require.NoError(t, nf.Stop(context.Background()))
+	})
+	return &harness{t: t, clock: clk, chain: chain, store: store, webhook: hook}
+}
+
+// advanceUntil 每次推进一秒模拟时间直到 cond 成立，真实时间只用于等待 worker 协程处理完当前周期
+func (h *harness) advanceUntil(cond func() bool) {
+	h.t.Helper()
+	require.Eventually(h.t, func() bool {
+		if cond() {
+			return true
+		}
+		h.clock.AdvanceTime(time.Second)
+		return false
+	}, 10*time.Second, time.Millisecond)
+}
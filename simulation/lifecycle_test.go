@@ -0,0 +1,126 @@
+package simulation
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/txverify"
+)
+
+const (
+	userAddress     = "0x1111111111111111111111111111111111111111"
+	externalAddress = "0x3333333333333333333333333333333333333333"
+)
+
+var (
+	hotKey, _  = crypto.ToECDSA(bytes.Repeat([]byte{0x22}, 32))
+	hotAddress = lowerHex(crypto.PubkeyToAddress(hotKey.PublicKey))
+)
+
+// addHotWallet 登记热钱包地址和公钥，发送前用公钥校验签名
+func addHotWallet(store *memStore) {
+	store.addAddress(businessId, hotAddress, 1)
+	store.addPublicKey(hotAddress, hexutil.Encode(crypto.CompressPubkey(&hotKey.PublicKey)))
+}
+
+func TestDepositLifecycle(t *testing.T) {
+	h := newHarness(t, func(store *memStore) {
+		store.addAddress(businessId, userAddress, 0)
+	})
+
+	hash := h.chain.transfer(externalAddress, userAddress, "1000")
+	height := h.chain.tx(hash).height
+
+	h.advanceUntil(func() bool {
+		return len(h.webhook.received("deposit", hash)) > 0
+	})
+
+	// 区块满足确认深度后才会被同步
+	fetchedAt, ok := h.chain.firstFetchedAt(height)
+	require.True(t, ok)
+	require.GreaterOrEqual(t, fetchedAt, height+confirmations)
+
+	notified := h.webhook.received("deposit", hash)
+	require.Len(t, notified, 1)
+	require.Equal(t, uint8(confirmations), notified[0].Confirms)
+	require.Equal(t, "1000", notified[0].Value)
+	require.Equal(t, height, notified[0].BlockNumber)
+
+	deposit := h.store.deposit(businessId, hash)
+	require.NotNil(t, deposit)
+	require.Equal(t, uint8(3), deposit.Status)
+	require.Equal(t, uint8(confirmations), deposit.Confirms)
+}
+
+func TestWithdrawLifecycle(t *testing.T) {
+	guid := uuid.New()
+	h := newHarness(t, func(store *memStore) {
+		addHotWallet(store)
+		store.addWithdraw(businessId, database.Withdraws{
+			GUID:        guid,
+			BlockNumber: big.NewInt(0),
+			FromAddress: chainaddr.Address(hotAddress),
+			ToAddress:   externalAddress,
+			Amount:      big.NewInt(500),
+			Fee:         big.NewInt(0),
+			Status:      1,
+			TxSignHex:   signedTx(t, hotKey, 7, externalAddress, "500"),
+			Nonce:       7,
+			ChainId:     "1",
+		})
+	})
+
+	// 发送后记录交易哈希
+	h.advanceUntil(func() bool {
+		return h.store.withdraw(businessId, guid).Status >= 2
+	})
+	sent := h.chain.sentTxs()
+	require.Len(t, sent, 1)
+	require.Equal(t, sent[0], h.store.withdraw(businessId, guid).Hash.String())
+
+	// 链上确认后同步为完成，再通知业务方
+	h.advanceUntil(func() bool {
+		return len(h.webhook.received("withdraw", sent[0])) > 0
+	})
+	notified := h.webhook.received("withdraw", sent[0])
+	require.Len(t, notified, 1)
+	require.Equal(t, "500", notified[0].Value)
+	require.Equal(t, uint8(5), h.store.withdraw(businessId, guid).Status)
+
+	// 已发送的提现不会重复广播
+	require.Len(t, h.chain.sentTxs(), 1)
+}
+
+func TestWithdrawTamperedSignedTxBlocked(t *testing.T) {
+	guid := uuid.New()
+	h := newHarness(t, func(store *memStore) {
+		addHotWallet(store)
+		store.addWithdraw(businessId, database.Withdraws{
+			GUID:        guid,
+			BlockNumber: big.NewInt(0),
+			FromAddress: chainaddr.Address(hotAddress),
+			ToAddress:   externalAddress,
+			Amount:      big.NewInt(500),
+			Fee:         big.NewInt(0),
+			Status:      1,
+			// 签名交易的收款地址和金额被篡改
+			TxSignHex: signedTx(t, hotKey, 7, userAddress, "5000"),
+			Nonce:     7,
+			ChainId:   "1",
+		})
+	})
+
+	h.advanceUntil(func() bool {
+		return h.store.withdraw(businessId, guid).Status == txverify.StatusBlocked
+	})
+	require.Empty(t, h.chain.sentTxs())
+	require.True(t, h.store.withdraw(businessId, guid).Hash.IsZero())
+}
@@ -0,0 +1,407 @@
+package simulation
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/rpcclient"
+)
+
+// memStore 按 SQL 实现的语义在内存中保存 worker 用到的表，未实现的方法调用时 panic
+type memStore struct {
+	mu           sync.Mutex
+	blocks       []database.Blocks
+	businesses   []database.Business
+	addresses    map[string]map[chainaddr.Address]uint8
+	publicKeys   map[chainaddr.Address]string
+	deposits     map[string][]*database.Deposits
+	withdraws    map[string][]*database.Withdraws
+	transactions map[string][]database.Transactions
+	events       map[string][]database.Events
+	cursors      map[string]map[string]uint64
+}
+
+func newMemoryDB() (*memStore, *database.DB) {
+	m := &memStore{
+		addresses:    make(map[string]map[chainaddr.Address]uint8),
+		publicKeys:   make(map[chainaddr.Address]string),
+		deposits:     make(map[string][]*database.Deposits),
+		withdraws:    make(map[string][]*database.Withdraws),
+		transactions: make(map[string][]database.Transactions),
+		events:       make(map[string][]database.Events),
+		cursors:      make(map[string]map[string]uint64),
+	}
+	return m, &database.DB{
+		Transactor:      memTransactor{},
+		Blocks:          &memBlocks{m: m},
+		Business:        &memBusiness{m: m},
+		Addresses:       &memAddresses{m: m},
+		Memos:           &memMemos{},
+		Deposits:        &memDeposits{m: m},
+		Withdraws:       &memWithdraws{m: m},
+		WithdrawBatches: &memWithdrawBatches{},
+		Internals:       &memInternals{},
+		Transactions:    &memTransactions{m: m},
+		Events:          &memEvents{m: m},
+		Leases:          &memLeases{},
+	}
+}
+
+// memTransactor 内存实现没有回滚，事务直接在当前实例上执行
+type memTransactor struct{}
+
+func (memTransactor) Transaction(db *database.DB, fn func(tx *database.DB) error) error {
+	if err := db.CheckFence(); err != nil {
+		return err
+	}
+	return fn(db)
+}
+
+func (memTransactor) LockBusiness(string) error {
+	return nil
+}
+
+func (m *memStore) addBusiness(businessId, notifyUrl string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.businesses = append(m.businesses, database.Business{GUID: uuid.New(), BusinessUid: businessId, NotifyUrl: notifyUrl, Sinks: "webhook"})
+	m.addresses[businessId] = make(map[chainaddr.Address]uint8)
+}
+
+func (m *memStore) addAddress(businessId string, address string, addressType uint8) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.addresses[businessId][chainaddr.Address(address)] = addressType
+}
+
+func (m *memStore) addPublicKey(address string, publicKey string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.publicKeys[chainaddr.Address(address)] = publicKey
+}
+
+func (m *memStore) addWithdraw(businessId string, withdraw database.Withdraws) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.withdraws[businessId] = append(m.withdraws[businessId], &withdraw)
+}
+
+func (m *memStore) deposit(businessId string, hash string) *database.Deposits {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, deposit := range m.deposits[businessId] {
+		if deposit.Hash == chainaddr.Hash(hash) {
+			copied := *deposit
+			return &copied
+		}
+	}
+	return nil
+}
+
+func (m *memStore) withdraw(businessId string, guid uuid.UUID) database.Withdraws {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, withdraw := range m.withdraws[businessId] {
+		if withdraw.GUID == guid {
+			return *withdraw
+		}
+	}
+	return database.Withdraws{}
+}
+
+type memBlocks struct {
+	database.BlocksDB
+	m *memStore
+}
+
+func (db *memBlocks) LatestBlocks() (*rpcclient.BlockHeader, error) {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	if len(db.m.blocks) == 0 {
+		return nil, nil
+	}
+	last := db.m.blocks[len(db.m.blocks)-1]
+	return &rpcclient.BlockHeader{Hash: last.Hash, ParentHash: last.ParentHash, Number: last.Number, Timestamp: last.Timestamp}, nil
+}
+
+func (db *memBlocks) StoreBlockss(blocks []database.Blocks) error {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	db.m.blocks = append(db.m.blocks, blocks...)
+	return nil
+}
+
+type memBusiness struct {
+	database.BusinessDB
+	m *memStore
+}
+
+func (db *memBusiness) QueryBusinessList() ([]database.Business, error) {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	return append([]database.Business(nil), db.m.businesses...), nil
+}
+
+type memAddresses struct {
+	database.AddressesDB
+	m *memStore
+}
+
+func (db *memAddresses) AddressExist(requestId string, address chainaddr.Address) (bool, uint8) {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	addressType, ok := db.m.addresses[requestId][address]
+	return ok, addressType
+}
+
+func (db *memAddresses) QueryAddressesByToAddress(requestId string, address chainaddr.Address) (*database.Addresses, error) {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	addressType, ok := db.m.addresses[requestId][address]
+	if !ok {
+		return nil, nil
+	}
+	return &database.Addresses{Address: address, AddressType: addressType, PublicKey: db.m.publicKeys[address]}, nil
+}
+
+type memMemos struct {
+	database.MemosDB
+}
+
+func (db *memMemos) IsSharedAddress(string, chainaddr.Address) (bool, error) {
+	return false, nil
+}
+
+type memDeposits struct {
+	database.DepositsDB
+	m *memStore
+}
+
+func (db *memDeposits) StoreDeposits(requestId string, deposits []database.Deposits, _ uint64) error {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	for i := range deposits {
+		deposit := deposits[i]
+		db.m.deposits[requestId] = append(db.m.deposits[requestId], &deposit)
+	}
+	return nil
+}
+
+func (db *memDeposits) UpdateDepositsComfirms(requestId string, blockNumber uint64, confirms uint64) error {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	for _, deposit := range db.m.deposits[requestId] {
+		if deposit.Status != 0 || deposit.BlockNumber.Uint64() > blockNumber {
+			continue
+		}
+		chainConfirm := blockNumber - deposit.BlockNumber.Uint64()
+		if chainConfirm >= confirms {
+			deposit.Confirms = uint8(confirms)
+			deposit.Status = 1
+		} else {
+			deposit.Confirms = uint8(chainConfirm)
+		}
+	}
+	return nil
+}
+
+func (db *memDeposits) QueryNotifyDeposits(requestId string) ([]database.Deposits, error) {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	var deposits []database.Deposits
+	for _, deposit := range db.m.deposits[requestId] {
+		confirming := deposit.Status == 0 && (!deposit.Notified || deposit.Confirms != deposit.NotifiedConfirms)
+		if (confirming || deposit.Status == 1) && !deposit.Quarantined {
+			deposits = append(deposits, *deposit)
+		}
+	}
+	return deposits, nil
+}
+
+func (db *memDeposits) UpdateDepositsNotifyStatus(requestId string, status uint8, depositList []database.Deposits) error {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	for _, notified := range depositList {
+		for _, deposit := range db.m.deposits[requestId] {
+			if deposit.Hash == notified.Hash {
+				deposit.Status = status
+			}
+		}
+	}
+	return nil
+}
+
+func (db *memDeposits) UpdateDepositsNotifiedConfirms(requestId string, depositList []database.Deposits) error {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	for _, notified := range depositList {
+		for _, deposit := range db.m.deposits[requestId] {
+			if deposit.GUID == notified.GUID {
+				deposit.Notified, deposit.NotifiedConfirms = true, notified.Confirms
+			}
+		}
+	}
+	return nil
+}
+
+type memWithdraws struct {
+	database.WithdrawsDB
+	m *memStore
+}
+
+func (db *memWithdraws) UnSendWithdrawsList(requestId string) ([]database.Withdraws, error) {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	var withdraws []database.Withdraws
+	for _, withdraw := range db.m.withdraws[requestId] {
+		if withdraw.Status == 1 && withdraw.BatchId == "" {
+			withdraws = append(withdraws, *withdraw)
+		}
+	}
+	return withdraws, nil
+}
+
+func (db *memWithdraws) UpdateWithdrawsSent(requestId string, withdrawsList []database.Withdraws) error {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	for _, sent := range withdrawsList {
+		for _, withdraw := range db.m.withdraws[requestId] {
+			if withdraw.GUID == sent.GUID {
+				withdraw.Hash, withdraw.Status = sent.Hash, sent.Status
+			}
+		}
+	}
+	return nil
+}
+
+func (db *memWithdraws) UpdateWithdrawStatus(requestId string, status uint8, withdrawsList []database.Withdraws) error {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	for _, updated := range withdrawsList {
+		for _, withdraw := range db.m.withdraws[requestId] {
+			if withdraw.Hash == updated.Hash {
+				withdraw.Status = status
+			}
+		}
+	}
+	return nil
+}
+
+func (db *memWithdraws) QueryNotifyWithdraws(requestId string) ([]database.Withdraws, error) {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	var withdraws []database.Withdraws
+	for _, withdraw := range db.m.withdraws[requestId] {
+		if withdraw.Status == 3 {
+			withdraws = append(withdraws, *withdraw)
+		}
+	}
+	return withdraws, nil
+}
+
+type memWithdrawBatches struct {
+	database.WithdrawBatchesDB
+}
+
+func (db *memWithdrawBatches) QueryWithdrawBatchByHash(string, chainaddr.Hash) (*database.WithdrawBatches, error) {
+	return nil, nil
+}
+
+func (db *memWithdrawBatches) UnSendWithdrawBatches(string) ([]database.WithdrawBatches, error) {
+	return nil, nil
+}
+
+type memInternals struct {
+	database.InternalsDB
+}
+
+func (db *memInternals) QueryNotifyInternal(string) ([]database.Internals, error) {
+	return nil, nil
+}
+
+type memTransactions struct {
+	database.TransactionsDB
+	m *memStore
+}
+
+func (db *memTransactions) QueryTransactionByHash(requestId string, hash chainaddr.Hash) (*database.Transactions, error) {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	for _, tx := range db.m.transactions[requestId] {
+		if tx.Hash == hash {
+			return &tx, nil
+		}
+	}
+	return nil, nil
+}
+
+func (db *memTransactions) StoreTransactions(requestId string, txs []database.Transactions, _ uint64) error {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	db.m.transactions[requestId] = append(db.m.transactions[requestId], txs...)
+	return nil
+}
+
+type memEvents struct {
+	database.EventsDB
+	m *memStore
+}
+
+func (db *memEvents) StoreEvents(requestId string, events []database.Events) error {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	for i := range events {
+		events[i].Sequence = uint64(len(db.m.events[requestId]) + 1)
+		db.m.events[requestId] = append(db.m.events[requestId], events[i])
+	}
+	return nil
+}
+
+func (db *memEvents) QueryEventsAfter(requestId string, sequence uint64, limit int) ([]database.Events, error) {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	var events []database.Events
+	for _, event := range db.m.events[requestId] {
+		if event.Sequence > sequence && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (db *memEvents) QueryEventCursor(requestId string, sink string) (uint64, error) {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	return db.m.cursors[requestId][sink], nil
+}
+
+func (db *memEvents) UpdateEventCursor(requestId string, sink string, sequence uint64) error {
+	db.m.mu.Lock()
+	defer db.m.mu.Unlock()
+	if db.m.cursors[requestId] == nil {
+		db.m.cursors[requestId] = make(map[string]uint64)
+	}
+	db.m.cursors[requestId][sink] = sequence
+	return nil
+}
+
+// memLeases 单实例模拟，总是当选
+type memLeases struct {
+	database.LeasesDB
+}
+
+func (db *memLeases) AcquireLease(string, string, time.Duration) (uint64, error) {
+	return 1, nil
+}
+
+func (db *memLeases) ReleaseLease(database.Fence) error {
+	return nil
+}
+
+func (db *memLeases) CheckLease(database.Fence) error {
+	return nil
+}
@@ -0,0 +1,161 @@
+package txverify
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+)
+
+// multisendABI 批量提现使用的 multisend 合约接口，与 Disperse 合约一致
+const multisendABI = `[
+	{"name":"disperseEther","type":"function","inputs":[{"name":"recipients","type":"address[]"},{"name":"values","type":"uint256[]"}]},
+	{"name":"disperseToken","type":"function","inputs":[{"name":"token","type":"address"},{"name":"recipients","type":"address[]"},{"name":"values","type":"uint256[]"}]}
+]`
+
+var multisend = func() abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(multisendABI))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}()
+
+// BatchExpected 库中记录的批量提现要素，Recipients 与 Amounts 一一对应
+type BatchExpected struct {
+	ChainId    string
+	Nonce      uint64
+	From       chainaddr.Address
+	Contract   chainaddr.Address // multisend 合约
+	Token      chainaddr.Address
+	Recipients []chainaddr.Address
+	Amounts    []*big.Int
+}
+
+func FromBatch(batch *database.WithdrawBatches, members []database.Withdraws, contract chainaddr.Address) BatchExpected {
+	expected := BatchExpected{ChainId: batch.ChainId, Nonce: batch.Nonce, From: batch.FromAddress, Contract: contract, Token: batch.TokenAddress}
+	for _, member := range members {
+		expected.Recipients = append(expected.Recipients, member.ToAddress)
+		expected.Amounts = append(expected.Amounts, member.Amount)
+	}
+	return expected
+}
+
+// VerifyBatch 本地解析 EVM 批量提现交易，比对 nonce、链 id、签名地址、multisend 合约以及每个收款方和金额，
+// 返回交易哈希。错误约定与 Verify 相同
+func (v *Verifier) VerifyBatch(requestId string, expected BatchExpected, signedTx string) (chainaddr.Hash, error) {
+	var mismatches []string
+	mismatch := func(field string, signed, stored any) {
+		mismatches = append(mismatches, fmt.Sprintf("%s: signed %v, stored %v", field, signed, stored))
+	}
+
+	var tx types.Transaction
+	if err := tx.UnmarshalBinary(common.FromHex(signedTx)); err != nil {
+		return "", fmt.Errorf("%w: raw_tx: %v", ErrMismatch, err)
+	}
+	if tx.Nonce() != expected.Nonce {
+		mismatch("nonce", tx.Nonce(), expected.Nonce)
+	}
+	if !SameChainId(tx.ChainId().String(), expected.ChainId) {
+		mismatch("chain_id", tx.ChainId(), expected.ChainId)
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), &tx)
+	if err != nil {
+		mismatch("signer", err, expected.From)
+	} else if from := parseAddress(sender.Hex()); from != expected.From {
+		mismatch("signer", from, expected.From)
+	}
+	if tx.To() == nil || parseAddress(tx.To().Hex()) != expected.Contract {
+		mismatch("multisend_contract", tx.To(), expected.Contract)
+	}
+	if err := v.checkSigner(requestId, expected.From, signedTx, mismatch); err != nil {
+		return "", err
+	}
+
+	recipients, amounts, token, err := decodeMultisend(tx.Data())
+	if err != nil {
+		mismatch("data", err, "multisend call")
+	} else {
+		total := big.NewInt(0)
+		for _, amount := range expected.Amounts {
+			total.Add(total, amount)
+		}
+		native := tokenAddress(expected.Token.String()) == chainaddr.NativeToken()
+		if native && tx.Value().Cmp(total) != 0 {
+			mismatch("value", tx.Value(), total)
+		}
+		if !native && tx.Value().Sign() != 0 {
+			mismatch("value", tx.Value(), 0)
+		}
+		if stored := tokenAddress(expected.Token.String()); token != stored {
+			mismatch("token_address", token, stored)
+		}
+		if signed, stored := payouts(recipients, amounts), payouts(expected.Recipients, expected.Amounts); signed != stored {
+			mismatch("recipients", signed, stored)
+		}
+	}
+
+	if len(mismatches) > 0 {
+		return "", fmt.Errorf("%w: %s", ErrMismatch, strings.Join(mismatches, "; "))
+	}
+	return chainaddr.ParseHash(tx.Hash().Hex())
+}
+
+// BlockBatch 拦截校验不通过的批次，成员提现一并置为已拦截，不再参与单笔或批量发起
+func BlockBatch(db *database.DB, requestId string, batchId string) error {
+	if err := db.WithdrawBatches.UpdateWithdrawBatchStatus(requestId, batchId, StatusBlocked, nil); err != nil {
+		return err
+	}
+	return db.Withdraws.UpdateBatchWithdraws(requestId, batchId, StatusBlocked, "")
+}
+
+// decodeMultisend 解析 disperseEther/disperseToken 调用，原生币的代币地址返回 NativeToken
+func decodeMultisend(data []byte) ([]chainaddr.Address, []*big.Int, chainaddr.Address, error) {
+	if len(data) < 4 {
+		return nil, nil, "", errors.New("missing method selector")
+	}
+	method, err := multisend.MethodById(data[:4])
+	if err != nil {
+		return nil, nil, "", err
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, nil, "", err
+	}
+	token := chainaddr.NativeToken()
+	if method.Name == "disperseToken" {
+		token = tokenAddress(args[0].(common.Address).Hex())
+		args = args[1:]
+	}
+	var recipients []chainaddr.Address
+	for _, recipient := range args[0].([]common.Address) {
+		recipients = append(recipients, parseAddress(recipient.Hex()))
+	}
+	return recipients, args[1].([]*big.Int), token, nil
+}
+
+// payouts 按收款方和金额两两组合后排序，比对结果与成员顺序无关
+func payouts(recipients []chainaddr.Address, amounts []*big.Int) string {
+	entries := make([]string, 0, max(len(recipients), len(amounts)))
+	for i := 0; i < max(len(recipients), len(amounts)); i++ {
+		var recipient chainaddr.Address
+		amount := "<nil>"
+		if i < len(recipients) {
+			recipient = recipients[i]
+		}
+		if i < len(amounts) && amounts[i] != nil {
+			amount = amounts[i].String()
+		}
+		entries = append(entries, fmt.Sprintf("%s:%s", recipient, amount))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
@@ -0,0 +1,107 @@
+package txverify
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/rpcclient"
+)
+
+const multisendAddress = "0x6666666666666666666666666666666666666666"
+
+// signBatch 签名一笔调用 multisend 合约 disperseEther 的交易，nonce 为 7、链 id 为 1
+func signBatch(t *testing.T, contract string, value int64, recipients []string, amounts []int64) (string, chainaddr.Address) {
+	key, err := crypto.ToECDSA(bytes.Repeat([]byte{0x22}, 32))
+	require.NoError(t, err)
+	var addresses []gethcommon.Address
+	var values []*big.Int
+	for i := range recipients {
+		addresses = append(addresses, gethcommon.HexToAddress(recipients[i]))
+		values = append(values, big.NewInt(amounts[i]))
+	}
+	data, err := multisend.Pack("disperseEther", addresses, values)
+	require.NoError(t, err)
+	to := gethcommon.HexToAddress(contract)
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{
+		ChainID: big.NewInt(1), Nonce: 7, Gas: 100000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), To: &to, Value: big.NewInt(value), Data: data,
+	})
+	require.NoError(t, err)
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	from, err := chainaddr.ParseAddress(crypto.PubkeyToAddress(key.PublicKey).Hex())
+	require.NoError(t, err)
+	return hexutil.Encode(raw), from
+}
+
+func TestVerifyBatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		contract   string
+		value      int64
+		recipients []string
+		amounts    []int64
+		tamper     func(e *BatchExpected)
+		mismatch   string
+	}{
+		{name: "match"},
+		{name: "member order", recipients: []string{otherAddress, toAddress}, amounts: []int64{200, 100}},
+		{name: "recipient", recipients: []string{toAddress, tokenAddr}, amounts: []int64{100, 200}, mismatch: "recipients"},
+		{name: "amount", recipients: []string{toAddress, otherAddress}, amounts: []int64{100, 201}, value: 301, mismatch: "recipients"},
+		{name: "missing member", recipients: []string{toAddress}, amounts: []int64{100}, value: 100, mismatch: "recipients"},
+		{name: "value", value: 301, mismatch: "value"},
+		{name: "contract", contract: otherAddress, mismatch: "multisend_contract"},
+		{name: "token", tamper: func(e *BatchExpected) { e.Token = tokenAddr }, mismatch: "token_address"},
+		{name: "nonce", tamper: func(e *BatchExpected) { e.Nonce = 8 }, mismatch: "nonce"},
+		{name: "chain id", tamper: func(e *BatchExpected) { e.ChainId = "5" }, mismatch: "chain_id"},
+		{name: "signer", tamper: func(e *BatchExpected) { e.From = toAddress }, mismatch: "signer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contract, value, recipients, amounts := multisendAddress, tt.value, tt.recipients, tt.amounts
+			if tt.contract != "" {
+				contract = tt.contract
+			}
+			if recipients == nil {
+				recipients, amounts = []string{toAddress, otherAddress}, []int64{100, 200}
+			}
+			if value == 0 {
+				value = 300
+			}
+			rawTx, from := signBatch(t, contract, value, recipients, amounts)
+			expected := BatchExpected{
+				ChainId:    "1",
+				Nonce:      7,
+				From:       from,
+				Contract:   multisendAddress,
+				Token:      chainaddr.NativeToken(),
+				Recipients: []chainaddr.Address{toAddress, otherAddress},
+				Amounts:    []*big.Int{big.NewInt(100), big.NewInt(200)},
+			}
+			if tt.tamper != nil {
+				tt.tamper(&expected)
+			}
+			client, err := rpcclient.NewWalletChainAccountClient(context.Background(), &stubAccount{verify: true}, "Ethereum")
+			require.NoError(t, err)
+			verifier := NewVerifier(client, &database.DB{Addresses: &stubAddresses{wallet: from}})
+
+			hash, err := verifier.VerifyBatch("biz", expected, rawTx)
+			if tt.mismatch == "" {
+				require.NoError(t, err)
+				require.NotEmpty(t, hash)
+				return
+			}
+			require.ErrorIs(t, err, ErrMismatch)
+			require.ErrorContains(t, err, tt.mismatch)
+		})
+	}
+}
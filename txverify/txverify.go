@@ -0,0 +1,252 @@
+// Package txverify 在签名交易入库和广播前，用 chain-account 解析签名交易并与库中的提现/内部交易记录逐项比对，
+// 防止签名机或 chain-account 返回被篡改的交易
+package txverify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/rpcclient"
+)
+
+// StatusBlocked 提现和内部交易校验不通过后的状态，需要人工处理
+const StatusBlocked uint8 = 6
+
+// ErrMismatch 签名交易与库中记录不一致
+var ErrMismatch = errors.New("signed transaction does not match the stored record")
+
+// Expected 库中记录的交易要素
+type Expected struct {
+	ChainId string
+	Nonce   uint64
+	From    chainaddr.Address
+	To      chainaddr.Address
+	Token   chainaddr.Address
+	TokenId string
+	Amount  *big.Int
+}
+
+func FromWithdraw(w *database.Withdraws) Expected {
+	return Expected{ChainId: w.ChainId, Nonce: w.Nonce, From: w.FromAddress, To: w.ToAddress, Token: w.TokenAddress, TokenId: w.TokenId, Amount: w.Amount}
+}
+
+func FromInternal(i *database.Internals) Expected {
+	return Expected{ChainId: i.ChainId, Nonce: i.Nonce, From: i.FromAddress, To: i.ToAddress, Token: i.TokenAddress, TokenId: i.TokenId, Amount: i.Amount}
+}
+
+type Verifier struct {
+	client *rpcclient.WalletChainAccountClient
+	db     *database.DB
+}
+
+func NewVerifier(client *rpcclient.WalletChainAccountClient, db *database.DB) *Verifier {
+	return &Verifier{client: client, db: db}
+}
+
+// Verify 返回签名交易的哈希，EVM 链在本地计算。交易要素不一致时返回 ErrMismatch，
+// 访问 chain-account 或数据库失败时返回原始错误，调用方稍后重试
+func (v *Verifier) Verify(requestId string, expected Expected, signedTx string) (chainaddr.Hash, error) {
+	decoded, err := v.client.DecodeTransaction(signedTx)
+	if err != nil {
+		return "", err
+	}
+	var mismatches []string
+	mismatch := func(field string, signed, stored any) {
+		mismatches = append(mismatches, fmt.Sprintf("%s: signed %v, stored %v", field, signed, stored))
+	}
+
+	if !SameChainId(decoded.ChainId, expected.ChainId) {
+		mismatch("chain_id", decoded.ChainId, expected.ChainId)
+	}
+	if decoded.Nonce != expected.Nonce {
+		mismatch("nonce", decoded.Nonce, expected.Nonce)
+	}
+	if from := parseAddress(decoded.FromAddress); from != expected.From {
+		mismatch("signer", from, expected.From)
+	}
+	if to := parseAddress(decoded.ToAddress); to != expected.To {
+		mismatch("to_address", to, expected.To)
+	}
+	if token, stored := tokenAddress(decoded.ContractAddress), tokenAddress(expected.Token.String()); token != stored {
+		mismatch("token_address", token, stored)
+	}
+	if decoded.TokenId != expected.TokenId {
+		mismatch("token_id", decoded.TokenId, expected.TokenId)
+	}
+	if amount, ok := new(big.Int).SetString(decoded.Value, 10); !ok || expected.Amount == nil || amount.Cmp(expected.Amount) != 0 {
+		mismatch("amount", decoded.Value, expected.Amount)
+	}
+
+	if err := v.checkSigner(requestId, expected.From, signedTx, mismatch); err != nil {
+		return "", err
+	}
+
+	hash := parseHash(decoded.Hash)
+	if chainaddr.Default().Family() == chainaddr.FamilyEVM {
+		local, err := evmCheck(signedTx, expected, mismatch)
+		if err != nil {
+			mismatch("raw_tx", err, "evm transaction")
+		} else if hash != "" && hash != local {
+			mismatch("hash", hash, local)
+		}
+		hash = local
+	}
+	if hash == "" {
+		mismatch("hash", "missing", "hash of the signed transaction")
+	}
+
+	if len(mismatches) > 0 {
+		return "", fmt.Errorf("%w: %s", ErrMismatch, strings.Join(mismatches, "; "))
+	}
+	return hash, nil
+}
+
+// checkSigner 发送地址必须是本钱包的地址，有公钥时再校验签名
+func (v *Verifier) checkSigner(requestId string, from chainaddr.Address, signedTx string, mismatch func(field string, signed, stored any)) error {
+	address, err := v.db.Addresses.QueryAddressesByToAddress(requestId, from)
+	if err != nil {
+		return err
+	}
+	if address == nil {
+		mismatch("signer", from, "not a wallet address")
+		return nil
+	}
+	if address.PublicKey == "" {
+		return nil
+	}
+	ok, err := v.client.VerifySignedTransaction(address.PublicKey, signedTx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		mismatch("signature", "invalid", address.PublicKey)
+	}
+	return nil
+}
+
+// CheckSentHash 广播返回的哈希必须与发送前计算的哈希一致
+func CheckSentHash(expected chainaddr.Hash, sent string) error {
+	if hash := parseHash(sent); hash != expected {
+		return fmt.Errorf("%w: broadcast hash %s, signed hash %s", ErrMismatch, hash, expected)
+	}
+	return nil
+}
+
+// Alert 签名交易被拦截时的安全告警
+func Alert(requestId string, txType string, guid string, err error) {
+	log.Error("SECURITY ALERT: signed transaction blocked", "business", requestId, "type", txType, "guid", guid, "err", err)
+}
+
+// evmCheck 本地解析 EVM 签名交易，不依赖 chain-account 校验 nonce、链 id、签名地址、收款方和金额并计算哈希
+func evmCheck(signedTx string, expected Expected, mismatch func(field string, signed, stored any)) (chainaddr.Hash, error) {
+	var tx types.Transaction
+	if err := tx.UnmarshalBinary(common.FromHex(signedTx)); err != nil {
+		return "", err
+	}
+	if tx.Nonce() != expected.Nonce {
+		mismatch("nonce", tx.Nonce(), expected.Nonce)
+	}
+	if !SameChainId(tx.ChainId().String(), expected.ChainId) {
+		mismatch("chain_id", tx.ChainId(), expected.ChainId)
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), &tx)
+	if err != nil {
+		mismatch("signer", err, expected.From)
+	} else if from := parseAddress(sender.Hex()); from != expected.From {
+		mismatch("signer", from, expected.From)
+	}
+
+	to := chainaddr.Address("")
+	if tx.To() != nil {
+		to = parseAddress(tx.To().Hex())
+	}
+	token := tokenAddress(expected.Token.String())
+	if token == chainaddr.NativeToken() {
+		if to != expected.To {
+			mismatch("to_address", to, expected.To)
+		}
+		if expected.Amount == nil || tx.Value().Cmp(expected.Amount) != 0 {
+			mismatch("amount", tx.Value(), expected.Amount)
+		}
+		return chainaddr.ParseHash(tx.Hash().Hex())
+	}
+
+	// 代币转账发往代币合约，不带原生币；ERC20 再解析 transfer 的收款方和金额
+	if to != token {
+		mismatch("token_address", to, token)
+	}
+	if tx.Value().Sign() != 0 {
+		mismatch("value", tx.Value(), 0)
+	}
+	if expected.TokenId == "" {
+		recipient, amount, err := decodeERC20Transfer(tx.Data())
+		if err != nil {
+			mismatch("data", err, "erc20 transfer")
+		} else {
+			if recipient != expected.To {
+				mismatch("to_address", recipient, expected.To)
+			}
+			if expected.Amount == nil || amount.Cmp(expected.Amount) != 0 {
+				mismatch("amount", amount, expected.Amount)
+			}
+		}
+	}
+	return chainaddr.ParseHash(tx.Hash().Hex())
+}
+
+// erc20TransferSelector transfer(address,uint256) 的方法签名
+var erc20TransferSelector = crypto.Keccak256([]byte("transfer(address,uint256)"))[:4]
+
+// decodeERC20Transfer 解析 ERC20 transfer 调用的收款方和金额
+func decodeERC20Transfer(data []byte) (chainaddr.Address, *big.Int, error) {
+	if len(data) != 4+2*32 || !bytes.Equal(data[:4], erc20TransferSelector) || !bytes.Equal(data[4:16], make([]byte, 12)) {
+		return "", nil, errors.New("not an erc20 transfer call")
+	}
+	recipient := parseAddress(common.BytesToAddress(data[4:36]).Hex())
+	return recipient, new(big.Int).SetBytes(data[36:68]), nil
+}
+
+// SameChainId 链 id 可能是十进制或 0x 开头的十六进制
+func SameChainId(a, b string) bool {
+	x, ok := new(big.Int).SetString(a, 0)
+	if !ok {
+		return false
+	}
+	y, ok := new(big.Int).SetString(b, 0)
+	return ok && x.Cmp(y) == 0
+}
+
+func parseAddress(s string) chainaddr.Address {
+	address, err := chainaddr.ParseAddress(s)
+	if err != nil {
+		return chainaddr.Address(s)
+	}
+	return address
+}
+
+// tokenAddress 空地址和零地址都表示原生币
+func tokenAddress(s string) chainaddr.Address {
+	address := parseAddress(s)
+	if address.IsZero() {
+		return chainaddr.NativeToken()
+	}
+	return address
+}
+
+func parseHash(s string) chainaddr.Hash {
+	hash, err := chainaddr.ParseHash(s)
+	if err != nil {
+		return chainaddr.Hash(s)
+	}
+	return hash
+}
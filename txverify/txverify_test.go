@@ -0,0 +1,214 @@
+package txverify
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	gethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/common/retry"
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/rpcclient"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/common"
+)
+
+const (
+	toAddress    = "0x3333333333333333333333333333333333333333"
+	otherAddress = "0x4444444444444444444444444444444444444444"
+	tokenAddr    = "0x5555555555555555555555555555555555555555"
+	publicKey    = "0x02aa"
+)
+
+// stubAccount 按 decoded 应答解析请求，verify 为签名校验结果，down 时返回 Unavailable
+type stubAccount struct {
+	account.WalletAccountServiceClient
+	decoded rpcclient.DecodedTx
+	verify  bool
+	down    bool
+}
+
+func (s *stubAccount) DecodeTransaction(context.Context, *account.DecodeTransactionRequest, ...grpc.CallOption) (*account.DecodeTransactionResponse, error) {
+	if s.down {
+		return nil, status.Error(codes.Unavailable, "node down")
+	}
+	data, _ := json.Marshal(s.decoded)
+	return &account.DecodeTransactionResponse{Code: common.ReturnCode_SUCCESS, Base64Tx: base64.StdEncoding.EncodeToString(data)}, nil
+}
+
+func (s *stubAccount) VerifySignedTransaction(_ context.Context, in *account.VerifyTransactionRequest, _ ...grpc.CallOption) (*account.VerifyTransactionResponse, error) {
+	return &account.VerifyTransactionResponse{Code: common.ReturnCode_SUCCESS, Verify: s.verify && in.PublicKey == publicKey}, nil
+}
+
+type stubAddresses struct {
+	database.AddressesDB
+	wallet chainaddr.Address
+}
+
+func (s *stubAddresses) QueryAddressesByToAddress(_ string, address chainaddr.Address) (*database.Addresses, error) {
+	if address != s.wallet {
+		return nil, nil
+	}
+	return &database.Addresses{Address: address, AddressType: 1, PublicKey: publicKey}, nil
+}
+
+// signed 返回签名交易及解析结果，nonce 为 7、链 id 为 1
+func signed(t *testing.T) (string, rpcclient.DecodedTx) {
+	return signTx(t, toAddress, big.NewInt(500), nil)
+}
+
+// signTx 签名一笔发往 to 的交易，解析结果按原生币转账填写
+func signTx(t *testing.T, to string, value *big.Int, data []byte) (string, rpcclient.DecodedTx) {
+	key, err := crypto.ToECDSA(bytes.Repeat([]byte{0x22}, 32))
+	require.NoError(t, err)
+	toAddr := gethcommon.HexToAddress(to)
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(big.NewInt(1)), &types.DynamicFeeTx{
+		ChainID: big.NewInt(1), Nonce: 7, Gas: 21000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(1), To: &toAddr, Value: value, Data: data,
+	})
+	require.NoError(t, err)
+	raw, err := tx.MarshalBinary()
+	require.NoError(t, err)
+	return hexutil.Encode(raw), rpcclient.DecodedTx{
+		Hash:        strings.ToLower(tx.Hash().Hex()),
+		ChainId:     "1",
+		Nonce:       7,
+		FromAddress: strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex()),
+		ToAddress:   toAddress,
+		Value:       "500",
+	}
+}
+
+// erc20Transfer 编码 transfer(to, amount) 调用
+func erc20Transfer(to string, amount int64) []byte {
+	data := append([]byte{}, erc20TransferSelector...)
+	data = append(data, gethcommon.LeftPadBytes(gethcommon.HexToAddress(to).Bytes(), 32)...)
+	return append(data, gethcommon.LeftPadBytes(big.NewInt(amount).Bytes(), 32)...)
+}
+
+func TestVerify(t *testing.T) {
+	rawTx, decoded := signed(t)
+	expected := Expected{
+		ChainId: "0x1",
+		Nonce:   7,
+		From:    chainaddr.Address(decoded.FromAddress),
+		To:      toAddress,
+		Amount:  big.NewInt(500),
+	}
+
+	tests := []struct {
+		name     string
+		tamper   func(stub *stubAccount, expected *Expected)
+		mismatch string
+	}{
+		{name: "match"},
+		{name: "recipient", tamper: func(_ *stubAccount, e *Expected) { e.To = otherAddress }, mismatch: "to_address"},
+		{name: "amount", tamper: func(_ *stubAccount, e *Expected) { e.Amount = big.NewInt(501) }, mismatch: "amount"},
+		{name: "token", tamper: func(_ *stubAccount, e *Expected) { e.Token = tokenAddr }, mismatch: "token_address"},
+		{name: "nonce", tamper: func(s *stubAccount, e *Expected) { e.Nonce, s.decoded.Nonce = 8, 8 }, mismatch: "nonce"},
+		{name: "chain id", tamper: func(s *stubAccount, e *Expected) { e.ChainId, s.decoded.ChainId = "5", "5" }, mismatch: "chain_id"},
+		{name: "signer", tamper: func(s *stubAccount, _ *Expected) { s.decoded.FromAddress = toAddress }, mismatch: "signer"},
+		{name: "signature", tamper: func(s *stubAccount, _ *Expected) { s.verify = false }, mismatch: "signature"},
+		{name: "not wallet address", tamper: func(_ *stubAccount, e *Expected) { e.From = toAddress }, mismatch: "not a wallet address"},
+		{name: "hash", tamper: func(s *stubAccount, _ *Expected) { s.decoded.Hash = "0x" + strings.Repeat("ab", 32) }, mismatch: "hash"},
+		// chain-account 返回的解析结果与记录一致，但签名交易本身不一致
+		{name: "local recipient", tamper: func(s *stubAccount, e *Expected) { e.To, s.decoded.ToAddress = otherAddress, otherAddress }, mismatch: "to_address"},
+		{name: "local amount", tamper: func(s *stubAccount, e *Expected) { e.Amount, s.decoded.Value = big.NewInt(501), "501" }, mismatch: "amount"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubAccount{decoded: decoded, verify: true}
+			e := expected
+			if tt.tamper != nil {
+				tt.tamper(stub, &e)
+			}
+			client, err := rpcclient.NewWalletChainAccountClient(context.Background(), stub, "Ethereum")
+			require.NoError(t, err)
+			verifier := NewVerifier(client, &database.DB{Addresses: &stubAddresses{wallet: chainaddr.Address(decoded.FromAddress)}})
+
+			hash, err := verifier.Verify("biz", e, rawTx)
+			if tt.mismatch == "" {
+				require.NoError(t, err)
+				require.Equal(t, chainaddr.Hash(decoded.Hash), hash)
+				return
+			}
+			require.ErrorIs(t, err, ErrMismatch)
+			require.ErrorContains(t, err, tt.mismatch)
+		})
+	}
+}
+
+func TestVerifyERC20Transfer(t *testing.T) {
+	tests := []struct {
+		name     string
+		to       string
+		value    int64
+		data     []byte
+		mismatch string
+	}{
+		{name: "match", to: tokenAddr, data: erc20Transfer(toAddress, 500)},
+		{name: "recipient", to: tokenAddr, data: erc20Transfer(otherAddress, 500), mismatch: "to_address"},
+		{name: "amount", to: tokenAddr, data: erc20Transfer(toAddress, 501), mismatch: "amount"},
+		{name: "contract", to: otherAddress, data: erc20Transfer(toAddress, 500), mismatch: "token_address"},
+		{name: "value", to: tokenAddr, value: 1, data: erc20Transfer(toAddress, 500), mismatch: "value"},
+		{name: "calldata", to: tokenAddr, data: []byte{0x01}, mismatch: "erc20 transfer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawTx, decoded := signTx(t, tt.to, big.NewInt(tt.value), tt.data)
+			// chain-account 按库中记录应答，只有本地解析能发现不一致
+			decoded.ContractAddress = tokenAddr
+			client, err := rpcclient.NewWalletChainAccountClient(context.Background(), &stubAccount{decoded: decoded, verify: true}, "Ethereum")
+			require.NoError(t, err)
+			verifier := NewVerifier(client, &database.DB{Addresses: &stubAddresses{wallet: chainaddr.Address(decoded.FromAddress)}})
+
+			_, err = verifier.Verify("biz", Expected{
+				ChainId: "1",
+				Nonce:   7,
+				From:    chainaddr.Address(decoded.FromAddress),
+				To:      toAddress,
+				Token:   tokenAddr,
+				Amount:  big.NewInt(500),
+			}, rawTx)
+			if tt.mismatch == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrMismatch)
+			require.ErrorContains(t, err, tt.mismatch)
+		})
+	}
+}
+
+func TestVerifyTransportError(t *testing.T) {
+	rawTx, decoded := signed(t)
+	client, err := rpcclient.NewWalletChainAccountClient(context.Background(), &stubAccount{down: true}, "Ethereum")
+	require.NoError(t, err)
+	client.RetryStrategy = retry.Fixed(0)
+	verifier := NewVerifier(client, &database.DB{Addresses: &stubAddresses{}})
+
+	// chain-account 不可用时不拦截交易，由调用方稍后重试
+	_, err = verifier.Verify("biz", Expected{From: chainaddr.Address(decoded.FromAddress)}, rawTx)
+	require.Error(t, err)
+	require.False(t, errors.Is(err, ErrMismatch))
+	require.True(t, rpcclient.IsTransportError(err))
+}
+
+func TestCheckSentHash(t *testing.T) {
+	hash := chainaddr.Hash("0x" + strings.Repeat("ab", 32))
+	require.NoError(t, CheckSentHash(hash, "0x"+strings.Repeat("AB", 32)))
+	require.ErrorIs(t, CheckSentHash(hash, "0x"+strings.Repeat("cd", 32)), ErrMismatch)
+}
@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/common/clock"
+	"github.com/CavnHan/multichain-sync-account/common/tasks"
+	"github.com/CavnHan/multichain-sync-account/config"
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/hdwallet"
+	"github.com/CavnHan/multichain-sync-account/registry"
+)
+
+// AddressPool 定时为注册了扩展公钥的业务方补满预生成地址池
+type AddressPool struct {
+	pool           *hdwallet.Pool
+	registry       *registry.Registry
+	resourceCtx    context.Context
+	resourceCancel context.CancelFunc
+	tasks          tasks.Group
+	ticker         clock.Ticker
+}
+
+func NewAddressPool(cfg *config.Config, db *database.DB, reg *registry.Registry, clk clock.Clock, shutdown context.CancelCauseFunc) (*AddressPool, error) {
+	resCtx, resCancel := context.WithCancel(context.Background())
+	return &AddressPool{
+		pool:           hdwallet.NewPool(db, cfg.ChainNode.ChainName, cfg.AddressPoolSize),
+		registry:       reg,
+		resourceCtx:    resCtx,
+		resourceCancel: resCancel,
+		tasks: tasks.Group{HandleCrit: func(err error) {
+			shutdown(fmt.Errorf("critical error in address pool: %w", err))
+		}},
+		ticker: clk.NewTicker(time.Second * 10),
+	}, nil
+}
+
+func (ap *AddressPool) Close() error {
+	var result error
+	ap.resourceCancel()
+	ap.ticker.Stop()
+	log.Info("stop address pool......")
+	if err := ap.tasks.Wait(); err != nil {
+		result = errors.Join(result, fmt.Errorf("failed to await address pool %w", err))
+		return result
+	}
+	log.Info("stop address pool success")
+	return nil
+}
+
+func (ap *AddressPool) Start() error {
+	log.Info("start address pool......")
+	ap.tasks.Go(func() error {
+		for {
+			select {
+			case <-ap.ticker.Ch():
+				for _, businessId := range ap.registry.BusinessIds() {
+					_, err := ap.pool.Fill(businessId)
+					if err != nil && !errors.Is(err, hdwallet.ErrXpubNotRegistered) {
+						log.Error("fill address pool fail", "businessId", businessId, "err", err)
+					}
+				}
+			case <-ap.resourceCtx.Done():
+				log.Info("stop address pool in worker")
+				return nil
+			}
+		}
+	})
+	return nil
+}
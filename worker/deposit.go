@@ -6,21 +6,16 @@ import (
 	"fmt"
 
 	"math/big"
-	"strconv"
 
-	"github.com/google/uuid"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 
+	"github.com/CavnHan/multichain-sync-account/common/clock"
 	"github.com/CavnHan/multichain-sync-account/common/retry"
 	"github.com/CavnHan/multichain-sync-account/common/tasks"
 	"github.com/CavnHan/multichain-sync-account/config"
 	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/registry"
 	"github.com/CavnHan/multichain-sync-account/rpcclient"
-	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
 )
 
 type Deposit struct {
@@ -33,29 +28,8 @@ type Deposit struct {
 	tasks          tasks.Group
 }
 
-func NewDeposit(cfg *config.Config, db *database.DB, shutdown context.CancelCauseFunc) (*Deposit, error) {
+func NewDeposit(cfg *config.Config, db *database.DB, reg *registry.Registry, accountClient *rpcclient.WalletChainAccountClient, clk clock.Clock, shutdown context.CancelCauseFunc) (*Deposit, error) {
 	log.Info("New deposit", "ChainAccountRpc", cfg.ChainAccountRpc)
-	conn, err := grpc.NewClient(cfg.ChainAccountRpc, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		log.Error("Connect to da retriever fail", "err", err)
-		return nil, err
-	}
-	client := account.NewWalletAccountServiceClient(conn)
-	accountClient, err := rpcclient.NewWalletChainAccountClient(context.Background(), client, "Ethereum")
-	if err != nil {
-		log.Error("new wallet account client fail", "err", err)
-		return nil, err
-	}
-
-	businessList, err := db.Business.QueryBusinessList()
-	if err != nil {
-		log.Error("query business list fail", "err", err)
-		return nil, err
-	}
-	var businessIds []string
-	for _, business := range businessList {
-		businessIds = append(businessIds, business.BusinessUid)
-	}
 
 	dbLatestBlockHeader, err := db.Blocks.LatestBlocks()
 	if err != nil {
@@ -91,9 +65,11 @@ func NewDeposit(cfg *config.Config, db *database.DB, shutdown context.CancelCaus
 		headerBufferSize: cfg.ChainNode.BlocksStep,
 		businessChannels: businessTxChannel,
 		rpcClient:        accountClient,
+		nodeClient:       newNodeClient(cfg.ChainNode.RpcUrl),
 		blockBatch:       rpcclient.NewBatchBlock(accountClient, fromHeader, big.NewInt(int64(cfg.ChainNode.Confirmations))),
 		database:         db,
-		businessIds:      businessIds,
+		registry:         reg,
+		clock:            clk,
 	}
 
 	resCtx, resCancel := context.WithCancel(context.Background())
@@ -144,151 +120,23 @@ func (deposit *Deposit) Start() error {
 }
 
 func (deposit *Deposit) handleBatch(batch map[string]*TransactionsChannel) error {
-
-	for _, businessId := range deposit.businessIds {
-		_, exists := batch[businessId]
-		if !exists {
-			continue
+	for businessId := range batch {
+		// 扫块后才被暂停或删除的业务方照常落库，否则游标前进后这些交易不会再被扫到。
+		// 业务方的动态表不会删除，通知服务只处理激活的业务方，恢复后补发通知
+		if !deposit.registry.IsActive(businessId) {
+			log.Warn("business is no longer active, persist batch without notify", "businessId", businessId)
 		}
 
-		var (
-			transationFlowList []database.Transactions
-			depositList        []database.Deposits
-			withdrawList       []database.Withdraws
-			balances           []database.TokenBalance
-		)
+		chainLatestBlock := batch[businessId].ChainLatestBlock
+		log.Info("handle business flow", "businessId", businessId, "chainLatestBlock", chainLatestBlock, "txn", len(batch[businessId].Transactions))
 
-		chainLatestBlock := batch[businessId].BlockHeight
-		batchTransactions := batch[businessId].Transactions
-		log.Info("handle business flow", "businessId", businessId, "chainLatestBlock", batch[businessId].BlockHeight, "txn", len(batch[businessId].Transactions))
-
-		for _, tx := range batchTransactions {
-			log.Info("Request transaction from chain account", "txHash", tx.Hash)
-			txItem, err := deposit.rpcClient.GetTransactionByHash(tx.Hash)
-			if err != nil {
-				log.Info("get transaction by hash fail", "err", err)
-				return err
-			}
-
-			amountBigInt, _ := new(big.Int).SetString(txItem.Values[0].Value, 10)
-			tokenBalanceItem := &database.TokenBalance{
-				Address:      common.Address{},
-				TokenAddress: common.HexToAddress(tx.TokenAddress),
-				Balance:      amountBigInt,
-				LockBalance:  big.NewInt(0),
-				TxType:       0,
-			}
-
-			log.Info("get transaction success", "txHash", txItem.Hash)
-			txFee, _ := new(big.Int).SetString(txItem.Fee, 10)
-			txAmount, _ := new(big.Int).SetString(txItem.Values[0].Value, 10)
-			timestamp, _ := strconv.Atoi(txItem.Datetime)
-			transationFlow := database.Transactions{
-				GUID:         uuid.New(),
-				BlockHash:    common.Hash{},
-				BlockNumber:  tx.BlockNumber,
-				Hash:         common.HexToHash(tx.Hash),
-				FromAddress:  common.HexToAddress(tx.FromAddress),
-				ToAddress:    common.HexToAddress(tx.ToAddress),
-				TokenAddress: common.HexToAddress(tx.TokenAddress),
-				TokenId:      "0x00",
-				TokenMeta:    "0x00",
-				Fee:          txFee,
-				Amount:       txAmount,
-				Status:       0,
-				TxType:       0,
-				Timestamp:    uint64(timestamp),
-			}
-			switch tx.TxType {
-			case "deposit":
-				depositItme := database.Deposits{
-					GUID:         uuid.New(),
-					BlockHash:    common.Hash{},
-					BlockNumber:  tx.BlockNumber,
-					Hash:         common.HexToHash(tx.Hash),
-					FromAddress:  common.HexToAddress(tx.FromAddress),
-					ToAddress:    common.HexToAddress(tx.ToAddress),
-					TokenAddress: common.HexToAddress(tx.TokenAddress),
-					TokenId:      "0x00",
-					TokenMeta:    "0x00",
-					Fee:          txFee,
-					Amount:       txAmount,
-					Status:       0,
-					Timestamp:    uint64(timestamp),
-				}
-				depositList = append(depositList, depositItme)
-				transationFlow.TxType = 0
-				tokenBalanceItem.Address = common.HexToAddress(txItem.Tos[0].Address)
-				break
-			case "withdraw":
-				withdrawItem := database.Withdraws{
-					GUID:         uuid.New(),
-					BlockHash:    common.Hash{},
-					BlockNumber:  tx.BlockNumber,
-					Hash:         common.HexToHash(tx.Hash),
-					FromAddress:  common.HexToAddress(tx.FromAddress),
-					ToAddress:    common.HexToAddress(tx.ToAddress),
-					TokenAddress: common.HexToAddress(tx.TokenAddress),
-					TokenId:      "0x00",
-					TokenMeta:    "0x00",
-					Fee:          txFee,
-					Amount:       txAmount,
-					Status:       2,
-					Timestamp:    uint64(timestamp),
-				}
-				withdrawList = append(withdrawList, withdrawItem)
-				transationFlow.TxType = 1
-				tokenBalanceItem.LockBalance = txAmount
-				break
-			case "collection":
-				transationFlow.TxType = 2
-				tokenBalanceItem.LockBalance = txAmount
-				break
-			case "hot2cold":
-				transationFlow.TxType = 3
-				break
-			case "cold2hot":
-				transationFlow.TxType = 4
-				break
-			default:
-				break
-			}
-			transationFlowList = append(transationFlowList, transationFlow)
+		flows, err := buildBusinessFlows(deposit.rpcClient, deposit.database.Addresses, deposit.database.Memos, batch[businessId].Transactions)
+		if err != nil {
+			return err
 		}
 		retryStrategy := &retry.ExponentialStrategy{Min: 1000, Max: 20_000, MaxJitter: 250}
 		if _, err := retry.Do[interface{}](deposit.resourceCtx, 10, retryStrategy, func() (interface{}, error) {
-			if err := deposit.database.Transaction(func(tx *database.DB) error {
-				if len(depositList) > 0 {
-					log.Info("Store deposit transaction success", "totalTx", len(depositList))
-					if err := tx.Deposits.StoreDeposits(businessId, depositList, uint64(len(depositList))); err != nil {
-						return err
-					}
-					log.Info("update deposit transaction confirms", "totalTx", len(depositList))
-					if err := tx.Deposits.UpdateDepositsComfirms(businessId, chainLatestBlock, uint64(deposit.confirms)); err != nil {
-						return err
-					}
-				}
-
-				if len(balances) > 0 {
-					log.Info("handle balances sunncess", "totalTx", len(balances))
-					if err := tx.Balances.UpdateOrCreate(businessId, balances); err != nil {
-						return err
-					}
-				}
-
-				if len(withdrawList) > 0 {
-					if err := tx.Withdraws.UpdateWithdrawStatus(businessId, 3, withdrawList); err != nil {
-						return err
-					}
-				}
-
-				if len(transationFlowList) > 0 {
-					if err := tx.Transactions.StoreTransactions(businessId, transationFlowList, uint64(len(transationFlowList))); err != nil {
-						return err
-					}
-				}
-				return nil
-			}); err != nil {
+			if _, err := persistBusinessFlows(deposit.database, businessId, chainLatestBlock, deposit.confirms, flows, true); err != nil {
 				log.Error("unable to persist batch", "err", err)
 				return nil, err
 			}
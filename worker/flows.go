@@ -0,0 +1,408 @@
+package worker
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/common/bigint"
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/rpcclient"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+)
+
+// businessFlows 是一个业务方在一个批次内需要落库的全部记录
+type businessFlows struct {
+	transactions []database.Transactions
+	deposits     []database.Deposits
+	withdraws    []database.Withdraws
+	balances     []database.TokenBalance
+	utxos        []database.Utxos
+	spentHashes  []chainaddr.Hash // UTXO 链上业务方地址作为输入的交易
+	failedHashes []chainaddr.Hash // 上链但执行失败的提现交易
+	nftMoves     []nftMove
+}
+
+func (f *businessFlows) empty() bool {
+	return len(f.transactions) == 0 && len(f.deposits) == 0 && len(f.withdraws) == 0 && len(f.balances) == 0 &&
+		len(f.utxos) == 0 && len(f.spentHashes) == 0 && len(f.nftMoves) == 0
+}
+
+// buildBusinessFlows 从链上拉取交易详情，按分类结果生成流水、充值和提现记录
+func buildBusinessFlows(rpcClient *rpcclient.WalletChainAccountClient, owner addressOwner, memos memoBook, txs []*Transaction) (*businessFlows, error) {
+	flows := &businessFlows{}
+	for _, tx := range txs {
+		log.Info("Request transaction from chain account", "txHash", tx.Hash)
+		txItem, err := rpcClient.GetTransactionByHash(tx.Hash)
+		if err != nil {
+			log.Info("get transaction by hash fail", "err", err)
+			return nil, err
+		}
+		if err := resolveMemo(memos, tx, txItem); err != nil {
+			return nil, err
+		}
+		flows.add(owner, tx, txItem, false)
+	}
+	return flows, nil
+}
+
+// add UTXO 链按输出逐个处理，账户模型链按分类结果处理
+func (flows *businessFlows) add(owner addressOwner, tx *Transaction, txItem *account.TxMessage, historical bool) {
+	if chainaddr.IsUTXO() {
+		flows.appendUtxo(owner, tx, txItem, historical)
+		return
+	}
+	flows.append(tx, txItem, historical)
+	if tx.TokenId != "" {
+		timestamp, _ := strconv.Atoi(txItem.Datetime)
+		flows.appendNftMoves(owner, tx, uint64(timestamp))
+	}
+}
+
+// append 根据交易分类和链上交易详情生成对应记录，historical 标记地址注册前的历史充值
+func (flows *businessFlows) append(tx *Transaction, txItem *account.TxMessage, historical bool) {
+	// NFT 转账的数量和 token id 来自事件日志，不使用交易本身的转账金额
+	tokenId, tokenMeta := "0x00", "0x00"
+	var amountBigInt *big.Int
+	if tx.TokenId != "" {
+		tokenId, tokenMeta = tx.TokenId, tx.TokenStandard
+		amountBigInt = new(big.Int).Set(tx.Amount)
+	} else {
+		amountBigInt, _ = new(big.Int).SetString(txItem.Values[0].Value, 10)
+	}
+	txHash := flowHash(tx.Hash)
+	fromAddress := flowAddress(tx.FromAddress)
+	toAddress := flowAddress(tx.ToAddress)
+	tokenAddress := flowTokenAddress(tx.TokenAddress)
+	tokenBalanceItem := &database.TokenBalance{
+		Address:      "",
+		TokenAddress: tokenAddress,
+		Balance:      amountBigInt,
+		LockBalance:  big.NewInt(0),
+		TxType:       0,
+	}
+
+	log.Info("get transaction success", "txHash", txItem.Hash)
+	txFee, _ := new(big.Int).SetString(txItem.Fee, 10)
+	txAmount := amountBigInt
+	timestamp, _ := strconv.Atoi(txItem.Datetime)
+	transationFlow := database.Transactions{
+		GUID:         uuid.New(),
+		BlockHash:    "",
+		BlockNumber:  tx.BlockNumber,
+		Hash:         txHash,
+		FromAddress:  fromAddress,
+		ToAddress:    toAddress,
+		TokenAddress: tokenAddress,
+		TokenId:      tokenId,
+		TokenMeta:    tokenMeta,
+		Fee:          txFee,
+		Amount:       txAmount,
+		Status:       0,
+		TxType:       0,
+		Timestamp:    uint64(timestamp),
+	}
+	switch tx.TxType {
+	case "deposit":
+		depositItme := database.Deposits{
+			GUID:         uuid.New(),
+			BlockHash:    "",
+			BlockNumber:  tx.BlockNumber,
+			Hash:         txHash,
+			FromAddress:  fromAddress,
+			ToAddress:    toAddress,
+			TokenAddress: tokenAddress,
+			TokenId:      tokenId,
+			TokenMeta:    tokenMeta,
+			Fee:          txFee,
+			Amount:       txAmount,
+			Status:       0,
+			Historical:   historical,
+			Memo:         tx.Memo,
+			Quarantined:  tx.Quarantined,
+			Timestamp:    uint64(timestamp),
+		}
+		flows.deposits = append(flows.deposits, depositItme)
+		transationFlow.TxType = 0
+		if tx.TokenId != "" {
+			tokenBalanceItem.Address = toAddress
+		} else {
+			tokenBalanceItem.Address = flowAddress(txItem.Tos[0].Address)
+		}
+		break
+	case "withdraw":
+		withdrawItem := database.Withdraws{
+			GUID:         uuid.New(),
+			BlockHash:    "",
+			BlockNumber:  tx.BlockNumber,
+			Hash:         txHash,
+			FromAddress:  fromAddress,
+			ToAddress:    toAddress,
+			TokenAddress: tokenAddress,
+			TokenId:      tokenId,
+			TokenMeta:    tokenMeta,
+			Fee:          txFee,
+			Amount:       txAmount,
+			Status:       2,
+			Timestamp:    uint64(timestamp),
+		}
+		flows.withdraws = append(flows.withdraws, withdrawItem)
+		if txItem.Status == account.TxStatus_Failed || txItem.Status == account.TxStatus_ContractExecuteFailed {
+			flows.failedHashes = append(flows.failedHashes, txHash)
+		}
+		transationFlow.TxType = 1
+		tokenBalanceItem.LockBalance = txAmount
+		break
+	case "collection":
+		transationFlow.TxType = 2
+		tokenBalanceItem.LockBalance = txAmount
+		break
+	case "hot2cold":
+		transationFlow.TxType = 3
+		break
+	case "cold2hot":
+		transationFlow.TxType = 4
+		break
+	default:
+		break
+	}
+	flows.transactions = append(flows.transactions, transationFlow)
+}
+
+// flowAddress 按当前链规范化地址，解析失败时保留原值，避免丢失链上数据
+func flowAddress(address string) chainaddr.Address {
+	parsed, err := chainaddr.ParseAddress(address)
+	if err != nil {
+		log.Warn("parse address fail, keep raw value", "address", address, "err", err)
+		return chainaddr.Address(address)
+	}
+	return parsed
+}
+
+// flowTokenAddress 代币地址无法解析时视为原生币
+func flowTokenAddress(address string) chainaddr.Address {
+	parsed, err := chainaddr.ParseAddress(address)
+	if err != nil || parsed.IsZero() {
+		return chainaddr.NativeToken()
+	}
+	return parsed
+}
+
+func flowHash(hash string) chainaddr.Hash {
+	parsed, err := chainaddr.ParseHash(hash)
+	if err != nil {
+		log.Warn("parse hash fail, keep raw value", "hash", hash, "err", err)
+		return chainaddr.Hash(hash)
+	}
+	return parsed
+}
+
+// persistBusinessFlows 在一个事务内写入业务方记录。事务会持有业务方的 advisory lock，
+// 实时同步和补扫互斥写入；dedupe 为 true 时跳过 transactions 表中已存在的交易哈希。
+// 返回实际写入的流水条数
+func persistBusinessFlows(db *database.DB, businessId string, chainLatestBlock uint64, confirms uint8, flows *businessFlows, dedupe bool) (int, error) {
+	persisted := 0
+	err := db.Transaction(func(tx *database.DB) error {
+		if err := tx.LockBusiness(businessId); err != nil {
+			return fmt.Errorf("lock business %s: %w", businessId, err)
+		}
+		batch := flows
+		if dedupe {
+			filtered, err := filterExistingFlows(tx, businessId, flows)
+			if err != nil {
+				return err
+			}
+			batch = filtered
+		}
+		persisted = len(batch.transactions)
+		if batch.empty() {
+			return nil
+		}
+
+		if historicalBalances := batch.historicalBalances(); len(historicalBalances) > 0 {
+			if err := tx.Balances.AddHistoricalBalances(businessId, historicalBalances); err != nil {
+				return err
+			}
+		}
+
+		if len(batch.deposits) > 0 {
+			log.Info("Store deposit transaction success", "totalTx", len(batch.deposits))
+			if err := tx.Deposits.StoreDeposits(businessId, batch.deposits, uint64(len(batch.deposits))); err != nil {
+				return err
+			}
+			log.Info("update deposit transaction confirms", "totalTx", len(batch.deposits))
+			if err := tx.Deposits.UpdateDepositsComfirms(businessId, chainLatestBlock, uint64(confirms)); err != nil {
+				return err
+			}
+		}
+
+		if len(batch.balances) > 0 {
+			log.Info("handle balances sunncess", "totalTx", len(batch.balances))
+			if err := tx.Balances.UpdateOrCreate(businessId, batch.balances); err != nil {
+				return err
+			}
+		}
+
+		if len(batch.withdraws) > 0 {
+			if err := settleWithdrawBatches(tx, businessId, batch); err != nil {
+				return err
+			}
+			if err := tx.Withdraws.UpdateWithdrawStatus(businessId, 3, batch.withdraws); err != nil {
+				return err
+			}
+		}
+
+		if len(batch.utxos) > 0 {
+			if err := tx.Utxos.StoreUtxos(businessId, batch.utxos); err != nil {
+				return err
+			}
+		}
+
+		if len(batch.spentHashes) > 0 {
+			if err := tx.Utxos.MarkUtxosSpent(businessId, batch.spentHashes); err != nil {
+				return err
+			}
+		}
+
+		if len(batch.nftMoves) > 0 {
+			credits, debits := batch.nftHoldings()
+			if err := tx.NftHoldings.AddNftHoldings(businessId, credits); err != nil {
+				return err
+			}
+			if err := tx.NftHoldings.SubNftHoldings(businessId, debits); err != nil {
+				return err
+			}
+		}
+
+		if len(batch.transactions) > 0 {
+			if err := tx.Transactions.StoreTransactions(businessId, batch.transactions, uint64(len(batch.transactions))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return persisted, nil
+}
+
+// historicalBalances 按地址和代币汇总历史充值金额
+func (flows *businessFlows) historicalBalances() []database.TokenBalance {
+	var balances []database.TokenBalance
+	index := make(map[[2]chainaddr.Address]int)
+	for _, deposit := range flows.deposits {
+		if !deposit.Historical || deposit.Amount == nil {
+			continue
+		}
+		key := [2]chainaddr.Address{deposit.ToAddress, deposit.TokenAddress}
+		if i, ok := index[key]; ok {
+			balances[i].Balance = new(big.Int).Add(balances[i].Balance, deposit.Amount)
+			continue
+		}
+		index[key] = len(balances)
+		balances = append(balances, database.TokenBalance{
+			Address:      deposit.ToAddress,
+			TokenAddress: deposit.TokenAddress,
+			Balance:      new(big.Int).Set(deposit.Amount),
+			LockBalance:  big.NewInt(0),
+			TxType:       0,
+		})
+	}
+	return balances
+}
+
+// settleWithdrawBatches 同步到批量提现交易时结算批次：成功则按实际手续费重新分摊并确认全部成员，
+// 执行失败则把成员提现和锁定的输出退回，业务方可以重新发起
+func settleWithdrawBatches(tx *database.DB, businessId string, flows *businessFlows) error {
+	failed := make(map[chainaddr.Hash]bool, len(flows.failedHashes))
+	for _, hash := range flows.failedHashes {
+		failed[hash] = true
+	}
+	for _, withdraw := range flows.withdraws {
+		batch, err := tx.WithdrawBatches.QueryWithdrawBatchByHash(businessId, withdraw.Hash)
+		if err != nil {
+			return err
+		}
+		if batch == nil {
+			continue
+		}
+		batchId := batch.GUID.String()
+		if failed[withdraw.Hash] {
+			log.Warn("batch withdraw failed on chain, release members", "businessId", businessId, "batchId", batchId, "hash", withdraw.Hash)
+			if err := tx.WithdrawBatches.UpdateWithdrawBatchStatus(businessId, batchId, database.WithdrawBatchStatusFailed, withdraw.Fee); err != nil {
+				return err
+			}
+			if err := tx.Withdraws.ReleaseBatchWithdraws(businessId, batchId); err != nil {
+				return err
+			}
+			if err := tx.Utxos.ReleaseUtxos(businessId, batchId); err != nil {
+				return err
+			}
+			continue
+		}
+		members, err := tx.Withdraws.QueryWithdrawsByBatchId(businessId, batchId)
+		if err != nil {
+			return err
+		}
+		fee := batch.Fee
+		if withdraw.Fee != nil && withdraw.Fee.Sign() > 0 {
+			fee = withdraw.Fee
+		}
+		for i, share := range bigint.Split(fee, len(members)) {
+			members[i].Fee = share
+		}
+		if err := tx.Withdraws.AssignWithdrawBatch(businessId, batchId, members); err != nil {
+			return err
+		}
+		if err := tx.Withdraws.UpdateBatchWithdraws(businessId, batchId, 3, withdraw.Hash); err != nil {
+			return err
+		}
+		if err := tx.WithdrawBatches.UpdateWithdrawBatchStatus(businessId, batchId, database.WithdrawBatchStatusConfirmed, fee); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func filterExistingFlows(tx *database.DB, businessId string, flows *businessFlows) (*businessFlows, error) {
+	existing := make(map[chainaddr.Hash]bool)
+	// UTXO 按 (tx_hash, vout) 幂等写入，不需要过滤
+	filtered := &businessFlows{balances: flows.balances, utxos: flows.utxos, spentHashes: flows.spentHashes, failedHashes: flows.failedHashes}
+	for _, flow := range flows.transactions {
+		stored, err := tx.Transactions.QueryTransactionByHash(businessId, flow.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if stored != nil {
+			existing[flow.Hash] = true
+			continue
+		}
+		filtered.transactions = append(filtered.transactions, flow)
+	}
+	if len(existing) == 0 {
+		return flows, nil
+	}
+	log.Info("skip transactions already stored", "businessId", businessId, "skipped", len(existing))
+	for _, deposit := range flows.deposits {
+		if !existing[deposit.Hash] {
+			filtered.deposits = append(filtered.deposits, deposit)
+		}
+	}
+	for _, withdraw := range flows.withdraws {
+		if !existing[withdraw.Hash] {
+			filtered.withdraws = append(filtered.withdraws, withdraw)
+		}
+	}
+	for _, move := range flows.nftMoves {
+		if !existing[move.hash] {
+			filtered.nftMoves = append(filtered.nftMoves, move)
+		}
+	}
+	return filtered, nil
+}
@@ -0,0 +1,64 @@
+package worker
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+)
+
+func TestHistoricalBalances(t *testing.T) {
+	user := chainaddr.Address("0x0000000000000000000000000000000000000001")
+	token := chainaddr.Address("0x0000000000000000000000000000000000000002")
+	flows := &businessFlows{
+		deposits: []database.Deposits{
+			{ToAddress: user, TokenAddress: token, Amount: big.NewInt(10), Historical: true},
+			{ToAddress: user, TokenAddress: token, Amount: big.NewInt(5), Historical: true},
+			{ToAddress: user, TokenAddress: token, Amount: big.NewInt(7)},
+			{ToAddress: user, TokenAddress: chainaddr.NativeToken(), Amount: big.NewInt(1), Historical: true},
+		},
+	}
+	require.False(t, flows.empty())
+
+	balances := flows.historicalBalances()
+	require.Len(t, balances, 2)
+	require.Equal(t, token, balances[0].TokenAddress)
+	require.Equal(t, int64(15), balances[0].Balance.Int64())
+	require.Equal(t, int64(1), balances[1].Balance.Int64())
+
+	require.Empty(t, (&businessFlows{}).historicalBalances())
+	require.True(t, (&businessFlows{}).empty())
+}
+
+func TestAppendFailedWithdraw(t *testing.T) {
+	hot := "0x0000000000000000000000000000000000000001"
+	user := "0x0000000000000000000000000000000000000002"
+	newTx := func(hash string) *Transaction {
+		return &Transaction{BlockNumber: big.NewInt(1), FromAddress: hot, ToAddress: user, Hash: hash, TxType: "withdraw"}
+	}
+	newItem := func(hash string, status account.TxStatus) *account.TxMessage {
+		return &account.TxMessage{
+			Hash:     hash,
+			Tos:      []*account.Address{{Address: user}},
+			Values:   []*account.Value{{Value: "100"}},
+			Fee:      "21000",
+			Status:   status,
+			Datetime: "1700000000",
+		}
+	}
+	okHash := "0x" + strings.Repeat("1", 64)
+	failedHash := "0x" + strings.Repeat("2", 64)
+
+	flows := &businessFlows{}
+	flows.append(newTx(okHash), newItem(okHash, account.TxStatus_Success), false)
+	flows.append(newTx(failedHash), newItem(failedHash, account.TxStatus_ContractExecuteFailed), false)
+
+	require.Len(t, flows.withdraws, 2)
+	require.Equal(t, []chainaddr.Hash{flowHash(failedHash)}, flows.failedHashes)
+	require.Equal(t, int64(21000), flows.withdraws[1].Fee.Int64())
+}
@@ -0,0 +1,164 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/common/clock"
+	"github.com/CavnHan/multichain-sync-account/common/tasks"
+	"github.com/CavnHan/multichain-sync-account/config"
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/registry"
+	"github.com/CavnHan/multichain-sync-account/rpcclient"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+)
+
+const (
+	historyAddressBatch = 20
+	historyPageSize     = 50
+	historyMaxPages     = 200
+)
+
+// History 回溯新导出地址在注册前收到的充值。
+// 只处理交易时间早于地址注册时间的充值，之后的交易由实时同步负责，两边按交易哈希去重
+type History struct {
+	rpcClient      *rpcclient.WalletChainAccountClient
+	db             *database.DB
+	registry       *registry.Registry
+	confirms       uint8
+	resourceCtx    context.Context
+	resourceCancel context.CancelFunc
+	tasks          tasks.Group
+	ticker         clock.Ticker
+}
+
+func NewHistory(cfg *config.Config, db *database.DB, reg *registry.Registry, accountClient *rpcclient.WalletChainAccountClient, clk clock.Clock, shutdown context.CancelCauseFunc) (*History, error) {
+	resCtx, resCancel := context.WithCancel(context.Background())
+	return &History{
+		rpcClient:      accountClient,
+		db:             db,
+		registry:       reg,
+		confirms:       uint8(cfg.ChainNode.Confirmations),
+		resourceCtx:    resCtx,
+		resourceCancel: resCancel,
+		tasks: tasks.Group{HandleCrit: func(err error) {
+			shutdown(fmt.Errorf("critical error in history: %w", err))
+		}},
+		ticker: clk.NewTicker(cfg.ChainNode.WorkerInterval),
+	}, nil
+}
+
+func (h *History) Close() error {
+	var result error
+	h.resourceCancel()
+	h.ticker.Stop()
+	log.Info("stop history......")
+	if err := h.tasks.Wait(); err != nil {
+		result = errors.Join(result, fmt.Errorf("failed to await history %w", err))
+		return result
+	}
+	log.Info("stop history success")
+	return nil
+}
+
+func (h *History) Start() error {
+	log.Info("start history......")
+	h.tasks.Go(func() error {
+		for {
+			select {
+			case <-h.ticker.Ch():
+				for _, businessId := range h.registry.BusinessIds() {
+					// 单个地址失败只记录日志，下一轮继续重试，不影响其他业务方
+					if err := h.backfillBusiness(businessId); err != nil {
+						log.Error("backfill history fail", "businessId", businessId, "err", err)
+					}
+				}
+			case <-h.resourceCtx.Done():
+				log.Info("stop history in worker")
+				return nil
+			}
+		}
+	})
+	return nil
+}
+
+func (h *History) backfillBusiness(businessId string) error {
+	addresses, err := h.db.Addresses.QueryBackfillAddresses(businessId, historyAddressBatch)
+	if err != nil {
+		return err
+	}
+	if len(addresses) == 0 {
+		return nil
+	}
+	latestHeader, err := h.rpcClient.GetBlockHeader(nil)
+	if err != nil {
+		return err
+	}
+	for _, address := range addresses {
+		if h.resourceCtx.Err() != nil {
+			return nil
+		}
+		inserted, err := h.backfillAddress(businessId, address, latestHeader.Number.Uint64())
+		if err != nil {
+			return fmt.Errorf("backfill address %s: %w", address.Address, err)
+		}
+		if err := h.db.Addresses.UpdateBackfillStatus(businessId, address.Address, database.BackfillStatusDone); err != nil {
+			return err
+		}
+		log.Info("backfill address history success", "businessId", businessId, "address", address.Address, "deposits", inserted)
+	}
+	return nil
+}
+
+func (h *History) backfillAddress(businessId string, address *database.Addresses, chainLatestBlock uint64) (int, error) {
+	flows := &businessFlows{}
+	for page := uint32(1); page <= historyMaxPages; page++ {
+		txList, err := h.rpcClient.GetTxByAddress(address.Address.String(), page, historyPageSize)
+		if err != nil {
+			return 0, err
+		}
+		for _, txItem := range txList {
+			tx := historicalDeposit(h.db, businessId, address, txItem)
+			if tx != nil {
+				flows.add(h.db.Addresses, tx, txItem, true)
+			}
+		}
+		if len(txList) < historyPageSize {
+			break
+		}
+	}
+	if flows.empty() {
+		return 0, nil
+	}
+	return persistBusinessFlows(h.db, businessId, chainLatestBlock, h.confirms, flows, true)
+}
+
+// historicalDeposit 用与实时同步相同的规则分类，只保留注册前的充值交易
+func historicalDeposit(db *database.DB, businessId string, address *database.Addresses, txItem *account.TxMessage) *Transaction {
+	if len(txItem.Froms) == 0 || len(txItem.Tos) == 0 || len(txItem.Values) == 0 {
+		return nil
+	}
+	timestamp, err := strconv.ParseUint(txItem.Datetime, 10, 64)
+	if err != nil || timestamp >= address.Timestamp {
+		return nil
+	}
+	number, ok := new(big.Int).SetString(txItem.Height, 10)
+	if !ok {
+		return nil
+	}
+	txs := classifyTransactions(db, businessId, number, []*account.BlockInfoTransactionList{{
+		From:   txItem.Froms[0].Address,
+		To:     txItem.Tos[0].Address,
+		Hash:   txItem.Hash,
+		Amount: txItem.Values[0].Value,
+	}})
+	if len(txs) == 0 || txs[0].TxType != "deposit" {
+		return nil
+	}
+	return txs[0]
+}
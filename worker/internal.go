@@ -6,45 +6,46 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/common/clock"
 	"github.com/CavnHan/multichain-sync-account/common/tasks"
 	"github.com/CavnHan/multichain-sync-account/config"
 	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/registry"
 	"github.com/CavnHan/multichain-sync-account/rpcclient"
+	"github.com/CavnHan/multichain-sync-account/txverify"
 )
 
-
 type Internal struct {
-	rpcClient      *rpcclient.WalletChainAccountClient
-	db             *database.DB
-	resourceCtx    context.Context
-	resourceCancel context.CancelFunc
-	tasks          tasks.Group
-	ticker         *time.Ticker
+	rpcClient  *rpcclient.WalletChainAccountClient
+	verifier   *txverify.Verifier
+	db         *database.DB
+	registry   *registry.Registry
+	supervisor *tasks.Supervisor
+	ticker     clock.Ticker
 }
 
-func NewInternal(cfg *config.Config, db *database.DB, shutdown context.CancelCauseFunc) (*Internal, error) {
-	resCtx, resCancel := context.WithCancel(context.Background())
+func NewInternal(cfg *config.Config, db *database.DB, reg *registry.Registry, accountClient *rpcclient.WalletChainAccountClient, clk clock.Clock, shutdown context.CancelCauseFunc) (*Internal, error) {
 	return &Internal{
-		db:             db,
-		resourceCtx:    resCtx,
-		resourceCancel: resCancel,
-		tasks: tasks.Group{HandleCrit: func(err error) {
+		rpcClient: accountClient,
+		verifier:  txverify.NewVerifier(accountClient, db),
+		db:        db,
+		registry:  reg,
+		supervisor: tasks.NewSupervisor(NewSupervisorConfig(cfg, clk), func(err error) {
 			shutdown(fmt.Errorf("critical error in internals: %w", err))
-		}},
-		ticker: time.NewTicker(time.Second * 5),
+		}),
+		ticker: clk.NewTicker(time.Second * 5),
 	}, nil
 }
 
 func (w *Internal) Close() error {
 	var result error
-	w.resourceCancel()
 	w.ticker.Stop()
 	log.Info("stop internal......")
-	if err := w.tasks.Wait(); err != nil {
-		result = errors.Join(result, fmt.Errorf("failed to await internal %w"), err)
+	if err := w.supervisor.Close(); err != nil {
+		result = errors.Join(result, fmt.Errorf("failed to await internal %w", err))
 		return result
 	}
 	log.Info("stop internal success")
@@ -53,44 +54,65 @@ func (w *Internal) Close() error {
 
 func (w *Internal) Start() error {
 	log.Info("start internals......")
-	w.tasks.Go(func() error {
-		for {
-			select {
-			case <-w.ticker.C:
-				businessList, err := w.db.Business.QueryBusinessList()
+	w.supervisor.Go("internal", func(ctx context.Context) error {
+		return supervised(w.run(ctx))
+	})
+	return nil
+}
+
+// Status 返回发送任务的运行状态和重启次数
+func (w *Internal) Status() []tasks.WorkerStatus {
+	return w.supervisor.Status()
+}
+
+func (w *Internal) run(ctx context.Context) error {
+	for {
+		select {
+		case <-w.ticker.Ch():
+			for _, businessId := range w.registry.BusinessIds() {
+				unSendInternalTxList, err := w.db.Internals.UnSendInternalsList(businessId)
 				if err != nil {
-					log.Error("query business list fail", "err", err)
 					return err
 				}
 
-				for _, businessId := range businessList {
-					unSendInternalTxList, err := w.db.Internals.UnSendInternalsList(businessId.BusinessUid)
+				for i := range unSendInternalTxList {
+					// 广播无法回滚，发送前确认仍是主节点
+					if err := w.db.CheckFence(); err != nil {
+						log.Error("check leader lease fail, stop sending", "err", err)
+						return err
+					}
+					hash, blocked, err := sendVerified(w.rpcClient, w.verifier, businessId, unSendInternalTxList[i].TxType, unSendInternalTxList[i].GUID, txverify.FromInternal(&unSendInternalTxList[i]), unSendInternalTxList[i].TxSignHex)
 					if err != nil {
 						return err
 					}
-
-					for _, unSendInternalTx := range unSendInternalTxList {
-						txHash, err := w.rpcClient.SendTx(unSendInternalTx.TxSignHex)
-						if err != nil {
-							log.Error("send transaction fail", "err", err)
-							return err
-						} else {
-							unSendInternalTx.Hash = common.HexToHash(txHash)
-							unSendInternalTx.Status = 2
-						}
+					unSendInternalTxList[i].Hash = hash
+					unSendInternalTxList[i].Status = 3
+					if blocked {
+						unSendInternalTxList[i].Status = txverify.StatusBlocked
 					}
-
-					err = w.db.Internals.UpdateInternalstatus(businessId.BusinessUid, 3, unSendInternalTxList)
-					if err != nil {
+					// 每笔发送后立即落库，后续交易发送失败时已广播的交易不会在重启后重复广播
+					if err := w.markSent(businessId, unSendInternalTxList[i]); err != nil {
 						log.Error("update internals status fail", "err", err)
 						return err
 					}
 				}
-			case <-w.resourceCtx.Done():
-				log.Info("stop internals in worker")
-				return nil
 			}
+		case <-ctx.Done():
+			log.Info("stop internals in worker")
+			return nil
 		}
+	}
+}
+
+// markSent 写入单笔内部交易广播后的哈希和状态，UTXO 链同时记录被花费的 utxo
+func (w *Internal) markSent(businessId string, internal database.Internals) error {
+	return w.db.Transaction(func(tx *database.DB) error {
+		if chainaddr.IsUTXO() {
+			if err := tx.Utxos.UpdateUtxosSpentHash(businessId, internal.GUID.String(), internal.Hash); err != nil {
+				log.Error("update utxo spent hash fail", "err", err)
+				return err
+			}
+		}
+		return tx.Internals.UpdateInternalsSent(businessId, []database.Internals{internal})
 	})
-	return nil
 }
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+)
+
+// memoBook 查询共享充值地址及其 memo 分配
+type memoBook interface {
+	IsSharedAddress(requestId string, address chainaddr.Address) (bool, error)
+	QueryMemo(requestId string, address chainaddr.Address, memo string) (*database.Memos, error)
+}
+
+// resolveMemo 充值到共享地址时按交易 memo 匹配用户，chain-account 在交易详情的 data 字段返回 memo/destination tag。
+// memo 缺失或没有分配给任何用户的充值标记为隔离
+func resolveMemo(memos memoBook, tx *Transaction, txItem *account.TxMessage) error {
+	if tx.TxType != "deposit" {
+		return nil
+	}
+	toAddress, err := chainaddr.ParseAddress(tx.ToAddress)
+	if err != nil {
+		return nil
+	}
+	shared, err := memos.IsSharedAddress(tx.BusinessId, toAddress)
+	if err != nil || !shared {
+		return err
+	}
+	tx.Memo = strings.TrimSpace(txItem.Data)
+	if tx.Memo == "" {
+		log.Warn("deposit to shared address without memo, quarantine", "txHash", tx.Hash, "address", toAddress)
+		tx.Quarantined = true
+		return nil
+	}
+	entry, err := memos.QueryMemo(tx.BusinessId, toAddress, tx.Memo)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		log.Warn("deposit with unknown memo, quarantine", "txHash", tx.Hash, "address", toAddress, "memo", tx.Memo)
+		tx.Quarantined = true
+	}
+	return nil
+}
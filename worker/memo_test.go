@@ -0,0 +1,61 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+)
+
+type fakeMemoBook map[chainaddr.Address][]string
+
+func (b fakeMemoBook) IsSharedAddress(_ string, address chainaddr.Address) (bool, error) {
+	_, ok := b[address]
+	return ok, nil
+}
+
+func (b fakeMemoBook) QueryMemo(_ string, address chainaddr.Address, memo string) (*database.Memos, error) {
+	for _, m := range b[address] {
+		if m == memo {
+			return &database.Memos{Address: address, Memo: memo}, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestResolveMemo(t *testing.T) {
+	shared := "0x0000000000000000000000000000000000000001"
+	plain := "0x0000000000000000000000000000000000000002"
+	book := fakeMemoBook{chainaddr.Address(shared): {"100001"}}
+
+	deposit := func(to string) *Transaction {
+		return &Transaction{BusinessId: "b", ToAddress: to, TxType: "deposit"}
+	}
+
+	tx := deposit(shared)
+	require.NoError(t, resolveMemo(book, tx, &account.TxMessage{Data: " 100001 "}))
+	require.Equal(t, "100001", tx.Memo)
+	require.False(t, tx.Quarantined)
+
+	tx = deposit(shared)
+	require.NoError(t, resolveMemo(book, tx, &account.TxMessage{Data: "999"}))
+	require.Equal(t, "999", tx.Memo)
+	require.True(t, tx.Quarantined)
+
+	tx = deposit(shared)
+	require.NoError(t, resolveMemo(book, tx, &account.TxMessage{}))
+	require.True(t, tx.Quarantined)
+
+	// 非共享地址和非充值交易不处理 memo
+	tx = deposit(plain)
+	require.NoError(t, resolveMemo(book, tx, &account.TxMessage{Data: "100001"}))
+	require.Empty(t, tx.Memo)
+	require.False(t, tx.Quarantined)
+
+	tx = &Transaction{BusinessId: "b", ToAddress: shared, TxType: "collection"}
+	require.NoError(t, resolveMemo(book, tx, &account.TxMessage{}))
+	require.False(t, tx.Quarantined)
+}
@@ -0,0 +1,264 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/google/uuid"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/rpcclient"
+)
+
+var (
+	// ERC-721 与 ERC-20 的 Transfer 事件签名相同，ERC-721 的 tokenId 为 indexed 参数，共 4 个 topic
+	erc721TransferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")).Hex()
+	transferSingleTopic = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)")).Hex()
+	transferBatchTopic  = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])")).Hex()
+
+	nftTransferTopics = []string{erc721TransferTopic, transferSingleTopic, transferBatchTopic}
+
+	errInvalidNftLog = errors.New("invalid nft transfer log")
+)
+
+// nftTransfer 一条事件日志中的一个 token 转移，TransferBatch 会拆成多条
+type nftTransfer struct {
+	BlockNumber *big.Int
+	Hash        string
+	Contract    string
+	From        string
+	To          string
+	TokenId     *big.Int
+	Amount      *big.Int
+	Standard    string
+}
+
+// fetchNftTransfers 拉取区块区间内的 NFT 转账日志，无法解析的日志(如 ERC-20 Transfer)直接跳过
+func fetchNftTransfers(ctx context.Context, nodeClient *rpcclient.NodeClient, fromBlock, toBlock *big.Int) ([]nftTransfer, error) {
+	logs, err := nodeClient.FilterLogs(ctx, fromBlock, toBlock, nftTransferTopics)
+	if err != nil {
+		log.Error("filter nft transfer logs fail", "from", fromBlock, "to", toBlock, "err", err)
+		return nil, err
+	}
+	var transfers []nftTransfer
+	for i := range logs {
+		if logs[i].Removed {
+			continue
+		}
+		decoded, err := decodeNftTransfers(&logs[i])
+		if err != nil {
+			continue
+		}
+		transfers = append(transfers, decoded...)
+	}
+	return transfers, nil
+}
+
+func decodeNftTransfers(l *rpcclient.Log) ([]nftTransfer, error) {
+	if len(l.Topics) == 0 {
+		return nil, errInvalidNftLog
+	}
+	blockNumber, err := hexutil.DecodeBig(l.BlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("%w: block number %q", errInvalidNftLog, l.BlockNumber)
+	}
+	transfer := nftTransfer{
+		BlockNumber: blockNumber,
+		Hash:        l.TxHash,
+		Contract:    l.Address,
+	}
+	switch strings.ToLower(l.Topics[0]) {
+	case erc721TransferTopic:
+		if len(l.Topics) != 4 {
+			return nil, errInvalidNftLog
+		}
+		transfer.From, transfer.To = topicAddress(l.Topics[1]), topicAddress(l.Topics[2])
+		transfer.TokenId = new(big.Int).SetBytes(hexToBytes(l.Topics[3]))
+		transfer.Amount = big.NewInt(1)
+		transfer.Standard = database.NftStandardERC721
+		return []nftTransfer{transfer}, nil
+	case transferSingleTopic:
+		data := hexToBytes(l.Data)
+		if len(l.Topics) != 4 || len(data) != 64 {
+			return nil, errInvalidNftLog
+		}
+		transfer.From, transfer.To = topicAddress(l.Topics[2]), topicAddress(l.Topics[3])
+		transfer.TokenId = new(big.Int).SetBytes(data[:32])
+		transfer.Amount = new(big.Int).SetBytes(data[32:])
+		transfer.Standard = database.NftStandardERC1155
+		return []nftTransfer{transfer}, nil
+	case transferBatchTopic:
+		if len(l.Topics) != 4 {
+			return nil, errInvalidNftLog
+		}
+		data := hexToBytes(l.Data)
+		ids, err := abiUint256Array(data, 0)
+		if err != nil {
+			return nil, err
+		}
+		values, err := abiUint256Array(data, 32)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) != len(values) {
+			return nil, errInvalidNftLog
+		}
+		transfer.From, transfer.To = topicAddress(l.Topics[2]), topicAddress(l.Topics[3])
+		transfer.Standard = database.NftStandardERC1155
+		transfers := make([]nftTransfer, 0, len(ids))
+		for i := range ids {
+			item := transfer
+			item.TokenId, item.Amount = ids[i], values[i]
+			transfers = append(transfers, item)
+		}
+		return transfers, nil
+	}
+	return nil, errInvalidNftLog
+}
+
+// abiUint256Array 读取 ABI 编码中 head 位置偏移量指向的 uint256[]
+func abiUint256Array(data []byte, head int) ([]*big.Int, error) {
+	if len(data) < head+32 {
+		return nil, errInvalidNftLog
+	}
+	offset := new(big.Int).SetBytes(data[head : head+32])
+	if !offset.IsUint64() || offset.Uint64()+32 > uint64(len(data)) {
+		return nil, errInvalidNftLog
+	}
+	start := int(offset.Uint64())
+	length := new(big.Int).SetBytes(data[start : start+32])
+	if !length.IsUint64() || length.Uint64() > uint64(len(data)-start-32)/32 {
+		return nil, errInvalidNftLog
+	}
+	values := make([]*big.Int, length.Uint64())
+	for i := range values {
+		pos := start + 32 + i*32
+		values[i] = new(big.Int).SetBytes(data[pos : pos+32])
+	}
+	return values, nil
+}
+
+func topicAddress(topic string) string {
+	raw := hexToBytes(topic)
+	if len(raw) < 20 {
+		return ""
+	}
+	return hexutil.Encode(raw[len(raw)-20:])
+}
+
+func hexToBytes(value string) []byte {
+	raw, err := hexutil.Decode(value)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+// classifyNftTransfers 按收发双方的地址类型筛选业务方相关的 NFT 转账，分类规则与普通交易一致
+func classifyNftTransfers(db *database.DB, businessId string, transfers []nftTransfer) []*Transaction {
+	var businessTransactions []*Transaction
+	for _, transfer := range transfers {
+		var existToAddress, existFromAddress bool
+		var toAddressType, fromAddressType uint8
+		toAddress, toErr := chainaddr.ParseAddress(transfer.To)
+		if toErr == nil && !toAddress.IsZero() {
+			existToAddress, toAddressType = db.Addresses.AddressExist(businessId, toAddress)
+		}
+		fromAddress, fromErr := chainaddr.ParseAddress(transfer.From)
+		if fromErr == nil && !fromAddress.IsZero() {
+			existFromAddress, fromAddressType = db.Addresses.AddressExist(businessId, fromAddress)
+		}
+		if !existToAddress && !existFromAddress {
+			continue
+		}
+		txType := transactionType(existFromAddress, fromAddressType, existToAddress, toAddressType)
+		log.Info("Found nft transfer", "txHash", transfer.Hash, "txType", txType, "contract", transfer.Contract, "tokenId", transfer.TokenId)
+		businessTransactions = append(businessTransactions, &Transaction{
+			BusinessId:    businessId,
+			BlockNumber:   transfer.BlockNumber,
+			FromAddress:   transfer.From,
+			ToAddress:     transfer.To,
+			Hash:          transfer.Hash,
+			TokenAddress:  transfer.Contract,
+			TxType:        txType,
+			TokenId:       transfer.TokenId.String(),
+			TokenStandard: transfer.Standard,
+			Amount:        transfer.Amount,
+		})
+	}
+	return businessTransactions
+}
+
+// nftMove 一次 NFT 持仓变动，随交易流水按哈希去重
+type nftMove struct {
+	hash    chainaddr.Hash
+	holding database.NftHoldings
+	debit   bool
+}
+
+// appendNftMoves 转出方为业务方地址时扣减持仓，转入方为业务方地址时增加持仓
+func (flows *businessFlows) appendNftMoves(owner addressOwner, tx *Transaction, timestamp uint64) {
+	hash := flowHash(tx.Hash)
+	tokenAddress := flowTokenAddress(tx.TokenAddress)
+	move := func(address string, debit bool) {
+		parsed, err := chainaddr.ParseAddress(address)
+		if err != nil || parsed.IsZero() {
+			return
+		}
+		if exist, _ := owner.AddressExist(tx.BusinessId, parsed); !exist {
+			return
+		}
+		flows.nftMoves = append(flows.nftMoves, nftMove{
+			hash:  hash,
+			debit: debit,
+			holding: database.NftHoldings{
+				GUID:         uuid.New(),
+				Address:      parsed,
+				TokenAddress: tokenAddress,
+				TokenId:      tx.TokenId,
+				Standard:     tx.TokenStandard,
+				Amount:       new(big.Int).Set(tx.Amount),
+				Timestamp:    timestamp,
+			},
+		})
+	}
+	move(tx.FromAddress, true)
+	move(tx.ToAddress, false)
+}
+
+// nftHoldings 拆分为转入和转出两组持仓变动
+func (flows *businessFlows) nftHoldings() (credits, debits []database.NftHoldings) {
+	for _, move := range flows.nftMoves {
+		if move.debit {
+			debits = append(debits, move.holding)
+		} else {
+			credits = append(credits, move.holding)
+		}
+	}
+	return credits, debits
+}
+
+// mergeNftTransactions NFT 转账按日志记录，同一哈希的外层交易不再按普通转账重复记录
+func mergeNftTransactions(txs, nftTxs []*Transaction) []*Transaction {
+	if len(nftTxs) == 0 {
+		return txs
+	}
+	nftHashes := make(map[chainaddr.Hash]bool, len(nftTxs))
+	for _, tx := range nftTxs {
+		nftHashes[flowHash(tx.Hash)] = true
+	}
+	merged := make([]*Transaction, 0, len(txs)+len(nftTxs))
+	for _, tx := range txs {
+		if !nftHashes[flowHash(tx.Hash)] {
+			merged = append(merged, tx)
+		}
+	}
+	return append(merged, nftTxs...)
+}
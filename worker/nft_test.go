@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/rpcclient"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+)
+
+func addressTopic(address string) string {
+	return "0x" + strings.Repeat("0", 24) + strings.TrimPrefix(address, "0x")
+}
+
+func word(v int64) string {
+	return strings.TrimPrefix(hexutil.EncodeBig(big.NewInt(v)), "0x")
+}
+
+func words(values ...int64) string {
+	var out string
+	for _, v := range values {
+		w := word(v)
+		out += strings.Repeat("0", 64-len(w)) + w
+	}
+	return out
+}
+
+func TestDecodeNftTransfers(t *testing.T) {
+	from := "0x00000000000000000000000000000000000000aa"
+	to := "0x00000000000000000000000000000000000000bb"
+	operator := "0x00000000000000000000000000000000000000cc"
+
+	erc721 := &rpcclient.Log{
+		Address:     "0x00000000000000000000000000000000000000dd",
+		Topics:      []string{erc721TransferTopic, addressTopic(from), addressTopic(to), "0x" + words(42)},
+		BlockNumber: "0x10",
+		TxHash:      "0x01",
+	}
+	transfers, err := decodeNftTransfers(erc721)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, from, transfers[0].From)
+	require.Equal(t, to, transfers[0].To)
+	require.Equal(t, int64(42), transfers[0].TokenId.Int64())
+	require.Equal(t, int64(1), transfers[0].Amount.Int64())
+	require.Equal(t, int64(16), transfers[0].BlockNumber.Int64())
+	require.Equal(t, database.NftStandardERC721, transfers[0].Standard)
+
+	// ERC-20 Transfer 只有 3 个 topic，不是 NFT
+	erc20 := &rpcclient.Log{Topics: erc721.Topics[:3], Data: "0x" + words(100), BlockNumber: "0x10"}
+	_, err = decodeNftTransfers(erc20)
+	require.ErrorIs(t, err, errInvalidNftLog)
+
+	single := &rpcclient.Log{
+		Topics:      []string{transferSingleTopic, addressTopic(operator), addressTopic(from), addressTopic(to)},
+		Data:        "0x" + words(7, 5),
+		BlockNumber: "0x10",
+	}
+	transfers, err = decodeNftTransfers(single)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, from, transfers[0].From)
+	require.Equal(t, int64(7), transfers[0].TokenId.Int64())
+	require.Equal(t, int64(5), transfers[0].Amount.Int64())
+	require.Equal(t, database.NftStandardERC1155, transfers[0].Standard)
+
+	batch := &rpcclient.Log{
+		Topics:      []string{transferBatchTopic, addressTopic(operator), addressTopic(from), addressTopic(to)},
+		Data:        "0x" + words(64, 160, 2, 1, 2, 2, 10, 20),
+		BlockNumber: "0x10",
+	}
+	transfers, err = decodeNftTransfers(batch)
+	require.NoError(t, err)
+	require.Len(t, transfers, 2)
+	require.Equal(t, int64(2), transfers[1].TokenId.Int64())
+	require.Equal(t, int64(20), transfers[1].Amount.Int64())
+
+	// 偏移量越界
+	batch.Data = "0x" + words(640, 160, 2, 1, 2, 2, 10, 20)
+	_, err = decodeNftTransfers(batch)
+	require.ErrorIs(t, err, errInvalidNftLog)
+}
+
+func TestNftFlows(t *testing.T) {
+	user := "0x0000000000000000000000000000000000000001"
+	hot := "0x0000000000000000000000000000000000000002"
+	external := "0x0000000000000000000000000000000000000003"
+	contract := "0x0000000000000000000000000000000000000004"
+	owner := fakeOwner{chainaddr.Address(user): 0, chainaddr.Address(hot): 1}
+	txItem := &account.TxMessage{Fee: "100", Datetime: "1700000000", Values: []*account.Value{{Value: "0"}}}
+	hash := "0x" + strings.Repeat("1", 64)
+
+	flows := &businessFlows{}
+	deposit := &Transaction{BusinessId: "b", BlockNumber: big.NewInt(1), FromAddress: external, ToAddress: user, Hash: hash, TokenAddress: contract, TxType: "deposit", TokenId: "42", TokenStandard: database.NftStandardERC721, Amount: big.NewInt(1)}
+	collection := &Transaction{BusinessId: "b", BlockNumber: big.NewInt(1), FromAddress: user, ToAddress: hot, Hash: hash, TokenAddress: contract, TxType: "collection", TokenId: "7", TokenStandard: database.NftStandardERC1155, Amount: big.NewInt(3)}
+	flows.add(owner, deposit, txItem, false)
+	flows.add(owner, collection, txItem, false)
+
+	require.Len(t, flows.deposits, 1)
+	require.Equal(t, "42", flows.deposits[0].TokenId)
+	require.Equal(t, database.NftStandardERC721, flows.deposits[0].TokenMeta)
+	require.Equal(t, int64(1), flows.deposits[0].Amount.Int64())
+	require.Len(t, flows.transactions, 2)
+
+	credits, debits := flows.nftHoldings()
+	require.Len(t, credits, 2)
+	require.Len(t, debits, 1)
+	require.Equal(t, chainaddr.Address(user), debits[0].Address)
+	require.Equal(t, int64(3), debits[0].Amount.Int64())
+	require.Equal(t, chainaddr.Address(hot), credits[1].Address)
+
+	outer := &Transaction{Hash: strings.ToUpper(hash[2:]), TxType: "unknow"}
+	other := &Transaction{Hash: "0x" + strings.Repeat("2", 64)}
+	merged := mergeNftTransactions([]*Transaction{outer, other}, []*Transaction{deposit})
+	require.Equal(t, []*Transaction{other, deposit}, merged)
+}
@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/notifier"
+	"github.com/CavnHan/multichain-sync-account/rpcclient"
+)
+
+// detectReorg 新批次第一个区块的父哈希与上一个已同步区块不一致时说明链发生了重组
+func detectReorg(lastHeader *rpcclient.BlockHeader, next rpcclient.BlockHeader) *notifier.Reorg {
+	if lastHeader == nil || lastHeader.Hash.IsZero() || next.ParentHash.IsZero() || next.ParentHash == lastHeader.Hash {
+		return nil
+	}
+	return &notifier.Reorg{
+		BlockNumber:        next.Number.Uint64(),
+		BlockHash:          next.Hash.String(),
+		ParentHash:         next.ParentHash.String(),
+		ExpectedParentHash: lastHeader.Hash.String(),
+	}
+}
+
+// publishReorg 向所有业务方写入 reorg 事件，已入库的交易不回滚，由业务方按事件自行核对
+func (syncer *BaseSynchronizer) publishReorg(reorg *notifier.Reorg) {
+	log.Warn("chain reorg detected", "number", reorg.BlockNumber, "parentHash", reorg.ParentHash, "expected", reorg.ExpectedParentHash)
+	for _, businessId := range syncer.registry.BusinessIds() {
+		event, err := notifier.NewReorgEvent(*reorg)
+		if err != nil {
+			log.Error("build reorg event fail", "err", err)
+			return
+		}
+		if err := syncer.database.Transaction(func(tx *database.DB) error {
+			return tx.Events.StoreEvents(businessId, []database.Events{event})
+		}); err != nil {
+			log.Error("store reorg event fail", "businessId", businessId, "err", err)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package worker
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/rpcclient"
+)
+
+func TestDetectReorg(t *testing.T) {
+	last := &rpcclient.BlockHeader{Hash: chainaddr.Hash("0x01"), Number: big.NewInt(9)}
+	next := rpcclient.BlockHeader{Hash: chainaddr.Hash("0x03"), ParentHash: chainaddr.Hash("0x01"), Number: big.NewInt(10)}
+	require.Nil(t, detectReorg(last, next))
+	require.Nil(t, detectReorg(nil, next))
+
+	next.ParentHash = chainaddr.Hash("0x02")
+	reorg := detectReorg(last, next)
+	require.NotNil(t, reorg)
+	require.Equal(t, uint64(10), reorg.BlockNumber)
+	require.Equal(t, "0x01", reorg.ExpectedParentHash)
+	require.Equal(t, "0x02", reorg.ParentHash)
+}
@@ -0,0 +1,149 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/rpcclient"
+)
+
+// MaxRescanBlocks 单次补扫允许的最大区块数，更大的区间需要分多次执行
+const MaxRescanBlocks = 10_000
+
+var ErrInvalidRescanRange = errors.New("invalid rescan block range")
+
+type RescanBusinessReport struct {
+	BusinessId string `json:"business_id"`
+	Found      int    `json:"found"`
+	Inserted   int    `json:"inserted"`
+	Skipped    int    `json:"skipped"`
+}
+
+type RescanReport struct {
+	FromHeight    uint64                  `json:"from_height"`
+	ToHeight      uint64                  `json:"to_height"`
+	BlocksScanned uint64                  `json:"blocks_scanned"`
+	Businesses    []*RescanBusinessReport `json:"businesses"`
+}
+
+// Rescanner 重新处理指定区块区间，用于补录遗漏的交易。
+// 不读写 blocks 表，不影响实时同步的游标；写入时与实时同步共用业务方锁并按交易哈希去重
+type Rescanner struct {
+	rpcClient  *rpcclient.WalletChainAccountClient
+	nodeClient *rpcclient.NodeClient
+	database   *database.DB
+	confirms   uint8
+}
+
+// NewRescanner rpcUrl 为链节点地址，EVM 链用于补扫 NFT 转账日志，为空时只补扫普通交易
+func NewRescanner(rpcClient *rpcclient.WalletChainAccountClient, rpcUrl string, db *database.DB, confirms uint8) *Rescanner {
+	return &Rescanner{
+		rpcClient:  rpcClient,
+		nodeClient: newNodeClient(rpcUrl),
+		database:   db,
+		confirms:   confirms,
+	}
+}
+
+// Rescan 补扫 [fromHeight, toHeight] 区间，businessId 为空时处理所有正常状态的业务方
+func (r *Rescanner) Rescan(ctx context.Context, businessId string, fromHeight, toHeight uint64) (*RescanReport, error) {
+	if fromHeight > toHeight {
+		return nil, fmt.Errorf("%w: from %d is greater than to %d", ErrInvalidRescanRange, fromHeight, toHeight)
+	}
+	if toHeight-fromHeight+1 > MaxRescanBlocks {
+		return nil, fmt.Errorf("%w: at most %d blocks per rescan", ErrInvalidRescanRange, MaxRescanBlocks)
+	}
+	businessIds, err := r.businessIds(businessId)
+	if err != nil {
+		return nil, err
+	}
+	latestHeader, err := r.rpcClient.GetBlockHeader(nil)
+	if err != nil {
+		log.Error("get latest block from chain account fail", "err", err)
+		return nil, err
+	}
+	if latestHeader == nil || latestHeader.Number.Uint64() < toHeight {
+		return nil, fmt.Errorf("%w: to %d is beyond chain head", ErrInvalidRescanRange, toHeight)
+	}
+
+	report := &RescanReport{FromHeight: fromHeight, ToHeight: toHeight}
+	businessReports := make(map[string]*RescanBusinessReport, len(businessIds))
+	for _, id := range businessIds {
+		businessReports[id] = &RescanBusinessReport{BusinessId: id}
+		report.Businesses = append(report.Businesses, businessReports[id])
+	}
+
+	log.Info("start rescan", "from", fromHeight, "to", toHeight, "businesses", len(businessIds))
+	for height := fromHeight; height <= toHeight; height++ {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		number := new(big.Int).SetUint64(height)
+		txList, err := r.rpcClient.GetBlockInfo(number)
+		if err != nil {
+			log.Error("get block info fail", "height", height, "err", err)
+			return report, err
+		}
+		var transfers []nftTransfer
+		if r.nodeClient != nil {
+			transfers, err = fetchNftTransfers(ctx, r.nodeClient, number, number)
+			if err != nil {
+				return report, err
+			}
+		}
+		for _, id := range businessIds {
+			txs := classifyTransactions(r.database, id, number, txList)
+			txs = mergeNftTransactions(txs, classifyNftTransfers(r.database, id, transfers))
+			if len(txs) == 0 {
+				continue
+			}
+			flows, err := buildBusinessFlows(r.rpcClient, r.database.Addresses, r.database.Memos, txs)
+			if err != nil {
+				return report, err
+			}
+			inserted, err := persistBusinessFlows(r.database, id, latestHeader.Number.Uint64(), r.confirms, flows, true)
+			if err != nil {
+				log.Error("persist rescan flows fail", "businessId", id, "height", height, "err", err)
+				return report, err
+			}
+			businessReport := businessReports[id]
+			businessReport.Found += len(flows.transactions)
+			businessReport.Inserted += inserted
+			businessReport.Skipped += len(flows.transactions) - inserted
+		}
+		report.BlocksScanned++
+	}
+	log.Info("rescan finished", "from", fromHeight, "to", toHeight, "blocks", report.BlocksScanned)
+	return report, nil
+}
+
+func (r *Rescanner) businessIds(businessId string) ([]string, error) {
+	if businessId != "" {
+		business, err := r.database.Business.QueryBusinessByUuid(businessId)
+		if err != nil {
+			return nil, err
+		}
+		if business == nil {
+			return nil, fmt.Errorf("business %s not found", businessId)
+		}
+		return []string{businessId}, nil
+	}
+	businessList, err := r.database.Business.QueryBusinessList()
+	if err != nil {
+		return nil, err
+	}
+	var businessIds []string
+	for _, business := range businessList {
+		if business.Status == database.BusinessStatusActive {
+			businessIds = append(businessIds, business.BusinessUid)
+		}
+	}
+	sort.Strings(businessIds)
+	return businessIds, nil
+}
@@ -0,0 +1,18 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRescanRejectsInvalidRange(t *testing.T) {
+	rescanner := NewRescanner(nil, "", nil, 0)
+
+	_, err := rescanner.Rescan(context.Background(), "", 10, 9)
+	require.ErrorIs(t, err, ErrInvalidRescanRange)
+
+	_, err = rescanner.Rescan(context.Background(), "", 1, MaxRescanBlocks+1)
+	require.ErrorIs(t, err, ErrInvalidRescanRange)
+}
@@ -0,0 +1,27 @@
+package worker
+
+import (
+	"errors"
+
+	"github.com/CavnHan/multichain-sync-account/common/clock"
+	"github.com/CavnHan/multichain-sync-account/common/tasks"
+	"github.com/CavnHan/multichain-sync-account/config"
+	"github.com/CavnHan/multichain-sync-account/database"
+)
+
+// NewSupervisorConfig 由配置生成 worker 的重启策略
+func NewSupervisorConfig(cfg *config.Config, clk clock.Clock) tasks.SupervisorConfig {
+	return tasks.SupervisorConfig{
+		FailureBudget: cfg.WorkerFailureBudget,
+		FailureWindow: cfg.WorkerFailureWindow,
+		Clock:         clk,
+	}
+}
+
+// supervised 失去租约无法通过重启恢复，其余数据库或 RPC 错误交给 supervisor 重启
+func supervised(err error) error {
+	if errors.Is(err, database.ErrLeaseLost) {
+		return tasks.Fatal(err)
+	}
+	return err
+}
@@ -6,12 +6,19 @@ import (
 	"math/big"
 	"time"
 
-	"github.com/ethereum/go-ethereum/common"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
 	"github.com/ethereum/go-ethereum/log"
 
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
 	"github.com/CavnHan/multichain-sync-account/common/clock"
+	"github.com/CavnHan/multichain-sync-account/config"
 	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/registry"
 	"github.com/CavnHan/multichain-sync-account/rpcclient"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/replay"
 )
 
 type Transaction struct {
@@ -23,6 +30,15 @@ type Transaction struct {
 	TokenAddress   string
 	ContractWallet string
 	TxType         string
+
+	// NFT 转账从事件日志解析，同一笔交易可能包含多个 token
+	TokenId       string
+	TokenStandard string
+	Amount        *big.Int
+
+	// 共享充值地址按 memo 区分用户，memo 缺失或未分配的充值隔离
+	Memo        string
+	Quarantined bool
 }
 
 type Config struct {
@@ -37,27 +53,31 @@ type BaseSynchronizer struct {
 	headerBufferSize uint64
 
 	businessChannels chan map[string]*TransactionsChannel
-	businessIds      []string
+	registry         *registry.Registry
 
 	rpcClient  *rpcclient.WalletChainAccountClient
+	nodeClient *rpcclient.NodeClient // 为空时不扫描 NFT 转账日志
 	blockBatch *rpcclient.BatchBlock
 	database   *database.DB
 
 	headers []rpcclient.BlockHeader
+	clock   clock.Clock
 	worker  *clock.LoopFn
 }
 
 type TransactionsChannel struct {
-	BlockHeight  uint64
-	ChannelId    string
-	Transactions []*Transaction
+	BlockHeight uint64
+	// ChainLatestBlock 处理批次时链上的最新高度，用于计算充值确认数
+	ChainLatestBlock uint64
+	ChannelId        string
+	Transactions     []*Transaction
 }
 
 func (syncer *BaseSynchronizer) Start() error {
 	if syncer.worker != nil {
 		return errors.New("already started")
 	}
-	syncer.worker = clock.NewLoopFn(clock.SystemClock, syncer.tick, func() error {
+	syncer.worker = clock.NewLoopFn(syncer.clock, syncer.tick, func() error {
 		log.Info("shutting down batch producer")
 		close(syncer.businessChannels)
 		return nil
@@ -76,12 +96,16 @@ func (syncer *BaseSynchronizer) tick(_ context.Context) {
 	if len(syncer.headers) > 0 {
 		log.Info("retrying previous batch")
 	} else {
+		lastHeader := syncer.blockBatch.LastTraversedHeader()
 		newHeaders, err := syncer.blockBatch.NextHeaders(syncer.headerBufferSize)
 		if err != nil {
 			log.Error("error querying for headers", "err", err)
 		} else if len(newHeaders) == 0 {
 			log.Warn("no new headers. syncer at head?")
 		} else {
+			if reorg := detectReorg(lastHeader, newHeaders[0]); reorg != nil {
+				syncer.publishReorg(reorg)
+			}
 			syncer.headers = newHeaders
 		}
 	}
@@ -97,6 +121,12 @@ func (syncer *BaseSynchronizer) processBatch(headers []rpcclient.BlockHeader) er
 	}
 	businessTxChannel := make(map[string]*TransactionsChannel)
 	blockHeaders := make([]database.Blocks, len(headers))
+	// 每个批次开始时读取一次快照，批次内的业务方集合保持一致
+	businessIds := syncer.registry.BusinessIds()
+	chainLatestBlock := headers[len(headers)-1].Number.Uint64()
+	if latestHeader := syncer.blockBatch.LatestHeader(); latestHeader != nil {
+		chainLatestBlock = latestHeader.Number.Uint64()
+	}
 
 	for i := range headers {
 		log.Info("Sync block data", "height", headers[i].Number)
@@ -106,69 +136,14 @@ func (syncer *BaseSynchronizer) processBatch(headers []rpcclient.BlockHeader) er
 			log.Error("get block info fail", "err", err)
 			return err
 		}
-		for _, businessId := range syncer.businessIds {
-			var businessTransactions []*Transaction
-			for _, tx := range txList {
-				toAddress := common.HexToAddress(tx.To)
-				fromAddress := common.HexToAddress(tx.From)
-				existToAddress, toAddressType := syncer.database.Addresses.AddressExist(businessId, &toAddress)
-				existFromAddress, FromAddressType := syncer.database.Addresses.AddressExist(businessId, &fromAddress)
-				if !existToAddress && !existFromAddress {
-					continue
-				}
-
-				log.Info("Found transaction", "txHash", tx.Hash, "from", fromAddress, "to", toAddress)
-
-				txItem := &Transaction{
-					BusinessId:     businessId,
-					BlockNumber:    headers[i].Number,
-					FromAddress:    tx.From,
-					ToAddress:      tx.To,
-					Hash:           tx.Hash,
-					//TODO FIX BUG
-					TokenAddress:   "tx.TokenAddress",
-					ContractWallet: "tx.ContractWallet",
-					TxType:         "unknow",
-				}
-
-				/*
-				 * If the 'from' address is an external address and the 'to' address is an internal user address, it is a deposit; call the callback interface to notifier the business side.
-				 * If the 'from' address is a user address and the 'to' address is a hot wallet address, it is consolidation; call the callback interface to notifier the business side.
-				 * If the 'from' address is a hot wallet address and the 'to' address is an external user address, it is a withdrawal; call the callback interface to notifier the business side.
-				 * If the 'from' address is a hot wallet address and the 'to' address is a cold wallet address, it is a hot-to-cold transfer; call the callback interface to notifier the business side.
-				 * If the 'from' address is a cold wallet address and the 'to' address is a hot wallet address, it is a cold-to-hot transfer; call the callback interface to notifier the business side.
-				 */
-				if !existFromAddress && (existToAddress && toAddressType == 0) { // 充值
-					log.Info("Found deposit transaction", "txHash", tx.Hash, "from", fromAddress, "to", toAddress)
-					txItem.TxType = "deposit"
-				}
-
-				if (existFromAddress && FromAddressType == 1) && !existToAddress { // 提现
-					log.Info("Found withdraw transaction", "txHash", tx.Hash, "from", fromAddress, "to", toAddress)
-					txItem.TxType = "withdraw"
-				}
-
-				if (existFromAddress && FromAddressType == 0) && (existToAddress && toAddressType == 1) { // 归集
-					log.Info("Found collection transaction", "txHash", tx.Hash, "from", fromAddress, "to", toAddress)
-					txItem.TxType = "collection"
-				}
-
-				if (existFromAddress && FromAddressType == 1) && (existToAddress && toAddressType == 2) { // 热转冷
-					log.Info("Found hot2cold transaction", "txHash", tx.Hash, "from", fromAddress, "to", toAddress)
-					txItem.TxType = "hot2cold"
-				}
-
-				if (existFromAddress && FromAddressType == 2) && (existToAddress && toAddressType == 1) { // 热转冷
-					log.Info("Found cold2hot transaction", "txHash", tx.Hash, "from", fromAddress, "to", toAddress)
-					txItem.TxType = "cold2hot"
-				}
-				businessTransactions = append(businessTransactions, txItem)
-			}
+		for _, businessId := range businessIds {
+			businessTransactions := classifyTransactions(syncer.database, businessId, headers[i].Number, txList)
 			if len(businessTransactions) > 0 {
 				if businessTxChannel[businessId] == nil {
 					businessTxChannel[businessId] = &TransactionsChannel{
-						BlockHeight:  headers[i].Number.Uint64(),
-						Transactions: businessTransactions,
+						BlockHeight:      headers[i].Number.Uint64(),
+						ChainLatestBlock: chainLatestBlock,
+						Transactions:     businessTransactions,
 					}
 				} else {
 					businessTxChannel[businessId].Transactions = append(businessTxChannel[businessId].Transactions, businessTransactions...)
@@ -177,9 +152,28 @@ func (syncer *BaseSynchronizer) processBatch(headers []rpcclient.BlockHeader) er
 		}
 	}
 
+	if syncer.nodeClient != nil {
+		transfers, err := fetchNftTransfers(context.Background(), syncer.nodeClient, headers[0].Number, headers[len(headers)-1].Number)
+		if err != nil {
+			return err
+		}
+		for _, businessId := range businessIds {
+			nftTransactions := classifyNftTransfers(syncer.database, businessId, transfers)
+			if len(nftTransactions) == 0 {
+				continue
+			}
+			if businessTxChannel[businessId] == nil {
+				businessTxChannel[businessId] = &TransactionsChannel{BlockHeight: nftTransactions[0].BlockNumber.Uint64(), ChainLatestBlock: chainLatestBlock}
+			}
+			businessTxChannel[businessId].Transactions = mergeNftTransactions(businessTxChannel[businessId].Transactions, nftTransactions)
+		}
+	}
+
 	if len(blockHeaders) > 0 {
 		log.Info("Store block headers success", "totalBlockHeader", len(blockHeaders))
-		if err := syncer.database.Blocks.StoreBlockss(blockHeaders); err != nil {
+		if err := syncer.database.Transaction(func(tx *database.DB) error {
+			return tx.Blocks.StoreBlockss(blockHeaders)
+		}); err != nil {
 			return err
 		}
 	}
@@ -190,3 +184,154 @@ func (syncer *BaseSynchronizer) processBatch(headers []rpcclient.BlockHeader) er
 
 	return nil
 }
+
+// classifyTransactions 筛选出区块中与业务方地址相关的交易并判断交易类型，实时同步和补扫共用
+func classifyTransactions(db *database.DB, businessId string, number *big.Int, txList []*account.BlockInfoTransactionList) []*Transaction {
+	var businessTransactions []*Transaction
+	seen := make(map[string]bool)
+	for _, tx := range txList {
+		// UTXO 链的区块交易列表可能按输出展开，同一笔交易只处理一次，输出在拉取交易详情后逐个处理
+		if seen[tx.Hash] {
+			continue
+		}
+		// 无法按当前链解析的地址不可能是业务方地址，直接视为外部地址
+		var existToAddress, existFromAddress bool
+		var toAddressType, FromAddressType uint8
+		toAddress, toErr := chainaddr.ParseAddress(tx.To)
+		if toErr == nil && !toAddress.IsZero() {
+			existToAddress, toAddressType = db.Addresses.AddressExist(businessId, toAddress)
+		}
+		fromAddress, fromErr := chainaddr.ParseAddress(tx.From)
+		if fromErr == nil && !fromAddress.IsZero() {
+			existFromAddress, FromAddressType = db.Addresses.AddressExist(businessId, fromAddress)
+		}
+		if !existToAddress && !existFromAddress {
+			continue
+		}
+
+		log.Info("Found transaction", "txHash", tx.Hash, "from", fromAddress, "to", toAddress)
+
+		txItem := &Transaction{
+			BusinessId:  businessId,
+			BlockNumber: number,
+			FromAddress: tx.From,
+			ToAddress:   tx.To,
+			Hash:        tx.Hash,
+			//TODO FIX BUG
+			TokenAddress:   "tx.TokenAddress",
+			ContractWallet: "tx.ContractWallet",
+			TxType:         "unknow",
+		}
+
+		txItem.TxType = transactionType(existFromAddress, FromAddressType, existToAddress, toAddressType)
+		log.Info("Classify transaction", "txHash", tx.Hash, "txType", txItem.TxType)
+		if chainaddr.IsUTXO() {
+			seen[tx.Hash] = true
+		}
+		businessTransactions = append(businessTransactions, txItem)
+	}
+	return businessTransactions
+}
+
+/*
+ * If the 'from' address is an external address and the 'to' address is an internal user address, it is a deposit; call the callback interface to notifier the business side.
+ * If the 'from' address is a user address and the 'to' address is a hot wallet address, it is consolidation; call the callback interface to notifier the business side.
+ * If the 'from' address is a hot wallet address and the 'to' address is an external user address, it is a withdrawal; call the callback interface to notifier the business side.
+ * If the 'from' address is a hot wallet address and the 'to' address is a cold wallet address, it is a hot-to-cold transfer; call the callback interface to notifier the business side.
+ * If the 'from' address is a cold wallet address and the 'to' address is a hot wallet address, it is a cold-to-hot transfer; call the callback interface to notifier the business side.
+ */
+func transactionType(existFromAddress bool, fromAddressType uint8, existToAddress bool, toAddressType uint8) string {
+	switch {
+	case !existFromAddress && (existToAddress && toAddressType == 0): // 充值
+		return "deposit"
+	case (existFromAddress && fromAddressType == 1) && !existToAddress: // 提现
+		return "withdraw"
+	case (existFromAddress && fromAddressType == 0) && (existToAddress && toAddressType == 1): // 归集
+		return "collection"
+	case (existFromAddress && fromAddressType == 1) && (existToAddress && toAddressType == 2): // 热转冷
+		return "hot2cold"
+	case (existFromAddress && fromAddressType == 2) && (existToAddress && toAddressType == 1): // 冷转热
+		return "cold2hot"
+	}
+	return "unknow"
+}
+
+// NewAccountClient 连接 chain-account 服务。配置了回放文件时离线应答，配置了录制文件时录制所有调用，
+// 返回的 closer 在所有 worker 停止后调用
+func NewAccountClient(cfg *config.Config) (*rpcclient.WalletChainAccountClient, func() error, error) {
+	if cfg.ChainAccountReplay != "" {
+		replayer, err := replay.Load(cfg.ChainAccountReplay)
+		if err != nil {
+			log.Error("load chain account recording fail", "err", err)
+			return nil, nil, err
+		}
+		client, err := rpcclient.NewWalletChainAccountClient(context.Background(), replayer, cfg.ChainNode.ChainName)
+		return client, func() error { return nil }, err
+	}
+
+	// 每个节点一个连接，由 Endpoints 负责超时、熔断和故障切换
+	var endpoints []rpcclient.Endpoint
+	var conns []*grpc.ClientConn
+	closeConns := func() error {
+		var result error
+		for _, conn := range conns {
+			result = errors.Join(result, conn.Close())
+		}
+		return result
+	}
+	for _, target := range cfg.ChainAccountEndpoints() {
+		conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			log.Error("Connect to da retriever fail", "endpoint", target, "err", err)
+			_ = closeConns()
+			return nil, nil, err
+		}
+		conns = append(conns, conn)
+		endpoints = append(endpoints, rpcclient.Endpoint{Name: target, Client: account.NewWalletAccountServiceClient(conn)})
+	}
+	pool, err := rpcclient.NewEndpoints(endpoints, rpcclient.EndpointsConfig{
+		Chain:         cfg.ChainNode.ChainName,
+		Timeout:       cfg.ChainAccount.Timeout,
+		MaxLag:        cfg.ChainAccount.MaxLag,
+		CheckInterval: cfg.ChainAccount.CheckInterval,
+	})
+	if err != nil {
+		_ = closeConns()
+		return nil, nil, err
+	}
+	pool.Start()
+	closer := func() error {
+		return errors.Join(pool.Close(), closeConns())
+	}
+
+	var rpc account.WalletAccountServiceClient = pool
+	if cfg.ChainAccountRecord != "" {
+		recorder, err := replay.NewRecorder(rpc, cfg.ChainAccountRecord, cfg.ChainAccountRecordWindow, clock.SystemClock)
+		if err != nil {
+			_ = closer()
+			return nil, nil, err
+		}
+		rpc = recorder
+		closePool := closer
+		closer = func() error {
+			return errors.Join(recorder.Close(), closePool())
+		}
+	}
+	client, err := rpcclient.NewWalletChainAccountClient(context.Background(), rpc, cfg.ChainNode.ChainName)
+	if err != nil {
+		_ = closer()
+		return nil, nil, err
+	}
+	if cfg.ChainAccount.Retries > 0 {
+		client.Retries = cfg.ChainAccount.Retries
+	}
+	return client, closer, nil
+}
+
+// newNodeClient 只有 EVM 链需要直接访问节点扫描 NFT 事件日志
+func newNodeClient(rpcUrl string) *rpcclient.NodeClient {
+	if rpcUrl == "" || chainaddr.Default().Family() != chainaddr.FamilyEVM {
+		return nil
+	}
+	return rpcclient.NewNodeClient(rpcUrl)
+}
@@ -0,0 +1,166 @@
+package worker
+
+import (
+	"math/big"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+)
+
+// addressOwner 判断地址是否属于业务方以及地址类型，0:用户地址 1:热钱包 2:冷钱包
+type addressOwner interface {
+	AddressExist(requestId string, address chainaddr.Address) (bool, uint8)
+}
+
+type utxoOutput struct {
+	vout        uint32
+	address     chainaddr.Address
+	amount      *big.Int
+	owned       bool
+	addressType uint8
+}
+
+// appendUtxo 处理 UTXO 链的交易。一笔交易有多个输入和输出，Tos 和 Values 按输出序号一一对应：
+// 外部转入时每个属于用户地址的输出各记一笔充值；热钱包花费时转回热钱包的输出是找零，不计入提现金额。
+// 属于业务方的输出都写入 UTXO 集合，供构建提现交易时选币
+func (flows *businessFlows) appendUtxo(owner addressOwner, tx *Transaction, txItem *account.TxMessage, historical bool) {
+	txHash := flowHash(tx.Hash)
+	txFee, _ := new(big.Int).SetString(txItem.Fee, 10)
+	timestamp, _ := strconv.Atoi(txItem.Datetime)
+
+	var fromAddress chainaddr.Address
+	fromOwned, fromType := false, uint8(0)
+	for _, from := range txItem.Froms {
+		address := flowAddress(from.Address)
+		if fromAddress.IsZero() {
+			fromAddress = address
+		}
+		if exist, addressType := owner.AddressExist(tx.BusinessId, address); exist {
+			fromAddress, fromOwned, fromType = address, true, addressType
+			break
+		}
+	}
+
+	var outputs []utxoOutput
+	for i, to := range txItem.Tos {
+		if i >= len(txItem.Values) {
+			break
+		}
+		amount, ok := new(big.Int).SetString(txItem.Values[i].Value, 10)
+		if !ok {
+			log.Warn("invalid utxo output value", "txHash", tx.Hash, "vout", i, "value", txItem.Values[i].Value)
+			continue
+		}
+		output := utxoOutput{vout: uint32(i), address: flowAddress(to.Address), amount: amount}
+		output.owned, output.addressType = owner.AddressExist(tx.BusinessId, output.address)
+		outputs = append(outputs, output)
+	}
+
+	newFlow := func(toAddress chainaddr.Address, amount *big.Int, txType uint8) database.Transactions {
+		return database.Transactions{
+			GUID:         uuid.New(),
+			BlockHash:    "",
+			BlockNumber:  tx.BlockNumber,
+			Hash:         txHash,
+			FromAddress:  fromAddress,
+			ToAddress:    toAddress,
+			TokenAddress: chainaddr.NativeToken(),
+			TokenId:      "0x00",
+			TokenMeta:    "0x00",
+			Fee:          txFee,
+			Amount:       amount,
+			Status:       0,
+			TxType:       txType,
+			Timestamp:    uint64(timestamp),
+		}
+	}
+
+	if !fromOwned {
+		for _, output := range outputs {
+			if !output.owned || output.addressType != 0 {
+				continue
+			}
+			flows.deposits = append(flows.deposits, database.Deposits{
+				GUID:         uuid.New(),
+				BlockHash:    "",
+				BlockNumber:  tx.BlockNumber,
+				Hash:         txHash,
+				FromAddress:  fromAddress,
+				ToAddress:    output.address,
+				TokenAddress: chainaddr.NativeToken(),
+				TokenId:      "0x00",
+				TokenMeta:    "0x00",
+				Fee:          txFee,
+				Amount:       output.amount,
+				Status:       0,
+				Historical:   historical,
+				Timestamp:    uint64(timestamp),
+			})
+			flows.transactions = append(flows.transactions, newFlow(output.address, output.amount, 0))
+		}
+	} else {
+		// 业务方地址花费的输出在构建交易时已锁定，同步到花费交易后标记为已花费
+		flows.spentHashes = append(flows.spentHashes, txHash)
+
+		external, externalTo := big.NewInt(0), chainaddr.Address("")
+		for _, output := range outputs {
+			switch {
+			case !output.owned:
+				external.Add(external, output.amount)
+				if externalTo.IsZero() {
+					externalTo = output.address
+				}
+			case fromType == 0 && output.addressType == 1:
+				flows.transactions = append(flows.transactions, newFlow(output.address, output.amount, 2))
+			case fromType == 1 && output.addressType == 2:
+				flows.transactions = append(flows.transactions, newFlow(output.address, output.amount, 3))
+			case fromType == 2 && output.addressType == 1:
+				flows.transactions = append(flows.transactions, newFlow(output.address, output.amount, 4))
+			}
+		}
+		if fromType == 1 && external.Sign() > 0 {
+			flows.withdraws = append(flows.withdraws, database.Withdraws{
+				GUID:         uuid.New(),
+				BlockHash:    "",
+				BlockNumber:  tx.BlockNumber,
+				Hash:         txHash,
+				FromAddress:  fromAddress,
+				ToAddress:    externalTo,
+				TokenAddress: chainaddr.NativeToken(),
+				TokenId:      "0x00",
+				TokenMeta:    "0x00",
+				Fee:          txFee,
+				Amount:       external,
+				Status:       2,
+				Timestamp:    uint64(timestamp),
+			})
+			flows.transactions = append(flows.transactions, newFlow(externalTo, external, 1))
+		}
+	}
+
+	// 历史充值的输出可能早已被花费，不写入 UTXO 集合
+	if historical {
+		return
+	}
+	for _, output := range outputs {
+		if !output.owned {
+			continue
+		}
+		flows.utxos = append(flows.utxos, database.Utxos{
+			GUID:        uuid.New(),
+			TxHash:      txHash,
+			Vout:        output.vout,
+			Address:     output.address,
+			Amount:      output.amount,
+			BlockNumber: tx.BlockNumber,
+			Status:      database.UtxoStatusUnspent,
+			Timestamp:   uint64(timestamp),
+		})
+	}
+}
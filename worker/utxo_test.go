@@ -0,0 +1,72 @@
+package worker
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/rpcclient/chain-account/account"
+)
+
+type fakeOwner map[chainaddr.Address]uint8
+
+func (o fakeOwner) AddressExist(_ string, address chainaddr.Address) (bool, uint8) {
+	addressType, ok := o[address]
+	return ok, addressType
+}
+
+func bitcoinAddress(b byte) string {
+	payload := make([]byte, 21)
+	payload[20] = b
+	return chainaddr.Base58CheckEncode(payload)
+}
+
+func utxoTx(from string, tos []string, values []string) *account.TxMessage {
+	txItem := &account.TxMessage{Fee: "500", Datetime: "1700000000"}
+	txItem.Froms = []*account.Address{{Address: from}}
+	for i := range tos {
+		txItem.Tos = append(txItem.Tos, &account.Address{Address: tos[i]})
+		txItem.Values = append(txItem.Values, &account.Value{Value: values[i]})
+	}
+	return txItem
+}
+
+func TestAppendUtxo(t *testing.T) {
+	require.NoError(t, chainaddr.SetDefaultChain("bitcoin"))
+	defer func() { require.NoError(t, chainaddr.SetDefaultChain("ethereum")) }()
+
+	external, user1, user2, hot := bitcoinAddress(1), bitcoinAddress(2), bitcoinAddress(3), bitcoinAddress(4)
+	owner := fakeOwner{chainaddr.Address(user1): 0, chainaddr.Address(user2): 0, chainaddr.Address(hot): 1}
+	hash := strings.Repeat("ab", 32)
+
+	// 外部转入的交易给两个用户地址各转一笔，各记一笔充值
+	flows := &businessFlows{}
+	tx := &Transaction{BusinessId: "b", BlockNumber: big.NewInt(10), Hash: hash}
+	flows.add(owner, tx, utxoTx(external, []string{user1, external, user2}, []string{"1000", "9000", "2000"}), false)
+	require.Len(t, flows.deposits, 2)
+	require.Equal(t, int64(1000), flows.deposits[0].Amount.Int64())
+	require.Equal(t, chainaddr.Address(user2), flows.deposits[1].ToAddress)
+	require.Len(t, flows.utxos, 2)
+	require.Equal(t, uint32(2), flows.utxos[1].Vout)
+	require.Empty(t, flows.spentHashes)
+
+	// 热钱包提现，转回热钱包的输出是找零
+	flows = &businessFlows{}
+	flows.add(owner, tx, utxoTx(hot, []string{external, hot}, []string{"7000", "3000"}), false)
+	require.Len(t, flows.withdraws, 1)
+	require.Equal(t, int64(7000), flows.withdraws[0].Amount.Int64())
+	require.Empty(t, flows.deposits)
+	require.Len(t, flows.utxos, 1)
+	require.Equal(t, chainaddr.Address(hot), flows.utxos[0].Address)
+	require.Len(t, flows.spentHashes, 1)
+
+	// 历史充值不写入 UTXO 集合
+	flows = &businessFlows{}
+	flows.add(owner, tx, utxoTx(external, []string{user1}, []string{"1000"}), true)
+	require.Len(t, flows.deposits, 1)
+	require.True(t, flows.deposits[0].Historical)
+	require.Empty(t, flows.utxos)
+}
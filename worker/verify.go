@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/google/uuid"
+
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/rpcclient"
+	"github.com/CavnHan/multichain-sync-account/txverify"
+)
+
+// sendVerified 校验签名交易后广播，返回交易哈希。签名交易与库中记录不一致时不广播，广播返回的哈希与本地计算的不一致时
+// 保留本地哈希，两种情况都告警并返回 blocked。UTXO 链的交易按锁定的 utxo 重建，不在此校验
+func sendVerified(client *rpcclient.WalletChainAccountClient, verifier *txverify.Verifier, businessId string, txType string, guid uuid.UUID, expected txverify.Expected, signedTx string) (chainaddr.Hash, bool, error) {
+	var signedHash chainaddr.Hash
+	if !chainaddr.IsUTXO() {
+		hash, err := verifier.Verify(businessId, expected, signedTx)
+		if errors.Is(err, txverify.ErrMismatch) {
+			txverify.Alert(businessId, txType, guid.String(), err)
+			return "", true, nil
+		}
+		if err != nil {
+			log.Error("verify signed transaction fail", "err", err)
+			return "", false, err
+		}
+		signedHash = hash
+	}
+	txHash, err := client.SendTx(signedTx)
+	if err != nil {
+		log.Error("send transaction fail", "err", err)
+		return "", false, err
+	}
+	if signedHash == "" {
+		return flowHash(txHash), false, nil
+	}
+	if err := txverify.CheckSentHash(signedHash, txHash); err != nil {
+		txverify.Alert(businessId, txType, guid.String(), err)
+		return signedHash, true, nil
+	}
+	return signedHash, false, nil
+}
@@ -6,45 +6,58 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/CavnHan/multichain-sync-account/common/chainaddr"
+	"github.com/CavnHan/multichain-sync-account/common/clock"
 	"github.com/CavnHan/multichain-sync-account/common/tasks"
 	"github.com/CavnHan/multichain-sync-account/config"
 	"github.com/CavnHan/multichain-sync-account/database"
+	"github.com/CavnHan/multichain-sync-account/registry"
 	"github.com/CavnHan/multichain-sync-account/rpcclient"
-	"github.com/ethereum/go-ethereum/common"
+	"github.com/CavnHan/multichain-sync-account/txverify"
 	"github.com/ethereum/go-ethereum/log"
 )
 
 type Withdraw struct {
-	rpcClient      *rpcclient.WalletChainAccountClient
-	db             *database.DB
-	chainNodeConf  *config.ChainNodeConfig
-	resourceCtx    context.Context
-	resourceCancel context.CancelFunc
-	tasks          tasks.Group
-	ticker         *time.Ticker
+	rpcClient     *rpcclient.WalletChainAccountClient
+	verifier      *txverify.Verifier
+	db            *database.DB
+	registry      *registry.Registry
+	chainNodeConf *config.ChainNodeConfig
+	// multisendContract 批量提现交易必须调用的合约，广播前校验
+	multisendContract chainaddr.Address
+	supervisor        *tasks.Supervisor
+	ticker            clock.Ticker
 }
 
-func NewWithdraw(cfg *config.Config, db *database.DB, shutdown context.CancelCauseFunc) (*Withdraw, error) {
-	resCtx, resCancel := context.WithCancel(context.Background())
+func NewWithdraw(cfg *config.Config, db *database.DB, reg *registry.Registry, accountClient *rpcclient.WalletChainAccountClient, clk clock.Clock, shutdown context.CancelCauseFunc) (*Withdraw, error) {
+	var multisendContract chainaddr.Address
+	if cfg.MultisendContract != "" && !chainaddr.IsUTXO() {
+		contract, err := chainaddr.ParseAddress(cfg.MultisendContract)
+		if err != nil {
+			return nil, fmt.Errorf("invalid multisend contract: %w", err)
+		}
+		multisendContract = contract
+	}
 	return &Withdraw{
-		db:             db,
-		chainNodeConf:  &cfg.ChainNode,
-		resourceCtx:    resCtx,
-		resourceCancel: resCancel,
-		tasks: tasks.Group{HandleCrit: func(err error) {
+		rpcClient:         accountClient,
+		verifier:          txverify.NewVerifier(accountClient, db),
+		db:                db,
+		registry:          reg,
+		chainNodeConf:     &cfg.ChainNode,
+		multisendContract: multisendContract,
+		supervisor: tasks.NewSupervisor(NewSupervisorConfig(cfg, clk), func(err error) {
 			shutdown(fmt.Errorf("critical error in withdraw: %w", err))
-		}},
-		ticker: time.NewTicker(time.Second * 5),
+		}),
+		ticker: clk.NewTicker(time.Second * 5),
 	}, nil
 }
 
 func (w *Withdraw) Close() error {
 	var result error
-	w.resourceCancel()
 	w.ticker.Stop()
 	log.Info("stop withdraw......")
-	if err := w.tasks.Wait(); err != nil {
-		result = errors.Join(result, fmt.Errorf("failed to await withdraw %w"), err)
+	if err := w.supervisor.Close(); err != nil {
+		result = errors.Join(result, fmt.Errorf("failed to await withdraw %w", err))
 		return result
 	}
 	log.Info("stop withdraw success")
@@ -53,46 +66,187 @@ func (w *Withdraw) Close() error {
 
 func (w *Withdraw) Start() error {
 	log.Info("start withdraw......")
-	w.tasks.Go(func() error {
-		for {
-			select {
-			case <-w.ticker.C:
-				businessList, err := w.db.Business.QueryBusinessList()
+	w.supervisor.Go("withdraw", func(ctx context.Context) error {
+		return supervised(w.run(ctx))
+	})
+	return nil
+}
+
+// Status 返回发送任务的运行状态和重启次数
+func (w *Withdraw) Status() []tasks.WorkerStatus {
+	return w.supervisor.Status()
+}
+
+func (w *Withdraw) run(ctx context.Context) error {
+	for {
+		select {
+		case <-w.ticker.Ch():
+			for _, businessId := range w.registry.BusinessIds() {
+				unSendTransactionList, err := w.db.Withdraws.UnSendWithdrawsList(businessId)
 				if err != nil {
-					log.Error("query business list fail", "err", err)
 					return err
 				}
 
-				for _, businessId := range businessList {
-					unSendTransactionList, err := w.db.Withdraws.UnSendWithdrawsList(businessId.BusinessUid)
+				for i := range unSendTransactionList {
+					// 广播无法回滚，发送前确认仍是主节点
+					if err := w.db.CheckFence(); err != nil {
+						log.Error("check leader lease fail, stop sending", "err", err)
+						return err
+					}
+					hash, blocked, err := sendVerified(w.rpcClient, w.verifier, businessId, "withdraw", unSendTransactionList[i].GUID, txverify.FromWithdraw(&unSendTransactionList[i]), unSendTransactionList[i].TxSignHex)
 					if err != nil {
 						return err
 					}
-
-					for _, unSendTransaction := range unSendTransactionList {
-						txHash, err := w.rpcClient.SendTx(unSendTransaction.TxSignHex)
-						if err != nil {
-							log.Error("send transaction fail", "err", err)
-							return err
-						} else {
-							unSendTransaction.Hash = common.HexToHash(txHash)
-							unSendTransaction.Status = 2
-						}
+					unSendTransactionList[i].Hash = hash
+					unSendTransactionList[i].Status = 2
+					if blocked {
+						unSendTransactionList[i].Status = txverify.StatusBlocked
 					}
-
-					err = w.db.Withdraws.UpdateWithdrawStatus(businessId.BusinessUid, 2, unSendTransactionList)
-					if err != nil {
+					// 每笔发送后立即落库，后续交易发送失败时已广播的交易不会在重启后重复广播
+					if err := w.markSent(businessId, unSendTransactionList[i]); err != nil {
 						log.Error("update withdraw status fail", "err", err)
 						return err
 					}
+				}
 
+				if err := w.sendWithdrawBatches(businessId); err != nil {
+					return err
 				}
 
-			case <-w.resourceCtx.Done():
-				log.Info("stop withdraw in worker")
-				return nil
+			}
+
+		case <-ctx.Done():
+			log.Info("stop withdraw in worker")
+			return nil
+		}
+	}
+}
+
+// verifyBatch EVM 链广播前按库中的批次和成员校验签名交易并返回本地计算的哈希，不一致时告警并整批拦截。
+// UTXO 链的交易按锁定的 utxo 构建，不在此校验
+func (w *Withdraw) verifyBatch(businessId string, batch *database.WithdrawBatches) (chainaddr.Hash, bool, error) {
+	if chainaddr.IsUTXO() {
+		return "", false, nil
+	}
+	batchId := batch.GUID.String()
+	members, err := w.db.Withdraws.QueryWithdrawsByBatchId(businessId, batchId)
+	if err != nil {
+		return "", false, err
+	}
+	hash, err := w.verifier.VerifyBatch(businessId, txverify.FromBatch(batch, members, w.multisendContract), batch.TxSignHex)
+	if errors.Is(err, txverify.ErrMismatch) {
+		txverify.Alert(businessId, "batch", batchId, err)
+		if err := w.db.Transaction(func(tx *database.DB) error {
+			return txverify.BlockBatch(tx, businessId, batchId)
+		}); err != nil {
+			log.Error("block mismatched batch fail", "batchId", batchId, "err", err)
+			return "", false, err
+		}
+		return "", true, nil
+	}
+	if err != nil {
+		log.Error("verify signed batch transaction fail", "batchId", batchId, "err", err)
+		return "", false, err
+	}
+	return hash, false, nil
+}
+
+// releaseBatch 节点明确拒绝的批次标记为失败，退回成员提现和锁定的 utxo
+func (w *Withdraw) releaseBatch(businessId string, batchId string) error {
+	return w.db.Transaction(func(tx *database.DB) error {
+		if err := tx.WithdrawBatches.UpdateWithdrawBatchStatus(businessId, batchId, database.WithdrawBatchStatusFailed, nil); err != nil {
+			return err
+		}
+		if err := tx.Withdraws.ReleaseBatchWithdraws(businessId, batchId); err != nil {
+			return err
+		}
+		return tx.Utxos.ReleaseUtxos(businessId, batchId)
+	})
+}
+
+// markSent 写入单笔提现广播后的哈希和状态，UTXO 链同时记录被花费的 utxo
+func (w *Withdraw) markSent(businessId string, withdraw database.Withdraws) error {
+	return w.db.Transaction(func(tx *database.DB) error {
+		if chainaddr.IsUTXO() {
+			if err := tx.Utxos.UpdateUtxosSpentHash(businessId, withdraw.GUID.String(), withdraw.Hash); err != nil {
+				log.Error("update utxo spent hash fail", "err", err)
+				return err
 			}
 		}
+		// 发送前库中还没有交易哈希，按 guid 写入哈希和状态，同步到链上交易时按哈希匹配
+		return tx.Withdraws.UpdateWithdrawsSent(businessId, []database.Withdraws{withdraw})
 	})
+}
+
+// sendWithdrawBatches 发送已签名的批量提现交易。EVM 链广播前按库中的批次和成员校验签名交易，不一致时整批拦截；
+// 广播结果未知或节点已有该交易时保持已签名状态，下一轮用同一笔签名交易重新广播；
+// 只有节点明确拒绝时整批退回，成员提现可以重新单笔或批量发起，上链失败的批次由 settleWithdrawBatches 退回
+func (w *Withdraw) sendWithdrawBatches(businessId string) error {
+	batches, err := w.db.WithdrawBatches.UnSendWithdrawBatches(businessId)
+	if err != nil {
+		return err
+	}
+	for _, batch := range batches {
+		batchId := batch.GUID.String()
+		if err := w.db.CheckFence(); err != nil {
+			log.Error("check leader lease fail, stop sending", "err", err)
+			return err
+		}
+		signedHash, blocked, err := w.verifyBatch(businessId, &batch)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			continue
+		}
+		txHash, err := w.rpcClient.SendTx(batch.TxSignHex)
+		if err != nil && signedHash != "" && !errors.Is(err, rpcclient.ErrBroadcastUnknown) {
+			// 节点拒绝时按本地哈希查询，能查到说明之前结果未知的广播已经成功
+			tx, queryErr := w.rpcClient.GetTransactionByHash(signedHash.String())
+			if queryErr != nil {
+				log.Warn("query rejected batch transaction fail, retry next round", "batchId", batchId, "err", queryErr)
+				continue
+			}
+			if tx != nil {
+				txHash, err = signedHash.String(), nil
+			}
+		}
+		if errors.Is(err, rpcclient.ErrBroadcastUnknown) || rpcclient.IsAlreadyKnown(err) {
+			log.Warn("batch transaction may have been broadcast, retry next round", "batchId", batchId, "err", err)
+			continue
+		}
+		if err != nil {
+			log.Error("batch transaction rejected", "batchId", batchId, "err", err)
+			if err := w.releaseBatch(businessId, batchId); err != nil {
+				log.Error("release rejected batch fail", "batchId", batchId, "err", err)
+				return err
+			}
+			continue
+		}
+		hash := flowHash(txHash)
+		if signedHash != "" {
+			// 交易已经广播，哈希不一致时告警并按本地哈希跟踪上链结果
+			if err := txverify.CheckSentHash(signedHash, txHash); err != nil {
+				txverify.Alert(businessId, "batch", batchId, err)
+			}
+			hash = signedHash
+		}
+		err = w.db.Transaction(func(tx *database.DB) error {
+			if err := tx.WithdrawBatches.UpdateWithdrawBatchSent(businessId, batchId, hash); err != nil {
+				return err
+			}
+			if err := tx.Withdraws.UpdateBatchWithdraws(businessId, batchId, 2, hash); err != nil {
+				return err
+			}
+			if chainaddr.IsUTXO() {
+				return tx.Utxos.UpdateUtxosSpentHash(businessId, batchId, hash)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Error("update batch withdraw status fail", "batchId", batchId, "err", err)
+			return err
+		}
+	}
 	return nil
 }